@@ -908,6 +908,8 @@ const (
 	ShowTriggers
 	ShowProcedureStatus
 	ShowIndex
+	ShowBindings
+	ShowProcessList
 )
 
 // ShowStmt is a statement to provide information about databases, tables, columns and so on.
@@ -924,7 +926,7 @@ type ShowStmt struct {
 	Full   bool
 	User   string // Used for show grants.
 
-	// Used by show variables
+	// Used by show variables and show bindings.
 	GlobalScope bool
 	Pattern     *PatternLikeExpr
 	Where       ExprNode
@@ -27,12 +27,15 @@ var (
 	_ StmtNode = &BeginStmt{}
 	_ StmtNode = &BinlogStmt{}
 	_ StmtNode = &CommitStmt{}
+	_ StmtNode = &CreateBindingStmt{}
 	_ StmtNode = &CreateUserStmt{}
 	_ StmtNode = &DeallocateStmt{}
 	_ StmtNode = &DoStmt{}
+	_ StmtNode = &DropBindingStmt{}
 	_ StmtNode = &ExecuteStmt{}
 	_ StmtNode = &ExplainStmt{}
 	_ StmtNode = &GrantStmt{}
+	_ StmtNode = &KillStmt{}
 	_ StmtNode = &PrepareStmt{}
 	_ StmtNode = &RollbackStmt{}
 	_ StmtNode = &SetPwdStmt{}
@@ -64,7 +67,8 @@ type AuthOption struct {
 	ByAuthString bool
 	AuthString   string
 	HashString   string
-	// TODO: support auth_plugin
+	// AuthPlugin is the authentication plugin of IDENTIFIED WITH, it's empty if not specified.
+	AuthPlugin string
 }
 
 // ExplainStmt is a statement to provide information about how is SQL statement executed
@@ -229,6 +233,27 @@ func (n *RollbackStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// KillStmt is a statement to kill a connection or the statement running in it.
+// See https://dev.mysql.com/doc/refman/5.7/en/kill.html
+type KillStmt struct {
+	stmtNode
+
+	// Query indicates whether to terminate only the statement the connection is executing,
+	// leaving the connection itself intact.
+	Query        bool
+	ConnectionID uint64
+}
+
+// Accept implements Node Accept interface.
+func (n *KillStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*KillStmt)
+	return v.Leave(n)
+}
+
 // UseStmt is a statement to use the DBName database as the current database.
 // See https://dev.mysql.com/doc/refman/5.7/en/use.html
 type UseStmt struct {
@@ -372,13 +397,42 @@ type UserSpec struct {
 	AuthOpt *AuthOption
 }
 
+// RequireType is the TLS requirement of user accounts in REQUIRE clause.
+type RequireType int
+
+// RequireType values.
+const (
+	// RequireUnspecified means there is no REQUIRE clause.
+	RequireUnspecified RequireType = iota
+	RequireNone
+	RequireSSL
+	RequireX509
+)
+
+// ResourceOptionType is the type of resource limits of user accounts.
+type ResourceOptionType int
+
+// ResourceOptionType values.
+const (
+	MaxQueriesPerHour ResourceOptionType = iota + 1
+	MaxUserConnections
+)
+
+// ResourceOption is a resource limit of user accounts in WITH clause, 0 means no limit.
+type ResourceOption struct {
+	Type  ResourceOptionType
+	Count uint64
+}
+
 // CreateUserStmt creates user account.
 // See https://dev.mysql.com/doc/refman/5.7/en/create-user.html
 type CreateUserStmt struct {
 	stmtNode
 
-	IfNotExists bool
-	Specs       []*UserSpec
+	IfNotExists     bool
+	Specs           []*UserSpec
+	Require         RequireType
+	ResourceOptions []*ResourceOption
 }
 
 // Accept implements Node Accept interface.
@@ -391,6 +445,43 @@ func (n *CreateUserStmt) Accept(v Visitor) (Node, bool) {
 	return v.Leave(n)
 }
 
+// CreateBindingStmt binds the statements like OriginSel to HintedSel, which is the same statement with index hints.
+type CreateBindingStmt struct {
+	stmtNode
+
+	GlobalScope bool
+	OriginSel   StmtNode
+	HintedSel   StmtNode
+}
+
+// Accept implements Node Accept interface.
+func (n *CreateBindingStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*CreateBindingStmt)
+	return v.Leave(n)
+}
+
+// DropBindingStmt drops the binding of the statements like OriginSel.
+type DropBindingStmt struct {
+	stmtNode
+
+	GlobalScope bool
+	OriginSel   StmtNode
+}
+
+// Accept implements Node Accept interface.
+func (n *DropBindingStmt) Accept(v Visitor) (Node, bool) {
+	newNode, skipChildren := v.Enter(n)
+	if skipChildren {
+		return v.Leave(newNode)
+	}
+	n = newNode.(*DropBindingStmt)
+	return v.Leave(n)
+}
+
 // DoStmt is the struct for DO statement.
 type DoStmt struct {
 	stmtNode
@@ -511,10 +602,12 @@ type GrantLevel struct {
 type GrantStmt struct {
 	stmtNode
 
-	Privs      []*PrivElem
-	ObjectType ObjectTypeType
-	Level      *GrantLevel
-	Users      []*UserSpec
+	Privs           []*PrivElem
+	ObjectType      ObjectTypeType
+	Level           *GrantLevel
+	Users           []*UserSpec
+	Require         RequireType
+	ResourceOptions []*ResourceOption
 }
 
 // Accept implements Node Accept interface.
@@ -0,0 +1,210 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bindinfo manages the SQL bindings. A binding makes the statements that differ from a statement only in
+// literals planned as another statement with index hints, so the plan of a statement can be pinned without
+// changing the SQL of the application.
+package bindinfo
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+)
+
+// BindRecord is a binding of the statements whose normalized text is OriginalSQL in DefaultDB.
+type BindRecord struct {
+	OriginalSQL string
+	BindSQL     string
+	DefaultDB   string
+	CreateTime  mysql.Time
+	UpdateTime  mysql.Time
+	// BindStmt is the parsed BindSQL, its index hints are used by the bound statements.
+	BindStmt ast.StmtNode
+}
+
+// Handle holds the bindings of a session or of all the sessions in a domain.
+type Handle struct {
+	mu       sync.RWMutex
+	bindings map[bindKey]*BindRecord
+	loaded   bool
+	// version is increased whenever the bindings change, so the cached plans made with the old bindings are not used.
+	version uint64
+}
+
+type bindKey struct {
+	originalSQL string
+	defaultDB   string
+}
+
+// NewHandle creates a Handle without bindings.
+func NewHandle() *Handle {
+	return &Handle{bindings: make(map[bindKey]*BindRecord)}
+}
+
+// Get returns the binding of the normalized statement in the database, or nil if there isn't one.
+func (h *Handle) Get(originalSQL, defaultDB string) *BindRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.bindings[bindKey{originalSQL: originalSQL, defaultDB: defaultDB}]
+}
+
+// Add adds a binding. The old binding of the same statement is replaced, but its create time is kept.
+func (h *Handle) Add(record *BindRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := bindKey{originalSQL: record.OriginalSQL, defaultDB: record.DefaultDB}
+	if old, ok := h.bindings[key]; ok {
+		record.CreateTime = old.CreateTime
+	}
+	h.bindings[key] = record
+	h.version++
+}
+
+// Remove removes the binding of the normalized statement in the database. It returns false if there isn't one.
+func (h *Handle) Remove(originalSQL, defaultDB string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := bindKey{originalSQL: originalSQL, defaultDB: defaultDB}
+	if _, ok := h.bindings[key]; !ok {
+		return false
+	}
+	delete(h.bindings, key)
+	h.version++
+	return true
+}
+
+// Version returns the version of the bindings, it is increased whenever the bindings change.
+func (h *Handle) Version() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.version
+}
+
+// Size returns the number of the bindings.
+func (h *Handle) Size() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.bindings)
+}
+
+// Bindings returns all the bindings ordered by the database and the normalized statement.
+func (h *Handle) Bindings() []*BindRecord {
+	h.mu.RLock()
+	records := make([]*BindRecord, 0, len(h.bindings))
+	for _, record := range h.bindings {
+		records = append(records, record)
+	}
+	h.mu.RUnlock()
+	sort.Sort(bindRecordSorter(records))
+	return records
+}
+
+// Loaded checks whether the bindings have been loaded by Reset.
+func (h *Handle) Loaded() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.loaded
+}
+
+// Reset replaces all the bindings by the records. The version is kept if the bindings don't change, so the
+// bindings can be reloaded periodically without invalidating the cached plans.
+func (h *Handle) Reset(records []*BindRecord) {
+	bindings := make(map[bindKey]*BindRecord, len(records))
+	for _, record := range records {
+		bindings[bindKey{originalSQL: record.OriginalSQL, defaultDB: record.DefaultDB}] = record
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loaded = true
+	if sameBindings(h.bindings, bindings) {
+		return
+	}
+	h.bindings = bindings
+	h.version++
+}
+
+func sameBindings(a, b map[bindKey]*BindRecord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, record := range a {
+		other, ok := b[key]
+		if !ok || other.BindSQL != record.BindSQL {
+			return false
+		}
+	}
+	return true
+}
+
+type bindRecordSorter []*BindRecord
+
+func (s bindRecordSorter) Len() int {
+	return len(s)
+}
+
+func (s bindRecordSorter) Less(i, j int) bool {
+	if s[i].DefaultDB != s[j].DefaultDB {
+		return s[i].DefaultDB < s[j].DefaultDB
+	}
+	return s[i].OriginalSQL < s[j].OriginalSQL
+}
+
+func (s bindRecordSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// A dummy type to avoid naming collision in context.
+type keyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k keyType) String() string {
+	if k == globalChangedKey {
+		return "bind_info_global_changed"
+	}
+	return "session_bind_info"
+}
+
+const sessionBindInfoKey keyType = 0
+
+// BindSessionHandle binds the Handle of the session bindings to context.
+func BindSessionHandle(ctx context.Context, h *Handle) {
+	ctx.SetValue(sessionBindInfoKey, h)
+}
+
+// GetSessionHandle gets the Handle of the session bindings from context.
+func GetSessionHandle(ctx context.Context) *Handle {
+	v, ok := ctx.Value(sessionBindInfoKey).(*Handle)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+const globalChangedKey keyType = 1
+
+// SetGlobalChanged marks that the transaction of the session changes the global bindings, the global bindings
+// are reloaded after the transaction is committed.
+func SetGlobalChanged(ctx context.Context) {
+	ctx.SetValue(globalChangedKey, true)
+}
+
+// TakeGlobalChanged checks whether the transaction of the session changes the global bindings, the mark is cleared.
+func TakeGlobalChanged(ctx context.Context) bool {
+	changed, _ := ctx.Value(globalChangedKey).(bool)
+	ctx.ClearValue(globalChangedKey)
+	return changed
+}
@@ -20,6 +20,7 @@ package tidb
 import (
 	"fmt"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
@@ -35,7 +36,7 @@ const (
 	CreateUserTable = `CREATE TABLE if not exists mysql.user (
 		Host			CHAR(64),
 		User			CHAR(16),
-		Password		CHAR(41),
+		Password		CHAR(70),
 		Select_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Insert_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Update_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
@@ -48,6 +49,12 @@ const (
 		Execute_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Index_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
 		Create_user_priv	ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Process_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		Super_priv		ENUM('N','Y') NOT NULL  DEFAULT 'N',
+		ssl_type		ENUM('','ANY','X509','SPECIFIED') NOT NULL  DEFAULT '',
+		plugin			CHAR(64) NOT NULL  DEFAULT 'mysql_native_password',
+		max_questions		INT UNSIGNED NOT NULL  DEFAULT 0,
+		max_user_connections	INT UNSIGNED NOT NULL  DEFAULT 0,
 		PRIMARY KEY (Host, User));`
 	// CreateDBPrivTable is the SQL statement creates DB scope privilege table in system db.
 	CreateDBPrivTable = `CREATE TABLE if not exists mysql.db (
@@ -113,6 +120,16 @@ const (
   		PRIMARY KEY (help_topic_id),
   		UNIQUE KEY name (name)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8 STATS_PERSISTENT=0 COMMENT='help topics';`
+
+	// CreateBindInfoTable is the SQL statement creates bind_info table in system db.
+	// original_sql is the normalized statement, and bind_sql is the statement with index hints it is planned as.
+	CreateBindInfoTable = `CREATE TABLE if not exists mysql.bind_info (
+		original_sql	VARCHAR(1024) NOT NULL,
+		bind_sql	TEXT NOT NULL,
+		default_db	VARCHAR(64) NOT NULL,
+		create_time	DATETIME NOT NULL,
+		update_time	DATETIME NOT NULL,
+		PRIMARY KEY (original_sql, default_db));`
 )
 
 // Bootstrap initiates system DB for a store.
@@ -131,8 +148,30 @@ func bootstrap(s Session) {
 const (
 	bootstrappedVar     = "bootstrapped"
 	bootstrappedVarTrue = "True"
+	// tidbServerVersionVar is the version of the system tables in mysql.tidb table, the stores
+	// bootstrapped before it's added are version 1.
+	tidbServerVersionVar = "tidb_server_version"
+	// currentBootstrapVersion is the version of the system tables created by bootstrap.
+	currentBootstrapVersion = version2
+)
+
+// The versions of the system tables.
+const (
+	version1 = 1
+	// version2 adds the privilege, SSL, authentication plugin and resource limit columns to mysql.user table.
+	version2 = 2
 )
 
+// userColumnsVer2 are the columns of mysql.user table added in version 2.
+var userColumnsVer2 = []string{
+	"Process_priv ENUM('N','Y') NOT NULL DEFAULT 'N'",
+	"Super_priv ENUM('N','Y') NOT NULL DEFAULT 'N'",
+	"ssl_type ENUM('','ANY','X509','SPECIFIED') NOT NULL DEFAULT ''",
+	"plugin CHAR(64) NOT NULL DEFAULT 'mysql_native_password'",
+	"max_questions INT UNSIGNED NOT NULL DEFAULT 0",
+	"max_user_connections INT UNSIGNED NOT NULL DEFAULT 0",
+}
+
 func checkBootstrapped(s Session) (bool, error) {
 	//  Check if system db exists.
 	_, err := s.Execute(fmt.Sprintf("USE %s;", mysql.SystemDB))
@@ -179,6 +218,72 @@ func checkBootstrappedVar(s Session) (bool, error) {
 	return isBootstrapped, nil
 }
 
+// upgrade upgrades the system tables of a store bootstrapped by an old version, the steps
+// can run again if another server is upgrading the store at the same time.
+func upgrade(s Session) {
+	ver, err := getBootstrapVersion(s)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if ver >= currentBootstrapVersion {
+		return
+	}
+	log.Infof("upgrade the system tables from version %d to %d", ver, currentBootstrapVersion)
+	if ver < version2 {
+		upgradeToVer2(s)
+	}
+	updateBootstrapVersion(s)
+}
+
+// updateBootstrapVersion records the current version of the system tables.
+func updateBootstrapVersion(s Session) {
+	mustExecute(s, fmt.Sprintf(`INSERT INTO %s.%s VALUES("%s", "%d", "Bootstrap version. Do not delete.")
+		ON DUPLICATE KEY UPDATE VARIABLE_VALUE="%d"`,
+		mysql.SystemDB, mysql.TiDBTable, tidbServerVersionVar, currentBootstrapVersion, currentBootstrapVersion))
+}
+
+// getBootstrapVersion gets the version of the system tables, it's version1 if the version isn't recorded.
+func getBootstrapVersion(s Session) (int64, error) {
+	sql := fmt.Sprintf(`SELECT VARIABLE_VALUE FROM %s.%s WHERE VARIABLE_NAME="%s"`,
+		mysql.SystemDB, mysql.TiDBTable, tidbServerVersionVar)
+	rs, err := s.Execute(sql)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if len(rs) != 1 {
+		return 0, errors.New("Wrong number of Recordset")
+	}
+	defer rs[0].Close()
+	row, err := rs[0].Next()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	ver := int64(version1)
+	if row != nil {
+		ver, err = strconv.ParseInt(row.Data[0].GetString(), 10, 64)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	// Make sure that doesn't affect the following operations.
+	return ver, errors.Trace(s.CommitTxn())
+}
+
+// upgradeToVer2 adds the columns of mysql.user table added in version 2. The users who can grant
+// privileges get the new privileges, so root can still kill and list the connections of the others.
+// The Password column stays CHAR(41) since the columns can't be modified, CREATE USER and SET PASSWORD
+// report an error if a password hash doesn't fit in it.
+func upgradeToVer2(s Session) {
+	for _, col := range userColumnsVer2 {
+		_, err := s.Execute(fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN %s", mysql.SystemDB, mysql.UserTable, col))
+		if err != nil && !infoschema.ErrColumnExists.Equal(err) {
+			log.Fatal(err)
+		}
+	}
+	mustExecute(s, fmt.Sprintf(`UPDATE %s.%s SET Process_priv="Y", Super_priv="Y" WHERE Grant_priv="Y"`,
+		mysql.SystemDB, mysql.UserTable))
+}
+
 // Execute DDL statements in bootstrap stage.
 func doDDLWorks(s Session) {
 	// Create a test database.
@@ -197,6 +302,8 @@ func doDDLWorks(s Session) {
 	mustExecute(s, CreateTiDBTable)
 	// Create help table.
 	mustExecute(s, CreateHelpTopic)
+	// Create bind_info table.
+	mustExecute(s, CreateBindInfoTable)
 }
 
 // Execute DML statements in bootstrap stage.
@@ -206,7 +313,7 @@ func doDMLWorks(s Session) {
 
 	// Insert a default user with empty password.
 	mustExecute(s, `INSERT INTO mysql.user VALUES
-		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")`)
+		("%", "root", "", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "", "mysql_native_password", 0, 0)`)
 
 	// Init global system variables table.
 	values := make([]string, 0, len(variable.SysVars))
@@ -222,6 +329,7 @@ func doDMLWorks(s Session) {
 		ON DUPLICATE KEY UPDATE VARIABLE_VALUE="%s"`,
 		mysql.SystemDB, mysql.TiDBTable, bootstrappedVar, bootstrappedVarTrue, bootstrappedVarTrue)
 	mustExecute(s, sql)
+	updateBootstrapVersion(s)
 	_, err := s.Execute("COMMIT")
 	if err != nil {
 		time.Sleep(1 * time.Second)
@@ -16,11 +16,14 @@ package distsql
 import (
 	"io"
 	"io/ioutil"
+	"sync/atomic"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/codec"
@@ -29,8 +32,7 @@ import (
 )
 
 var (
-	errInvalidResp = terror.ClassXEval.New(codeInvalidResp, "invalid response")
-	errNilResp     = terror.ClassXEval.New(codeNilResp, "client returns nil response")
+	errNilResp = terror.ClassXEval.New(codeNilResp, "client returns nil response")
 )
 
 var (
@@ -73,8 +75,17 @@ type selectResult struct {
 
 	results chan PartialResult
 	done    chan error
+
+	// killed points to the kill flag of the session, the request is cancelled
+	// when it is set by KILL QUERY.
+	killed *uint32
+	// details collects the waiting time and the rows of the statement, it can be nil.
+	details *variable.StmtExecDetails
 }
 
+// killCheckInterval is the interval to check the kill flag while waiting for the response.
+const killCheckInterval = 100 * time.Millisecond
+
 func (r *selectResult) Fetch() {
 	go r.fetch()
 }
@@ -97,6 +108,7 @@ func (r *selectResult) fetch() {
 			aggregate:  r.aggregate,
 			ignoreData: r.ignoreData,
 			done:       make(chan error),
+			details:    r.details,
 		}
 		go pr.fetch()
 		r.results <- pr
@@ -105,10 +117,34 @@ func (r *selectResult) fetch() {
 
 // Next returns the next row.
 func (r *selectResult) Next() (pr PartialResult, err error) {
-	var ok bool
-	select {
-	case pr, ok = <-r.results:
-	case err = <-r.done:
+	var (
+		ok     bool
+		ticker <-chan time.Time
+	)
+	if r.killed != nil {
+		t := time.NewTicker(killCheckInterval)
+		defer t.Stop()
+		ticker = t.C
+	}
+	if r.details != nil {
+		start := time.Now()
+		defer func() {
+			r.details.AddCopWaitTime(time.Since(start))
+		}()
+	}
+	for {
+		if r.isKilled() {
+			// Cancel the in-flight coprocessor requests.
+			r.resp.Close()
+			return nil, errors.Trace(variable.ErrQueryInterrupted)
+		}
+		select {
+		case pr, ok = <-r.results:
+		case err = <-r.done:
+		case <-ticker:
+			continue
+		}
+		break
 	}
 	if err != nil {
 		return nil, err
@@ -119,6 +155,10 @@ func (r *selectResult) Next() (pr PartialResult, err error) {
 	return
 }
 
+func (r *selectResult) isKilled() bool {
+	return r.killed != nil && atomic.LoadUint32(r.killed) == 1
+}
+
 // SetFields sets select result field types.
 func (r *selectResult) SetFields(fields []*types.FieldType) {
 	r.fields = fields
@@ -147,6 +187,7 @@ type partialResult struct {
 
 	done    chan error
 	fetched bool
+	details *variable.StmtExecDetails
 }
 
 func (pr *partialResult) fetch() {
@@ -163,7 +204,9 @@ func (pr *partialResult) fetch() {
 		return
 	}
 	if pr.resp.Error != nil {
-		pr.done <- errInvalidResp.Gen("[%d %s]", pr.resp.Error.GetCode(), pr.resp.Error.GetMsg())
+		// The error is returned by the expression evaluation of the coprocessor, report it as it is.
+		pr.done <- errors.New(pr.resp.Error.GetMsg())
+		return
 	}
 	pr.done <- nil
 }
@@ -174,6 +217,7 @@ var dummyData = make([]types.Datum, 0)
 // If no more row to return, data would be nil.
 func (pr *partialResult) Next() (handle int64, data []types.Datum, err error) {
 	if !pr.fetched {
+		start := time.Now()
 		select {
 		case err = <-pr.done:
 		}
@@ -181,6 +225,10 @@ func (pr *partialResult) Next() (handle int64, data []types.Datum, err error) {
 		if err != nil {
 			return 0, nil, err
 		}
+		if pr.details != nil {
+			pr.details.AddCopWaitTime(time.Since(start))
+			pr.details.AddRowsExamined(int64(pr.numRows()))
+		}
 	}
 	if len(pr.resp.Chunks) > 0 {
 		// For new resp rows structure.
@@ -235,6 +283,18 @@ func (pr *partialResult) Next() (handle int64, data []types.Datum, err error) {
 	return
 }
 
+// numRows returns the number of the rows in the response.
+func (pr *partialResult) numRows() int {
+	if len(pr.resp.Chunks) == 0 {
+		return len(pr.resp.Rows)
+	}
+	n := 0
+	for _, chunk := range pr.resp.Chunks {
+		n += len(chunk.RowsMeta)
+	}
+	return n
+}
+
 func (pr *partialResult) getChunk() *tipb.Chunk {
 	for {
 		if pr.chunkIdx >= len(pr.resp.Chunks) {
@@ -259,7 +319,10 @@ func (pr *partialResult) Close() error {
 // conncurrency: The max concurrency for underlying coprocessor request.
 // keepOrder: If the result should returned in key order. For example if we need keep data in order by
 //            scan index, we should set keepOrder to true.
-func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool) (SelectResult, error) {
+// killed: The kill flag of the session, the request is cancelled once it is set. It can be nil.
+// details: The execution details of the statement which the waiting time and the rows are added to. It can be nil.
+func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange, concurrency int, keepOrder bool,
+	killed *uint32, details *variable.StmtExecDetails) (SelectResult, error) {
 	// Convert tipb.*Request to kv.Request.
 	kvReq, err := composeRequest(req, keyRanges, concurrency, keepOrder)
 	if err != nil {
@@ -273,6 +336,8 @@ func Select(client kv.Client, req *tipb.SelectRequest, keyRanges []kv.KeyRange,
 		resp:    resp,
 		results: make(chan PartialResult, 5),
 		done:    make(chan error, 1),
+		killed:  killed,
+		details: details,
 	}
 	// If Aggregates is not nil, we should set result fields latter.
 	if len(req.Aggregates) == 0 && len(req.GroupBy) == 0 {
@@ -323,8 +388,7 @@ func SupportExpression(client kv.Client, expr *tipb.Expr) bool {
 
 // XAPI error codes.
 const (
-	codeInvalidResp = 1
-	codeNilResp     = 2
+	codeNilResp = 2
 )
 
 // FieldTypeFromPBColumn creates a types.FieldType from tipb.ColumnInfo.
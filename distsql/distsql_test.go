@@ -14,11 +14,16 @@
 package distsql
 
 import (
+	"io"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -43,3 +48,37 @@ func (s *testTableCodecSuite) TestColumnToProto(c *C) {
 	pc := columnToProto(col)
 	c.Assert(pc.GetFlag(), Equals, int32(10))
 }
+
+type mockResponse struct {
+	closed bool
+}
+
+func (resp *mockResponse) Next() (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (resp *mockResponse) Close() error {
+	resp.closed = true
+	return nil
+}
+
+func (s *testTableCodecSuite) TestSelectResultKilled(c *C) {
+	defer testleak.AfterTest(c)()
+	var killed uint32
+	resp := &mockResponse{}
+	result := &selectResult{
+		resp:    resp,
+		results: make(chan PartialResult, 5),
+		done:    make(chan error, 1),
+		killed:  &killed,
+	}
+	// Nothing is fetched, so Next keeps waiting until the statement is killed.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreUint32(&killed, 1)
+	}()
+	pr, err := result.Next()
+	c.Assert(pr, IsNil)
+	c.Assert(terror.ErrorEqual(err, variable.ErrQueryInterrupted), IsTrue)
+	c.Assert(resp.closed, IsTrue)
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xeval
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/evaluator"
+	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
+)
+
+// builtinFuncNames maps the pushed down control, string and time functions to the
+// names of their implementations in evaluator.Funcs, so that the coprocessor computes
+// exactly the same result as TiDB does.
+var builtinFuncNames = map[tipb.ExprType]string{
+	// control functions
+	tipb.ExprType_If:       "if",
+	tipb.ExprType_IfNull:   "ifnull",
+	tipb.ExprType_NullIf:   "nullif",
+	tipb.ExprType_Case:     ast.Case,
+	tipb.ExprType_Coalesce: "coalesce",
+
+	// string functions
+	tipb.ExprType_Concat:         "concat",
+	tipb.ExprType_ConcatWS:       "concat_ws",
+	tipb.ExprType_Left:           "left",
+	tipb.ExprType_Length:         "length",
+	tipb.ExprType_Lower:          "lower",
+	tipb.ExprType_Upper:          "upper",
+	tipb.ExprType_Repeat:         "repeat",
+	tipb.ExprType_Replace:        "replace",
+	tipb.ExprType_Strcmp:         "strcmp",
+	tipb.ExprType_Substring:      "substring",
+	tipb.ExprType_SubstringIndex: "substring_index",
+	tipb.ExprType_Locate:         "locate",
+	tipb.ExprType_Trim:           "trim",
+
+	// time functions
+	tipb.ExprType_Year:        "year",
+	tipb.ExprType_Month:       "month",
+	tipb.ExprType_Day:         "day",
+	tipb.ExprType_DayOfMonth:  "dayofmonth",
+	tipb.ExprType_DayOfWeek:   "dayofweek",
+	tipb.ExprType_DayOfYear:   "dayofyear",
+	tipb.ExprType_Weekday:     "weekday",
+	tipb.ExprType_WeekOfYear:  "weekofyear",
+	tipb.ExprType_DayName:     "dayname",
+	tipb.ExprType_Hour:        "hour",
+	tipb.ExprType_Minute:      "minute",
+	tipb.ExprType_Second:      "second",
+	tipb.ExprType_Microsecond: "microsecond",
+}
+
+func (e *Evaluator) evalBuiltinFunc(expr *tipb.Expr) (types.Datum, error) {
+	var d types.Datum
+	f, ok := evaluator.Funcs[builtinFuncNames[expr.GetTp()]]
+	if !ok {
+		return d, ErrInvalid.Gen("unsupported function %s", expr.GetTp())
+	}
+	if len(expr.Children) < f.MinArgs || (f.MaxArgs != -1 && len(expr.Children) > f.MaxArgs) {
+		return d, ErrInvalid.Gen("invalid argument count %d for %s", len(expr.Children), expr.GetTp())
+	}
+	args := make([]types.Datum, 0, len(expr.Children))
+	for _, child := range expr.Children {
+		arg, err := e.Eval(child)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		args = append(args, arg)
+	}
+	d, err := f.F(args, nil)
+	return d, errors.Trace(err)
+}
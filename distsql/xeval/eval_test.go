@@ -216,6 +216,27 @@ func (s *testEvalSuite) TestEval(c *C) {
 			notExpr(datumExpr(types.Datum{})),
 			types.Datum{},
 		},
+		// Arithmetic operations.
+		{
+			binaryExpr(types.NewIntDatum(3), types.NewIntDatum(5), tipb.ExprType_Minus),
+			types.NewIntDatum(-2),
+		},
+		{
+			binaryExpr(types.NewIntDatum(3), types.NewIntDatum(5), tipb.ExprType_Mul),
+			types.NewIntDatum(15),
+		},
+		{
+			binaryExpr(types.NewIntDatum(7), types.NewIntDatum(2), tipb.ExprType_IntDiv),
+			types.NewIntDatum(3),
+		},
+		{
+			binaryExpr(types.NewIntDatum(7), types.NewIntDatum(2), tipb.ExprType_Mod),
+			types.NewIntDatum(1),
+		},
+		{
+			binaryExpr(types.NewIntDatum(7), types.Datum{}, tipb.ExprType_Mul),
+			types.Datum{},
+		},
 	}
 	for _, ca := range cases {
 		result, err := xevaluator.Eval(ca.expr)
@@ -227,6 +248,138 @@ func (s *testEvalSuite) TestEval(c *C) {
 	}
 }
 
+func (s *testEvalSuite) TestEvalBuiltinFunc(c *C) {
+	row := make(map[int64]types.Datum)
+	tm, err := mysql.ParseTime("2016-10-09 12:34:56.789", mysql.TypeDatetime, mysql.MaxFsp)
+	c.Assert(err, IsNil)
+	row[1] = types.NewDatum(tm)
+	row[2] = types.NewStringDatum("Hello")
+	row[3] = types.Datum{}
+	xevaluator := &Evaluator{Row: row}
+	cases := []struct {
+		expr   *tipb.Expr
+		result types.Datum
+	}{
+		// Control functions.
+		{
+			funcExpr(tipb.ExprType_If, types.NewIntDatum(1), types.NewIntDatum(2), types.NewIntDatum(3)),
+			types.NewIntDatum(2),
+		},
+		{
+			funcExpr(tipb.ExprType_If, types.Datum{}, types.NewIntDatum(2), types.NewIntDatum(3)),
+			types.NewIntDatum(3),
+		},
+		{
+			funcExpr(tipb.ExprType_IfNull, columnExpr(3), types.NewIntDatum(2)),
+			types.NewIntDatum(2),
+		},
+		{
+			funcExpr(tipb.ExprType_NullIf, types.NewIntDatum(2), types.NewIntDatum(2)),
+			types.Datum{},
+		},
+		{
+			funcExpr(tipb.ExprType_Case, types.NewIntDatum(0), types.NewIntDatum(1),
+				types.NewIntDatum(1), types.NewIntDatum(2), types.NewIntDatum(3)),
+			types.NewIntDatum(2),
+		},
+		{
+			funcExpr(tipb.ExprType_Coalesce, columnExpr(3), types.NewIntDatum(5)),
+			types.NewIntDatum(5),
+		},
+		// String functions.
+		{
+			funcExpr(tipb.ExprType_Concat, columnExpr(2), types.NewStringDatum(" world")),
+			types.NewStringDatum("Hello world"),
+		},
+		{
+			funcExpr(tipb.ExprType_ConcatWS, types.NewStringDatum("-"), types.NewStringDatum("a"), types.NewStringDatum("b")),
+			types.NewStringDatum("a-b"),
+		},
+		{
+			funcExpr(tipb.ExprType_Left, columnExpr(2), types.NewIntDatum(2)),
+			types.NewStringDatum("He"),
+		},
+		{
+			funcExpr(tipb.ExprType_Length, columnExpr(2)),
+			types.NewIntDatum(5),
+		},
+		{
+			funcExpr(tipb.ExprType_Lower, columnExpr(2)),
+			types.NewStringDatum("hello"),
+		},
+		{
+			funcExpr(tipb.ExprType_Upper, columnExpr(2)),
+			types.NewStringDatum("HELLO"),
+		},
+		{
+			funcExpr(tipb.ExprType_Substring, columnExpr(2), types.NewIntDatum(2), types.NewIntDatum(3)),
+			types.NewStringDatum("ell"),
+		},
+		{
+			funcExpr(tipb.ExprType_Locate, types.NewStringDatum("l"), columnExpr(2)),
+			types.NewIntDatum(3),
+		},
+		{
+			funcExpr(tipb.ExprType_Upper, columnExpr(3)),
+			types.Datum{},
+		},
+		// Time functions.
+		{
+			funcExpr(tipb.ExprType_Year, columnExpr(1)),
+			types.NewIntDatum(2016),
+		},
+		{
+			funcExpr(tipb.ExprType_Month, columnExpr(1)),
+			types.NewIntDatum(10),
+		},
+		{
+			funcExpr(tipb.ExprType_DayOfMonth, columnExpr(1)),
+			types.NewIntDatum(9),
+		},
+		{
+			funcExpr(tipb.ExprType_Hour, columnExpr(1)),
+			types.NewIntDatum(12),
+		},
+		{
+			funcExpr(tipb.ExprType_Minute, columnExpr(1)),
+			types.NewIntDatum(34),
+		},
+		{
+			funcExpr(tipb.ExprType_Second, columnExpr(1)),
+			types.NewIntDatum(56),
+		},
+		{
+			funcExpr(tipb.ExprType_DayName, columnExpr(1)),
+			types.NewStringDatum("Sunday"),
+		},
+	}
+	for _, ca := range cases {
+		result, err := xevaluator.Eval(ca.expr)
+		c.Assert(err, IsNil)
+		c.Assert(result.Kind(), Equals, ca.result.Kind(), Commentf("%v", ca.expr.Tp))
+		cmp, err := result.CompareDatum(ca.result)
+		c.Assert(err, IsNil)
+		c.Assert(cmp, Equals, 0, Commentf("%v", ca.expr.Tp))
+	}
+
+	// Wrong argument count.
+	_, err = xevaluator.Eval(funcExpr(tipb.ExprType_If, types.NewIntDatum(1)))
+	c.Assert(err, NotNil)
+}
+
+func funcExpr(tp tipb.ExprType, args ...interface{}) *tipb.Expr {
+	expr := &tipb.Expr{Tp: tp}
+	for _, arg := range args {
+		switch x := arg.(type) {
+		case types.Datum:
+			expr.Children = append(expr.Children, datumExpr(x))
+		case *tipb.Expr:
+			expr.Children = append(expr.Children, x)
+		}
+	}
+	return expr
+}
+
 func binaryExpr(left, right interface{}, tp tipb.ExprType) *tipb.Expr {
 	expr := new(tipb.Expr)
 	expr.Tp = tp
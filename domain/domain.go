@@ -20,6 +20,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
@@ -42,6 +43,9 @@ type Domain struct {
 	lastLeaseTS    int64 // nano seconds
 	m              sync.Mutex
 	SchemaValidity *schemaValidityInfo
+	bindHandle     *bindinfo.Handle
+	exit           chan struct{}
+	exitOnce       sync.Once
 }
 
 func (do *Domain) loadInfoSchema(txn kv.Transaction) (err error) {
@@ -108,6 +112,11 @@ func (do *Domain) PerfSchema() perfschema.PerfSchema {
 	return do.infoHandle.GetPerfHandle()
 }
 
+// BindHandle gets the handle of the global bindings from domain.
+func (do *Domain) BindHandle() *bindinfo.Handle {
+	return do.bindHandle
+}
+
 // DDL gets DDL from domain.
 func (do *Domain) DDL() ddl.DDL {
 	return do.ddl
@@ -243,6 +252,8 @@ func (do *Domain) loadSchemaInLoop(lease time.Duration) {
 
 	for {
 		select {
+		case <-do.exit:
+			return
 		case <-ticker.C:
 			err := do.MustReload()
 			if err != nil {
@@ -323,7 +334,9 @@ func (s *schemaValidityInfo) Check(lastFailedTS uint64) error {
 // NewDomain creates a new domain.
 func NewDomain(store kv.Storage, lease time.Duration) (d *Domain, err error) {
 	d = &Domain{store: store,
-		SchemaValidity: &schemaValidityInfo{}}
+		SchemaValidity: &schemaValidityInfo{},
+		bindHandle:     bindinfo.NewHandle(),
+		exit:           make(chan struct{})}
 
 	d.infoHandle, err = infoschema.NewHandle(d.store)
 	if err != nil {
@@ -346,6 +359,19 @@ func NewDomain(store kv.Storage, lease time.Duration) (d *Domain, err error) {
 	return d, nil
 }
 
+// Close stops the background loops of the domain, it's called before the storage is closed.
+func (do *Domain) Close() {
+	do.exitOnce.Do(func() {
+		close(do.exit)
+	})
+}
+
+// Exited returns a channel that is closed when the domain is closed, the background
+// loops using the domain exit by it.
+func (do *Domain) Exited() <-chan struct{} {
+	return do.exit
+}
+
 // Domain error codes.
 const (
 	codeLoadSchemaTimeOut terror.ErrCode = 1
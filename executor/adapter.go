@@ -14,14 +14,23 @@
 package executor
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/db"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/slowlog"
 )
 
 // recordSet wraps an executor, implements ast.RecordSet interface
@@ -29,6 +38,22 @@ type recordSet struct {
 	fields   []*ast.ResultField
 	executor Executor
 	schema   expression.Schema
+	// chunk and cursor buffer the rows when the executor is a ChunkExecutor.
+	chunk  *Chunk
+	cursor int
+	// killed is the kill flag of the session.
+	killed *uint32
+	// slowLog logs the statement when the record set is closed, it is nil if the statement is not logged.
+	slowLog  *slowQuery
+	rowsSent int64
+}
+
+// checkKilled returns ErrQueryInterrupted if the running statement is killed by KILL QUERY.
+func checkKilled(killed *uint32) error {
+	if atomic.LoadUint32(killed) == 1 {
+		return errors.Trace(variable.ErrQueryInterrupted)
+	}
+	return nil
 }
 
 func (a *recordSet) Fields() ([]*ast.ResultField, error) {
@@ -50,15 +75,89 @@ func (a *recordSet) Fields() ([]*ast.ResultField, error) {
 }
 
 func (a *recordSet) Next() (*ast.Row, error) {
+	if err := checkKilled(a.killed); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if ce, ok := a.executor.(ChunkExecutor); ok {
+		if a.chunk == nil || a.cursor >= a.chunk.NumRows() {
+			chk, err := ce.NextChunk()
+			if err != nil || chk == nil {
+				return nil, errors.Trace(err)
+			}
+			a.chunk, a.cursor = chk, 0
+		}
+		row := a.chunk.Row(a.cursor)
+		a.cursor++
+		a.rowsSent++
+		return &ast.Row{Data: row.Data}, nil
+	}
 	row, err := a.executor.Next()
 	if err != nil || row == nil {
 		return nil, errors.Trace(err)
 	}
+	a.rowsSent++
 	return &ast.Row{Data: row.Data}, nil
 }
 
 func (a *recordSet) Close() error {
-	return a.executor.Close()
+	err := a.executor.Close()
+	if a.slowLog != nil {
+		a.slowLog.log(a.rowsSent)
+		a.slowLog = nil
+	}
+	return errors.Trace(err)
+}
+
+// slowQuery is a statement which may be written to the slow query log when it finishes.
+type slowQuery struct {
+	ctx       context.Context
+	text      string
+	plan      plan.Plan
+	execStart time.Time
+	// details are the execution details of the statement, the session may run other statements
+	// before the record set is closed.
+	details *variable.StmtExecDetails
+}
+
+// log writes the statement to the slow query log if it runs longer than long_query_time.
+func (q *slowQuery) log(rowsSent int64) {
+	now := time.Now()
+	vars := variable.GetSessionVars(q.ctx)
+	details := q.details
+	startTime := details.StartTime
+	if startTime.IsZero() {
+		startTime = q.execStart
+	}
+	queryTime := now.Sub(startTime)
+	if queryTime < vars.LongQueryTime {
+		return
+	}
+	user, host := vars.User, ""
+	if i := strings.LastIndex(user, "@"); i >= 0 {
+		user, host = user[:i], user[i+1:]
+	}
+	slowlog.Write(&slowlog.Entry{
+		StartTime:    startTime,
+		User:         user,
+		Host:         host,
+		ConnID:       vars.ConnectionID,
+		DB:           db.GetCurrentSchema(q.ctx),
+		QueryTime:    queryTime,
+		ParseTime:    details.ParseTime,
+		CompileTime:  details.CompileTime,
+		ExecTime:     now.Sub(q.execStart),
+		CopWaitTime:  time.Duration(atomic.LoadInt64(&details.CopWaitTime)),
+		RowsSent:     rowsSent,
+		RowsExamined: atomic.LoadInt64(&details.RowsExamined),
+		PlanDigest:   digest(plan.ToString(q.plan)),
+		Digest:       digest(parser.Normalize(q.text)),
+		SQL:          q.text,
+	})
+}
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
 }
 
 type statement struct {
@@ -82,6 +181,10 @@ func (a *statement) IsDDL() bool {
 }
 
 func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
+	var slow *slowQuery
+	if vars := variable.GetSessionVars(ctx); !vars.InRestrictedSQL {
+		slow = &slowQuery{ctx: ctx, text: a.text, plan: a.plan, execStart: time.Now(), details: vars.StmtDetails}
+	}
 	b := newExecutorBuilder(ctx, a.is)
 	e := b.build(a.plan)
 	if b.err != nil {
@@ -94,6 +197,9 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 			return nil, errors.Trace(err)
 		}
 		e = executorExec.StmtExec
+		if slow != nil {
+			slow.text, slow.plan = executorExec.Stmt.Text(), executorExec.Plan
+		}
 	}
 
 	if len(e.Fields()) == 0 && len(e.Schema()) == 0 {
@@ -108,7 +214,14 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 
 		// No result fields means no Recordset.
 		defer e.Close()
+		if slow != nil {
+			defer slow.log(0)
+		}
+		killed := &variable.GetSessionVars(ctx).Killed
 		for {
+			if err := checkKilled(killed); err != nil {
+				return nil, errors.Trace(err)
+			}
 			row, err := e.Next()
 			if err != nil {
 				return nil, errors.Trace(err)
@@ -130,5 +243,7 @@ func (a *statement) Exec(ctx context.Context) (ast.RecordSet, error) {
 		executor: e,
 		fields:   fs,
 		schema:   e.Schema(),
+		killed:   &variable.GetSessionVars(ctx).Killed,
+		slowLog:  slow,
 	}, nil
 }
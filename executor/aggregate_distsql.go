@@ -14,6 +14,7 @@
 package executor
 
 import (
+	"bytes"
 	"strings"
 
 	"github.com/juju/errors"
@@ -49,6 +50,8 @@ func (n *finalAggregater) update(count uint64, value types.Datum) error {
 		return n.updateMaxMin(value, true)
 	case ast.AggFuncMin:
 		return n.updateMaxMin(value, false)
+	case ast.AggFuncGroupConcat:
+		return n.updateGroupConcat(value)
 	}
 	return nil
 }
@@ -114,6 +117,24 @@ func (n *finalAggregater) updateSum(val types.Datum, count uint64) error {
 	return nil
 }
 
+func (n *finalAggregater) updateGroupConcat(val types.Datum) error {
+	ctx := n.getContext()
+	if val.IsNull() {
+		return nil
+	}
+	s, err := val.ToString()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ctx.Buffer == nil {
+		ctx.Buffer = &bytes.Buffer{}
+	} else {
+		ctx.Buffer.WriteString(",")
+	}
+	ctx.Buffer.WriteString(s)
+	return nil
+}
+
 // The argument if the name of a aggregate function.
 // This function will check if the aggregate function need count in partial result.
 func needCount(name string) bool {
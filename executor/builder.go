@@ -31,6 +31,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx/autocommit"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 )
@@ -41,13 +42,45 @@ type executorBuilder struct {
 	ctx context.Context
 	is  infoschema.InfoSchema
 	err error
+	// memTracker tracks the memory usage of the whole query against the session memory quota.
+	memTracker *memory.Tracker
+	// details are the execution details of the statement which the executors add to.
+	details *variable.StmtExecDetails
+
+	hashAggConcurrency    int
+	projectionConcurrency int
+	unionConcurrency      int
+	unionKeepOrder        bool
 }
 
 func newExecutorBuilder(ctx context.Context, is infoschema.InfoSchema) *executorBuilder {
-	return &executorBuilder{
-		ctx: ctx,
-		is:  is,
-	}
+	b := &executorBuilder{
+		ctx:                   ctx,
+		is:                    is,
+		hashAggConcurrency:    variable.DefHashAggConcurrency,
+		projectionConcurrency: variable.DefProjectionConcurrency,
+		unionConcurrency:      variable.DefUnionConcurrency,
+		unionKeepOrder:        variable.DefUnionKeepOrder,
+		details:               &variable.StmtExecDetails{},
+	}
+	quota := int64(variable.DefMemQuotaQuery)
+	if sessionVars := variable.GetSessionVars(ctx); sessionVars != nil {
+		quota = sessionVars.MemQuotaQuery
+		b.hashAggConcurrency = sessionVars.HashAggConcurrency
+		b.projectionConcurrency = sessionVars.ProjectionConcurrency
+		b.unionConcurrency = sessionVars.UnionConcurrency
+		b.unionKeepOrder = sessionVars.UnionKeepOrder
+		b.details = sessionVars.StmtDetails
+	}
+	b.memTracker = memory.NewTracker("query", quota)
+	return b
+}
+
+// newMemTracker creates a memory tracker for an executor that may spill to disk.
+func (b *executorBuilder) newMemTracker(label string) *memory.Tracker {
+	t := memory.NewTracker(label, -1)
+	t.AttachTo(b.memTracker)
+	return t
 }
 
 func (b *executorBuilder) build(p plan.Plan) Executor {
@@ -109,6 +142,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildTableScan(v, nil)
 	case *plan.PhysicalIndexScan:
 		return b.buildIndexScan(v, nil)
+	case *plan.PhysicalIndexMerge:
+		return b.buildIndexMerge(v)
 	case *plan.TableDual:
 		return b.buildTableDual(v)
 	case *plan.PhysicalApply:
@@ -121,6 +156,8 @@ func (b *executorBuilder) build(p plan.Plan) Executor {
 		return b.buildTrim(v)
 	case *plan.PhysicalDummyScan:
 		return b.buildDummyScan(v)
+	case *plan.PointGetPlan:
+		return b.buildPointGet(v)
 	default:
 		b.err = ErrUnknownPlan.Gen("Unknown Plan %T", p)
 		return nil
@@ -215,7 +252,13 @@ func (b *executorBuilder) buildLimit(v *plan.Limit) Executor {
 }
 
 func (b *executorBuilder) buildDistinct(v *plan.Distinct) Executor {
-	return &DistinctExec{Src: b.build(v.GetChildByIndex(0)), schema: v.GetSchema()}
+	src := b.build(v.GetChildByIndex(0))
+	if union, ok := src.(*UnionExec); ok && union.Concurrency > 1 {
+		// The union workers remove the duplicated rows of their own children in parallel,
+		// so the final distinct only checks the remaining rows.
+		union.partialDistinct = true
+	}
+	return &DistinctExec{Src: src, schema: v.GetSchema()}
 }
 
 func (b *executorBuilder) buildPrepare(v *plan.Prepare) Executor {
@@ -335,11 +378,13 @@ func (b *executorBuilder) buildReplace(vals *InsertValues) Executor {
 
 func (b *executorBuilder) buildGrant(grant *ast.GrantStmt) Executor {
 	return &GrantExec{
-		ctx:        b.ctx,
-		Privs:      grant.Privs,
-		ObjectType: grant.ObjectType,
-		Level:      grant.Level,
-		Users:      grant.Users,
+		ctx:             b.ctx,
+		Privs:           grant.Privs,
+		ObjectType:      grant.ObjectType,
+		Level:           grant.Level,
+		Users:           grant.Users,
+		Require:         grant.Require,
+		ResourceOptions: grant.ResourceOptions,
 	}
 }
 
@@ -368,6 +413,13 @@ func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) *UnionSc
 		us.buildAndSortAddedRows(x.table, x.asName)
 	case *XSelectIndexExec:
 		us.desc = x.indexPlan.Desc
+		// The merged handles of index merge are not in any index order.
+		if x.indexMerge != nil {
+			us.dirty = getDirtyDB(b.ctx).getDirtyTable(x.table.Meta().ID)
+			us.condition = v.Condition
+			us.buildAndSortAddedRows(x.table, x.asName)
+			break
+		}
 		for _, ic := range x.indexPlan.Index.Columns {
 			for i, col := range x.indexPlan.GetSchema() {
 				if col.ColName.L == ic.Name.L {
@@ -402,6 +454,7 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 		ctx:         b.ctx,
 		targetTypes: targetTypes,
 		concurrency: v.Concurrency,
+		memTracker:  b.newMemTracker("hash join"),
 	}
 	if v.SmallTable == 1 {
 		e.smallFilter = expression.ComposeCNFCondition(v.RightConditions)
@@ -464,6 +517,7 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 		smallHashKey: rightHashKey,
 		withAux:      v.WithAux,
 		anti:         v.Anti,
+		nullAware:    v.NullAware,
 		targetTypes:  targetTypes,
 	}
 	return e
@@ -471,12 +525,23 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 
 func (b *executorBuilder) buildAggregation(v *plan.Aggregation) Executor {
 	src := b.build(v.GetChildByIndex(0))
+	if v.Streamed {
+		return &StreamAggExec{
+			Src:          src,
+			schema:       v.GetSchema(),
+			ctx:          b.ctx,
+			AggFuncs:     v.AggFuncs,
+			GroupByItems: v.GroupByItems,
+		}
+	}
 	e := &AggregationExec{
 		Src:          src,
 		schema:       v.GetSchema(),
 		ctx:          b.ctx,
 		AggFuncs:     v.AggFuncs,
 		GroupByItems: v.GroupByItems,
+		Concurrency:  b.hashAggConcurrency,
+		memTracker:   b.newMemTracker("hash aggregation"),
 	}
 	// Check if the underlying is distsql executor, we should try to push aggregate function down.
 	xSrc, ok := src.(XExecutor)
@@ -589,15 +654,16 @@ func (b *executorBuilder) buildSelection(v *plan.Selection) Executor {
 
 func (b *executorBuilder) buildProjection(v *plan.Projection) Executor {
 	return &ProjectionExec{
-		Src:    b.build(v.GetChildByIndex(0)),
-		ctx:    b.ctx,
-		exprs:  v.Exprs,
-		schema: v.GetSchema(),
+		Src:         b.build(v.GetChildByIndex(0)),
+		ctx:         b.ctx,
+		exprs:       v.Exprs,
+		schema:      v.GetSchema(),
+		Concurrency: b.projectionConcurrency,
 	}
 }
 
 func (b *executorBuilder) buildTableDual(v *plan.TableDual) Executor {
-	return &TableDualExec{schema: v.GetSchema()}
+	return &TableDualExec{schema: v.GetSchema(), empty: v.Empty, executed: v.Empty}
 }
 
 func (b *executorBuilder) getStartTS() uint64 {
@@ -630,6 +696,7 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan, s *plan.Sele
 		st := &XSelectTableExec{
 			tableInfo:   v.Table,
 			ctx:         b.ctx,
+			details:     b.details,
 			startTS:     startTS,
 			supportDesc: supportDesc,
 			asName:      v.TableAsName,
@@ -644,11 +711,20 @@ func (b *executorBuilder) buildTableScan(v *plan.PhysicalTableScan, s *plan.Sele
 		}
 		return st
 	}
+	if v.DBName.L == strings.ToLower(infoschema.Name) && infoschema.IsDynamicTable(v.Table.Name.L) {
+		return &DynamicTableScanExec{
+			tableName: v.Table.Name,
+			ctx:       b.ctx,
+			schema:    v.GetSchema(),
+			columns:   v.Columns,
+		}
+	}
 
 	ts := &TableScanExec{
 		t:          table,
 		asName:     v.TableAsName,
 		ctx:        b.ctx,
+		details:    b.details,
 		columns:    v.Columns,
 		schema:     v.GetSchema(),
 		seekHandle: math.MinInt64,
@@ -677,6 +753,7 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan, s *plan.Sele
 		st := &XSelectIndexExec{
 			tableInfo:   v.Table,
 			ctx:         b.ctx,
+			details:     b.details,
 			supportDesc: supportDesc,
 			asName:      v.TableAsName,
 			table:       table,
@@ -690,9 +767,36 @@ func (b *executorBuilder) buildIndexScan(v *plan.PhysicalIndexScan, s *plan.Sele
 	return nil
 }
 
+func (b *executorBuilder) buildIndexMerge(v *plan.PhysicalIndexMerge) Executor {
+	startTS := b.getStartTS()
+	if b.err != nil {
+		return nil
+	}
+	table, _ := b.is.TableByID(v.Table.ID)
+	// The first partial index scan is used to read the rows by the merged handles.
+	indexPlan := *v.IndexScans[0]
+	indexPlan.Columns = v.Columns
+	indexPlan.DoubleRead = true
+	indexPlan.OutOfOrder = true
+	indexPlan.Desc = false
+	indexPlan.LimitCount = nil
+	indexPlan.SetSchema(v.GetSchema())
+	return &XSelectIndexExec{
+		tableInfo:  v.Table,
+		ctx:        b.ctx,
+		details:    b.details,
+		asName:     v.TableAsName,
+		table:      table,
+		indexPlan:  &indexPlan,
+		indexMerge: v,
+		startTS:    startTS,
+	}
+}
+
 func (b *executorBuilder) buildSort(v *plan.Sort) Executor {
 	src := b.build(v.GetChildByIndex(0))
 	if v.ExecLimit != nil {
+		b.pushTopN(src, v)
 		return &TopnExec{
 			SortExec: SortExec{
 				Src:     src,
@@ -703,11 +807,59 @@ func (b *executorBuilder) buildSort(v *plan.Sort) Executor {
 		}
 	}
 	return &SortExec{
-		Src:     src,
-		ByItems: v.ByItems,
-		ctx:     b.ctx,
-		schema:  v.GetSchema(),
+		Src:        src,
+		ByItems:    v.ByItems,
+		ctx:        b.ctx,
+		schema:     v.GetSchema(),
+		memTracker: b.newMemTracker("sort"),
+	}
+}
+
+// pushTopN tries to push the sort items and the limit down to the distsql executor, so that every region
+// only returns its top n rows. The TopnExec above still merges the partial results.
+func (b *executorBuilder) pushTopN(src Executor, v *plan.Sort) {
+	byItems := make([]*plan.ByItems, 0, len(v.ByItems))
+	for _, item := range v.ByItems {
+		byItems = append(byItems, &plan.ByItems{Expr: item.Expr, Desc: item.Desc})
+	}
+	// A projection doesn't change the number of rows, the order by items can be pushed through it.
+	if proj, ok := src.(*ProjectionExec); ok {
+		for _, item := range byItems {
+			item.Expr = substituteProjectedColumns(item.Expr, proj.schema, proj.exprs)
+		}
+		src = proj.Src
+	}
+	xSrc, ok := src.(XExecutor)
+	if !ok {
+		return
+	}
+	client := b.ctx.GetClient()
+	orderBy := make([]*tipb.ByItem, 0, len(byItems))
+	for _, item := range byItems {
+		pbExpr := b.ExprToPBExpr(client, item.Expr, xSrc.GetTable())
+		if pbExpr == nil {
+			return
+		}
+		orderBy = append(orderBy, &tipb.ByItem{Expr: pbExpr, Desc: item.Desc})
+	}
+	xSrc.AddTopN(orderBy, int64(v.ExecLimit.Offset+v.ExecLimit.Count))
+}
+
+// substituteProjectedColumns replaces the columns of the projection schema in expr with the projected expressions.
+func substituteProjectedColumns(expr expression.Expression, schema expression.Schema, exprs []expression.Expression) expression.Expression {
+	switch v := expr.(type) {
+	case *expression.Column:
+		if idx := schema.GetIndex(v); idx != -1 {
+			return exprs[idx]
+		}
+	case *expression.ScalarFunction:
+		newFunc := v.DeepCopy().(*expression.ScalarFunction)
+		for i, arg := range newFunc.Args {
+			newFunc.Args[i] = substituteProjectedColumns(arg, schema, exprs)
+		}
+		return newFunc
 	}
+	return expr
 }
 
 func (b *executorBuilder) buildApply(v *plan.PhysicalApply) Executor {
@@ -753,9 +905,12 @@ func (b *executorBuilder) buildTrim(v *plan.Trim) Executor {
 
 func (b *executorBuilder) buildUnion(v *plan.Union) Executor {
 	e := &UnionExec{
-		schema: v.GetSchema(),
-		fields: v.Fields(),
-		Srcs:   make([]Executor, len(v.GetChildren())),
+		schema:      v.GetSchema(),
+		fields:      v.Fields(),
+		Srcs:        make([]Executor, len(v.GetChildren())),
+		ctx:         b.ctx,
+		Concurrency: b.unionConcurrency,
+		KeepOrder:   b.unionKeepOrder,
 	}
 	for i, sel := range v.GetChildren() {
 		selExec := b.build(sel)
@@ -775,6 +930,17 @@ func (b *executorBuilder) buildDummyScan(v *plan.PhysicalDummyScan) Executor {
 	}
 }
 
+func (b *executorBuilder) buildPointGet(v *plan.PointGetPlan) Executor {
+	table, _ := b.is.TableByID(v.Table.ID)
+	return &PointGetExec{
+		ctx:     b.ctx,
+		details: b.details,
+		plan:    v,
+		table:   table,
+		schema:  v.GetSchema(),
+	}
+}
+
 func (b *executorBuilder) buildDelete(v *plan.Delete) Executor {
 	selExec := b.build(v.SelectPlan)
 	return &DeleteExec{
@@ -0,0 +1,182 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// maxChunkSize is the max number of rows in a chunk.
+var maxChunkSize = 1024
+
+// ChunkExecutor is an Executor that can return a batch of rows at a time.
+// An executor is consumed either by Next or by NextChunk, the two must not be mixed.
+type ChunkExecutor interface {
+	Executor
+	// NextChunk returns the next chunk of at most maxChunkSize rows, nil means there is no more data.
+	NextChunk() (*Chunk, error)
+}
+
+// Chunk is a batch of rows stored column by column.
+type Chunk struct {
+	columns [][]types.Datum
+	rowKeys [][]*RowKeyEntry
+}
+
+func newChunk(numCols int) *Chunk {
+	c := &Chunk{
+		columns: make([][]types.Datum, numCols),
+		rowKeys: make([][]*RowKeyEntry, 0, maxChunkSize),
+	}
+	for i := range c.columns {
+		c.columns[i] = make([]types.Datum, 0, maxChunkSize)
+	}
+	return c
+}
+
+// NumRows returns the number of rows in the chunk.
+func (c *Chunk) NumRows() int {
+	return len(c.rowKeys)
+}
+
+// NumCols returns the number of columns in the chunk.
+func (c *Chunk) NumCols() int {
+	return len(c.columns)
+}
+
+// Column returns the values of the i-th column.
+func (c *Chunk) Column(i int) []types.Datum {
+	return c.columns[i]
+}
+
+// AppendRow appends a row to the chunk.
+func (c *Chunk) AppendRow(row *Row) {
+	c.appendDatums(row.Data, row.RowKeys)
+}
+
+func (c *Chunk) appendDatums(data []types.Datum, rowKeys []*RowKeyEntry) {
+	for i := range c.columns {
+		c.columns[i] = append(c.columns[i], data[i])
+	}
+	c.rowKeys = append(c.rowKeys, rowKeys)
+}
+
+// appendChunkRow appends the i-th row of src to the chunk.
+func (c *Chunk) appendChunkRow(src *Chunk, i int) {
+	for j := range c.columns {
+		c.columns[j] = append(c.columns[j], src.columns[j][i])
+	}
+	c.rowKeys = append(c.rowKeys, src.rowKeys[i])
+}
+
+// Row returns the i-th row of the chunk.
+func (c *Chunk) Row(i int) *Row {
+	data := make([]types.Datum, len(c.columns))
+	for j, col := range c.columns {
+		data[j] = col[i]
+	}
+	return &Row{Data: data, RowKeys: c.rowKeys[i]}
+}
+
+// chunkReader reads the chunks of an executor. The executors that do not
+// implement ChunkExecutor are adapted by fetching their rows one by one, the
+// reader makes sure that an executor is not read again after its end.
+type chunkReader struct {
+	src  Executor
+	done bool
+}
+
+func newChunkReader(src Executor) *chunkReader {
+	return &chunkReader{src: src}
+}
+
+// next returns the next chunk, nil means there is no more data.
+func (r *chunkReader) next() (*Chunk, error) {
+	if r.done {
+		return nil, nil
+	}
+	if ce, ok := r.src.(ChunkExecutor); ok {
+		chk, err := ce.NextChunk()
+		if err != nil || chk == nil {
+			r.done = true
+		}
+		return chk, errors.Trace(err)
+	}
+	return rowsToChunk(func() (*Row, error) {
+		row, err := r.src.Next()
+		if err != nil || row == nil {
+			r.done = true
+		}
+		return row, errors.Trace(err)
+	})
+}
+
+// rowsToChunk fetches at most maxChunkSize rows by next and puts them into a chunk,
+// it is used by the executors whose output is produced row by row.
+func rowsToChunk(next func() (*Row, error)) (*Chunk, error) {
+	var chk *Chunk
+	for chk == nil || chk.NumRows() < maxChunkSize {
+		row, err := next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		if chk == nil {
+			chk = newChunk(len(row.Data))
+		}
+		chk.AppendRow(row)
+	}
+	return chk, nil
+}
+
+// filterChunk evaluates the condition on every row of the chunk and returns
+// which rows are selected. The condition is evaluated column by column if it
+// is vectorizable, otherwise row by row.
+func filterChunk(ctx context.Context, cond expression.Expression, chk *Chunk) ([]bool, error) {
+	selected := make([]bool, chk.NumRows())
+	if expression.Vectorizable(cond) {
+		vals, err := expression.VectorizedEval(cond, chk.columns, chk.NumRows(), ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for i := range vals {
+			if vals[i].IsNull() {
+				continue
+			}
+			b, err := vals[i].ToBool()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			selected[i] = b != 0
+		}
+		return selected, nil
+	}
+	row := make([]types.Datum, chk.NumCols())
+	for i := range selected {
+		for j, col := range chk.columns {
+			row[j] = col[i]
+		}
+		match, err := expression.EvalBool(cond, row, ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		selected[i] = match
+	}
+	return selected, nil
+}
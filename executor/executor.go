@@ -32,10 +32,12 @@ import (
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/db"
 	"github.com/pingcap/tidb/sessionctx/forupdate"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/distinct"
+	"github.com/pingcap/tidb/util/memory"
 	"github.com/pingcap/tidb/util/types"
 	"github.com/pingcap/tipb/go-tipb"
 )
@@ -56,6 +58,10 @@ var (
 	ErrSchemaChanged   = terror.ClassExecutor.New(CodeSchemaChanged, "Schema has changed")
 	ErrWrongParamCount = terror.ClassExecutor.New(CodeWrongParamCount, "Wrong parameter count")
 	ErrRowKeyCount     = terror.ClassExecutor.New(CodeRowKeyCount, "Wrong row key entry count")
+	ErrBindingNotMatch = terror.ClassExecutor.New(CodeBindingNotMatch, "Binding statement doesn't match")
+	ErrUnknownThread   = terror.ClassExecutor.New(CodeUnknownThread, "Unknown thread id")
+	ErrPluginNotLoaded = terror.ClassExecutor.New(CodePluginNotLoaded, "Plugin is not loaded")
+	ErrKillDenied      = terror.ClassExecutor.New(CodeKillDenied, "You are not owner of thread")
 )
 
 // Error codes.
@@ -66,6 +72,10 @@ const (
 	CodeSchemaChanged   terror.ErrCode = 4
 	CodeWrongParamCount terror.ErrCode = 5
 	CodeRowKeyCount     terror.ErrCode = 6
+	CodeBindingNotMatch terror.ErrCode = 7
+	CodeUnknownThread   terror.ErrCode = 8
+	CodePluginNotLoaded terror.ErrCode = 9
+	CodeKillDenied      terror.ErrCode = 10
 )
 
 // Row represents a record row.
@@ -467,7 +477,7 @@ func (e *ReverseExec) Close() error {
 
 func init() {
 	plan.EvalSubquery = func(p plan.PhysicalPlan, is infoschema.InfoSchema, ctx context.Context) (d []types.Datum, err error) {
-		e := &executorBuilder{is: is, ctx: ctx}
+		e := newExecutorBuilder(ctx, is)
 		exec := e.build(p)
 		row, err := exec.Next()
 		if err != nil {
@@ -478,9 +488,22 @@ func init() {
 		}
 		return row.Data, nil
 	}
+
+	// Register terror to mysql error map.
+	mySQLErrCodes := map[terror.ErrCode]uint16{
+		CodeUnknownThread:   mysql.ErrNoSuchThread,
+		CodePluginNotLoaded: mysql.ErrPluginIsNotLoaded,
+		CodeKillDenied:      mysql.ErrKillDenied,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassExecutor] = mySQLErrCodes
 }
 
 // HashJoinExec implements the hash join algorithm.
+// When the hash table of the small table exceeds the query memory quota, its largest
+// partition is moved to a temporary file, and the big table rows of that partition are
+// written to a paired file instead of being probed. The spilled partition pairs are
+// joined one by one after all the big table rows are consumed, a pair whose small
+// partition still exceeds the quota is partitioned again with another seed.
 type HashJoinExec struct {
 	hashTable    map[string][]*Row
 	smallHashKey []*expression.Column
@@ -512,6 +535,31 @@ type HashJoinExec struct {
 	// Channels for output.
 	resultErr  chan error
 	resultRows chan *Row
+
+	memTracker *memory.Tracker
+	// partitionMem is the memory used by each partition of the hash table.
+	partitionMem []int64
+	// spilled is not nil after any partition of the hash table is spilled.
+	spilled     []bool
+	smallSpills []*spillFile
+	bigSpills   []*spillFile
+	// spillLock protects writing big table rows to spill files.
+	spillLock sync.Mutex
+	// pending holds the spilled partition pairs that have not been joined.
+	pending []*hashJoinPartition
+}
+
+// maxHashJoinSpillLevel is the number of times a spilled partition pair of HashJoinExec
+// can be partitioned again. Deeper partitions are joined block by block, so a skewed
+// join key that can not be split by partitioning still fits in the memory quota.
+const maxHashJoinSpillLevel = 4
+
+// hashJoinPartition is a spilled partition pair of HashJoinExec.
+type hashJoinPartition struct {
+	small *spillFile
+	big   *spillFile
+	// level is the seed used to partition the pair again.
+	level int
 }
 
 type hashJoinCtx struct {
@@ -524,11 +572,43 @@ type hashJoinCtx struct {
 
 // Close implements Executor Close interface.
 func (e *HashJoinExec) Close() error {
+	if e.prepared {
+		// Wait for the join workers to exit, so the spill files are not in use.
+		e.finished = true
+	drain:
+		for {
+			select {
+			case _, ok := <-e.resultRows:
+				if !ok {
+					break drain
+				}
+			case <-e.resultErr:
+			}
+		}
+	}
 	e.prepared = false
 	e.cursor = 0
+	if e.memTracker != nil {
+		e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	}
+	err := e.closeSpills()
+	if err != nil {
+		e.smallExec.Close()
+		return errors.Trace(err)
+	}
 	return e.smallExec.Close()
 }
 
+func (e *HashJoinExec) closeSpills() error {
+	files := append(e.smallSpills, e.bigSpills...)
+	for _, p := range e.pending {
+		files = append(files, p.small, p.big)
+	}
+	err := closeSpillFiles(files)
+	e.spilled, e.smallSpills, e.bigSpills, e.pending = nil, nil, nil, nil
+	return errors.Trace(err)
+}
+
 func joinTwoRow(a *Row, b *Row) *Row {
 	ret := &Row{
 		RowKeys: make([]*RowKeyEntry, 0, len(a.RowKeys)+len(b.RowKeys)),
@@ -579,7 +659,7 @@ func (e *HashJoinExec) Fields() []*ast.ResultField {
 
 var batchSize = 128
 
-// Worker to get big table rows.
+// fetchBigExec reads the big table by chunks and dispatches them to the join workers in batches.
 func (e *HashJoinExec) fetchBigExec() {
 	cnt := 0
 	defer func() {
@@ -588,34 +668,27 @@ func (e *HashJoinExec) fetchBigExec() {
 		}
 		e.bigExec.Close()
 	}()
-	curBatchSize := 1
-	for {
-		if e.finished {
-			break
+	bigReader := newChunkReader(e.bigExec)
+	for !e.finished {
+		chk, err := bigReader.next()
+		if err != nil {
+			e.bigTableErr <- errors.Trace(err)
+			return
 		}
-		rows := make([]*Row, 0, batchSize)
-		done := false
-		for i := 0; i < curBatchSize; i++ {
-			row, err := e.bigExec.Next()
-			if err != nil {
-				e.bigTableErr <- errors.Trace(err)
-				done = true
-				break
+		if chk == nil {
+			return
+		}
+		for start := 0; start < chk.NumRows(); start += batchSize {
+			end := start + batchSize
+			if end > chk.NumRows() {
+				end = chk.NumRows()
 			}
-			if row == nil {
-				done = true
-				break
+			rows := make([]*Row, 0, end-start)
+			for i := start; i < end; i++ {
+				rows = append(rows, chk.Row(i))
 			}
-			rows = append(rows, row)
-		}
-		idx := cnt % e.concurrency
-		e.bigTableRows[idx] <- rows
-		cnt++
-		if done {
-			break
-		}
-		if curBatchSize < batchSize {
-			curBatchSize *= 2
+			e.bigTableRows[cnt%e.concurrency] <- rows
+			cnt++
 		}
 	}
 }
@@ -633,37 +706,31 @@ func (e *HashJoinExec) prepare() error {
 
 	e.hashTable = make(map[string][]*Row)
 	e.cursor = 0
+	e.partitionMem = make([]int64, spillPartitions)
+	smallReader := newChunkReader(e.smallExec)
 	for {
-		row, err := e.smallExec.Next()
+		chk, err := smallReader.next()
 		if err != nil {
 			return errors.Trace(err)
 		}
-		if row == nil {
+		if chk == nil {
 			e.smallExec.Close()
 			break
 		}
-
-		matched := true
+		var selected []bool
 		if e.smallFilter != nil {
-			matched, err = expression.EvalBool(e.smallFilter, row.Data, e.ctx)
+			selected, err = filterChunk(e.ctx, e.smallFilter, chk)
 			if err != nil {
 				return errors.Trace(err)
 			}
-			if !matched {
+		}
+		for i := 0; i < chk.NumRows(); i++ {
+			if selected != nil && !selected[i] {
 				continue
 			}
-		}
-		hasNull, hashcode, err := getHashKey(e.smallHashKey, row, e.targetTypes, e.hashJoinContexts[0].datumBuffer, nil)
-		if err != nil {
-			return errors.Trace(err)
-		}
-		if hasNull {
-			continue
-		}
-		if rows, ok := e.hashTable[string(hashcode)]; !ok {
-			e.hashTable[string(hashcode)] = []*Row{row}
-		} else {
-			e.hashTable[string(hashcode)] = append(rows, row)
+			if err = e.buildHashTable(chk.Row(i)); err != nil {
+				return errors.Trace(err)
+			}
 		}
 	}
 
@@ -681,12 +748,318 @@ func (e *HashJoinExec) prepare() error {
 	return nil
 }
 
+// buildHashTable puts a row of the small table into the hash table.
+func (e *HashJoinExec) buildHashTable(row *Row) error {
+	hasNull, hashcode, err := getHashKey(e.smallHashKey, row, e.targetTypes, e.hashJoinContexts[0].datumBuffer, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if hasNull {
+		return nil
+	}
+	if e.memTracker != nil {
+		partition := spillPartition(hashcode, 0)
+		if e.spilled != nil && e.spilled[partition] {
+			return errors.Trace(e.smallSpills[partition].write(row, nil))
+		}
+		usage := rowMemUsage(row) + int64(len(hashcode))
+		e.partitionMem[partition] += usage
+		e.memTracker.Consume(usage)
+	}
+	if rows, ok := e.hashTable[string(hashcode)]; !ok {
+		e.hashTable[string(hashcode)] = []*Row{row}
+	} else {
+		e.hashTable[string(hashcode)] = append(rows, row)
+	}
+	if e.memTracker != nil && e.memTracker.Exceeded() {
+		return errors.Trace(e.spillPartition())
+	}
+	return nil
+}
+
+// spillPartition moves the largest partition of the hash table to a spill file.
+func (e *HashJoinExec) spillPartition() error {
+	partition := -1
+	for i, mem := range e.partitionMem {
+		if mem > 0 && (partition == -1 || mem > e.partitionMem[partition]) {
+			partition = i
+		}
+	}
+	if partition == -1 {
+		return nil
+	}
+	if e.spilled == nil {
+		e.spilled = make([]bool, spillPartitions)
+		e.smallSpills = make([]*spillFile, spillPartitions)
+		e.bigSpills = make([]*spillFile, spillPartitions)
+	}
+	small, err := newSpillFile()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.smallSpills[partition] = small
+	e.bigSpills[partition], err = newSpillFile()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.spilled[partition] = true
+	for key, rows := range e.hashTable {
+		if spillPartition([]byte(key), 0) != partition {
+			continue
+		}
+		for _, row := range rows {
+			if err = small.write(row, nil); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		delete(e.hashTable, key)
+	}
+	e.memTracker.Consume(-e.partitionMem[partition])
+	e.partitionMem[partition] = 0
+	return nil
+}
+
 func (e *HashJoinExec) closeChanWorker() {
 	e.wg.Wait()
+	if e.spilled != nil && !e.finished {
+		if err := e.joinSpilledPartitions(); err != nil {
+			e.resultErr <- errors.Trace(err)
+		}
+	}
 	close(e.resultRows)
 	e.hashTable = nil
 }
 
+// joinSpilledPartitions joins the spilled partitions of the small table and the big table
+// one by one, it runs after all the join workers exit.
+func (e *HashJoinExec) joinSpilledPartitions() error {
+	spilled := e.spilled
+	// The hash table only holds a single partition from now on, stop redirecting rows to files.
+	e.spilled = nil
+	for partition, isSpilled := range spilled {
+		if !isSpilled {
+			continue
+		}
+		e.pending = append(e.pending, &hashJoinPartition{small: e.smallSpills[partition], big: e.bigSpills[partition], level: 1})
+		e.smallSpills[partition], e.bigSpills[partition] = nil, nil
+	}
+	// The pairs split from a pair are joined first, so only a few levels of pairs are kept open.
+	for len(e.pending) > 0 && !e.finished {
+		p := e.pending[len(e.pending)-1]
+		e.pending = e.pending[:len(e.pending)-1]
+		err := e.joinPartition(p)
+		if err1 := closeSpillFiles([]*spillFile{p.small, p.big}); err == nil {
+			err = err1
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// joinPartition joins a spilled partition pair. If the small partition exceeds the memory
+// quota, the pair is partitioned again, or joined block by block at the deepest level.
+func (e *HashJoinExec) joinPartition(p *hashJoinPartition) error {
+	// The big table drives the join, a pair without big rows returns nothing.
+	if p.big.rows == 0 {
+		return nil
+	}
+	if err := p.small.rewind(); err != nil {
+		return errors.Trace(err)
+	}
+	exceeded, err := e.loadSmallPartition(p.small)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if exceeded {
+		if p.level < maxHashJoinSpillLevel {
+			return errors.Trace(e.repartition(p))
+		}
+		return errors.Trace(e.joinPartitionInBlocks(p))
+	}
+	if err = p.big.rewind(); err != nil {
+		return errors.Trace(err)
+	}
+	for !e.finished {
+		row, _, err := p.big.read()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		if !e.join(e.hashJoinContexts[0], row) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// loadSmallPartition builds a new hash table by the rows read from a small partition, it stops
+// and returns true once the memory quota is exceeded, the rest rows are left in the file.
+func (e *HashJoinExec) loadSmallPartition(small *spillFile) (bool, error) {
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	e.hashTable = make(map[string][]*Row)
+	for {
+		row, _, err := small.read()
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if row == nil {
+			return false, nil
+		}
+		_, hashcode, err := getHashKey(e.smallHashKey, row, e.targetTypes, e.hashJoinContexts[0].datumBuffer, nil)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		e.hashTable[string(hashcode)] = append(e.hashTable[string(hashcode)], row)
+		e.memTracker.Consume(rowMemUsage(row) + int64(len(hashcode)))
+		if e.memTracker.Exceeded() {
+			return true, nil
+		}
+	}
+}
+
+// repartition splits a partition pair whose hash table is partially loaded into new pending
+// pairs, with the seed of its level.
+func (e *HashJoinExec) repartition(p *hashJoinPartition) error {
+	parts := make([]*hashJoinPartition, spillPartitions)
+	for i := range parts {
+		// The pairs are pending before they are filled, so Close removes their files on error.
+		parts[i] = &hashJoinPartition{level: p.level + 1}
+		e.pending = append(e.pending, parts[i])
+		var err error
+		if parts[i].small, err = newSpillFile(); err != nil {
+			return errors.Trace(err)
+		}
+		if parts[i].big, err = newSpillFile(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for key, rows := range e.hashTable {
+		small := parts[spillPartition([]byte(key), p.level)].small
+		for _, row := range rows {
+			if err := small.write(row, nil); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	e.hashTable = make(map[string][]*Row)
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	// The rest small rows are read from the current position.
+	for {
+		row, _, err := p.small.read()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		_, hashcode, err := getHashKey(e.smallHashKey, row, e.targetTypes, e.hashJoinContexts[0].datumBuffer, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = parts[spillPartition(hashcode, p.level)].small.write(row, nil); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err := p.big.rewind(); err != nil {
+		return errors.Trace(err)
+	}
+	for {
+		row, _, err := p.big.read()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		_, hashcode, err := getHashKey(e.bigHashKey, row, e.targetTypes, e.hashJoinContexts[0].datumBuffer, nil)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = parts[spillPartition(hashcode, p.level)].big.write(row, nil); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	// Drop the pairs without big rows, they return nothing.
+	e.pending = e.pending[:len(e.pending)-len(parts)]
+	var files []*spillFile
+	for _, part := range parts {
+		if part.big.rows == 0 {
+			files = append(files, part.small, part.big)
+		} else {
+			e.pending = append(e.pending, part)
+		}
+	}
+	return errors.Trace(closeSpillFiles(files))
+}
+
+// joinPartitionInBlocks joins a partition pair whose small rows do not fit in the memory quota.
+// The small rows are loaded block by block, and all the big rows are probed against each block.
+// For outer join, the big rows matched by no block are filled with null rows after the last block.
+func (e *HashJoinExec) joinPartitionInBlocks(p *hashJoinPartition) error {
+	// The first block is loaded by joinPartition.
+	exceeded := true
+	var matched []bool
+	if e.outer {
+		matched = make([]bool, p.big.rows)
+	}
+	ctx := e.hashJoinContexts[0]
+	for {
+		if err := p.big.rewind(); err != nil {
+			return errors.Trace(err)
+		}
+		for i := 0; !e.finished; i++ {
+			row, _, err := p.big.read()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if row == nil {
+				break
+			}
+			matchedRows, _, err := e.constructMatchedRows(ctx, row)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if matched != nil && len(matchedRows) > 0 {
+				matched[i] = true
+			}
+			for _, r := range matchedRows {
+				e.resultRows <- r
+			}
+		}
+		if e.finished || !exceeded {
+			break
+		}
+		var err error
+		exceeded, err = e.loadSmallPartition(p.small)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+	if err := p.big.rewind(); err != nil {
+		return errors.Trace(err)
+	}
+	for i := 0; !e.finished; i++ {
+		row, _, err := p.big.read()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		if !matched[i] {
+			e.resultRows <- e.fillNullRow(row)
+		}
+	}
+	return nil
+}
+
 // doJoin does join job in one goroutine.
 func (e *HashJoinExec) doJoin(idx int) {
 	for {
@@ -730,11 +1103,15 @@ func (e *HashJoinExec) join(ctx *hashJoinCtx, bigRow *Row) bool {
 		}
 	}
 	if bigMatched {
-		matchedRows, err = e.constructMatchedRows(ctx, bigRow)
+		var spilled bool
+		matchedRows, spilled, err = e.constructMatchedRows(ctx, bigRow)
 		if err != nil {
 			e.resultErr <- errors.Trace(err)
 			return false
 		}
+		if spilled {
+			return true
+		}
 	}
 	for _, r := range matchedRows {
 		e.resultRows <- r
@@ -746,15 +1123,26 @@ func (e *HashJoinExec) join(ctx *hashJoinCtx, bigRow *Row) bool {
 	return true
 }
 
-func (e *HashJoinExec) constructMatchedRows(ctx *hashJoinCtx, bigRow *Row) (matchedRows []*Row, err error) {
+// constructMatchedRows returns the joined rows of bigRow, spilled is true if
+// bigRow belongs to a spilled partition and is written to the spill file.
+func (e *HashJoinExec) constructMatchedRows(ctx *hashJoinCtx, bigRow *Row) (matchedRows []*Row, spilled bool, err error) {
 	hasNull, hashcode, err := getHashKey(e.bigHashKey, bigRow, e.targetTypes, ctx.datumBuffer, ctx.hashKeyBuffer[0:0:cap(ctx.hashKeyBuffer)])
 	if err != nil {
-		return nil, errors.Trace(err)
+		return nil, false, errors.Trace(err)
 	}
 
 	if hasNull {
 		return
 	}
+	if e.spilled != nil {
+		partition := spillPartition(hashcode, 0)
+		if e.spilled[partition] {
+			e.spillLock.Lock()
+			err = e.bigSpills[partition].write(bigRow, nil)
+			e.spillLock.Unlock()
+			return nil, true, errors.Trace(err)
+		}
+	}
 	rows, ok := e.hashTable[string(hashcode)]
 	if !ok {
 		return
@@ -771,7 +1159,7 @@ func (e *HashJoinExec) constructMatchedRows(ctx *hashJoinCtx, bigRow *Row) (matc
 		if e.otherFilter != nil {
 			otherMatched, err = expression.EvalBool(ctx.otherFilter, matchedRow.Data, e.ctx)
 			if err != nil {
-				return nil, errors.Trace(err)
+				return nil, false, errors.Trace(err)
 			}
 		}
 		if otherMatched {
@@ -779,7 +1167,7 @@ func (e *HashJoinExec) constructMatchedRows(ctx *hashJoinCtx, bigRow *Row) (matc
 		}
 	}
 
-	return matchedRows, nil
+	return matchedRows, false, nil
 }
 
 func (e *HashJoinExec) fillNullRow(bigRow *Row) (returnRow *Row) {
@@ -824,6 +1212,11 @@ func (e *HashJoinExec) Next() (*Row, error) {
 	return row, nil
 }
 
+// NextChunk implements ChunkExecutor NextChunk interface.
+func (e *HashJoinExec) NextChunk() (*Chunk, error) {
+	return rowsToChunk(e.Next)
+}
+
 // HashSemiJoinExec implements the hash join algorithm for semi join.
 type HashSemiJoinExec struct {
 	hashTable         map[string][]*Row
@@ -842,6 +1235,9 @@ type HashSemiJoinExec struct {
 	smallTableHasNull bool
 	// If anti is true, semi join only output the unmatched row.
 	anti bool
+	// If nullAware is true, the row is not matched but the result is NULL when its join key is NULL,
+	// or when it's not matched and the small table has NULL join keys.
+	nullAware bool
 }
 
 // Close implements Executor Close interface.
@@ -893,7 +1289,9 @@ func (e *HashSemiJoinExec) prepare() error {
 			return errors.Trace(err)
 		}
 		if hasNull {
-			e.smallTableHasNull = true
+			if e.nullAware {
+				e.smallTableHasNull = true
+			}
 			continue
 		}
 		if rows, ok := e.hashTable[string(hashcode)]; !ok {
@@ -913,7 +1311,7 @@ func (e *HashSemiJoinExec) rowIsMatched(bigRow *Row) (matched bool, hasNull bool
 		return false, false, errors.Trace(err)
 	}
 	if hasNull {
-		return false, true, nil
+		return false, e.nullAware, nil
 	}
 	rows, ok := e.hashTable[string(hashcode)]
 	if !ok {
@@ -990,6 +1388,9 @@ func (e *HashSemiJoinExec) Next() (*Row, error) {
 
 // AggregationExec deals with all the aggregate functions.
 // It is built from Aggregate Plan. When Next() is called, it reads all the data from Src and updates all the items in AggFuncs.
+// When the query memory quota is exceeded, the groups already in memory keep being updated, while rows
+// of new groups are written to partition files by the hash of their group keys. Each partition is
+// aggregated separately after the in-memory groups are returned.
 type AggregationExec struct {
 	Src               Executor
 	schema            expression.Schema
@@ -1001,6 +1402,23 @@ type AggregationExec struct {
 	groups            [][]byte
 	currentGroupIndex int
 	GroupByItems      []expression.Expression
+	// Concurrency is the number of partial aggregation workers, see parallelExecute.
+	Concurrency int
+
+	memTracker *memory.Tracker
+	// spillSrc is the partition file being aggregated, nil means reading from Src.
+	spillSrc   *spillFile
+	spillLevel int
+	// spillOut is not nil after the memory quota is exceeded, it holds the partitions of new groups.
+	spillOut []*spillFile
+	// pending holds the spilled partitions that have not been aggregated.
+	pending []*aggPartition
+}
+
+// aggPartition is a spilled partition of AggregationExec.
+type aggPartition struct {
+	file  *spillFile
+	level int
 }
 
 // Close implements Executor Close interface.
@@ -1011,6 +1429,18 @@ func (e *AggregationExec) Close() error {
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
+	if e.memTracker != nil {
+		e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	}
+	files := append(e.spillOut, e.spillSrc)
+	for _, p := range e.pending {
+		files = append(files, p.file)
+	}
+	e.spillSrc, e.spillOut, e.pending = nil, nil, nil
+	if err := closeSpillFiles(files); err != nil {
+		e.Src.Close()
+		return errors.Trace(err)
+	}
 	return e.Src.Close()
 }
 
@@ -1029,14 +1459,14 @@ func (e *AggregationExec) Next() (*Row, error) {
 	// In this stage we consider all data from src as a single group.
 	if !e.executed {
 		e.groupMap = make(map[string]bool)
-		for {
-			hasMore, err := e.innerNext()
-			if err != nil {
-				return nil, errors.Trace(err)
-			}
-			if !hasMore {
-				break
-			}
+		var err error
+		if e.canParallel() {
+			err = e.parallelExecute()
+		} else {
+			err = e.execute()
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
 		}
 		e.executed = true
 		if (len(e.groups) == 0) && (len(e.GroupByItems) == 0) {
@@ -1046,26 +1476,131 @@ func (e *AggregationExec) Next() (*Row, error) {
 			// "select count(c) from t group by c1;" should return empty result set.
 			e.groups = append(e.groups, []byte{})
 		}
+		if err := e.finishSpill(); err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
-	if e.currentGroupIndex >= len(e.groups) {
-		return nil, nil
+	for e.currentGroupIndex >= len(e.groups) {
+		if len(e.pending) == 0 {
+			return nil, nil
+		}
+		if err := e.aggregatePartition(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	retRow := &Row{Data: make([]types.Datum, 0, len(e.AggFuncs))}
+	groupKey := e.groups[e.currentGroupIndex]
+	for _, af := range e.AggFuncs {
+		retRow.Data = append(retRow.Data, af.GetGroupResult(groupKey))
+	}
+	e.currentGroupIndex++
+	return retRow, nil
+}
+
+// NextChunk implements ChunkExecutor NextChunk interface.
+func (e *AggregationExec) NextChunk() (*Chunk, error) {
+	return rowsToChunk(e.Next)
+}
+
+// execute reads all the rows from the source and updates the aggregate functions.
+// The source is read by chunks unless a spilled partition is being aggregated.
+func (e *AggregationExec) execute() error {
+	if e.spillSrc == nil && e.Src != nil {
+		reader := newChunkReader(e.Src)
+		for {
+			chk, err := reader.next()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if chk == nil {
+				return nil
+			}
+			if err = e.aggregateChunk(chk); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	for {
+		hasMore, err := e.innerNext()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasMore {
+			return nil
+		}
+	}
+}
+
+func (e *AggregationExec) getGroupKey(row *Row) ([]byte, error) {
+	return evalGroupKey(e.ctx, e.GroupByItems, row)
+}
+
+func (e *AggregationExec) aggregateChunk(chk *Chunk) error {
+	keys, err := e.chunkGroupKeys(chk)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for i := 0; i < chk.NumRows(); i++ {
+		if err = e.aggregateRow(chk.Row(i), keys[i]); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// chunkGroupKeys evaluates the group keys of all the rows in a chunk, the
+// vectorizable group by items are evaluated column by column.
+func (e *AggregationExec) chunkGroupKeys(chk *Chunk) ([][]byte, error) {
+	numRows := chk.NumRows()
+	keys := make([][]byte, numRows)
+	if len(e.GroupByItems) == 0 {
+		for i := range keys {
+			keys[i] = []byte{}
+		}
+		return keys, nil
+	}
+	itemVals := make([][]types.Datum, 0, len(e.GroupByItems))
+	for _, item := range e.GroupByItems {
+		var vals []types.Datum
+		if expression.Vectorizable(item) {
+			var err error
+			vals, err = expression.VectorizedEval(item, chk.columns, numRows, e.ctx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		} else {
+			vals = make([]types.Datum, numRows)
+			for i := range vals {
+				v, err := item.Eval(chk.Row(i).Data, e.ctx)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				vals[i] = v
+			}
+		}
+		itemVals = append(itemVals, vals)
 	}
-	retRow := &Row{Data: make([]types.Datum, 0, len(e.AggFuncs))}
-	groupKey := e.groups[e.currentGroupIndex]
-	for _, af := range e.AggFuncs {
-		retRow.Data = append(retRow.Data, af.GetGroupResult(groupKey))
+	rowVals := make([]types.Datum, len(itemVals))
+	for i := range keys {
+		for j, vals := range itemVals {
+			rowVals[j] = vals[i]
+		}
+		key, err := codec.EncodeValue([]byte{}, rowVals...)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		keys[i] = key
 	}
-	e.currentGroupIndex++
-	return retRow, nil
+	return keys, nil
 }
 
-func (e *AggregationExec) getGroupKey(row *Row) ([]byte, error) {
-	if len(e.GroupByItems) == 0 {
+func evalGroupKey(ctx context.Context, groupByItems []expression.Expression, row *Row) ([]byte, error) {
+	if len(groupByItems) == 0 {
 		return []byte{}, nil
 	}
-	vals := make([]types.Datum, 0, len(e.GroupByItems))
-	for _, item := range e.GroupByItems {
-		v, err := item.Eval(row.Data, e.ctx)
+	vals := make([]types.Datum, 0, len(groupByItems))
+	for _, item := range groupByItems {
+		v, err := item.Eval(row.Data, ctx)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -1082,7 +1617,15 @@ func (e *AggregationExec) getGroupKey(row *Row) ([]byte, error) {
 // If the first return value is false, it means there is no more data from src.
 func (e *AggregationExec) innerNext() (ret bool, err error) {
 	var srcRow *Row
-	if e.Src != nil {
+	if e.spillSrc != nil {
+		srcRow, _, err = e.spillSrc.read()
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if srcRow == nil {
+			return false, nil
+		}
+	} else if e.Src != nil {
 		srcRow, err = e.Src.Next()
 		if err != nil {
 			return false, errors.Trace(err)
@@ -1101,14 +1644,103 @@ func (e *AggregationExec) innerNext() (ret bool, err error) {
 	if err != nil {
 		return false, errors.Trace(err)
 	}
+	err = e.aggregateRow(srcRow, groupKey)
+	return err == nil, errors.Trace(err)
+}
+
+// aggregateRow updates the aggregate functions with a row, or writes the row
+// to a spill partition if it belongs to a new group after spilling started.
+func (e *AggregationExec) aggregateRow(srcRow *Row, groupKey []byte) error {
 	if _, ok := e.groupMap[string(groupKey)]; !ok {
+		if e.spillOut != nil {
+			return errors.Trace(e.spillOut[spillPartition(groupKey, e.spillLevel)].write(srcRow, nil))
+		}
 		e.groupMap[string(groupKey)] = true
 		e.groups = append(e.groups, groupKey)
+		if err := e.trackGroup(groupKey); err != nil {
+			return errors.Trace(err)
+		}
 	}
 	for _, af := range e.AggFuncs {
 		af.Update(srcRow.Data, groupKey, e.ctx)
 	}
-	return true, nil
+	return nil
+}
+
+// trackGroup consumes the memory of a new group, and starts spilling new
+// groups when the memory quota is exceeded.
+func (e *AggregationExec) trackGroup(groupKey []byte) error {
+	if e.memTracker == nil || len(e.GroupByItems) == 0 {
+		return nil
+	}
+	e.memTracker.Consume(groupMemUsage(groupKey, len(e.AggFuncs)))
+	if !e.memTracker.Exceeded() {
+		return nil
+	}
+	return errors.Trace(e.startSpill())
+}
+
+// groupMemUsage estimates the memory used by a group of the hash aggregation.
+func groupMemUsage(groupKey []byte, aggFuncs int) int64 {
+	// The group key is held by the group map, the group list and every aggregate function.
+	keyCopies := int64(2 + aggFuncs)
+	return keyCopies*int64(len(groupKey)) + aggGroupMemUsage*int64(aggFuncs)
+}
+
+// startSpill creates the partition files of the groups that are not in memory.
+func (e *AggregationExec) startSpill() error {
+	e.spillOut = make([]*spillFile, spillPartitions)
+	for i := range e.spillOut {
+		f, err := newSpillFile()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.spillOut[i] = f
+	}
+	return nil
+}
+
+// finishSpill moves the non-empty spilled partitions to the pending list.
+func (e *AggregationExec) finishSpill() error {
+	for i, f := range e.spillOut {
+		e.spillOut[i] = nil
+		if f.rows == 0 {
+			if err := f.close(); err != nil {
+				return errors.Trace(err)
+			}
+			continue
+		}
+		if err := f.rewind(); err != nil {
+			f.close()
+			return errors.Trace(err)
+		}
+		e.pending = append(e.pending, &aggPartition{file: f, level: e.spillLevel + 1})
+	}
+	e.spillOut = nil
+	return nil
+}
+
+// aggregatePartition drops the returned groups and aggregates the next pending partition.
+func (e *AggregationExec) aggregatePartition() error {
+	if e.spillSrc != nil {
+		if err := e.spillSrc.close(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	p := e.pending[0]
+	e.pending = e.pending[1:]
+	e.spillSrc, e.spillLevel = p.file, p.level
+	for _, af := range e.AggFuncs {
+		af.Clear()
+	}
+	e.groupMap = make(map[string]bool)
+	e.groups = nil
+	e.currentGroupIndex = 0
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	if err := e.execute(); err != nil {
+		return errors.Trace(err)
+	}
+	return e.finishSpill()
 }
 
 // StreamAggExec deals with all the aggregate functions.
@@ -1131,6 +1763,7 @@ type StreamAggExec struct {
 func (e *StreamAggExec) Close() error {
 	e.executed = false
 	e.hasData = false
+	e.curGroupKey = nil
 	for _, agg := range e.AggFuncs {
 		agg.Clear()
 	}
@@ -1238,6 +1871,18 @@ type ProjectionExec struct {
 	executed     bool
 	ctx          context.Context
 	exprs        []expression.Expression
+	// Concurrency is the number of workers evaluating the expressions, see parallelNext.
+	Concurrency int
+
+	prepared bool
+	taskCh   chan *projectionTask
+	resultCh chan *projectionTask
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+	curTask  *projectionTask
+	curIndex int
+
+	srcReader *chunkReader
 }
 
 // Schema implements Executor Schema interface.
@@ -1252,6 +1897,9 @@ func (e *ProjectionExec) Fields() []*ast.ResultField {
 
 // Next implements Executor Next interface.
 func (e *ProjectionExec) Next() (retRow *Row, err error) {
+	if e.Concurrency > 1 && e.Src != nil {
+		return e.parallelNext()
+	}
 	var rowKeys []*RowKeyEntry
 	var srcRow *Row
 	if e.Src != nil {
@@ -1270,12 +1918,77 @@ func (e *ProjectionExec) Next() (retRow *Row, err error) {
 		}
 	}
 	e.executed = true
+	var data []types.Datum
+	if srcRow != nil {
+		data = srcRow.Data
+	}
+	return evalProjection(e.ctx, e.exprs, data, rowKeys)
+}
+
+// NextChunk implements ChunkExecutor NextChunk interface.
+// The expressions are evaluated column by column if all of them are vectorizable,
+// otherwise row by row, so the expressions with side effects like user variable
+// assignments are still evaluated in row order.
+func (e *ProjectionExec) NextChunk() (*Chunk, error) {
+	if e.Src == nil || e.Concurrency > 1 {
+		return rowsToChunk(e.Next)
+	}
+	if e.srcReader == nil {
+		e.srcReader = newChunkReader(e.Src)
+	}
+	src, err := e.srcReader.next()
+	if err != nil || src == nil {
+		return nil, errors.Trace(err)
+	}
+	numRows := src.NumRows()
+	chk := &Chunk{
+		columns: make([][]types.Datum, len(e.exprs)),
+		rowKeys: src.rowKeys,
+	}
+	if e.vectorizable() {
+		for i, expr := range e.exprs {
+			chk.columns[i], err = expression.VectorizedEval(expr, src.columns, numRows, e.ctx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		return chk, nil
+	}
+	for i := range chk.columns {
+		chk.columns[i] = make([]types.Datum, 0, numRows)
+	}
+	data := make([]types.Datum, src.NumCols())
+	for i := 0; i < numRows; i++ {
+		for j, col := range src.columns {
+			data[j] = col[i]
+		}
+		for j, expr := range e.exprs {
+			val, err := expr.Eval(data, e.ctx)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			chk.columns[j] = append(chk.columns[j], val)
+		}
+	}
+	return chk, nil
+}
+
+func (e *ProjectionExec) vectorizable() bool {
+	for _, expr := range e.exprs {
+		if !expression.Vectorizable(expr) {
+			return false
+		}
+	}
+	return true
+}
+
+func evalProjection(ctx context.Context, exprs []expression.Expression, data []types.Datum, rowKeys []*RowKeyEntry) (*Row, error) {
 	row := &Row{
 		RowKeys: rowKeys,
-		Data:    make([]types.Datum, 0, len(e.exprs)),
+		Data:    make([]types.Datum, 0, len(exprs)),
 	}
-	for _, expr := range e.exprs {
-		val, err := expr.Eval(srcRow.Data, e.ctx)
+	for _, expr := range exprs {
+		val, err := expr.Eval(data, ctx)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -1286,6 +1999,8 @@ func (e *ProjectionExec) Next() (retRow *Row, err error) {
 
 // Close implements Executor Close interface.
 func (e *ProjectionExec) Close() error {
+	e.stopWorkers()
+	e.srcReader = nil
 	if e.Src != nil {
 		return e.Src.Close()
 	}
@@ -1295,12 +2010,13 @@ func (e *ProjectionExec) Close() error {
 // TableDualExec represents a dual table executor.
 type TableDualExec struct {
 	schema   expression.Schema
+	empty    bool
 	executed bool
 }
 
 // Init implements Executor Init interface.
 func (e *TableDualExec) Init() {
-	e.executed = false
+	e.executed = e.empty
 }
 
 // Schema implements Executor Schema interface.
@@ -1333,6 +2049,7 @@ type SelectionExec struct {
 	Condition expression.Expression
 	ctx       context.Context
 	schema    expression.Schema
+	srcReader *chunkReader
 }
 
 // Schema implements Executor Schema interface.
@@ -1365,8 +2082,38 @@ func (e *SelectionExec) Next() (*Row, error) {
 	}
 }
 
+// NextChunk implements ChunkExecutor NextChunk interface.
+func (e *SelectionExec) NextChunk() (*Chunk, error) {
+	if e.srcReader == nil {
+		e.srcReader = newChunkReader(e.Src)
+	}
+	for {
+		chk, err := e.srcReader.next()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if chk == nil {
+			return nil, nil
+		}
+		selected, err := filterChunk(e.ctx, e.Condition, chk)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		result := newChunk(chk.NumCols())
+		for i, ok := range selected {
+			if ok {
+				result.appendChunkRow(chk, i)
+			}
+		}
+		if result.NumRows() > 0 {
+			return result, nil
+		}
+	}
+}
+
 // Close implements Executor Close interface.
 func (e *SelectionExec) Close() error {
+	e.srcReader = nil
 	return e.Src.Close()
 }
 
@@ -1381,6 +2128,7 @@ type TableScanExec struct {
 	cursor     int
 	schema     expression.Schema
 	columns    []*model.ColumnInfo
+	details    *variable.StmtExecDetails
 }
 
 // Schema implements Executor Schema interface.
@@ -1429,6 +2177,7 @@ func (e *TableScanExec) Next() (*Row, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
+		e.details.AddRowsExamined(1)
 		e.seekHandle = handle + 1
 		return row, nil
 	}
@@ -1483,7 +2232,61 @@ func (e *TableScanExec) Close() error {
 	return nil
 }
 
+// DynamicTableScanExec scans an information_schema table whose rows are generated when it is read,
+// such as PROCESSLIST.
+type DynamicTableScanExec struct {
+	tableName model.CIStr
+	ctx       context.Context
+	schema    expression.Schema
+	columns   []*model.ColumnInfo
+	rows      [][]types.Datum
+	cursor    int
+	fetched   bool
+}
+
+// Schema implements Executor Schema interface.
+func (e *DynamicTableScanExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements Executor interface.
+func (e *DynamicTableScanExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements Executor interface.
+func (e *DynamicTableScanExec) Next() (*Row, error) {
+	if !e.fetched {
+		rows, err := infoschema.DataForDynamicTable(e.ctx, e.tableName.O)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.rows = rows
+		e.fetched = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	record := e.rows[e.cursor]
+	e.cursor++
+	row := &Row{Data: make([]types.Datum, len(e.columns))}
+	for i, col := range e.columns {
+		row.Data[i] = record[col.Offset]
+	}
+	return row, nil
+}
+
+// Close implements Executor Close interface.
+func (e *DynamicTableScanExec) Close() error {
+	e.rows = nil
+	e.cursor = 0
+	e.fetched = false
+	return nil
+}
+
 // SortExec represents sorting executor.
+// When the query memory quota is exceeded, it sorts the buffered rows, writes
+// them to a temporary file as a sorted run, and merges all runs at the end.
 type SortExec struct {
 	Src     Executor
 	ByItems []*plan.ByItems
@@ -1493,12 +2296,27 @@ type SortExec struct {
 	fetched bool
 	err     error
 	schema  expression.Schema
+
+	// memTracker is nil if the executor can not spill, like TopnExec.
+	memTracker *memory.Tracker
+	spillFiles []*spillFile
+	runHeap    *sortRunHeap
 }
 
 // Close implements Executor Close interface.
 func (e *SortExec) Close() error {
 	e.fetched = false
 	e.Rows = nil
+	e.runHeap = nil
+	if e.memTracker != nil {
+		e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	}
+	err := closeSpillFiles(e.spillFiles)
+	e.spillFiles = nil
+	if err != nil {
+		e.Src.Close()
+		return errors.Trace(err)
+	}
 	return e.Src.Close()
 }
 
@@ -1524,9 +2342,13 @@ func (e *SortExec) Swap(i, j int) {
 
 // Less implements sort.Interface Less interface.
 func (e *SortExec) Less(i, j int) bool {
+	return e.lessRow(e.Rows[i], e.Rows[j])
+}
+
+func (e *SortExec) lessRow(row1, row2 *orderByRow) bool {
 	for index, by := range e.ByItems {
-		v1 := e.Rows[i].key[index]
-		v2 := e.Rows[j].key[index]
+		v1 := row1.key[index]
+		v2 := row2.key[index]
 
 		ret, err := v1.CompareDatum(v2)
 		if err != nil {
@@ -1570,13 +2392,31 @@ func (e *SortExec) Next() (*Row, error) {
 				}
 			}
 			e.Rows = append(e.Rows, orderRow)
+			if e.memTracker == nil {
+				continue
+			}
+			e.memTracker.Consume(rowMemUsage(srcRow) + datumsMemUsage(orderRow.key))
+			if e.memTracker.Exceeded() {
+				if err = e.spillRows(); err != nil {
+					return nil, errors.Trace(err)
+				}
+			}
+		}
+		if len(e.spillFiles) > 0 {
+			if err := e.prepareMerge(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		} else {
+			sort.Sort(e)
 		}
-		sort.Sort(e)
 		e.fetched = true
 	}
 	if e.err != nil {
 		return nil, errors.Trace(e.err)
 	}
+	if e.runHeap != nil {
+		return e.mergeNext()
+	}
 	if e.Idx >= len(e.Rows) {
 		return nil, nil
 	}
@@ -1585,6 +2425,125 @@ func (e *SortExec) Next() (*Row, error) {
 	return row, nil
 }
 
+// spillRows sorts the buffered rows and writes them to a new sorted run.
+func (e *SortExec) spillRows() error {
+	sort.Sort(e)
+	if e.err != nil {
+		return errors.Trace(e.err)
+	}
+	f, err := newSpillFile()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.spillFiles = append(e.spillFiles, f)
+	for _, row := range e.Rows {
+		if err = f.write(row.row, row.key); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	e.Rows = nil
+	e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	return nil
+}
+
+// prepareMerge spills the remaining rows and reads the first row of every sorted run.
+func (e *SortExec) prepareMerge() error {
+	if len(e.Rows) > 0 {
+		if err := e.spillRows(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	e.runHeap = &sortRunHeap{sortExec: e}
+	for _, f := range e.spillFiles {
+		if err := f.rewind(); err != nil {
+			return errors.Trace(err)
+		}
+		run := &sortRun{file: f}
+		hasRow, err := run.next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if hasRow {
+			e.runHeap.runs = append(e.runHeap.runs, run)
+		}
+	}
+	heap.Init(e.runHeap)
+	return errors.Trace(e.err)
+}
+
+// mergeNext returns the smallest row among the heads of all sorted runs.
+func (e *SortExec) mergeNext() (*Row, error) {
+	if e.runHeap.Len() == 0 {
+		return nil, nil
+	}
+	run := e.runHeap.runs[0]
+	row := run.cur.row
+	hasRow, err := run.next()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if hasRow {
+		heap.Fix(e.runHeap, 0)
+	} else {
+		heap.Pop(e.runHeap)
+	}
+	if e.err != nil {
+		return nil, errors.Trace(e.err)
+	}
+	return row, nil
+}
+
+// sortRun is a sorted run spilled by SortExec.
+type sortRun struct {
+	file *spillFile
+	cur  *orderByRow
+}
+
+func (r *sortRun) next() (bool, error) {
+	row, key, err := r.file.read()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if row == nil {
+		return false, nil
+	}
+	r.cur = &orderByRow{row: row, key: key}
+	return true, nil
+}
+
+// sortRunHeap is a min heap of sorted runs ordered by their current rows.
+type sortRunHeap struct {
+	sortExec *SortExec
+	runs     []*sortRun
+}
+
+// Len implements heap.Interface Len interface.
+func (h *sortRunHeap) Len() int {
+	return len(h.runs)
+}
+
+// Less implements heap.Interface Less interface.
+func (h *sortRunHeap) Less(i, j int) bool {
+	return h.sortExec.lessRow(h.runs[i].cur, h.runs[j].cur)
+}
+
+// Swap implements heap.Interface Swap interface.
+func (h *sortRunHeap) Swap(i, j int) {
+	h.runs[i], h.runs[j] = h.runs[j], h.runs[i]
+}
+
+// Push implements heap.Interface Push interface.
+func (h *sortRunHeap) Push(x interface{}) {
+	h.runs = append(h.runs, x.(*sortRun))
+}
+
+// Pop implements heap.Interface Pop interface.
+func (h *sortRunHeap) Pop() interface{} {
+	run := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return run
+}
+
 // TopnExec implements a top n algo.
 type TopnExec struct {
 	SortExec
@@ -1896,6 +2855,9 @@ func (b *executorBuilder) scalarFuncToPBExpr(client kv.Client, expr *expression.
 		}
 		tp = tipb.ExprType_Like
 	default:
+		if tp, ok := plan.PushDownFuncType(expr.FuncName.L); ok {
+			return b.funcToPBExpr(client, tp, expr.Args, tbl)
+		}
 		return nil
 	}
 
@@ -1916,6 +2878,22 @@ func (b *executorBuilder) scalarFuncToPBExpr(client kv.Client, expr *expression.
 		Children: []*tipb.Expr{expr0, expr1}}
 }
 
+func (b *executorBuilder) funcToPBExpr(client kv.Client, tp tipb.ExprType, args []expression.Expression,
+	tbl *model.TableInfo) *tipb.Expr {
+	if !client.SupportRequestType(kv.ReqTypeSelect, int64(tp)) {
+		return nil
+	}
+	children := make([]*tipb.Expr, 0, len(args))
+	for _, arg := range args {
+		pbArg := b.ExprToPBExpr(client, arg, tbl)
+		if pbArg == nil {
+			return nil
+		}
+		children = append(children, pbArg)
+	}
+	return &tipb.Expr{Tp: tp, Children: children}
+}
+
 // ApplyExec represents apply executor.
 // Apply gets one row from outer executor and gets one row from inner executor according to outer row.
 type ApplyExec struct {
@@ -2150,6 +3128,21 @@ type UnionExec struct {
 	schema expression.Schema
 	Srcs   []Executor
 	cursor int
+	ctx    context.Context
+	// Concurrency is the number of workers executing the children, see parallelNext.
+	Concurrency int
+	// KeepOrder indicates whether the parallel union returns the rows in the order of the children.
+	KeepOrder bool
+	// partialDistinct indicates the workers remove the duplicated rows of their children,
+	// it is set when the union is the source of a DistinctExec.
+	partialDistinct bool
+
+	prepared  bool
+	resultChs []chan *unionResult
+	closeCh   chan struct{}
+	finishCh  chan struct{}
+	curResult *unionResult
+	curIndex  int
 }
 
 // Schema implements Executor Schema interface.
@@ -2164,6 +3157,9 @@ func (e *UnionExec) Fields() []*ast.ResultField {
 
 // Next implements Executor Next interface.
 func (e *UnionExec) Next() (*Row, error) {
+	if e.Concurrency > 1 && len(e.Srcs) > 1 {
+		return e.parallelNext()
+	}
 	for {
 		if e.cursor >= len(e.Srcs) {
 			return nil, nil
@@ -2178,23 +3174,30 @@ func (e *UnionExec) Next() (*Row, error) {
 			continue
 		}
 		if e.cursor != 0 {
-			for i := range row.Data {
-				// The column value should be casted as the same type of the first select statement in corresponding position.
-				col := e.schema[i]
-				var val types.Datum
-				val, err = row.Data[i].ConvertTo(col.RetType)
-				if err != nil {
-					return nil, errors.Trace(err)
-				}
-				row.Data[i] = val
+			if err = e.convertRow(row); err != nil {
+				return nil, errors.Trace(err)
 			}
 		}
 		return row, nil
 	}
 }
 
+// convertRow casts the column values to the types of the first select statement in corresponding positions.
+func (e *UnionExec) convertRow(row *Row) error {
+	for i := range row.Data {
+		col := e.schema[i]
+		val, err := row.Data[i].ConvertTo(col.RetType)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		row.Data[i] = val
+	}
+	return nil
+}
+
 // Close implements Executor Close interface.
 func (e *UnionExec) Close() error {
+	e.stopWorkers()
 	e.cursor = 0
 	for _, sel := range e.Srcs {
 		er := sel.Close()
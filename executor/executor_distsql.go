@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/util/codec"
@@ -601,6 +602,8 @@ type XExecutor interface {
 	GetTable() *model.TableInfo
 	// AddLimit try to add limit to XExecutor. If success, return true.
 	AddLimit(l *plan.Limit) bool
+	// AddTopN try to let every region return its top count rows ordered by orderBy. If success, return true.
+	AddTopN(orderBy []*tipb.ByItem, count int64) bool
 }
 
 // Closeable is a interface for closeable structures.
@@ -627,6 +630,7 @@ type XSelectIndexExec struct {
 	table         table.Table
 	asName        *model.CIStr
 	ctx           context.Context
+	details       *variable.StmtExecDetails
 	supportDesc   bool
 	isMemDB       bool
 	result        distsql.SelectResult
@@ -639,6 +643,9 @@ type XSelectIndexExec struct {
 	taskCurr *lookupTableTask
 
 	indexPlan *plan.PhysicalIndexScan
+	// indexMerge is not nil if the handles are read from several index scans and merged.
+	// The indexPlan is used to read the rows by the merged handles then.
+	indexMerge *plan.PhysicalIndexMerge
 
 	returnedRows uint64 // returned row count
 
@@ -672,6 +679,9 @@ func (e *XSelectIndexExec) AddAggregate(funcs []*tipb.Expr, byItems []*tipb.ByIt
 
 // AddLimit implements XExecutor interface.
 func (e *XSelectIndexExec) AddLimit(limit *plan.Limit) bool {
+	if e.indexMerge != nil {
+		return false
+	}
 	cnt := int64(limit.Offset + limit.Count)
 	if e.indexPlan.LimitCount == nil {
 		e.indexPlan.LimitCount = &cnt
@@ -680,6 +690,12 @@ func (e *XSelectIndexExec) AddLimit(limit *plan.Limit) bool {
 	return false
 }
 
+// AddTopN implements XExecutor interface.
+// Index request returns the handles in index order, so top n can't be pushed down for now.
+func (e *XSelectIndexExec) AddTopN(orderBy []*tipb.ByItem, count int64) bool {
+	return false
+}
+
 // GetTable implements XExecutor interface.
 func (e *XSelectIndexExec) GetTable() *model.TableInfo {
 	return e.tableInfo
@@ -780,6 +796,11 @@ func (e *XSelectIndexExec) indexRowToTableRow(handle int64, indexRow []types.Dat
 
 func (e *XSelectIndexExec) nextForDoubleRead() (*Row, error) {
 	var startTs time.Time
+	if e.tasks == nil && e.indexMerge != nil {
+		startTs = time.Now()
+		e.tasks = make(chan *lookupTableTask, 50)
+		go e.fetchMergedHandles(e.tasks)
+	}
 	if e.tasks == nil {
 		startTs = time.Now()
 		idxResult, err := e.doIndexRequest()
@@ -848,20 +869,132 @@ func (e *XSelectIndexExec) fetchHandles(idxResult distsql.SelectResult, ch chan<
 		}
 
 		totalHandles += len(handles)
-		tasks := e.buildTableTasks(handles)
-		for _, task := range tasks {
-			if concurrency < len(tasks) {
-				addWorker(e, workCh, &concurrency)
-			}
+		e.dispatchTableTasks(handles, workCh, ch, &concurrency)
+	}
+}
+
+// dispatchTableTasks builds the lookup table tasks for the handles, sends them to the workers by workCh
+// and to the reader by ch.
+func (e *XSelectIndexExec) dispatchTableTasks(handles []int64, workCh chan *lookupTableTask, ch chan<- *lookupTableTask, concurrency *int) {
+	tasks := e.buildTableTasks(handles)
+	for _, task := range tasks {
+		if *concurrency < len(tasks) {
+			addWorker(e, workCh, concurrency)
+		}
+
+		select {
+		case workCh <- task:
+		default:
+			addWorker(e, workCh, concurrency)
+			workCh <- task
+		}
+		ch <- task
+	}
+}
+
+// fetchMergedHandles reads the handles of the partial index scans of index merge, merges them and
+// puts the lookup table tasks in ch.
+func (e *XSelectIndexExec) fetchMergedHandles(ch chan<- *lookupTableTask) {
+	defer close(ch)
+
+	startTs := time.Now()
+	handles, err := e.mergeHandles()
+	if err != nil {
+		e.tasksErr = errors.Trace(err)
+		return
+	}
+	log.Debugf("[TIME_INDEX_MERGE] time: %v handles: %d", time.Since(startTs), len(handles))
 
-			select {
-			case workCh <- task:
-			default:
-				addWorker(e, workCh, &concurrency)
-				workCh <- task
+	workCh := make(chan *lookupTableTask, 1)
+	defer close(workCh)
+
+	var concurrency int
+	addWorker(e, workCh, &concurrency)
+	e.dispatchTableTasks(handles, workCh, ch, &concurrency)
+}
+
+// mergeHandles executes the partial index scans in parallel, and unions or intersects their handles.
+func (e *XSelectIndexExec) mergeHandles() ([]int64, error) {
+	partials := e.indexMerge.IndexScans
+	partialHandles := make([][]int64, len(partials))
+	errs := make([]error, len(partials))
+	var wg sync.WaitGroup
+	for i, is := range partials {
+		wg.Add(1)
+		go func(i int, is *plan.PhysicalIndexScan) {
+			defer wg.Done()
+			partialHandles[i], errs[i] = e.fetchPartialHandles(is)
+		}(i, is)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	if !e.indexMerge.Intersection {
+		seen := make(map[int64]struct{})
+		var handles []int64
+		for _, hs := range partialHandles {
+			for _, h := range hs {
+				if _, ok := seen[h]; !ok {
+					seen[h] = struct{}{}
+					handles = append(handles, h)
+				}
 			}
-			ch <- task
 		}
+		return handles, nil
+	}
+	// counts records how many partial index scans in a row a handle appears in.
+	counts := make(map[int64]int)
+	for i, hs := range partialHandles {
+		for _, h := range hs {
+			if counts[h] == i {
+				counts[h] = i + 1
+			}
+		}
+	}
+	var handles []int64
+	for h, cnt := range counts {
+		if cnt == len(partials) {
+			handles = append(handles, h)
+		}
+	}
+	return handles, nil
+}
+
+// fetchPartialHandles reads all the handles of a partial index scan of index merge.
+func (e *XSelectIndexExec) fetchPartialHandles(is *plan.PhysicalIndexScan) ([]int64, error) {
+	selIdxReq := new(tipb.SelectRequest)
+	selIdxReq.StartTs = e.startTS
+	selIdxReq.IndexInfo = distsql.IndexToProto(e.table.Meta(), is.Index)
+	fieldTypes := make([]*types.FieldType, len(is.Index.Columns))
+	for i, v := range is.Index.Columns {
+		fieldTypes[i] = &(e.table.Cols()[v.Offset].FieldType)
+	}
+	keyRanges, err := indexRangesToKVRanges(e.table.Meta().ID, is.Index.ID, is.Ranges, fieldTypes)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	vars := variable.GetSessionVars(e.ctx)
+	idxResult, err := distsql.Select(e.ctx.GetClient(), selIdxReq, keyRanges, defaultConcurrency, false, &vars.Killed, e.details)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer idxResult.Close()
+	idxResult.IgnoreData()
+	idxResult.Fetch()
+	var handles []int64
+	for {
+		hs, finish, err := extractHandlesFromIndexResult(idxResult)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if finish {
+			return handles, nil
+		}
+		handles = append(handles, hs...)
 	}
 }
 
@@ -893,7 +1026,8 @@ func (e *XSelectIndexExec) doIndexRequest() (distsql.SelectResult, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return distsql.Select(e.ctx.GetClient(), selIdxReq, keyRanges, concurrency, !e.indexPlan.OutOfOrder)
+	vars := variable.GetSessionVars(e.ctx)
+	return distsql.Select(e.ctx.GetClient(), selIdxReq, keyRanges, concurrency, !e.indexPlan.OutOfOrder, &vars.Killed, e.details)
 }
 
 func (e *XSelectIndexExec) buildTableTasks(handles []int64) []*lookupTableTask {
@@ -1018,7 +1152,8 @@ func (e *XSelectIndexExec) doTableRequest(handles []int64) (distsql.SelectResult
 	selTableReq.Aggregates = e.aggFuncs
 	selTableReq.GroupBy = e.byItems
 	keyRanges := tableHandlesToKVRanges(e.table.Meta().ID, handles)
-	resp, err := distsql.Select(e.ctx.GetClient(), selTableReq, keyRanges, defaultConcurrency, false)
+	vars := variable.GetSessionVars(e.ctx)
+	resp, err := distsql.Select(e.ctx.GetClient(), selTableReq, keyRanges, defaultConcurrency, false, &vars.Killed, e.details)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -1036,6 +1171,7 @@ type XSelectTableExec struct {
 	table         table.Table
 	asName        *model.CIStr
 	ctx           context.Context
+	details       *variable.StmtExecDetails
 	supportDesc   bool
 	isMemDB       bool
 	result        distsql.SelectResult
@@ -1062,6 +1198,11 @@ type XSelectTableExec struct {
 	byItems   []*tipb.ByItem
 	aggFields []*types.FieldType
 	aggregate bool
+
+	// orderBy and topnCount are used for top n push down, every region returns its top topnCount rows
+	// ordered by orderBy, the final result is still computed by TopnExec.
+	orderBy   []*tipb.ByItem
+	topnCount *int64
 }
 
 // AddLimit implements XExecutor interface.
@@ -1074,6 +1215,19 @@ func (e *XSelectTableExec) AddLimit(limit *plan.Limit) bool {
 	return false
 }
 
+// AddTopN implements XExecutor interface.
+func (e *XSelectTableExec) AddTopN(orderBy []*tipb.ByItem, count int64) bool {
+	if e.aggregate || e.limitCount != nil || e.desc || len(e.orderBy) > 0 {
+		return false
+	}
+	if !e.ctx.GetClient().SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeTopN) {
+		return false
+	}
+	e.orderBy = orderBy
+	e.topnCount = &count
+	return true
+}
+
 // Schema implements Executor Schema interface.
 func (e *XSelectTableExec) Schema() expression.Schema {
 	return e.schema
@@ -1092,13 +1246,18 @@ func (e *XSelectTableExec) doRequest() error {
 		selReq.OrderBy = append(selReq.OrderBy, &tipb.ByItem{Desc: e.desc})
 	}
 	selReq.Limit = e.limitCount
+	if len(e.orderBy) > 0 {
+		selReq.OrderBy = e.orderBy
+		selReq.Limit = e.topnCount
+	}
 	selReq.TableInfo.Columns = distsql.ColumnsToProto(columns, e.tableInfo.PKIsHandle)
 	// Aggregate Info
 	selReq.Aggregates = e.aggFuncs
 	selReq.GroupBy = e.byItems
 
 	kvRanges := tableRangesToKVRanges(e.table.Meta().ID, e.ranges)
-	e.result, err = distsql.Select(e.ctx.GetClient(), selReq, kvRanges, defaultConcurrency, e.keepOrder)
+	vars := variable.GetSessionVars(e.ctx)
+	e.result, err = distsql.Select(e.ctx.GetClient(), selReq, kvRanges, defaultConcurrency, e.keepOrder, &vars.Killed, e.details)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -1125,13 +1284,50 @@ func (e *XSelectTableExec) Close() error {
 
 // Next implements Executor interface.
 func (e *XSelectTableExec) Next() (*Row, error) {
+	h, rowData, err := e.nextRowData()
+	if err != nil || rowData == nil {
+		return nil, errors.Trace(err)
+	}
+	if e.aggregate {
+		// compose aggreagte row
+		return &Row{Data: rowData}, nil
+	}
+	return resultRowToRow(e.table, h, rowData, e.asName), nil
+}
+
+// NextChunk implements ChunkExecutor NextChunk interface.
+// The row data of the scan result are appended to the chunk columns directly.
+func (e *XSelectTableExec) NextChunk() (*Chunk, error) {
+	var chk *Chunk
+	for chk == nil || chk.NumRows() < maxChunkSize {
+		h, rowData, err := e.nextRowData()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if rowData == nil {
+			break
+		}
+		if chk == nil {
+			chk = newChunk(len(rowData))
+		}
+		var rowKeys []*RowKeyEntry
+		if !e.aggregate {
+			rowKeys = []*RowKeyEntry{{Handle: h, Tbl: e.table, TableAsName: e.asName}}
+		}
+		chk.appendDatums(rowData, rowKeys)
+	}
+	return chk, nil
+}
+
+// nextRowData returns the handle and data of the next row, nil data means there is no more data.
+func (e *XSelectTableExec) nextRowData() (int64, []types.Datum, error) {
 	if e.limitCount != nil && e.returnedRows >= uint64(*e.limitCount) {
-		return nil, nil
+		return 0, nil, nil
 	}
 	if e.result == nil {
 		err := e.doRequest()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 	}
 	for {
@@ -1140,10 +1336,10 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 			startTs := time.Now()
 			e.partialResult, err = e.result.Next()
 			if err != nil {
-				return nil, errors.Trace(err)
+				return 0, nil, errors.Trace(err)
 			}
 			if e.partialResult == nil {
-				return nil, nil
+				return 0, nil, nil
 			}
 			duration := time.Since(startTs)
 			if duration > 30*time.Millisecond {
@@ -1154,18 +1350,14 @@ func (e *XSelectTableExec) Next() (*Row, error) {
 		}
 		h, rowData, err := e.partialResult.Next()
 		if err != nil {
-			return nil, errors.Trace(err)
+			return 0, nil, errors.Trace(err)
 		}
 		if rowData == nil {
 			e.partialResult = nil
 			continue
 		}
 		e.returnedRows++
-		if e.aggregate {
-			// compose aggreagte row
-			return &Row{Data: rowData}, nil
-		}
-		return resultRowToRow(e.table, h, rowData, e.asName), nil
+		return h, rowData, nil
 	}
 }
 
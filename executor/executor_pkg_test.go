@@ -14,9 +14,19 @@
 package executor
 
 import (
+	"os"
+	"time"
+
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/types"
 )
 
 var _ = Suite(&testExecSuite{})
@@ -61,3 +71,135 @@ func (s *testExecSuite) TestMergeHandles(c *C) {
 		c.Assert(kr.EndKey, DeepEquals, ekr.EndKey)
 	}
 }
+
+func (s *testExecSuite) TestSpillFile(c *C) {
+	dec := new(mysql.MyDecimal)
+	c.Assert(dec.FromString([]byte("-12.345")), IsNil)
+	tm, err := mysql.ParseTime("2016-10-18 12:34:56.789", mysql.TypeDatetime, 3)
+	c.Assert(err, IsNil)
+	data := types.MakeDatums(nil, int64(-1), uint64(1<<63), float32(1.5), 2.25, "abc", []byte("def"),
+		dec, tm, mysql.Duration{Duration: -time.Second, Fsp: 2}, mysql.Enum{Name: "a", Value: 1},
+		mysql.Set{Name: "a,b", Value: 3}, mysql.Hex{Value: 10}, mysql.Bit{Value: 5, Width: 3})
+	rk := &RowKeyEntry{Handle: 7, TableAsName: &model.CIStr{O: "t", L: "t"}}
+	rows := []*Row{
+		{Data: data, RowKeys: []*RowKeyEntry{rk}},
+		{Data: types.MakeDatums(int64(1))},
+	}
+
+	f, err := newSpillFile()
+	c.Assert(err, IsNil)
+	for i, row := range rows {
+		c.Assert(f.write(row, types.MakeDatums(int64(i))), IsNil)
+	}
+	c.Assert(f.rewind(), IsNil)
+	for i, row := range rows {
+		got, key, err := f.read()
+		c.Assert(err, IsNil)
+		c.Assert(key, DeepEquals, types.MakeDatums(int64(i)))
+		c.Assert(got.Data, HasLen, len(row.Data))
+		for j := range row.Data {
+			c.Assert(got.Data[j].Kind(), Equals, row.Data[j].Kind())
+			cmp, err := got.Data[j].CompareDatum(row.Data[j])
+			c.Assert(err, IsNil)
+			c.Assert(cmp, Equals, 0)
+		}
+		c.Assert(got.RowKeys, HasLen, len(row.RowKeys))
+		for j := range row.RowKeys {
+			c.Assert(got.RowKeys[j].Handle, Equals, row.RowKeys[j].Handle)
+			c.Assert(got.RowKeys[j].TableAsName, Equals, row.RowKeys[j].TableAsName)
+		}
+	}
+	got, _, err := f.read()
+	c.Assert(err, IsNil)
+	c.Assert(got, IsNil)
+
+	name := f.file.Name()
+	c.Assert(f.close(), IsNil)
+	_, err = os.Stat(name)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
+
+// mockRowExec returns its rows one by one, reading it after the end is an error.
+type mockRowExec struct {
+	rows   []*Row
+	cursor int
+	ended  bool
+}
+
+func (e *mockRowExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+func (e *mockRowExec) Schema() expression.Schema {
+	return nil
+}
+
+func (e *mockRowExec) Next() (*Row, error) {
+	if e.ended {
+		return nil, errors.New("read after end")
+	}
+	if e.cursor >= len(e.rows) {
+		e.ended = true
+		return nil, nil
+	}
+	e.cursor++
+	return e.rows[e.cursor-1], nil
+}
+
+func (e *mockRowExec) Close() error {
+	return nil
+}
+
+func (s *testExecSuite) TestChunk(c *C) {
+	defer func(size int) { maxChunkSize = size }(maxChunkSize)
+	maxChunkSize = 4
+	src := &mockRowExec{}
+	for i := 0; i < 10; i++ {
+		var d types.Datum
+		if i != 3 {
+			d.SetInt64(int64(i))
+		}
+		row := &Row{Data: []types.Datum{d, types.NewIntDatum(int64(i % 3))}}
+		row.RowKeys = []*RowKeyEntry{{Handle: int64(i)}}
+		src.rows = append(src.rows, row)
+	}
+	ctx := mock.NewContext()
+	col0 := &expression.Column{Index: 0, RetType: types.NewFieldType(mysql.TypeLonglong)}
+	col1 := &expression.Column{Index: 1, RetType: types.NewFieldType(mysql.TypeLonglong)}
+	sum, err := expression.NewFunction(ast.Plus, types.NewFieldType(mysql.TypeLonglong), col0, col1)
+	c.Assert(err, IsNil)
+	cond, err := expression.NewFunction(ast.GT, types.NewFieldType(mysql.TypeTiny), sum,
+		&expression.Constant{Value: types.NewIntDatum(4), RetType: types.NewFieldType(mysql.TypeLonglong)})
+	c.Assert(err, IsNil)
+	c.Assert(expression.Vectorizable(cond), IsTrue)
+
+	reader := newChunkReader(src)
+	var handles []int64
+	numChunks := 0
+	for {
+		chk, err := reader.next()
+		c.Assert(err, IsNil)
+		if chk == nil {
+			break
+		}
+		numChunks++
+		c.Assert(chk.NumCols(), Equals, 2)
+		selected, err := filterChunk(ctx, cond, chk)
+		c.Assert(err, IsNil)
+		for i, ok := range selected {
+			row := chk.Row(i)
+			match, err := expression.EvalBool(cond, row.Data, ctx)
+			c.Assert(err, IsNil)
+			c.Assert(ok, Equals, match)
+			if ok {
+				handles = append(handles, row.RowKeys[0].Handle)
+			}
+		}
+	}
+	c.Assert(numChunks, Equals, 3)
+	c.Assert(handles, DeepEquals, []int64{4, 5, 6, 7, 8, 9})
+	// The source has ended, it must not be read again.
+	chk, err := reader.next()
+	c.Assert(err, IsNil)
+	c.Assert(chk, IsNil)
+}
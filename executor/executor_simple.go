@@ -21,6 +21,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
@@ -28,7 +29,9 @@ import (
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/db"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -82,10 +85,16 @@ func (e *SimpleExec) Next() (*Row, error) {
 		err = e.executeRollback(x)
 	case *ast.CreateUserStmt:
 		err = e.executeCreateUser(x)
+	case *ast.CreateBindingStmt:
+		err = e.executeCreateBinding(x)
+	case *ast.DropBindingStmt:
+		err = e.executeDropBinding(x)
 	case *ast.SetPwdStmt:
 		err = e.executeSetPwd(x)
 	case *ast.AnalyzeTableStmt:
 		err = e.executeAnalyzeTable(x)
+	case *ast.KillStmt:
+		err = e.executeKill(x)
 	case *ast.BinlogStmt:
 		// We just ignore it.
 		return nil, nil
@@ -102,6 +111,30 @@ func (e *SimpleExec) Close() error {
 	return nil
 }
 
+func (e *SimpleExec) executeKill(s *ast.KillStmt) error {
+	sm := util.GetSessionManager(e.ctx)
+	if sm == nil {
+		return ErrUnknownThread.Gen("Unknown thread id: %d", s.ConnectionID)
+	}
+	// Like MySQL, only the connections of the current user can be killed without the SUPER privilege.
+	for _, pi := range sm.ShowProcessList() {
+		if pi.ID != s.ConnectionID || util.IsCurrentUser(e.ctx, pi.User) {
+			continue
+		}
+		ok, err := privilege.CheckGlobal(e.ctx, mysql.SuperPriv)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !ok {
+			return ErrKillDenied.Gen("You are not owner of thread %d", s.ConnectionID)
+		}
+	}
+	if !sm.Kill(s.ConnectionID, s.Query) {
+		return ErrUnknownThread.Gen("Unknown thread id: %d", s.ConnectionID)
+	}
+	return nil
+}
+
 func (e *SimpleExec) executeUse(s *ast.UseStmt) error {
 	dbname := model.NewCIStr(s.DBName)
 	dbinfo, exists := sessionctx.GetDomain(e.ctx).InfoSchema().SchemaByName(dbname)
@@ -288,6 +321,13 @@ func (e *SimpleExec) executeRollback(s *ast.RollbackStmt) error {
 
 func (e *SimpleExec) executeCreateUser(s *ast.CreateUserStmt) error {
 	users := make([]string, 0, len(s.Specs))
+	withPlugin := false
+	for _, spec := range s.Specs {
+		if spec.AuthOpt != nil && spec.AuthOpt.AuthPlugin != "" {
+			withPlugin = true
+		}
+	}
+	resourceColumns, resourceCounts := userResourceLimits(s.ResourceOptions)
 	for _, spec := range s.Specs {
 		userName, host := parseUser(spec.User)
 		exists, err1 := userExists(e.ctx, userName, host)
@@ -300,19 +340,54 @@ func (e *SimpleExec) executeCreateUser(s *ast.CreateUserStmt) error {
 			}
 			continue
 		}
-		pwd := ""
-		if spec.AuthOpt.ByAuthString {
-			pwd = util.EncodePassword(spec.AuthOpt.AuthString)
-		} else {
-			pwd = util.EncodePassword(spec.AuthOpt.HashString)
+		pwd, plugin := "", mysql.AuthName
+		if spec.AuthOpt != nil {
+			if spec.AuthOpt.AuthPlugin != "" {
+				plugin = strings.ToLower(spec.AuthOpt.AuthPlugin)
+				if plugin != mysql.AuthName && plugin != mysql.AuthCachingSha2Password {
+					return ErrPluginNotLoaded.Gen("Plugin '%s' is not loaded", spec.AuthOpt.AuthPlugin)
+				}
+			}
+			if spec.AuthOpt.ByAuthString {
+				pwd = encodePassword(plugin, spec.AuthOpt.AuthString)
+			} else if spec.AuthOpt.AuthPlugin != "" {
+				// The hash string of IDENTIFIED WITH ... AS is stored as is.
+				pwd = spec.AuthOpt.HashString
+			} else {
+				pwd = util.EncodePassword(spec.AuthOpt.HashString)
+			}
+			if err1 = checkPasswordLen(e.ctx, pwd); err1 != nil {
+				return errors.Trace(err1)
+			}
+		}
+		user := fmt.Sprintf(`"%s", "%s", %s`, host, userName, quoteString(pwd))
+		if withPlugin {
+			user += fmt.Sprintf(`, "%s"`, plugin)
+		}
+		if s.Require != ast.RequireUnspecified {
+			user += fmt.Sprintf(`, "%s"`, requireSSLType(s.Require))
+		}
+		for _, count := range resourceCounts {
+			user += fmt.Sprintf(", %d", count)
 		}
-		user := fmt.Sprintf(`("%s", "%s", "%s")`, host, userName, pwd)
-		users = append(users, user)
+		users = append(users, "("+user+")")
 	}
 	if len(users) == 0 {
 		return nil
 	}
-	sql := fmt.Sprintf(`INSERT INTO %s.%s (Host, User, Password) VALUES %s;`, mysql.SystemDB, mysql.UserTable, strings.Join(users, ", "))
+	// The plugin column is only set when it's specified, so the mysql.user tables
+	// bootstrapped by the old versions without the column still work.
+	columns := "Host, User, Password"
+	if withPlugin {
+		columns += ", plugin"
+	}
+	if s.Require != ast.RequireUnspecified {
+		columns += ", ssl_type"
+	}
+	for _, column := range resourceColumns {
+		columns += ", " + column
+	}
+	sql := fmt.Sprintf(`INSERT INTO %s.%s (%s) VALUES %s;`, mysql.SystemDB, mysql.UserTable, columns, strings.Join(users, ", "))
 	_, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
 	if err != nil {
 		return errors.Trace(err)
@@ -320,6 +395,47 @@ func (e *SimpleExec) executeCreateUser(s *ast.CreateUserStmt) error {
 	return nil
 }
 
+// userResourceColumns are the columns of the resource limits in mysql.user table.
+var userResourceColumns = []struct {
+	tp     ast.ResourceOptionType
+	column string
+}{
+	{ast.MaxQueriesPerHour, "max_questions"},
+	{ast.MaxUserConnections, "max_user_connections"},
+}
+
+// userResourceLimits converts the resource options of WITH clause to the columns and values in mysql.user table.
+// The last one is used if an option is specified more than once.
+func userResourceLimits(opts []*ast.ResourceOption) (columns []string, counts []uint64) {
+	for _, rc := range userResourceColumns {
+		var (
+			count uint64
+			found bool
+		)
+		for _, opt := range opts {
+			if opt.Type == rc.tp {
+				count, found = opt.Count, true
+			}
+		}
+		if found {
+			columns = append(columns, rc.column)
+			counts = append(counts, count)
+		}
+	}
+	return
+}
+
+// requireSSLType converts the REQUIRE clause to the value of ssl_type column in mysql.user table.
+func requireSSLType(require ast.RequireType) string {
+	switch require {
+	case ast.RequireSSL:
+		return mysql.SSLTypeAny
+	case ast.RequireX509:
+		return mysql.SSLTypeX509
+	}
+	return mysql.SSLTypeNone
+}
+
 // parse user string into username and host
 // root@localhost -> roor, localhost
 func parseUser(user string) (string, string) {
@@ -341,15 +457,138 @@ func userExists(ctx context.Context, name string, host string) (bool, error) {
 	return row != nil, nil
 }
 
+func (e *SimpleExec) executeCreateBinding(s *ast.CreateBindingStmt) error {
+	originalSQL := parser.Normalize(s.OriginSel.Text())
+	if parser.Normalize(s.HintedSel.Text()) != originalSQL {
+		return ErrBindingNotMatch.Gen("%s doesn't match %s except for the index hints", s.HintedSel.Text(), s.OriginSel.Text())
+	}
+	now := mysql.CurrentTime(mysql.TypeDatetime)
+	record := &bindinfo.BindRecord{
+		OriginalSQL: originalSQL,
+		BindSQL:     s.HintedSel.Text(),
+		DefaultDB:   db.GetCurrentSchema(e.ctx),
+		CreateTime:  now,
+		UpdateTime:  now,
+		BindStmt:    s.HintedSel,
+	}
+	if !s.GlobalScope {
+		sessionBindHandle(e.ctx).Add(record)
+		return nil
+	}
+	sql := fmt.Sprintf(`INSERT INTO %s.%s VALUES (%s, %s, %s, "%s", "%s") ON DUPLICATE KEY UPDATE bind_sql = %s, update_time = "%s";`,
+		mysql.SystemDB, mysql.BindInfoTable, quoteString(record.OriginalSQL), quoteString(record.BindSQL),
+		quoteString(record.DefaultDB), now, now, quoteString(record.BindSQL), now)
+	_, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The global bindings are reloaded after the transaction is committed.
+	bindinfo.SetGlobalChanged(e.ctx)
+	return nil
+}
+
+func (e *SimpleExec) executeDropBinding(s *ast.DropBindingStmt) error {
+	originalSQL := parser.Normalize(s.OriginSel.Text())
+	dbName := db.GetCurrentSchema(e.ctx)
+	if !s.GlobalScope {
+		sessionBindHandle(e.ctx).Remove(originalSQL, dbName)
+		return nil
+	}
+	sql := fmt.Sprintf(`DELETE FROM %s.%s WHERE original_sql = %s AND default_db = %s;`,
+		mysql.SystemDB, mysql.BindInfoTable, quoteString(originalSQL), quoteString(dbName))
+	_, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The global bindings are reloaded after the transaction is committed.
+	bindinfo.SetGlobalChanged(e.ctx)
+	return nil
+}
+
+// sessionBindHandle returns the handle of the session bindings, it is created if the session doesn't have one.
+func sessionBindHandle(ctx context.Context) *bindinfo.Handle {
+	h := bindinfo.GetSessionHandle(ctx)
+	if h == nil {
+		h = bindinfo.NewHandle()
+		bindinfo.BindSessionHandle(ctx, h)
+	}
+	return h
+}
+
+// quoteString quotes a string as a string literal of SQL.
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
 func (e *SimpleExec) executeSetPwd(s *ast.SetPwdStmt) error {
 	// TODO: If len(s.User) == 0, use CURRENT_USER()
 	userName, host := parseUser(s.User)
+	plugin, err := userAuthPlugin(e.ctx, userName, host)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	pwd := encodePassword(plugin, s.Password)
+	if err = checkPasswordLen(e.ctx, pwd); err != nil {
+		return errors.Trace(err)
+	}
 	// Update mysql.user
-	sql := fmt.Sprintf(`UPDATE %s.%s SET password="%s" WHERE User="%s" AND Host="%s";`, mysql.SystemDB, mysql.UserTable, util.EncodePassword(s.Password), userName, host)
-	_, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	sql := fmt.Sprintf(`UPDATE %s.%s SET password="%s" WHERE User="%s" AND Host="%s";`, mysql.SystemDB, mysql.UserTable, pwd, userName, host)
+	_, err = e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
 	return errors.Trace(err)
 }
 
+// encodePassword converts the plaintext password to the hashed string stored in mysql.user table for the plugin.
+func encodePassword(plugin, pwd string) string {
+	if plugin == mysql.AuthCachingSha2Password {
+		return util.EncodeSha2Password(pwd)
+	}
+	return util.EncodePassword(pwd)
+}
+
+// checkPasswordLen returns an error if the password hash doesn't fit in the Password column of mysql.user table.
+// The column of the stores bootstrapped by the old versions is CHAR(41), it can't hold the caching_sha2_password hashes.
+func checkPasswordLen(ctx context.Context, pwd string) error {
+	tbl, err := sessionctx.GetDomain(ctx).InfoSchema().TableByName(model.NewCIStr(mysql.SystemDB), model.NewCIStr(mysql.UserTable))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, col := range tbl.Cols() {
+		if col.Name.L == "password" && col.Flen > 0 && len(pwd) > col.Flen {
+			return errors.Errorf("the password hash is longer than mysql.user.Password CHAR(%d), the store is bootstrapped by an old version", col.Flen)
+		}
+	}
+	return nil
+}
+
+// userAuthPlugin gets the authentication plugin of the user.
+// The mysql.user tables bootstrapped by the old versions don't have the plugin column, their users use mysql_native_password.
+func userAuthPlugin(ctx context.Context, name string, host string) (string, error) {
+	sql := fmt.Sprintf(`SELECT * FROM %s.%s WHERE User="%s" AND Host="%s";`, mysql.SystemDB, mysql.UserTable, name, host)
+	rs, err := ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(ctx, sql)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer rs.Close()
+	fields, err := rs.Fields()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	row, err := rs.Next()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if row == nil {
+		return mysql.AuthName, nil
+	}
+	for i, f := range fields {
+		if f.ColumnAsName.L == "plugin" {
+			return row.Data[i].GetString(), nil
+		}
+	}
+	return mysql.AuthName, nil
+}
+
 func (e *SimpleExec) executeFlushTable(s *ast.FlushTableStmt) error {
 	// TODO: A dummy implement
 	return nil
@@ -18,12 +18,14 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan/statistics"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
@@ -209,6 +211,46 @@ func (s *testSuite) TestSetPwd(c *C) {
 	result.Check(testkit.Rows(rowStr))
 }
 
+func (s *testSuite) TestCreateUserWithPlugin(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec(`CREATE USER 'testsha2'@'localhost' IDENTIFIED WITH caching_sha2_password BY '123', 'testnative'@'localhost' IDENTIFIED BY '123'`)
+	tk.MustQuery(`SELECT User, plugin FROM mysql.User WHERE User in ("testsha2", "testnative") and Host="localhost" order by User`).Check(testkit.Rows(
+		fmt.Sprintf("%v %v", []byte("testnative"), []byte(mysql.AuthName)),
+		fmt.Sprintf("%v %v", []byte("testsha2"), []byte(mysql.AuthCachingSha2Password)),
+	))
+	checkSha2Password := func(pwd string) {
+		rows := tk.MustQuery(`SELECT Password FROM mysql.User WHERE User="testsha2" and Host="localhost"`).Rows()
+		c.Assert(util.CheckSha2Password(string(rows[0][0].([]byte)), []byte(pwd)), IsTrue)
+	}
+	checkSha2Password("123")
+	// SET PASSWORD hashes the password with the plugin of the user.
+	tk.MustExec(`SET PASSWORD FOR 'testsha2'@'localhost' = 'password';`)
+	checkSha2Password("password")
+
+	// The hash string of AS is stored as is.
+	tk.MustExec(`CREATE USER 'testas'@'localhost' IDENTIFIED WITH 'mysql_native_password' AS '40bd001563085fc35165329ea1ff5c5ecbdbbeef'`)
+	tk.MustQuery(`SELECT Password FROM mysql.User WHERE User="testas" and Host="localhost"`).Check(testkit.Rows(
+		fmt.Sprintf("%v", []byte("40bd001563085fc35165329ea1ff5c5ecbdbbeef"))))
+
+	_, err := tk.Exec(`CREATE USER 'testplugin'@'localhost' IDENTIFIED WITH sha256_password BY '123'`)
+	c.Assert(terror.ErrorEqual(err, executor.ErrPluginNotLoaded), IsTrue)
+}
+
+func (s *testSuite) TestUserResourceOptions(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec(`CREATE USER 'testres'@'localhost' WITH MAX_QUERIES_PER_HOUR 10 MAX_USER_CONNECTIONS 2 MAX_USER_CONNECTIONS 3`)
+	checkLimits := func(expected string) {
+		tk.MustQuery(`SELECT max_questions, max_user_connections FROM mysql.User WHERE User="testres" and Host="localhost"`).Check(testkit.Rows(expected))
+	}
+	checkLimits("10 3")
+	tk.MustExec(`GRANT SELECT ON *.* TO 'testres'@'localhost' WITH MAX_USER_CONNECTIONS 0`)
+	checkLimits("10 0")
+	tk.MustExec(`GRANT SELECT ON *.* TO 'testres'@'localhost'`)
+	checkLimits("10 0")
+}
+
 func (s *testSuite) TestAnalyzeTable(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
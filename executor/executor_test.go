@@ -16,7 +16,10 @@ package executor_test
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -32,10 +35,14 @@ import (
 	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/slowlog"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -193,6 +200,156 @@ func (s *testSuite) TestPrepared(c *C) {
 	exec.Close()
 }
 
+func (s *testSuite) TestPlanCache(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists plan_cache")
+	tk.MustExec("create table plan_cache (id int primary key, c1 int, c2 varchar(20), index idx_c1(c1))")
+	tk.MustExec("insert plan_cache values (1, 10, 'a'), (2, 20, 'ab'), (3, 30, 'b'), (4, 40, 'bc')")
+
+	tk.MustExec(`prepare stmt_range from 'select id from plan_cache where id > ? and id <= ?'`)
+	tk.MustExec(`prepare stmt_index from 'select id from plan_cache where c1 = ? or c1 in (?, 40)'`)
+	tk.MustExec(`prepare stmt_like from 'select id from plan_cache where c2 like ? and c1 + ? > 25'`)
+	tk.MustExec(`prepare stmt_cond from 'select count(*) from plan_cache where ?'`)
+	hits, misses := executor.PlanCacheHits(), executor.PlanCacheMisses()
+	tk.MustExec("set @a = 1, @b = 3")
+	tk.MustQuery("execute stmt_range using @a, @b").Check(testkit.Rows("2", "3"))
+	tk.MustExec("set @a = 2, @b = 4")
+	tk.MustQuery("execute stmt_range using @a, @b").Check(testkit.Rows("3", "4"))
+	tk.MustExec("set @a = 10, @b = 20")
+	tk.MustQuery("execute stmt_index using @a, @b").Check(testkit.Rows("1", "2", "4"))
+	tk.MustExec("set @a = 30, @b = 0")
+	tk.MustQuery("execute stmt_index using @a, @b").Check(testkit.Rows("3", "4"))
+	tk.MustExec("set @a = 'a%', @b = 0")
+	tk.MustQuery("execute stmt_like using @a, @b").Check(testkit.Rows())
+	tk.MustExec("set @a = '%b%', @b = 10")
+	tk.MustQuery("execute stmt_like using @a, @b").Check(testkit.Rows("2", "3", "4"))
+	tk.MustExec("set @a = 0")
+	tk.MustQuery("execute stmt_cond using @a").Check(testkit.Rows("0"))
+	tk.MustExec("set @a = 1")
+	tk.MustQuery("execute stmt_cond using @a").Check(testkit.Rows("4"))
+	c.Assert(executor.PlanCacheMisses()-misses, Equals, int64(4))
+	c.Assert(executor.PlanCacheHits()-hits, Equals, int64(4))
+
+	// A parameter of another kind needs another plan.
+	stmtID, _, _, err := tk.Se.PrepareStmt("select id from plan_cache where id > ? and id <= ?")
+	c.Assert(err, IsNil)
+	for _, args := range [][]interface{}{{1, 3}, {2, 4}, {"1", 3}} {
+		rs, err := tk.Se.ExecutePreparedStmt(stmtID, args...)
+		c.Assert(err, IsNil)
+		_, err = tidb.GetRows(rs)
+		c.Assert(err, IsNil)
+	}
+	c.Assert(executor.PlanCacheMisses()-misses, Equals, int64(6))
+	c.Assert(executor.PlanCacheHits()-hits, Equals, int64(5))
+
+	// The plan in a transaction with uncommitted changes is not cached.
+	tk.MustExec("begin")
+	tk.MustExec("insert plan_cache values (5, 50, 'c')")
+	rs, err := tk.Se.ExecutePreparedStmt(stmtID, 3, 10)
+	c.Assert(err, IsNil)
+	rows, err := tidb.GetRows(rs)
+	c.Assert(err, IsNil)
+	c.Assert(rows, HasLen, 2)
+	tk.MustExec("rollback")
+	tk.MustExec("set @a = 3, @b = 9")
+	tk.MustQuery("execute stmt_range using @a, @b").Check(testkit.Rows("4"))
+	c.Assert(executor.PlanCacheMisses()-misses, Equals, int64(6))
+	c.Assert(executor.PlanCacheHits()-hits, Equals, int64(6))
+
+	// The plan is optimized again after the schema changes.
+	tk.MustExec("alter table plan_cache add column c3 int default 1")
+	tk.MustQuery("execute stmt_range using @a, @b").Check(testkit.Rows("4"))
+	c.Assert(executor.PlanCacheMisses()-misses, Equals, int64(7))
+
+	// Statements with subqueries are not cached.
+	tk.MustExec(`prepare stmt_sub from 'select id from plan_cache where c1 > (select ?)'`)
+	tk.MustExec("set @c = 30")
+	tk.MustQuery("execute stmt_sub using @c").Check(testkit.Rows("4"))
+	tk.MustQuery("execute stmt_sub using @c").Check(testkit.Rows("4"))
+	c.Assert(executor.PlanCacheMisses()-misses, Equals, int64(7))
+
+	tk.MustExec("set @@tidb_enable_plan_cache = 0")
+	tk.MustQuery("execute stmt_range using @a, @b").Check(testkit.Rows("4"))
+	c.Assert(executor.PlanCacheMisses()-misses, Equals, int64(7))
+	c.Assert(executor.PlanCacheHits()-hits, Equals, int64(6))
+}
+
+func (s *testSuite) TestPointGet(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists point_get, point_get_u")
+	tk.MustExec("create table point_get (id int primary key, c1 int, c2 varchar(20), c3 int, unique key uk_c1(c1), unique key uk_c2_c3(c2, c3))")
+	tk.MustExec("insert point_get values (1, 10, 'a', 1), (2, 20, 'b', 2), (3, 30, 'c', 3), (4, 40, null, 4)")
+
+	tk.MustQuery("select * from point_get where id = 2").Check(testkit.Rows(fmt.Sprintf("2 20 %v 2", []byte("b"))))
+	tk.MustQuery("select * from point_get where id = 5").Check(testkit.Rows())
+	tk.MustQuery("select c1, id from point_get where id in (3, 1, 3, 5)").Check(testkit.Rows("10 1", "30 3"))
+	tk.MustQuery("select t.id, t.c1 as x from point_get t where 2 = t.id").Check(testkit.Rows("2 20"))
+	tk.MustQuery("select id from point_get where c1 = 30").Check(testkit.Rows("3"))
+	tk.MustQuery("select id from point_get where c1 in (40, 10, 50)").Check(testkit.Rows("1", "4"))
+	tk.MustQuery("select id from point_get where c3 = 2 and c2 = 'b'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from point_get where c2 = 'b' and c3 = 3").Check(testkit.Rows())
+	tk.MustQuery("select id from point_get where c2 = 'b' and c3 = 2 and c1 = 20").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from point_get where c1 = '20'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from point_get where id = 1.0").Check(testkit.Rows("1"))
+	result := tk.MustQuery("select * from point_get where id = 1")
+	c.Assert(result.Rows()[0], HasLen, 4)
+
+	// Unsigned handles and index values.
+	tk.MustExec("create table point_get_u (id bigint unsigned primary key, c1 int unsigned, unique key uk_c1(c1))")
+	tk.MustExec("insert point_get_u values (18446744073709551615, 1), (1, 4294967295)")
+	tk.MustQuery("select c1 from point_get_u where id = 18446744073709551615").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from point_get_u where c1 in (4294967295, 2)").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from point_get_u where id = ?", -1).Check(testkit.Rows())
+
+	// Prepared statements.
+	stmtID, _, _, err := tk.Se.PrepareStmt("select c2 from point_get where id = ?")
+	c.Assert(err, IsNil)
+	hits, misses := executor.PlanCacheHits(), executor.PlanCacheMisses()
+	for i, expected := range []string{"a", "b"} {
+		rs, err := tk.Se.ExecutePreparedStmt(stmtID, i+1)
+		c.Assert(err, IsNil)
+		rows, err := tidb.GetRows(rs)
+		c.Assert(err, IsNil)
+		c.Assert(rows, HasLen, 1)
+		c.Assert(rows[0][0].GetString(), Equals, expected)
+	}
+	// The point get plans are neither cached nor fetched from the cache.
+	c.Assert(executor.PlanCacheHits(), Equals, hits)
+	c.Assert(executor.PlanCacheMisses(), Equals, misses)
+
+	// Uncommitted changes are read.
+	tk.MustExec("begin")
+	tk.MustExec("insert point_get values (5, 50, 'e', 5)")
+	tk.MustExec("delete from point_get where id = 1")
+	tk.MustQuery("select id from point_get where id in (1, 5)").Check(testkit.Rows("5"))
+	tk.MustQuery("select id from point_get where c1 in (10, 50)").Check(testkit.Rows("5"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select id from point_get where id in (1, 5)").Check(testkit.Rows("1"))
+
+	// Update and delete.
+	tk.MustExec("update point_get set c1 = c1 + 1, c2 = 'x' where id = 1")
+	tk.MustQuery("select * from point_get where id = 1").Check(testkit.Rows(fmt.Sprintf("1 11 %v 1", []byte("x"))))
+	tk.MustQuery("select id from point_get where c1 = 11").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from point_get where c1 = 10").Check(testkit.Rows())
+	tk.MustExec("update point_get t set t.c3 = 7 where t.c2 = 'b' and t.c3 = 2")
+	tk.MustQuery("select c3 from point_get where id = 2").Check(testkit.Rows("7"))
+	tk.MustExec("update point_get set c1 = 100 where id = 10")
+	c.Assert(tk.Se.AffectedRows(), Equals, uint64(0))
+	tk.MustExec("delete from point_get where c1 in (11, 30)")
+	tk.MustQuery("select id from point_get").Check(testkit.Rows("2", "4"))
+	tk.MustExec("delete from point_get where id = 2")
+	tk.MustQuery("select id from point_get").Check(testkit.Rows("4"))
+	_, err = tk.Exec("update point_get set c1 = 40 where id = 3")
+	c.Assert(err, IsNil)
+	tk.MustExec("insert point_get values (3, 30, 'c', 3)")
+	_, err = tk.Exec("update point_get set c1 = 40 where id = 3")
+	c.Assert(err, NotNil)
+}
+
 func (s *testSuite) fillData(tk *testkit.TestKit, table string) {
 	tk.MustExec("use test")
 	tk.MustExec(fmt.Sprintf("create table %s(id int not null default 1, name varchar(255), PRIMARY KEY(id));", table))
@@ -1972,6 +2129,224 @@ func (s *testSuite) TestNewSubquery(c *C) {
 	result.Check(testkit.Rows("1 2"))
 }
 
+func (s *testSuite) TestDecorrelatedSubquery(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t, s")
+	tk.MustExec("create table t (a int, b int)")
+	tk.MustExec("insert t values (1, 1), (2, 2), (3, NULL), (NULL, 4)")
+	tk.MustExec("create table s (a int, b int)")
+	tk.MustExec("insert s values (1, 10), (1, 20), (2, 30), (NULL, 40)")
+	result := tk.MustQuery("select a from t where exists(select * from s where s.a = t.a)")
+	result.Check(testkit.Rows("1", "2"))
+	result = tk.MustQuery("select a from t where not exists(select * from s where s.a = t.a)")
+	result.Check(testkit.Rows("3", "<nil>"))
+	result = tk.MustQuery("select a from t where not exists(select * from s where s.a = t.a and t.b > 1)")
+	result.Check(testkit.Rows("1", "3", "<nil>"))
+	result = tk.MustQuery("select a, exists(select * from s where s.a = t.a) from t")
+	result.Check(testkit.Rows("1 1", "2 1", "3 0", "<nil> 0"))
+	result = tk.MustQuery("select a, not exists(select * from s where s.a = t.a) from t")
+	result.Check(testkit.Rows("1 0", "2 0", "3 1", "<nil> 1"))
+	result = tk.MustQuery("select a from t where t.b in (select s.a from s where s.b > t.a * 10)")
+	result.Check(testkit.Rows("1", "2"))
+	// The nullable not in subquery is still evaluated by apply.
+	result = tk.MustQuery("select a from t where t.b not in (select s.a from s where s.b > t.a * 10)")
+	result.Check(testkit.Rows("<nil>"))
+	result = tk.MustQuery("select a, (select max(s.b) from s where s.a = t.a) from t order by a")
+	result.Check(testkit.Rows("<nil> <nil>", "1 20", "2 30", "3 <nil>"))
+	result = tk.MustQuery("select a from t where (select sum(s.b) from s where s.a = t.a) > 25")
+	result.Check(testkit.Rows("1", "2"))
+	result = tk.MustQuery("select a, (select count(*) from s where s.a = t.a) from t order by a")
+	result.Check(testkit.Rows("<nil> 0", "1 2", "2 1", "3 0"))
+	result = tk.MustQuery("select a, (select max(s.b) from s where s.a < t.a) from t order by a")
+	result.Check(testkit.Rows("<nil> <nil>", "1 <nil>", "2 20", "3 30"))
+	// Different varchar values equal the same int, the subquery is not grouped by the varchar column.
+	tk.MustExec("drop table if exists v")
+	tk.MustExec("create table v (a varchar(10), b int)")
+	tk.MustExec("insert v values ('1', 10), ('1.0', 20), ('2', 30)")
+	result = tk.MustQuery("select a, (select max(v.b) from v where v.a = t.a) from t order by a")
+	result.Check(testkit.Rows("<nil> <nil>", "1 20", "2 30", "3 <nil>"))
+	result = tk.MustQuery("select a, (select sum(v.b) from v where v.a = t.a) from t order by a")
+	result.Check(testkit.Rows("<nil> <nil>", "1 30", "2 30", "3 <nil>"))
+
+	tk.MustExec("drop table if exists t1, s1")
+	tk.MustExec("create table t1 (a int not null, b int not null)")
+	tk.MustExec("insert t1 values (1, 1), (2, 2), (3, 3)")
+	tk.MustExec("create table s1 (a int not null, b int not null)")
+	tk.MustExec("insert s1 values (1, 10), (2, 20), (3, 5)")
+	result = tk.MustQuery("select a from t1 where b not in (select s1.a from s1 where s1.b >= t1.a * 5)")
+	result.Check(testkit.Rows("3"))
+	result = tk.MustQuery("select a, b in (select s1.a from s1 where s1.b >= t1.a * 5) from t1")
+	result.Check(testkit.Rows("1 1", "2 1", "3 0"))
+}
+
+func (s *testSuite) TestCoprocessorPushDown(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, b varchar(20), c datetime)")
+	tk.MustExec(`insert t values (1, 5, 'Apple', '2016-01-02 10:00:00'), (2, 3, 'banana', '2016-03-04 11:30:00'),
+		(3, NULL, 'cherry', '2015-12-31 23:59:59'), (4, 8, NULL, NULL), (5, 1, 'date', '2016-03-01 00:00:00')`)
+
+	// Arithmetic operators, control, string and time functions in where condition.
+	tk.MustQuery("select id from t where a - 2 > 2").Check(testkit.Rows("1", "4"))
+	tk.MustQuery("select id from t where a * 2 = 6 or a % 2 = 1").Check(testkit.Rows("1", "2", "5"))
+	tk.MustQuery("select id from t where a div 2 = 2").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from t where if(a > 4, 1, 0) = 1").Check(testkit.Rows("1", "4"))
+	tk.MustQuery("select id from t where ifnull(a, 0) = 0").Check(testkit.Rows("3"))
+	tk.MustQuery("select id from t where case when a > 4 then 'big' else 'small' end = 'small'").Check(testkit.Rows("2", "3", "5"))
+	tk.MustQuery("select id from t where upper(b) = 'BANANA'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t where concat(b, '!') like 'c%'").Check(testkit.Rows("3"))
+	tk.MustQuery("select id from t where length(b) = 4").Check(testkit.Rows("5"))
+	tk.MustQuery("select id from t where substring(b, 2, 3) = 'ana'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t where year(c) = 2016 and month(c) = 3").Check(testkit.Rows("2", "5"))
+	tk.MustQuery("select id from t where hour(c) > 10").Check(testkit.Rows("2", "3"))
+
+	// Top n.
+	tk.MustQuery("select id from t order by a desc limit 2").Check(testkit.Rows("4", "1"))
+	tk.MustQuery("select id from t order by a limit 2").Check(testkit.Rows("3", "5"))
+	tk.MustQuery("select id from t order by a limit 1, 2").Check(testkit.Rows("5", "2"))
+	tk.MustQuery("select id from t order by length(b), id limit 3").Check(testkit.Rows("4", "5", "1"))
+	tk.MustQuery("select id from t where a > 1 order by c desc limit 2").Check(testkit.Rows("2", "1"))
+	tk.MustQuery("select id from t order by a limit 0").Check(testkit.Rows())
+
+	// Group concat.
+	tk.MustQuery("select group_concat(b) from t where id < 3").Check(testkit.Rows("Apple,banana"))
+	tk.MustQuery("select a % 2 as k, group_concat(id) from t group by k order by k").Check(testkit.Rows("<nil> 3", "0 4", "1 1,2,5"))
+
+	// The rows in the dirty transaction are not seen by the coprocessor.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (6, 0, 'egg', NULL)")
+	tk.MustQuery("select id from t order by a limit 2").Check(testkit.Rows("3", "6"))
+	tk.MustQuery("select id from t where a * 2 = 0").Check(testkit.Rows("6"))
+	tk.MustExec("rollback")
+}
+
+func (s *testSuite) TestIndexMerge(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, b int, c int, key k_a(a), key k_b(b))")
+	tk.MustExec("insert t values (1, 1, 1, 1), (2, 1, 2, 2), (3, 2, 1, 3), (4, 3, 3, 4), (5, NULL, 1, 5)")
+
+	// Union of the handles.
+	tk.MustQuery("select id from t where a = 1 or b = 1 order by id").Check(testkit.Rows("1", "2", "3", "5"))
+	tk.MustQuery("select id from t where (a = 1 and c = 2) or b = 3 order by id").Check(testkit.Rows("2", "4"))
+	tk.MustQuery("select count(*) from t where a in (2, 3) or b = 2").Check(testkit.Rows("3"))
+	tk.MustQuery("select id from t where a = 1 or b = 1 order by id limit 1, 2").Check(testkit.Rows("2", "3"))
+	// Intersection of the handles.
+	tk.MustQuery("select id from t where a = 1 and b = 1").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from t where a >= 1 and b < 2 order by id").Check(testkit.Rows("1", "3"))
+	tk.MustQuery("select id from t where a = 3 and b = 1").Check(testkit.Rows())
+
+	// Index merge in a dirty transaction.
+	tk.MustExec("begin")
+	tk.MustExec("insert t values (6, 1, 5, 6)")
+	tk.MustExec("delete from t where id = 3")
+	tk.MustExec("update t set b = 1 where id = 4")
+	tk.MustQuery("select count(*), sum(id) from t where a = 1 or b = 1").Check(testkit.Rows("5 18"))
+	tk.MustQuery("select id from t where a = 3 and b = 1").Check(testkit.Rows("4"))
+	tk.MustExec("rollback")
+	tk.MustQuery("select count(*), sum(id) from t where a = 1 or b = 1").Check(testkit.Rows("4 11"))
+}
+
+func (s *testSuite) TestAggPushDownAndJoinElimination(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1 (id int primary key, a int, b int, c int)")
+	tk.MustExec("create table t2 (id int primary key, a int, b int, unique key uk_b(b))")
+	tk.MustExec("insert t1 values (1, 1, 10, 1), (2, 1, 20, 1), (3, 2, NULL, 2), (4, 2, 40, 3), (5, NULL, 50, NULL)")
+	tk.MustExec("insert t2 values (1, 1, 1), (2, 1, 2), (3, 2, 3), (4, 3, NULL)")
+
+	// The aggregation is pushed down through the join.
+	tk.MustQuery("select sum(t1.b), count(*), count(t1.b) from t1, t2 where t1.c = t2.a").Check(testkit.Rows("100 6 5"))
+	tk.MustQuery("select t2.id, count(*), avg(t1.b), max(t1.b), min(t1.b) from t1 join t2 on t1.c = t2.a group by t2.id order by t2.id").Check(
+		testkit.Rows("1 2 15.0000 20 10", "2 2 15.0000 20 10", "3 1 <nil> <nil> <nil>", "4 1 40.0000 40 40"))
+	tk.MustQuery("select t1.a, sum(t1.b) from t1 left join t2 on t1.c = t2.a group by t1.a order by t1.a").Check(
+		testkit.Rows("<nil> 50", "1 60", "2 40"))
+	tk.MustQuery("select count(*), sum(t1.b) from t1, t2").Check(testkit.Rows("20 480"))
+	tk.MustQuery("select count(*), sum(t1.b) from t1, t2 where t1.c = t2.a and t2.a > 5").Check(testkit.Rows("0 <nil>"))
+	tk.MustQuery("select t1.a, count(*) from t1, t2 where t1.c = t2.a and t2.a > 5 group by t1.a").Check(testkit.Rows())
+
+	// The outer joins whose inner side contributes no columns are eliminated.
+	tk.MustQuery("select t1.id from t1 left join t2 on t1.c = t2.id order by t1.id").Check(testkit.Rows("1", "2", "3", "4", "5"))
+	tk.MustQuery("select count(*) from t1 left join t2 on t1.c = t2.b").Check(testkit.Rows("5"))
+	tk.MustQuery("select t2.id from t1 right join t2 on t1.id = t2.a order by t2.id").Check(testkit.Rows("1", "2", "3", "4"))
+	// The join isn't on a unique key, the rows are duplicated.
+	tk.MustQuery("select count(*) from t1 left join t2 on t1.c = t2.a").Check(testkit.Rows("7"))
+	// The null-rejecting predicate turns the outer join into an inner join.
+	tk.MustQuery("select t1.id from t1 left join t2 on t1.c = t2.id where t2.b > 1 order by t1.id").Check(testkit.Rows("3", "4"))
+}
+
+func (s *testSuite) TestPredicateSimplification(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, b int, s varchar(10), key k_a(a))")
+	tk.MustExec("insert t values (1, 1, 1, 'a'), (2, 5, 5, 'b'), (3, 10, 5, 'c'), (4, 15, NULL, 'd'), (5, NULL, 10, 'e')")
+
+	// The comparisons of a column are folded.
+	tk.MustQuery("select id from t where a > 1 and a >= 5 and 10 >= a order by id").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select id from t where a >= 5 and a > 5 order by id").Check(testkit.Rows("3", "4"))
+	tk.MustQuery("select id from t where a = b and b = 5 and a >= 5").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t where s > 'a' and s < 'd' and s >= 'b' order by id").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select id from t where (a > 10 and a < 5) or b = 10").Check(testkit.Rows("5"))
+	tk.MustQuery("select id from t where 1 = 1 and a = 1").Check(testkit.Rows("1"))
+	// The conditions that can never be satisfied return nothing.
+	tk.MustQuery("select id from t where a > 10 and a < 5").Check(testkit.Rows())
+	tk.MustQuery("select id from t where a = b and b = 5 and a > 5").Check(testkit.Rows())
+	tk.MustQuery("select id from t where a = 1 and a = 2").Check(testkit.Rows())
+	tk.MustQuery("select id from t where a = null").Check(testkit.Rows())
+	tk.MustQuery("select count(*), sum(a) from t where a > 10 and a < 5").Check(testkit.Rows("0 <nil>"))
+	tk.MustQuery("select count(*) from t having 1 = 0").Check(testkit.Rows())
+	tk.MustQuery("select t1.id from t t1 join t t2 on t1.id = t2.id and t1.a < 0 and t2.a > 0").Check(testkit.Rows())
+	tk.MustQuery("select t1.id, t2.id from t t1 left join t t2 on t1.id = t2.id and t2.a > 10 and t2.a < 5 where t1.id < 3").Check(
+		testkit.Rows("1 <nil>", "2 <nil>"))
+	tk.MustExec("update t set b = 0 where a > 10 and a < 5")
+	tk.MustExec("delete from t where id = 1 and id = 2")
+	tk.MustQuery("select count(*), sum(b) from t").Check(testkit.Rows("5 21"))
+
+	// A string column compared with a number is compared as a number, so the constants are not folded together.
+	tk.MustExec("drop table if exists t1")
+	tk.MustExec("create table t1 (id int primary key, s varchar(10))")
+	tk.MustExec("insert t1 values (1, '1'), (2, '1.0')")
+	tk.MustQuery("select id from t1 where s = 1 and s = '1.0'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t1 where s = '1.0' and s = 1").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from t1 where s > 0 and s < '1.0' and s >= 1").Check(testkit.Rows("1"))
+}
+
+func (s *testSuite) TestStreamAggByIndexOrder(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (id int primary key, a int, b int, c int, key k_a_b(a, b))")
+	tk.MustQuery("select a, count(*) from t group by a").Check(testkit.Rows())
+	tk.MustExec("insert t values (1, 1, 1, 1), (2, 1, 2, 2), (3, 2, 1, 3), (4, 3, 3, 4), (5, NULL, 1, 5), (6, NULL, NULL, 6), (7, 3, 3, 7)")
+
+	tk.MustQuery("select a, count(*), sum(id), max(id), min(id), avg(id) from t group by a order by a").Check(testkit.Rows(
+		"<nil> 2 11 6 5 5.5000", "1 2 3 2 1 1.5000", "2 1 3 3 3 3.0000", "3 2 11 7 4 5.5000"))
+	tk.MustQuery("select a, count(*) from t group by a order by a desc").Check(testkit.Rows("3 2", "2 1", "1 2", "<nil> 2"))
+	tk.MustQuery("select a, b, count(*) from t group by a, b order by a, b").Check(testkit.Rows(
+		"<nil> <nil> 1", "<nil> 1 1", "1 1 1", "1 2 1", "2 1 1", "3 3 2"))
+	tk.MustQuery("select a, count(distinct b) from t group by a").Check(testkit.Rows("<nil> 1", "1 2", "2 1", "3 1"))
+	tk.MustQuery("select a, group_concat(id), group_concat(distinct b) from t group by a order by a").Check(testkit.Rows(
+		"<nil> 6,5 1", "1 1,2 1,2", "2 3 1", "3 4,7 3"))
+	tk.MustQuery("select a, count(*) as cnt from t group by a order by cnt, a").Check(testkit.Rows("2 1", "<nil> 2", "1 2", "3 2"))
+	tk.MustQuery("select a, count(*) from t where a > 1 group by a order by a").Check(testkit.Rows("2 1", "3 2"))
+	tk.MustQuery("select a, b from t where a > 1 order by a desc, b desc").Check(testkit.Rows("3 3", "3 3", "2 1"))
+	tk.MustQuery("select distinct a from t order by a desc").Check(testkit.Rows("3", "2", "1", "<nil>"))
+	// The stream aggregation is executed again for every outer row.
+	tk.MustQuery("select id, (select count(*) from t t2 where t2.a = t1.id group by t2.a) from t t1 order by id").Check(testkit.Rows(
+		"1 2", "2 1", "3 2", "4 <nil>", "5 <nil>", "6 <nil>", "7 <nil>"))
+}
+
 func (s *testSuite) TestNewTableDual(c *C) {
 	defer testleak.AfterTest(c)()
 	tk := testkit.NewTestKit(c, s.store)
@@ -2314,3 +2689,355 @@ func (s *testSuite) TestHistoryRead(c *C) {
 	tk.MustExec("update history_read set a = 4 where a = 3")
 	tk.MustExec("delete from history_read where a = 1")
 }
+
+func (s *testSuite) TestSpillToDisk(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b varchar(20), c decimal(10,2), d datetime)")
+	for i := 0; i < 100; i++ {
+		tk.MustExec(fmt.Sprintf("insert t values (%d, 'b%d', %d.25, '2016-10-%02d 12:00:00')", i, i%7, i%13, i%28+1))
+	}
+	tk.MustExec("insert t values (NULL, NULL, NULL, NULL)")
+	queries := []string{
+		"select * from t order by b desc, a",
+		"select k, count(*), sum(c), max(d) from (select a % 10 as k, c, d from t limit 1000) x group by k",
+		"select b, count(distinct a), group_concat(a) from (select * from t limit 1000) x group by b",
+		"select t1.a, t2.b, t2.d from t t1 join t t2 on t1.a = t2.a",
+		"select t1.a, t2.a from t t1 left join t t2 on t1.a = t2.a + 50",
+		// Skewed join keys can not be split by partitioning, they are joined block by block.
+		"select t1.a, t2.a from t t1 join t t2 on t1.b = t2.b",
+		"select t1.a, t2.a from t t1 left join t t2 on t1.b = t2.b and t2.a < 30",
+	}
+	var expected [][][]interface{}
+	for _, sql := range queries {
+		expected = append(expected, tk.MustQuery(sql).Rows())
+	}
+	filesBefore, err := filepath.Glob(filepath.Join(os.TempDir(), "tidb-spill-*"))
+	c.Assert(err, IsNil)
+	tk.MustExec("set @@tidb_mem_quota_query = 1")
+	for i, sql := range queries {
+		spilled := executor.SpillFiles()
+		rows := tk.MustQuery(sql).Rows()
+		c.Assert(executor.SpillFiles(), Greater, spilled, Commentf("sql: %s", sql))
+		if strings.Contains(sql, "order by") {
+			c.Assert(formatRows(rows), DeepEquals, formatRows(expected[i]), Commentf("sql: %s", sql))
+		} else {
+			c.Assert(sortedRows(rows), DeepEquals, sortedRows(expected[i]), Commentf("sql: %s", sql))
+		}
+	}
+	filesAfter, err := filepath.Glob(filepath.Join(os.TempDir(), "tidb-spill-*"))
+	c.Assert(err, IsNil)
+	c.Assert(filesAfter, HasLen, len(filesBefore))
+}
+
+func formatRows(rows [][]interface{}) []string {
+	strs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		vals := make([]interface{}, len(row))
+		for i, val := range row {
+			// The same value may be returned as either bytes or string.
+			if b, ok := val.([]byte); ok {
+				val = string(b)
+			}
+			vals[i] = val
+		}
+		strs = append(strs, fmt.Sprintf("%v", vals))
+	}
+	return strs
+}
+
+func sortedRows(rows [][]interface{}) []string {
+	strs := formatRows(rows)
+	sort.Strings(strs)
+	return strs
+}
+
+func (s *testSuite) TestParallelAggAndProjection(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int, b varchar(20), c decimal(10,2), d double)")
+	for i := 0; i < 50; i++ {
+		values := make([]string, 0, 20)
+		for j := 0; j < 20; j++ {
+			n := i*20 + j
+			values = append(values, fmt.Sprintf("(%d, 'b%d', %d.25, %d.5)", n, n%7, n%13, n%11))
+		}
+		tk.MustExec("insert t values " + strings.Join(values, ","))
+	}
+	tk.MustExec("insert t values (NULL, NULL, NULL, NULL)")
+	queries := []string{
+		"select count(*), count(a), sum(c), avg(d), max(b), min(c) from (select * from t limit 2000) x",
+		"select b, count(*), sum(a), avg(c), max(d), min(a) from (select * from t limit 2000) x group by b",
+		"select a % 10, sum(d), avg(a) from (select * from t limit 2000) x where a > 100 group by a % 10",
+		"select b, count(distinct a % 10) from (select * from t limit 2000) x group by b",
+		"select a + 1, concat(b, '-', a), c * 2 from t order by a",
+		"select a, b from t where a < 0",
+	}
+	var expected [][][]interface{}
+	for _, sql := range queries {
+		expected = append(expected, tk.MustQuery(sql).Rows())
+	}
+	tk.MustExec("set @@tidb_hashagg_concurrency = 4")
+	tk.MustExec("set @@tidb_projection_concurrency = 3")
+	for i, sql := range queries {
+		rows := tk.MustQuery(sql).Rows()
+		if strings.Contains(sql, "order by") {
+			c.Assert(formatRows(rows), DeepEquals, formatRows(expected[i]), Commentf("sql: %s", sql))
+		} else {
+			c.Assert(sortedRows(rows), DeepEquals, sortedRows(expected[i]), Commentf("sql: %s", sql))
+		}
+	}
+	// Parallel aggregation falls back to the spilling serial path once the memory quota is exceeded.
+	tk.MustExec("set @@tidb_mem_quota_query = 1")
+	for i := 1; i <= 2; i++ {
+		spilled := executor.SpillFiles()
+		rows := tk.MustQuery(queries[i]).Rows()
+		c.Assert(executor.SpillFiles(), Greater, spilled, Commentf("sql: %s", queries[i]))
+		c.Assert(sortedRows(rows), DeepEquals, sortedRows(expected[i]), Commentf("sql: %s", queries[i]))
+	}
+	tk.MustExec(fmt.Sprintf("set @@tidb_mem_quota_query = %d", variable.DefMemQuotaQuery))
+	// A projection that is not fully consumed must stop its workers when it is closed.
+	tk.MustQuery("select a + 1 from t order by a limit 3").Check(testkit.Rows("<nil>", "1", "2"))
+	_, err := tk.Exec("set @@tidb_hashagg_concurrency = 0")
+	c.Assert(err, NotNil)
+}
+
+func (s *testSuite) TestParallelUnion(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2, t3")
+	for i, name := range []string{"t1", "t2", "t3"} {
+		tk.MustExec(fmt.Sprintf("create table %s (a int, b varchar(20))", name))
+		values := make([]string, 0, 300)
+		for j := 0; j < 300; j++ {
+			values = append(values, fmt.Sprintf("(%d, 'b%d')", i*100+j, j%5))
+		}
+		tk.MustExec(fmt.Sprintf("insert %s values %s", name, strings.Join(values, ",")))
+	}
+	queries := []string{
+		"select a, b from t1 union all select a, b from t2 union all select a, b from t3",
+		"select a, b from t1 union select a, b from t2 union select a, b from t3",
+		"select b from t1 union select b from t2 union select b from t3",
+		"select a from t1 where a < 10 union all select '7' union all select a + 1 from t3 where a = 250",
+		"select count(*) from (select a from t1 union all select a from t2 union all select a from t3) x",
+	}
+	var expected [][][]interface{}
+	for _, sql := range queries {
+		expected = append(expected, tk.MustQuery(sql).Rows())
+	}
+	tk.MustExec("set @@tidb_union_concurrency = 2")
+	for i, sql := range queries {
+		rows := tk.MustQuery(sql).Rows()
+		c.Assert(sortedRows(rows), DeepEquals, sortedRows(expected[i]), Commentf("sql: %s", sql))
+	}
+	// With tidb_union_keep_order, the rows are returned in the order of the children.
+	tk.MustExec("set @@tidb_union_keep_order = 1")
+	for i, sql := range queries {
+		if strings.Contains(sql, "union select") {
+			continue
+		}
+		rows := tk.MustQuery(sql).Rows()
+		c.Assert(formatRows(rows), DeepEquals, formatRows(expected[i]), Commentf("sql: %s", sql))
+	}
+	// A union that is not fully consumed must stop its workers when it is closed.
+	tk.MustQuery("select * from (select a from t1 union all select a from t2 union all select a from t3) x limit 2").Check(testkit.Rows("0", "1"))
+	tk.MustExec("set @@tidb_union_keep_order = 0")
+	tk.MustQuery("select count(*) from (select * from (select a from t1 union all select a from t2) y limit 10) x").Check(testkit.Rows("10"))
+	_, err := tk.Exec("set @@tidb_union_concurrency = 0")
+	c.Assert(err, NotNil)
+}
+
+type mockSessionManager struct {
+	processes []util.ProcessInfo
+	killed    map[uint64]bool
+}
+
+func (sm *mockSessionManager) ShowProcessList() []util.ProcessInfo {
+	return sm.processes
+}
+
+func (sm *mockSessionManager) Kill(connectionID uint64, query bool) bool {
+	for _, pi := range sm.processes {
+		if pi.ID == connectionID {
+			sm.killed[connectionID] = query
+			return true
+		}
+	}
+	return false
+}
+
+func (s *testSuite) TestProcessListAndKill(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	longSQL := "select 1" + strings.Repeat(" ", 100) + "from dual"
+	sm := &mockSessionManager{
+		processes: []util.ProcessInfo{
+			{ID: 1, User: "root", Host: "127.0.0.1:1234", DB: "test", Command: "Query", Time: time.Now(), State: "executing", Info: longSQL},
+			{ID: 2, User: "u", Host: "127.0.0.1:5678", Command: "Sleep", Time: time.Now()},
+		},
+		killed: make(map[uint64]bool),
+	}
+	// Without a session manager the process list is empty.
+	tk.MustQuery("show processlist").Check(testkit.Rows())
+	_, err := tk.Exec("kill 1")
+	c.Assert(terror.ErrorEqual(err, executor.ErrUnknownThread), IsTrue)
+
+	tk.Se.SetSessionManager(sm)
+	tk.MustQuery("show processlist").Check(testkit.Rows(
+		fmt.Sprintf("1 root 127.0.0.1:1234 test Query 0 executing %s", longSQL[:100]),
+		"2 u 127.0.0.1:5678 <nil> Sleep 0  <nil>"))
+	tk.MustQuery("show full processlist").Check(testkit.Rows(
+		fmt.Sprintf("1 root 127.0.0.1:1234 test Query 0 executing %s", longSQL),
+		"2 u 127.0.0.1:5678 <nil> Sleep 0  <nil>"))
+	tk.MustQuery("select id, user, command from information_schema.processlist where db is null").Check(testkit.Rows("2 u Sleep"))
+	tk.MustQuery("select count(*) from information_schema.processlist").Check(testkit.Rows("2"))
+
+	tk.MustExec("kill query 1")
+	tk.MustExec("kill connection 2")
+	c.Assert(sm.killed, DeepEquals, map[uint64]bool{1: true, 2: false})
+	_, err = tk.Exec("kill 3")
+	c.Assert(terror.ErrorEqual(err, executor.ErrUnknownThread), IsTrue)
+
+	// Without PROCESS and SUPER a user only sees and kills its own connections.
+	tk.MustExec("create user 'u'@'%'")
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	tk1.Se.SetSessionManager(sm)
+	variable.GetSessionVars(tk1.Se.(context.Context)).User = "u@127.0.0.1"
+	tk1.MustQuery("show processlist").Check(testkit.Rows("2 u 127.0.0.1:5678 <nil> Sleep 0  <nil>"))
+	tk1.MustQuery("select id from information_schema.processlist").Check(testkit.Rows("2"))
+	_, err = tk1.Exec("kill query 1")
+	c.Assert(terror.ErrorEqual(err, executor.ErrKillDenied), IsTrue)
+	_, err = tk1.Exec("kill 3")
+	c.Assert(terror.ErrorEqual(err, executor.ErrUnknownThread), IsTrue)
+	sm.killed = make(map[uint64]bool)
+	tk1.MustExec("kill 2")
+	c.Assert(sm.killed, DeepEquals, map[uint64]bool{2: false})
+
+	tk.MustExec("grant process, super on *.* to 'u'@'%'")
+	tk2 := testkit.NewTestKit(c, s.store)
+	tk2.MustExec("use test")
+	tk2.Se.SetSessionManager(sm)
+	variable.GetSessionVars(tk2.Se.(context.Context)).User = "u@127.0.0.1"
+	tk2.MustQuery("select id from information_schema.processlist").Check(testkit.Rows("1", "2"))
+	tk2.MustExec("kill query 1")
+	c.Assert(sm.killed, DeepEquals, map[uint64]bool{1: true, 2: false})
+
+	// The running statement stops once it is killed, and the next statement runs normally.
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert t values (1), (2), (3)")
+	rs, err := tk.Exec("select a from t")
+	c.Assert(err, IsNil)
+	row, err := rs.Next()
+	c.Assert(err, IsNil)
+	c.Assert(row, NotNil)
+	tk.Se.KillQuery()
+	_, err = rs.Next()
+	c.Assert(terror.ErrorEqual(err, variable.ErrQueryInterrupted), IsTrue)
+	rs.Close()
+	tk.MustQuery("select count(*) from t").Check(testkit.Rows("3"))
+}
+
+func (s *testSuite) TestSlowQueryLog(c *C) {
+	defer testleak.AfterTest(c)()
+	dir, err := ioutil.TempDir("", "slowlog")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	c.Assert(slowlog.SetFile(filepath.Join(dir, "slow.log")), IsNil)
+	defer slowlog.SetFile("")
+
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a int)")
+	tk.MustExec("insert t values (1), (2), (3)")
+	// Nothing is logged under the default threshold.
+	tk.MustQuery("select a from t where a > 1").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select count(*) from information_schema.slow_query").Check(testkit.Rows("0"))
+
+	tk.MustExec("set long_query_time = 0")
+	tk.MustQuery("select a from t where a > 1").Check(testkit.Rows("2", "3"))
+	tk.MustQuery("select a from t where a > 2").Check(testkit.Rows("3"))
+	tk.MustExec("update t set a = a + 1 where a = 3")
+	tk.MustExec(`prepare stmt from "select a from t where a > ?"`)
+	tk.MustExec("set @a = 3")
+	tk.MustQuery("execute stmt using @a").Check(testkit.Rows("4"))
+	tk.MustExec("set long_query_time = 10")
+
+	tk.MustQuery("select db, rows_sent, rows_examined, query from information_schema.slow_query where query not like 'set%'").Check(testkit.Rows(
+		"test 2 2 select a from t where a > 1",
+		"test 1 1 select a from t where a > 2",
+		"test 0 1 update t set a = a + 1 where a = 3",
+		`test 0 0 prepare stmt from "select a from t where a > ?"`,
+		"test 1 1 select a from t where a > ?"))
+	// The queries with the same shape have the same digests.
+	tk.MustQuery("select count(distinct digest), count(distinct plan_digest) from information_schema.slow_query where query like 'select a from t where a > %'").Check(testkit.Rows("1 1"))
+	// The phases are in the query time, the times are rounded to microseconds.
+	tk.MustQuery("select count(*) from information_schema.slow_query where query_time + 0.000003 < parse_time + compile_time + exec_time or exec_time + 0.000003 < cop_wait_time").Check(testkit.Rows("0"))
+
+	// The record sets of a multi-statement query are read after all the statements run, each statement
+	// logs its own execution details.
+	variable.GetSessionVars(tk.Se.(context.Context)).ClientCapability |= mysql.ClientMultiResults
+	tk.MustExec("set long_query_time = 0")
+	rss, err := tk.Se.Execute("select a from t where a > 3; select a from t where a > 0")
+	c.Assert(err, IsNil)
+	c.Assert(rss, HasLen, 2)
+	for _, rs := range rss {
+		_, err = tidb.GetRows(rs)
+		c.Assert(err, IsNil)
+	}
+	tk.MustExec("set long_query_time = 10")
+	tk.MustQuery("select rows_sent, rows_examined, query from information_schema.slow_query where query in ('select a from t where a > 3', 'select a from t where a > 0')").Check(testkit.Rows(
+		"1 1 select a from t where a > 3",
+		"3 3 select a from t where a > 0"))
+
+	// The password is masked, and the slow queries of the other users need the PROCESS privilege.
+	tk.MustExec("set long_query_time = 0")
+	tk.MustExec("create user 'slow_u'@'%' identified by 'secret'")
+	tk.MustExec("set long_query_time = 10")
+	tk.MustQuery("select query from information_schema.slow_query where query like 'create user%'").Check(testkit.Rows(
+		"create user 'slow_u'@'%' identified by '***'"))
+	tk1 := testkit.NewTestKit(c, s.store)
+	tk1.MustExec("use test")
+	variable.GetSessionVars(tk1.Se.(context.Context)).User = "slow_u@127.0.0.1"
+	tk1.MustExec("set long_query_time = 0")
+	tk1.MustQuery("select 1").Check(testkit.Rows("1"))
+	tk1.MustExec("set long_query_time = 10")
+	tk1.MustQuery("select user, query from information_schema.slow_query").Check(testkit.Rows(
+		"slow_u set long_query_time = 0", "slow_u select 1"))
+	tk.MustQuery("select count(*) from information_schema.slow_query where user = 'slow_u'").Check(testkit.Rows("2"))
+}
+
+func (s *testSuite) TestChunkExecution(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t (a bigint, b int, c double)")
+	tk.MustExec("insert t values (1, 2, 1.5), (3, 1, 2.5), (NULL, 1, NULL), (5, NULL, 0.5), (9223372036854775807, 1, 1)")
+	tk.MustQuery("select a + b, a - b, b * 2, c / 2 from t where a < 10 and b <= 2").Check(testkit.Rows("3 -1 4 0.75", "4 2 2 1.25"))
+	tk.MustQuery("select a, b from t where a > b or c = 0.5").Check(testkit.Rows("3 1", "5 <nil>", "9223372036854775807 1"))
+	tk.MustQuery("select b, count(*), sum(a) from t where a <=> 5 or b = 1 group by b + 1 order by b").Check(testkit.Rows("<nil> 1 5", "1 3 9223372036854775810"))
+	// The overflow of the vectorized int64 arithmetic is reported.
+	rs, err := tk.Exec("select a + b from t where b = 1 and a > 5")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+	// The overflow of the pushed down expressions is reported with the original message.
+	rs, err = tk.Exec("select a from t where a + b > 0")
+	c.Assert(err, IsNil)
+	_, err = tidb.GetRows(rs)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "operation overflow")
+	// Expressions with side effects are evaluated row by row.
+	tk.MustExec("set @x = 0")
+	tk.MustQuery("select @x := @x + 1, @x from t where a is not null order by a").Check(testkit.Rows("1 1", "2 2", "3 3", "4 4"))
+	tk.MustQuery("select t1.a, t2.c from t t1 join t t2 on t1.b = t2.a where t1.a - 1 > 1 order by t1.a").Check(testkit.Rows("3 1.5", "9223372036854775807 1.5"))
+}
@@ -41,10 +41,12 @@ var (
 
 // GrantExec executes GrantStmt.
 type GrantExec struct {
-	Privs      []*ast.PrivElem
-	ObjectType ast.ObjectTypeType
-	Level      *ast.GrantLevel
-	Users      []*ast.UserSpec
+	Privs           []*ast.PrivElem
+	ObjectType      ast.ObjectTypeType
+	Level           *ast.GrantLevel
+	Users           []*ast.UserSpec
+	Require         ast.RequireType
+	ResourceOptions []*ast.ResourceOption
 
 	ctx  context.Context
 	done bool
@@ -108,6 +110,18 @@ func (e *GrantExec) Next() (*Row, error) {
 				return nil, errors.Trace(err)
 			}
 		}
+		if e.Require != ast.RequireUnspecified {
+			err := e.grantRequire(userName, host)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if len(e.ResourceOptions) > 0 {
+			err := e.grantResourceOptions(userName, host)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 	}
 	e.done = true
 	return nil, nil
@@ -220,6 +234,25 @@ func (e *GrantExec) grantPriv(priv *ast.PrivElem, user *ast.UserSpec) error {
 	}
 }
 
+// Set the TLS requirement of REQUIRE clause in mysql.user table.
+func (e *GrantExec) grantRequire(userName, host string) error {
+	sql := fmt.Sprintf(`UPDATE %s.%s SET ssl_type="%s" WHERE User="%s" AND Host="%s"`, mysql.SystemDB, mysql.UserTable, requireSSLType(e.Require), userName, host)
+	_, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	return errors.Trace(err)
+}
+
+// Set the resource limits of WITH clause in mysql.user table.
+func (e *GrantExec) grantResourceOptions(userName, host string) error {
+	columns, counts := userResourceLimits(e.ResourceOptions)
+	asgns := make([]string, 0, len(columns))
+	for i, column := range columns {
+		asgns = append(asgns, fmt.Sprintf("%s=%d", column, counts[i]))
+	}
+	sql := fmt.Sprintf(`UPDATE %s.%s SET %s WHERE User="%s" AND Host="%s"`, mysql.SystemDB, mysql.UserTable, strings.Join(asgns, ", "), userName, host)
+	_, err := e.ctx.(sqlexec.RestrictedSQLExecutor).ExecRestrictedSQL(e.ctx, sql)
+	return errors.Trace(err)
+}
+
 // Manipulate mysql.user table.
 func (e *GrantExec) grantGlobalPriv(priv *ast.PrivElem, user *ast.UserSpec) error {
 	asgns, err := composeGlobalPrivUpdate(priv.Priv)
@@ -0,0 +1,494 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/util/distinct"
+	"github.com/pingcap/tidb/util/memory"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// canParallel checks whether the aggregation can be executed by parallelExecute.
+// Distinct aggregate functions can not merge partial results, because the
+// distinct values of different workers overlap.
+func (e *AggregationExec) canParallel() bool {
+	if e.Concurrency <= 1 || e.Src == nil {
+		return false
+	}
+	for _, af := range e.AggFuncs {
+		if af.IsDistinct() {
+			return false
+		}
+	}
+	return true
+}
+
+// aggWorker is a partial aggregation worker of AggregationExec, it aggregates
+// the row batches it receives with its own copies of the aggregate functions.
+type aggWorker struct {
+	ctx          context.Context
+	aggFuncs     []expression.AggregationFunction
+	groupByItems []expression.Expression
+	groupMap     map[string]bool
+	groups       [][]byte
+	memTracker   *memory.Tracker
+	err          error
+}
+
+func (e *AggregationExec) newAggWorker() *aggWorker {
+	w := &aggWorker{
+		ctx:        e.ctx,
+		aggFuncs:   make([]expression.AggregationFunction, 0, len(e.AggFuncs)),
+		groupMap:   make(map[string]bool),
+		memTracker: e.memTracker,
+	}
+	for _, af := range e.AggFuncs {
+		args := make([]expression.Expression, 0, len(af.GetArgs()))
+		for _, arg := range af.GetArgs() {
+			args = append(args, arg.DeepCopy())
+		}
+		w.aggFuncs = append(w.aggFuncs, expression.NewAggFunction(af.GetName(), args, af.IsDistinct()))
+	}
+	for _, item := range e.GroupByItems {
+		w.groupByItems = append(w.groupByItems, item.DeepCopy())
+	}
+	return w
+}
+
+// run aggregates the batches until the channel is closed. After an error
+// happens, it keeps draining the channel so the fetcher is never blocked.
+func (w *aggWorker) run(batchCh <-chan []*Row, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for rows := range batchCh {
+		if w.err != nil {
+			continue
+		}
+		w.err = w.aggregate(rows)
+	}
+}
+
+func (w *aggWorker) aggregate(rows []*Row) error {
+	for _, row := range rows {
+		groupKey, err := evalGroupKey(w.ctx, w.groupByItems, row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !w.groupMap[string(groupKey)] {
+			w.groupMap[string(groupKey)] = true
+			w.groups = append(w.groups, groupKey)
+			if w.memTracker != nil {
+				w.memTracker.Consume(groupMemUsage(groupKey, len(w.aggFuncs)))
+			}
+		}
+		for _, af := range w.aggFuncs {
+			if err = af.Update(row.Data, groupKey, w.ctx); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// parallelExecute aggregates the source in two phases. In the partial phase,
+// Concurrency workers aggregate disjoint batches of the source rows, then the
+// final phase merges their partial results of every group into AggFuncs.
+// Once the query memory quota is exceeded, the workers stop receiving rows and
+// the rest rows are aggregated by the serial path, which spills new groups.
+func (e *AggregationExec) parallelExecute() error {
+	workers := make([]*aggWorker, e.Concurrency)
+	batchCh := make(chan []*Row, e.Concurrency)
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = e.newAggWorker()
+		wg.Add(1)
+		go workers[i].run(batchCh, &wg)
+	}
+	rest, exceeded, err := e.fetchBatches(batchCh)
+	close(batchCh)
+	wg.Wait()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, w := range workers {
+		if w.err != nil {
+			return errors.Trace(w.err)
+		}
+	}
+	if e.memTracker != nil {
+		// The partial results are released after merging, only the merged groups are tracked.
+		e.memTracker.Consume(-e.memTracker.BytesConsumed())
+	}
+	for _, w := range workers {
+		for _, groupKey := range w.groups {
+			if !e.groupMap[string(groupKey)] {
+				e.groupMap[string(groupKey)] = true
+				e.groups = append(e.groups, groupKey)
+				if e.memTracker != nil && len(e.GroupByItems) > 0 {
+					e.memTracker.Consume(groupMemUsage(groupKey, len(e.AggFuncs)))
+				}
+			}
+			for i, af := range e.AggFuncs {
+				partial := w.aggFuncs[i].GetContext()[string(groupKey)]
+				if partial == nil {
+					continue
+				}
+				if err = af.MergeGroupContext(groupKey, partial); err != nil {
+					return errors.Trace(err)
+				}
+			}
+		}
+	}
+	if !exceeded {
+		return nil
+	}
+	if e.memTracker.Exceeded() && len(e.GroupByItems) > 0 {
+		if err = e.startSpill(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, row := range rest {
+		groupKey, err := e.getGroupKey(row)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = e.aggregateRow(row, groupKey); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for {
+		hasMore, err := e.innerNext()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasMore {
+			return nil
+		}
+	}
+}
+
+// fetchBatches reads the source rows and sends them to the workers in batches.
+// It stops once the memory quota is exceeded, exceeded is true and the rows read
+// but not sent are returned, the rest source rows are not read.
+func (e *AggregationExec) fetchBatches(batchCh chan<- []*Row) (rest []*Row, exceeded bool, err error) {
+	rows := make([]*Row, 0, batchSize)
+	for {
+		if e.memTracker != nil && e.memTracker.Exceeded() {
+			return rows, true, nil
+		}
+		row, err := e.Src.Next()
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		rows = append(rows, row)
+		if len(rows) == batchSize {
+			batchCh <- rows
+			rows = make([]*Row, 0, batchSize)
+		}
+	}
+	if len(rows) > 0 {
+		batchCh <- rows
+	}
+	return nil, false, nil
+}
+
+// projectionTask is a batch of source rows, a projection worker replaces
+// them with the projected rows and closes done.
+type projectionTask struct {
+	rows []*Row
+	err  error
+	done chan struct{}
+}
+
+// parallelNext evaluates the projection with Concurrency workers. A fetcher
+// goroutine splits the source rows into tasks and sends every task to both
+// the workers and the result channel, so the rows are returned in order.
+func (e *ProjectionExec) parallelNext() (*Row, error) {
+	if !e.prepared {
+		e.prepare()
+	}
+	for {
+		if e.curTask != nil && e.curIndex < len(e.curTask.rows) {
+			row := e.curTask.rows[e.curIndex]
+			e.curIndex++
+			return row, nil
+		}
+		task, ok := <-e.resultCh
+		if !ok {
+			return nil, nil
+		}
+		<-task.done
+		if task.err != nil {
+			return nil, errors.Trace(task.err)
+		}
+		e.curTask, e.curIndex = task, 0
+	}
+}
+
+func (e *ProjectionExec) prepare() {
+	e.taskCh = make(chan *projectionTask, e.Concurrency)
+	e.resultCh = make(chan *projectionTask, e.Concurrency)
+	e.closeCh = make(chan struct{})
+	e.wg.Add(e.Concurrency + 1)
+	go e.fetchTasks()
+	for i := 0; i < e.Concurrency; i++ {
+		exprs := make([]expression.Expression, 0, len(e.exprs))
+		for _, expr := range e.exprs {
+			exprs = append(exprs, expr.DeepCopy())
+		}
+		go e.evalWorker(exprs)
+	}
+	e.prepared = true
+}
+
+func (e *ProjectionExec) fetchTasks() {
+	defer func() {
+		close(e.taskCh)
+		close(e.resultCh)
+		e.wg.Done()
+	}()
+	for {
+		task := &projectionTask{
+			rows: make([]*Row, 0, batchSize),
+			done: make(chan struct{}),
+		}
+		for len(task.rows) < batchSize {
+			row, err := e.Src.Next()
+			if err != nil {
+				task.err = errors.Trace(err)
+				break
+			}
+			if row == nil {
+				break
+			}
+			task.rows = append(task.rows, row)
+		}
+		if task.err != nil {
+			close(task.done)
+			select {
+			case e.resultCh <- task:
+			case <-e.closeCh:
+			}
+			return
+		}
+		if len(task.rows) == 0 {
+			return
+		}
+		select {
+		case e.taskCh <- task:
+		case <-e.closeCh:
+			return
+		}
+		select {
+		case e.resultCh <- task:
+		case <-e.closeCh:
+			return
+		}
+		if len(task.rows) < batchSize {
+			return
+		}
+	}
+}
+
+func (e *ProjectionExec) evalWorker(exprs []expression.Expression) {
+	defer e.wg.Done()
+	for task := range e.taskCh {
+		for i, row := range task.rows {
+			newRow, err := evalProjection(e.ctx, exprs, row.Data, row.RowKeys)
+			if err != nil {
+				task.err = errors.Trace(err)
+				break
+			}
+			task.rows[i] = newRow
+		}
+		close(task.done)
+	}
+}
+
+// stopWorkers stops the fetcher and the workers, then the source can be closed safely.
+func (e *ProjectionExec) stopWorkers() {
+	if !e.prepared {
+		return
+	}
+	close(e.closeCh)
+	e.wg.Wait()
+	e.prepared = false
+	e.curTask, e.curIndex = nil, 0
+}
+
+// unionResult is a batch of rows of a union child.
+type unionResult struct {
+	rows []*Row
+	err  error
+}
+
+// parallelNext executes the children of the union with Concurrency workers.
+// Every worker takes the next unexecuted child and sends its rows in batches.
+// With KeepOrder, every child has its own result channel which is read in the
+// order of the children, otherwise the batches are returned as they arrive.
+func (e *UnionExec) parallelNext() (*Row, error) {
+	if !e.prepared {
+		if err := e.prepare(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for {
+		if e.curResult != nil && e.curIndex < len(e.curResult.rows) {
+			row := e.curResult.rows[e.curIndex]
+			e.curIndex++
+			return row, nil
+		}
+		if e.cursor >= len(e.resultChs) {
+			return nil, nil
+		}
+		result, ok := <-e.resultChs[e.cursor]
+		if !ok {
+			e.cursor++
+			continue
+		}
+		if result.err != nil {
+			return nil, errors.Trace(result.err)
+		}
+		e.curResult, e.curIndex = result, 0
+	}
+}
+
+func (e *UnionExec) prepare() error {
+	if e.ctx != nil {
+		// The children share the transaction of the session, create it before
+		// the workers start, so they never initialize it concurrently.
+		if _, err := e.ctx.GetTxn(false); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	childCh := make(chan int, len(e.Srcs))
+	for i := range e.Srcs {
+		childCh <- i
+	}
+	close(childCh)
+	if e.KeepOrder {
+		e.resultChs = make([]chan *unionResult, len(e.Srcs))
+		for i := range e.resultChs {
+			e.resultChs[i] = make(chan *unionResult, 1)
+		}
+	} else {
+		e.resultChs = []chan *unionResult{make(chan *unionResult, e.Concurrency)}
+	}
+	e.closeCh = make(chan struct{})
+	e.finishCh = make(chan struct{})
+	concurrency := e.Concurrency
+	if concurrency > len(e.Srcs) {
+		concurrency = len(e.Srcs)
+	}
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go e.unionWorker(childCh, &wg)
+	}
+	go func() {
+		wg.Wait()
+		if !e.KeepOrder {
+			close(e.resultChs[0])
+		}
+		close(e.finishCh)
+	}()
+	e.prepared = true
+	return nil
+}
+
+// unionWorker executes the children it takes from childCh. The children are
+// taken in order, so with KeepOrder the child being read is always executing.
+func (e *UnionExec) unionWorker(childCh <-chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	var checker *distinct.Checker
+	if e.partialDistinct {
+		checker = distinct.CreateDistinctChecker()
+	}
+	for child := range childCh {
+		resultCh := e.resultChs[0]
+		if e.KeepOrder {
+			resultCh = e.resultChs[child]
+		}
+		if !e.fetchChild(child, checker, resultCh) {
+			return
+		}
+		if e.KeepOrder {
+			close(resultCh)
+		}
+	}
+}
+
+// fetchChild sends the rows of a child to resultCh in batches, the duplicated
+// rows are skipped if checker is not nil. It returns false if the union is closed.
+func (e *UnionExec) fetchChild(child int, checker *distinct.Checker, resultCh chan<- *unionResult) bool {
+	src := e.Srcs[child]
+	for {
+		result := &unionResult{rows: make([]*Row, 0, batchSize)}
+		for len(result.rows) < batchSize {
+			row, err := src.Next()
+			if err != nil {
+				result.err = errors.Trace(err)
+				break
+			}
+			if row == nil {
+				break
+			}
+			if child != 0 {
+				if err = e.convertRow(row); err != nil {
+					result.err = errors.Trace(err)
+					break
+				}
+			}
+			if checker != nil {
+				ok, err := checker.Check(types.DatumsToInterfaces(row.Data))
+				if err != nil {
+					result.err = errors.Trace(err)
+					break
+				}
+				if !ok {
+					continue
+				}
+			}
+			result.rows = append(result.rows, row)
+		}
+		if len(result.rows) > 0 || result.err != nil {
+			select {
+			case resultCh <- result:
+			case <-e.closeCh:
+				return false
+			}
+		}
+		if result.err != nil || len(result.rows) < batchSize {
+			return true
+		}
+	}
+}
+
+// stopWorkers stops the workers, then the children can be closed safely.
+func (e *UnionExec) stopWorkers() {
+	if !e.prepared {
+		return
+	}
+	close(e.closeCh)
+	<-e.finishCh
+	e.prepared = false
+	e.curResult, e.curIndex = nil, 0
+}
@@ -0,0 +1,249 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/plan"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// PointGetExec gets the rows by the handles or the unique index values from the kv storage
+// directly, without sending requests to the coprocessor.
+type PointGetExec struct {
+	ctx     context.Context
+	details *variable.StmtExecDetails
+	plan    *plan.PointGetPlan
+	table   table.Table
+	schema  expression.Schema
+	rows    []*Row
+	fetched bool
+	cursor  int
+}
+
+// Schema implements Executor Schema interface.
+func (e *PointGetExec) Schema() expression.Schema {
+	return e.schema
+}
+
+// Fields implements Executor Fields interface.
+func (e *PointGetExec) Fields() []*ast.ResultField {
+	return nil
+}
+
+// Next implements Executor Next interface.
+func (e *PointGetExec) Next() (*Row, error) {
+	if !e.fetched {
+		if err := e.fetchRows(); err != nil {
+			return nil, errors.Trace(err)
+		}
+		e.fetched = true
+	}
+	if e.cursor >= len(e.rows) {
+		return nil, nil
+	}
+	row := e.rows[e.cursor]
+	e.cursor++
+	return row, nil
+}
+
+// Close implements Executor Close interface.
+func (e *PointGetExec) Close() error {
+	e.rows = nil
+	e.fetched = false
+	e.cursor = 0
+	return nil
+}
+
+func (e *PointGetExec) fetchRows() error {
+	getter, err := e.getter()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	handles := e.plan.Handles
+	if e.plan.IndexInfo != nil {
+		handles, err = e.indexHandles(getter)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	keys := make([]kv.Key, 0, len(handles))
+	for _, h := range handles {
+		keys = append(keys, tablecodec.EncodeRowKeyWithHandle(e.plan.Table.ID, h))
+	}
+	values, err := getter(keys)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	e.details.AddRowsExamined(int64(len(values)))
+	for i, h := range handles {
+		value, ok := values[string(keys[i])]
+		if !ok {
+			continue
+		}
+		data, err := e.decodeRow(h, value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		e.rows = append(e.rows, &Row{
+			Data:    data,
+			RowKeys: []*RowKeyEntry{{Tbl: e.table, Handle: h, TableAsName: e.plan.TableAsName}},
+		})
+	}
+	return nil
+}
+
+// indexHandles gets the handles of the unique index values, in the order of the index keys.
+func (e *PointGetExec) indexHandles(getter pointGetter) ([]int64, error) {
+	var idx table.Index
+	for _, v := range e.table.Indices() {
+		if v.Meta().ID == e.plan.IndexInfo.ID {
+			idx = v
+			break
+		}
+	}
+	if idx == nil {
+		return nil, errors.Errorf("index %s not found", e.plan.IndexInfo.Name)
+	}
+	keys := make([]kv.Key, 0, len(e.plan.IndexValues))
+	seen := make(map[string]struct{}, len(e.plan.IndexValues))
+	for _, vals := range e.plan.IndexValues {
+		key, _, err := idx.GenIndexKey(vals, 0)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, ok := seen[string(key)]; ok {
+			continue
+		}
+		seen[string(key)] = struct{}{}
+		keys = append(keys, key)
+	}
+	sort.Sort(keySlice(keys))
+	values, err := getter(keys)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	handles := make([]int64, 0, len(values))
+	for _, key := range keys {
+		value, ok := values[string(key)]
+		if !ok {
+			continue
+		}
+		h, err := decodeHandle(value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		handles = append(handles, h)
+	}
+	return handles, nil
+}
+
+// pointGetter gets the values of the keys, the keys not found are absent in the result.
+type pointGetter func(keys []kv.Key) (map[string][]byte, error)
+
+// getter returns the pointGetter of the statement. A snapshot is used if the transaction
+// has nothing to write so the keys are got in a batch, otherwise the keys are got from the
+// transaction one by one to read the uncommitted changes.
+func (e *PointGetExec) getter() (pointGetter, error) {
+	ts := variable.GetSnapshotTS(e.ctx)
+	if ts == 0 {
+		txn, err := e.ctx.GetTxn(false)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !txn.IsReadOnly() {
+			return func(keys []kv.Key) (map[string][]byte, error) {
+				values := make(map[string][]byte, len(keys))
+				for _, key := range keys {
+					value, err := txn.Get(key)
+					if kv.IsErrNotFound(err) {
+						continue
+					}
+					if err != nil {
+						return nil, errors.Trace(err)
+					}
+					values[string(key)] = value
+				}
+				return values, nil
+			}, nil
+		}
+		ts = txn.StartTS()
+	}
+	snapshot, err := sessionctx.GetDomain(e.ctx).Store().GetSnapshot(kv.Version{Ver: ts})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return snapshot.BatchGet, nil
+}
+
+func (e *PointGetExec) decodeRow(h int64, value []byte) ([]types.Datum, error) {
+	colTps := make(map[int64]*types.FieldType, len(e.plan.Columns))
+	for _, col := range e.plan.Columns {
+		if !e.isPKHandleColumn(col) {
+			colTps[col.ID] = &col.FieldType
+		}
+	}
+	row, err := tablecodec.DecodeRow(value, colTps)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data := make([]types.Datum, len(e.plan.Columns))
+	for i, col := range e.plan.Columns {
+		if e.isPKHandleColumn(col) {
+			if mysql.HasUnsignedFlag(col.Flag) {
+				data[i].SetUint64(uint64(h))
+			} else {
+				data[i].SetInt64(h)
+			}
+			continue
+		}
+		d, ok := row[col.ID]
+		if !ok && mysql.HasNotNullFlag(col.Flag) {
+			return nil, errors.New("Miss column")
+		}
+		data[i] = d
+	}
+	return data, nil
+}
+
+func (e *PointGetExec) isPKHandleColumn(col *model.ColumnInfo) bool {
+	return e.plan.Table.PKIsHandle && mysql.HasPriKeyFlag(col.Flag)
+}
+
+func decodeHandle(data []byte) (int64, error) {
+	var h int64
+	buf := bytes.NewBuffer(data)
+	err := binary.Read(buf, binary.BigEndian, &h)
+	return h, errors.Trace(err)
+}
+
+type keySlice []kv.Key
+
+func (s keySlice) Len() int           { return len(s) }
+func (s keySlice) Less(i, j int) bool { return s[i].Cmp(s[j]) < 0 }
+func (s keySlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
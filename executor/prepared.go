@@ -15,6 +15,7 @@ package executor
 
 import (
 	"sort"
+	"sync/atomic"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
@@ -26,6 +27,7 @@ import (
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/sqlexec"
 )
 
@@ -183,6 +185,7 @@ type ExecuteExec struct {
 	ID        uint32
 	StmtExec  Executor
 	Stmt      ast.StmtNode
+	Plan      plan.Plan
 }
 
 // Schema implements Executor Schema interface.
@@ -243,7 +246,7 @@ func (e *ExecuteExec) Build() error {
 		}
 		prepared.SchemaVersion = e.IS.SchemaMetaVersion()
 	}
-	p, err := plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+	p, err := e.getPlan(prepared)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -254,9 +257,111 @@ func (e *ExecuteExec) Build() error {
 	}
 	e.StmtExec = stmtExec
 	e.Stmt = prepared.Stmt
+	e.Plan = p
 	return nil
 }
 
+// getPlan gets the plan of the prepared statement from the plan cache of the session,
+// the statement is optimized and the plan is cached if it is not found.
+func (e *ExecuteExec) getPlan(prepared *Prepared) (plan.Plan, error) {
+	vars := variable.GetSessionVars(e.Ctx)
+	cacheable, err := e.planCacheable(prepared)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !cacheable {
+		p, err := plan.Optimize(e.Ctx, prepared.Stmt, e.IS)
+		return p, errors.Trace(err)
+	}
+	// A point get plan is cheaper to build than to rebuild a cached plan.
+	if p := plan.TryFastPlan(e.Ctx, prepared.Stmt, e.IS); p != nil {
+		return p, nil
+	}
+	key := newPlanCacheKey(e.Ctx, e.ID, prepared)
+	if v, ok := vars.PreparedPlanCache.Get(key); ok {
+		// The kinds of the parameters are the same as the cached plan, but their
+		// lengths may change, so the types are inferred again.
+		if err = plan.InferType(prepared.Stmt); err != nil {
+			return nil, errors.Trace(err)
+		}
+		cached := v.(*plan.CachedPlan)
+		if err = cached.Rebuild(e.Ctx); err != nil {
+			return nil, errors.Trace(err)
+		}
+		atomic.AddInt64(&planCacheHits, 1)
+		return cached.Plan, nil
+	}
+	atomic.AddInt64(&planCacheMisses, 1)
+	cached, err := plan.OptimizeForCache(e.Ctx, prepared.Stmt, e.IS)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	vars.PreparedPlanCache.Put(key, cached)
+	return cached.Plan, nil
+}
+
+// planCacheable checks whether the plan of the prepared statement can be cached. The
+// plan in a transaction with uncommitted changes reads the changes by a union scan,
+// so it is neither cached nor fetched from the cache.
+func (e *ExecuteExec) planCacheable(prepared *Prepared) (bool, error) {
+	if !variable.GetSessionVars(e.Ctx).EnablePlanCache || !plan.Cacheable(prepared.Stmt) {
+		return false, nil
+	}
+	txn, err := e.Ctx.GetTxn(false)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return txn == nil || txn.IsReadOnly(), nil
+}
+
+// planCacheKey is the key of a cached plan. A plan is optimized for a schema version,
+// the versions of the bindings and the kinds of the parameter values, it can't be reused
+// if any of them changes.
+type planCacheKey struct {
+	stmtID             uint32
+	schemaVersion      int64
+	sessionBindVersion uint64
+	globalBindVersion  uint64
+	paramKinds         []byte
+}
+
+func newPlanCacheKey(ctx context.Context, stmtID uint32, prepared *Prepared) *planCacheKey {
+	key := &planCacheKey{
+		stmtID:        stmtID,
+		schemaVersion: prepared.SchemaVersion,
+		paramKinds:    make([]byte, 0, len(prepared.Params)),
+	}
+	key.sessionBindVersion, key.globalBindVersion = plan.BindingVersions(ctx)
+	for _, param := range prepared.Params {
+		key.paramKinds = append(key.paramKinds, param.Datum.Kind())
+	}
+	return key
+}
+
+// Hash implements kvcache.Key Hash interface.
+func (key *planCacheKey) Hash() []byte {
+	b := make([]byte, 0, 32+len(key.paramKinds))
+	b = codec.EncodeUint(b, uint64(key.stmtID))
+	b = codec.EncodeInt(b, key.schemaVersion)
+	b = codec.EncodeUint(b, key.sessionBindVersion)
+	b = codec.EncodeUint(b, key.globalBindVersion)
+	return append(b, key.paramKinds...)
+}
+
+// planCacheHits and planCacheMisses count the lookups of the plan caches of all sessions.
+var planCacheHits, planCacheMisses int64
+
+// PlanCacheHits returns the number of executions of prepared statements that reuse a cached plan.
+func PlanCacheHits() int64 {
+	return atomic.LoadInt64(&planCacheHits)
+}
+
+// PlanCacheMisses returns the number of executions of prepared statements whose plan
+// can be cached but is not found in the plan cache.
+func PlanCacheMisses() int64 {
+	return atomic.LoadInt64(&planCacheMisses)
+}
+
 // DeallocateExec represent a DEALLOCATE executor.
 type DeallocateExec struct {
 	Name string
@@ -21,14 +21,17 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -43,7 +46,7 @@ type ShowExec struct {
 	Full   bool
 	User   string // Used for show grants.
 
-	// Used by show variables
+	// Used by show variables and show bindings.
 	GlobalScope bool
 
 	fields []*ast.ResultField
@@ -86,6 +89,8 @@ func (e *ShowExec) Next() (*Row, error) {
 
 func (e *ShowExec) fetchAll() error {
 	switch e.Tp {
+	case ast.ShowBindings:
+		return e.fetchShowBindings()
 	case ast.ShowCharset:
 		return e.fetchShowCharset()
 	case ast.ShowCollation:
@@ -100,6 +105,8 @@ func (e *ShowExec) fetchAll() error {
 		return e.fetchShowEngines()
 	case ast.ShowGrants:
 		return e.fetchShowGrants()
+	case ast.ShowProcessList:
+		return e.fetchShowProcessList()
 	case ast.ShowIndex:
 		return e.fetchShowIndex()
 	case ast.ShowProcedureStatus:
@@ -343,7 +350,7 @@ func (e *ShowExec) fetchShowVariables() error {
 }
 
 func (e *ShowExec) fetchShowStatus() error {
-	statusVars, err := variable.GetStatusVars()
+	statusVars, err := variable.GetStatusVars(variable.GetSessionVars(e.ctx))
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -508,6 +515,35 @@ func (e *ShowExec) fetchShowCollation() error {
 	return nil
 }
 
+func (e *ShowExec) fetchShowBindings() error {
+	var h *bindinfo.Handle
+	if e.GlobalScope {
+		h = sessionctx.GetDomain(e.ctx).BindHandle()
+	} else {
+		h = bindinfo.GetSessionHandle(e.ctx)
+	}
+	if h == nil {
+		return nil
+	}
+	for _, record := range h.Bindings() {
+		data := types.MakeDatums(record.OriginalSQL, record.BindSQL, record.DefaultDB, record.CreateTime, record.UpdateTime)
+		e.rows = append(e.rows, &Row{Data: data})
+	}
+	return nil
+}
+
+func (e *ShowExec) fetchShowProcessList() error {
+	processes, err := util.VisibleProcessList(e.ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, pi := range processes {
+		data := types.MakeDatums(pi.ToRow(e.Full)...)
+		e.rows = append(e.rows, &Row{Data: data})
+	}
+	return nil
+}
+
 func (e *ShowExec) fetchShowGrants() error {
 	// Get checker
 	checker := privilege.GetPrivilegeChecker(e.ctx)
@@ -0,0 +1,357 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/types"
+)
+
+const (
+	spillFilePrefix = "tidb-spill-"
+	// spillPartitions is the number of partitions used by hash aggregation and
+	// hash join when they spill to disk.
+	spillPartitions = 16
+)
+
+var (
+	datumSize       = int64(unsafe.Sizeof(types.Datum{}))
+	rowSize         = int64(unsafe.Sizeof(Row{}))
+	rowKeyEntrySize = int64(unsafe.Sizeof(RowKeyEntry{}))
+	// aggGroupMemUsage is the memory used by the evaluation context of an aggregate function for a group.
+	aggGroupMemUsage = int64(unsafe.Sizeof(ast.AggEvaluateContext{}))
+)
+
+// datumsMemUsage estimates the memory used by datums.
+func datumsMemUsage(data []types.Datum) int64 {
+	size := datumSize * int64(len(data))
+	for i := range data {
+		size += int64(len(data[i].GetBytes()))
+	}
+	return size
+}
+
+// rowMemUsage estimates the memory used by a row.
+func rowMemUsage(row *Row) int64 {
+	return rowSize + datumsMemUsage(row.Data) + rowKeyEntrySize*int64(len(row.RowKeys))
+}
+
+// spillPartition returns the spill partition of a hash key.
+// The seed makes the partitioning differ between recursive spill levels.
+func spillPartition(hashKey []byte, seed int) int {
+	h := fnv.New32a()
+	h.Write([]byte{byte(seed)})
+	h.Write(hashKey)
+	return int(h.Sum32() % spillPartitions)
+}
+
+// spillFile is a temporary file holding rows that can not be kept in memory
+// under the query memory quota. Rows are appended first, then the file is
+// rewound and read back sequentially.
+type spillFile struct {
+	file   *os.File
+	writer *bufio.Writer
+	reader *bufio.Reader
+	buf    []byte
+	// tables records the distinct table and alias pairs of spilled row keys,
+	// the file only stores their offsets in this slice.
+	tables []*RowKeyEntry
+	rows   int
+}
+
+// spillFiles counts the spill files created by all the sessions.
+var spillFiles int64
+
+// SpillFiles returns the number of spill files created by executors exceeding the query memory quota.
+func SpillFiles() int64 {
+	return atomic.LoadInt64(&spillFiles)
+}
+
+func newSpillFile() (*spillFile, error) {
+	f, err := ioutil.TempFile("", spillFilePrefix)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	atomic.AddInt64(&spillFiles, 1)
+	return &spillFile{
+		file:   f,
+		writer: bufio.NewWriter(f),
+	}, nil
+}
+
+// write appends a row and its optional extra datums, like sort keys, to the file.
+func (s *spillFile) write(row *Row, extra []types.Datum) error {
+	b, err := encodeSpillDatums(s.buf[:0], extra)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	b, err = encodeSpillDatums(b, row.Data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	b = codec.EncodeUvarint(b, uint64(len(row.RowKeys)))
+	for _, rk := range row.RowKeys {
+		b = codec.EncodeUvarint(b, uint64(s.tableOffset(rk)))
+		b = codec.EncodeVarint(b, rk.Handle)
+	}
+	s.buf = b
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+	if _, err = s.writer.Write(lenBuf[:n]); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err = s.writer.Write(b); err != nil {
+		return errors.Trace(err)
+	}
+	s.rows++
+	return nil
+}
+
+func (s *spillFile) tableOffset(rk *RowKeyEntry) int {
+	for i, t := range s.tables {
+		if t.Tbl == rk.Tbl && t.TableAsName == rk.TableAsName {
+			return i
+		}
+	}
+	s.tables = append(s.tables, &RowKeyEntry{Tbl: rk.Tbl, TableAsName: rk.TableAsName})
+	return len(s.tables) - 1
+}
+
+// rewind flushes the written rows and moves the read position to the first row.
+func (s *spillFile) rewind() error {
+	if err := s.writer.Flush(); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := s.file.Seek(0, 0); err != nil {
+		return errors.Trace(err)
+	}
+	s.reader = bufio.NewReader(s.file)
+	return nil
+}
+
+// read reads the next row and its extra datums, a nil row means the end of file.
+func (s *spillFile) read() (*Row, []types.Datum, error) {
+	size, err := binary.ReadUvarint(s.reader)
+	if err == io.EOF {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	// The buffer can not be reused, decoded datums refer to it.
+	b := make([]byte, size)
+	if _, err = io.ReadFull(s.reader, b); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	b, extra, err := decodeSpillDatums(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	row := &Row{}
+	b, row.Data, err = decodeSpillDatums(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	b, cnt, err := codec.DecodeUvarint(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	for i := uint64(0); i < cnt; i++ {
+		var offset uint64
+		b, offset, err = codec.DecodeUvarint(b)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		var handle int64
+		b, handle, err = codec.DecodeVarint(b)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if offset >= uint64(len(s.tables)) {
+			return nil, nil, errors.New("invalid spilled row key")
+		}
+		t := s.tables[offset]
+		row.RowKeys = append(row.RowKeys, &RowKeyEntry{Tbl: t.Tbl, Handle: handle, TableAsName: t.TableAsName})
+	}
+	return row, extra, nil
+}
+
+// close closes and removes the file.
+func (s *spillFile) close() error {
+	err := s.file.Close()
+	err1 := os.Remove(s.file.Name())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(err1)
+}
+
+// closeSpillFiles closes and removes all the files, the first error is returned.
+func closeSpillFiles(files []*spillFile) error {
+	var firstErr error
+	for _, f := range files {
+		if f == nil {
+			continue
+		}
+		if err := f.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return errors.Trace(firstErr)
+}
+
+// encodeSpillDatums encodes datums without losing their kinds, which the
+// codec package does not guarantee for time, decimal, enum and set values.
+func encodeSpillDatums(b []byte, data []types.Datum) ([]byte, error) {
+	b = codec.EncodeUvarint(b, uint64(len(data)))
+	for i := range data {
+		d := &data[i]
+		b = append(b, d.Kind(), d.Collation())
+		b = codec.EncodeUvarint(b, uint64(d.Frac()))
+		b = codec.EncodeUvarint(b, uint64(d.Length()))
+		var bs []byte
+		switch d.Kind() {
+		case types.KindMysqlDecimal:
+			bs = d.GetMysqlDecimal().ToString()
+		case types.KindMysqlTime:
+			t := d.GetMysqlTime()
+			bs = codec.EncodeVarint([]byte{t.Type}, int64(t.Fsp))
+			tb, err := t.Time.MarshalBinary()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			bs = append(bs, tb...)
+		default:
+			b = codec.EncodeVarint(b, d.GetInt64())
+			bs = d.GetBytes()
+		}
+		b = codec.EncodeUvarint(b, uint64(len(bs)))
+		b = append(b, bs...)
+	}
+	return b, nil
+}
+
+func decodeSpillDatums(b []byte) ([]byte, []types.Datum, error) {
+	b, cnt, err := codec.DecodeUvarint(b)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if cnt == 0 {
+		return b, nil, nil
+	}
+	data := make([]types.Datum, cnt)
+	for i := range data {
+		if len(b) < 2 {
+			return nil, nil, errors.New("invalid spilled datum")
+		}
+		kind, collation := b[0], b[1]
+		var frac, length uint64
+		b, frac, err = codec.DecodeUvarint(b[2:])
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		b, length, err = codec.DecodeUvarint(b)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		var v int64
+		if kind != types.KindMysqlDecimal && kind != types.KindMysqlTime {
+			b, v, err = codec.DecodeVarint(b)
+			if err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+		}
+		var size uint64
+		b, size, err = codec.DecodeUvarint(b)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if uint64(len(b)) < size {
+			return nil, nil, errors.New("invalid spilled datum")
+		}
+		bs := b[:size:size]
+		b = b[size:]
+
+		d := &data[i]
+		switch kind {
+		case types.KindNull:
+		case types.KindInt64:
+			d.SetInt64(v)
+		case types.KindUint64:
+			d.SetUint64(uint64(v))
+		case types.KindFloat32:
+			d.SetFloat32(float32(math.Float64frombits(uint64(v))))
+		case types.KindFloat64:
+			d.SetFloat64(math.Float64frombits(uint64(v)))
+		case types.KindString:
+			d.SetBytesAsString(bs)
+		case types.KindBytes:
+			d.SetBytes(bs)
+		case types.KindMysqlBit:
+			d.SetMysqlBit(mysql.Bit{Value: uint64(v), Width: int(length)})
+		case types.KindMysqlDecimal:
+			dec := new(mysql.MyDecimal)
+			if err = dec.FromString(bs); err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			d.SetMysqlDecimal(dec)
+		case types.KindMysqlDuration:
+			d.SetMysqlDuration(mysql.Duration{Duration: time.Duration(v), Fsp: int(frac)})
+		case types.KindMysqlEnum:
+			d.SetMysqlEnum(mysql.Enum{Name: string(bs), Value: uint64(v)})
+		case types.KindMysqlHex:
+			d.SetMysqlHex(mysql.Hex{Value: v})
+		case types.KindMysqlSet:
+			d.SetMysqlSet(mysql.Set{Name: string(bs), Value: uint64(v)})
+		case types.KindMysqlTime:
+			if len(bs) == 0 {
+				return nil, nil, errors.New("invalid spilled time")
+			}
+			t := mysql.Time{Type: bs[0]}
+			tb, fsp, err1 := codec.DecodeVarint(bs[1:])
+			if err1 != nil {
+				return nil, nil, errors.Trace(err1)
+			}
+			t.Fsp = int(fsp)
+			if err = t.Time.UnmarshalBinary(tb); err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			d.SetMysqlTime(t)
+		case types.KindMinNotNull:
+			*d = types.MinNotNullDatum()
+		case types.KindMaxValue:
+			*d = types.MaxValueDatum()
+		default:
+			return nil, nil, errors.Errorf("unsupported spilled datum kind %d", kind)
+		}
+		d.SetCollation(collation)
+		d.SetFrac(int(frac))
+		d.SetLength(int(length))
+	}
+	return b, data, nil
+}
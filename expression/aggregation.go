@@ -37,6 +37,9 @@ type AggregationFunction interface {
 	// SetMode sets aggFunctionMode for aggregate function
 	SetMode(mode AggFunctionMode)
 
+	// GetMode gets aggFunctionMode from aggregate function.
+	GetMode() AggFunctionMode
+
 	// GetGroupResult will be called when all data have been processed.
 	GetGroupResult(groupKey []byte) types.Datum
 
@@ -60,6 +63,14 @@ type AggregationFunction interface {
 
 	// SetContext sets the aggregate evaluation context.
 	SetContext(ctx map[string](*ast.AggEvaluateContext))
+
+	// GetContext gets the aggregate evaluation context.
+	GetContext() map[string](*ast.AggEvaluateContext)
+
+	// MergeGroupContext merges a partial evaluation context of a group, which is computed by
+	// another copy of this function over a disjoint part of the data, into this function.
+	// It is used by parallel hash aggregation and does not support distinct functions.
+	MergeGroupContext(groupKey []byte, partial *ast.AggEvaluateContext) error
 }
 
 // NewAggFunction creates a new AggregationFunction.
@@ -130,6 +141,10 @@ func (af *aggFunction) SetMode(mode AggFunctionMode) {
 	af.mode = mode
 }
 
+func (af *aggFunction) GetMode() AggFunctionMode {
+	return af.mode
+}
+
 // GetArgs implements AggregationFunction interface.
 func (af *aggFunction) GetArgs() []Expression {
 	return af.Args
@@ -166,6 +181,28 @@ func (af *aggFunction) SetContext(ctx map[string](*ast.AggEvaluateContext)) {
 	af.resultMapper = ctx
 }
 
+// GetContext implements AggregationFunction interface.
+func (af *aggFunction) GetContext() map[string](*ast.AggEvaluateContext) {
+	return af.resultMapper
+}
+
+func (af *aggFunction) mergeSum(groupKey []byte, partial *ast.AggEvaluateContext) error {
+	if af.Distinct {
+		return errors.Errorf("can not merge partial result of distinct %s", af.name)
+	}
+	ctx := af.getContext(groupKey)
+	if partial.Value.IsNull() {
+		return nil
+	}
+	var err error
+	ctx.Value, err = types.CalculateSum(ctx.Value, partial.Value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	ctx.Count += partial.Count
+	return nil
+}
+
 func (af *aggFunction) updateSum(row []types.Datum, groupKey []byte, ectx context.Context) error {
 	ctx := af.getContext(groupKey)
 	a := af.Args[0]
@@ -233,6 +270,11 @@ func (sf *sumFunction) StreamUpdate(row []types.Datum, ectx context.Context) err
 	return sf.streamUpdateSum(row, ectx)
 }
 
+// MergeGroupContext implements AggregationFunction interface.
+func (sf *sumFunction) MergeGroupContext(groupKey []byte, partial *ast.AggEvaluateContext) error {
+	return sf.mergeSum(groupKey, partial)
+}
+
 // GetGroupResult implements AggregationFunction interface.
 func (sf *sumFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
 	return sf.getContext(groupKey).Value
@@ -243,7 +285,7 @@ func (sf *sumFunction) GetStreamResult() (d types.Datum) {
 		sf.streamCtx = &ast.AggEvaluateContext{}
 	}
 	d = sf.streamCtx.Value
-	sf.streamCtx = &ast.AggEvaluateContext{}
+	sf.streamCtx = nil
 	return
 }
 
@@ -319,6 +361,15 @@ func (cf *countFunction) StreamUpdate(row []types.Datum, ectx context.Context) e
 	return nil
 }
 
+// MergeGroupContext implements AggregationFunction interface.
+func (cf *countFunction) MergeGroupContext(groupKey []byte, partial *ast.AggEvaluateContext) error {
+	if cf.Distinct {
+		return errors.New("can not merge partial result of distinct count")
+	}
+	cf.getContext(groupKey).Count += partial.Count
+	return nil
+}
+
 // GetGroupResult implements AggregationFunction interface.
 func (cf *countFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
 	d.SetInt64(cf.getContext(groupKey).Count)
@@ -330,7 +381,7 @@ func (cf *countFunction) GetStreamResult() (d types.Datum) {
 		cf.streamCtx = &ast.AggEvaluateContext{}
 	}
 	d.SetInt64(cf.streamCtx.Count)
-	cf.streamCtx = &ast.AggEvaluateContext{}
+	cf.streamCtx = nil
 	return
 }
 
@@ -381,6 +432,11 @@ func (af *avgFunction) StreamUpdate(row []types.Datum, ctx context.Context) erro
 	return af.streamUpdateSum(row, ctx)
 }
 
+// MergeGroupContext implements AggregationFunction interface.
+func (af *avgFunction) MergeGroupContext(groupKey []byte, partial *ast.AggEvaluateContext) error {
+	return af.mergeSum(groupKey, partial)
+}
+
 func (af *avgFunction) calculateResult(ctx *ast.AggEvaluateContext) (d types.Datum) {
 	switch ctx.Value.Kind() {
 	case types.KindFloat64:
@@ -408,7 +464,7 @@ func (af *avgFunction) GetStreamResult() (d types.Datum) {
 		af.streamCtx = &ast.AggEvaluateContext{}
 	}
 	d = af.calculateResult(af.streamCtx)
-	af.streamCtx = &ast.AggEvaluateContext{}
+	af.streamCtx = nil
 	return
 }
 
@@ -463,7 +519,7 @@ func (cf *concatFunction) StreamUpdate(row []types.Datum, ectx context.Context)
 		if value.GetValue() == nil {
 			return nil
 		}
-		vals = append(vals, value)
+		vals = append(vals, value.GetValue())
 	}
 	if cf.Distinct {
 		d, err := ctx.DistinctChecker.Check(vals)
@@ -487,6 +543,24 @@ func (cf *concatFunction) StreamUpdate(row []types.Datum, ectx context.Context)
 	return nil
 }
 
+// MergeGroupContext implements AggregationFunction interface.
+func (cf *concatFunction) MergeGroupContext(groupKey []byte, partial *ast.AggEvaluateContext) error {
+	if cf.Distinct {
+		return errors.New("can not merge partial result of distinct group_concat")
+	}
+	if partial.Buffer == nil {
+		return nil
+	}
+	ctx := cf.getContext(groupKey)
+	if ctx.Buffer == nil {
+		ctx.Buffer = &bytes.Buffer{}
+	} else {
+		ctx.Buffer.WriteString(",")
+	}
+	ctx.Buffer.Write(partial.Buffer.Bytes())
+	return nil
+}
+
 // GetGroupResult implements AggregationFunction interface.
 func (cf *concatFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
 	ctx := cf.getContext(groupKey)
@@ -507,7 +581,7 @@ func (cf *concatFunction) GetStreamResult() (d types.Datum) {
 	} else {
 		d.SetNull()
 	}
-	cf.streamCtx = &ast.AggEvaluateContext{}
+	cf.streamCtx = nil
 	return
 }
 
@@ -516,6 +590,26 @@ type maxMinFunction struct {
 	isMax bool
 }
 
+// MergeGroupContext implements AggregationFunction interface.
+func (mmf *maxMinFunction) MergeGroupContext(groupKey []byte, partial *ast.AggEvaluateContext) error {
+	ctx := mmf.getContext(groupKey)
+	if partial.Value.IsNull() {
+		return nil
+	}
+	if ctx.Value.IsNull() {
+		ctx.Value = partial.Value
+		return nil
+	}
+	c, err := ctx.Value.CompareDatum(partial.Value)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if (mmf.isMax && c < 0) || (!mmf.isMax && c > 0) {
+		ctx.Value = partial.Value
+	}
+	return nil
+}
+
 // GetGroupResult implements AggregationFunction interface.
 func (mmf *maxMinFunction) GetGroupResult(groupKey []byte) (d types.Datum) {
 	return mmf.getContext(groupKey).Value
@@ -526,7 +620,7 @@ func (mmf *maxMinFunction) GetStreamResult() (d types.Datum) {
 		mmf.streamCtx = &ast.AggEvaluateContext{}
 	}
 	d = mmf.streamCtx.Value
-	mmf.streamCtx = &ast.AggEvaluateContext{}
+	mmf.streamCtx = nil
 	return
 }
 
@@ -623,6 +717,15 @@ func (ff *firstRowFunction) StreamUpdate(row []types.Datum, ectx context.Context
 	return nil
 }
 
+// MergeGroupContext implements AggregationFunction interface.
+func (ff *firstRowFunction) MergeGroupContext(groupKey []byte, partial *ast.AggEvaluateContext) error {
+	ctx := ff.getContext(groupKey)
+	if ctx.Value.IsNull() {
+		ctx.Value = partial.Value
+	}
+	return nil
+}
+
 // GetGroupResult implements AggregationFunction interface.
 func (ff *firstRowFunction) GetGroupResult(groupKey []byte) types.Datum {
 	return ff.getContext(groupKey).Value
@@ -633,6 +736,6 @@ func (ff *firstRowFunction) GetStreamResult() (d types.Datum) {
 		ff.streamCtx = &ast.AggEvaluateContext{}
 	}
 	d = ff.streamCtx.Value
-	ff.streamCtx = &ast.AggEvaluateContext{}
+	ff.streamCtx = nil
 	return
 }
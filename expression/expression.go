@@ -261,8 +261,9 @@ func NewFunction(funcName string, retType *types.FieldType, args ...Expression)
 
 	datums := make([]types.Datum, 0, len(args))
 
+	// The parameters of a cached plan are not folded, their values change between executions.
 	for i := 0; i < len(args) && canConstantFolding; i++ {
-		if v, ok := args[i].(*Constant); ok {
+		if v, ok := args[i].(*Constant); ok && v.ParamMarker == nil {
 			datums = append(datums, types.NewDatum(v.Value.GetValue()))
 		} else {
 			canConstantFolding = false
@@ -357,6 +358,9 @@ func (sf *ScalarFunction) HashCode() []byte {
 type Constant struct {
 	Value   types.Datum
 	RetType *types.FieldType
+	// ParamMarker is set if the constant is a parameter of a cached plan, its
+	// value is refreshed from the marker before the plan is executed again.
+	ParamMarker *ast.ParamMarkerExpr
 }
 
 // String implements fmt.Stringer interface.
@@ -372,6 +376,10 @@ func (c *Constant) MarshalJSON() ([]byte, error) {
 
 // DeepCopy implements Expression interface.
 func (c *Constant) DeepCopy() Expression {
+	if c.ParamMarker != nil {
+		// The parameter must be shared by the copies, so they all see the refreshed value.
+		return c
+	}
 	con := *c
 	return &con
 }
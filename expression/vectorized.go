@@ -0,0 +1,170 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// vectorizedFuncs are the scalar functions that can be evaluated column by column.
+// Their results only depend on their arguments, so evaluating a whole batch for
+// one function before another does not change the result of any row.
+var vectorizedFuncs = map[string]bool{
+	ast.EQ:     true,
+	ast.NE:     true,
+	ast.LT:     true,
+	ast.LE:     true,
+	ast.GT:     true,
+	ast.GE:     true,
+	ast.NullEQ: true,
+	ast.Plus:   true,
+	ast.Minus:  true,
+	ast.Mul:    true,
+	ast.Div:    true,
+	ast.AndAnd: true,
+	ast.OrOr:   true,
+}
+
+// Vectorizable checks whether the expression can be evaluated by VectorizedEval.
+func Vectorizable(expr Expression) bool {
+	switch x := expr.(type) {
+	case *Column, *Constant:
+		return true
+	case *ScalarFunction:
+		if !vectorizedFuncs[x.FuncName.L] {
+			return false
+		}
+		for _, arg := range x.Args {
+			if !Vectorizable(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// VectorizedEval evaluates a vectorizable expression on a batch of numRows rows,
+// columns[i] holds the values of the i-th column of the input rows.
+// The returned slice must not be modified, it may be one of the columns.
+func VectorizedEval(expr Expression, columns [][]types.Datum, numRows int, ctx context.Context) ([]types.Datum, error) {
+	switch x := expr.(type) {
+	case *Column:
+		if !x.Correlated {
+			return columns[x.Index][:numRows], nil
+		}
+		return fillDatums(*x.data, numRows), nil
+	case *Constant:
+		return fillDatums(x.Value, numRows), nil
+	case *ScalarFunction:
+		return x.vectorizedEval(columns, numRows, ctx)
+	}
+	return nil, errors.Errorf("expression %s can not be vectorized", expr)
+}
+
+func fillDatums(d types.Datum, n int) []types.Datum {
+	result := make([]types.Datum, n)
+	for i := range result {
+		result[i] = d
+	}
+	return result
+}
+
+func (sf *ScalarFunction) vectorizedEval(columns [][]types.Datum, numRows int, ctx context.Context) ([]types.Datum, error) {
+	if !vectorizedFuncs[sf.FuncName.L] {
+		return nil, errors.Errorf("function %s can not be vectorized", sf.FuncName.L)
+	}
+	argVecs := make([][]types.Datum, 0, len(sf.Args))
+	for _, arg := range sf.Args {
+		vec, err := VectorizedEval(arg, columns, numRows, ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		argVecs = append(argVecs, vec)
+	}
+	result := make([]types.Datum, numRows)
+	args := make([]types.Datum, len(argVecs))
+	for i := 0; i < numRows; i++ {
+		if len(argVecs) == 2 && vectorizedIntOp(sf.FuncName.L, argVecs[0][i], argVecs[1][i], &result[i]) {
+			continue
+		}
+		for j, vec := range argVecs {
+			args[j] = vec[i]
+		}
+		var err error
+		result[i], err = sf.Function(args, ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return result, nil
+}
+
+// vectorizedIntOp computes the common comparisons and arithmetic operations of two int64
+// values without going through the generic builtin function, which coerces the arguments
+// and dispatches on the operator for every row. It returns false if the operation is
+// not handled, then the builtin function should be used.
+func vectorizedIntOp(funcName string, a, b types.Datum, result *types.Datum) bool {
+	if a.Kind() != types.KindInt64 || b.Kind() != types.KindInt64 {
+		return false
+	}
+	x, y := a.GetInt64(), b.GetInt64()
+	var cmp bool
+	switch funcName {
+	case ast.EQ, ast.NullEQ:
+		cmp = x == y
+	case ast.NE:
+		cmp = x != y
+	case ast.LT:
+		cmp = x < y
+	case ast.LE:
+		cmp = x <= y
+	case ast.GT:
+		cmp = x > y
+	case ast.GE:
+		cmp = x >= y
+	case ast.Plus:
+		r, err := types.AddInt64(x, y)
+		if err != nil {
+			return false
+		}
+		result.SetInt64(r)
+		return true
+	case ast.Minus:
+		r, err := types.SubInt64(x, y)
+		if err != nil {
+			return false
+		}
+		result.SetInt64(r)
+		return true
+	case ast.Mul:
+		r, err := types.MulInt64(x, y)
+		if err != nil {
+			return false
+		}
+		result.SetInt64(r)
+		return true
+	default:
+		return false
+	}
+	if cmp {
+		result.SetInt64(1)
+	} else {
+		result.SetInt64(0)
+	}
+	return true
+}
@@ -16,13 +16,20 @@ package infoschema
 import (
 	"fmt"
 	"sort"
+	"strings"
 
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/meta/autoid"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
 	"github.com/pingcap/tidb/table"
 	"github.com/pingcap/tidb/table/tables"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/slowlog"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -38,6 +45,8 @@ const (
 	tableProfiling     = "PROFILING"
 	tablePartitions    = "PARTITIONS"
 	tableKeyColumm     = "KEY_COLUMN_USAGE"
+	tableProcessList   = "PROCESSLIST"
+	tableSlowQuery     = "SLOW_QUERY"
 )
 
 type columnInfo struct {
@@ -242,6 +251,37 @@ var partitionsCols = []columnInfo{
 	{"TABLESPACE_NAME", mysql.TypeVarchar, 64, 0, nil, nil},
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/processlist-table.html
+var processListCols = []columnInfo{
+	{"ID", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"USER", mysql.TypeVarchar, 16, 0, nil, nil},
+	{"HOST", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"DB", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"COMMAND", mysql.TypeVarchar, 16, 0, nil, nil},
+	{"TIME", mysql.TypeLong, 7, 0, nil, nil},
+	{"STATE", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"INFO", mysql.TypeBlob, 196606, 0, nil, nil},
+}
+
+// slowQueryCols are the columns of the slow query log, the times are in seconds.
+var slowQueryCols = []columnInfo{
+	{"TIME", mysql.TypeDatetime, 26, 0, nil, nil},
+	{"USER", mysql.TypeVarchar, 16, 0, nil, nil},
+	{"HOST", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"CONN_ID", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"DB", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"QUERY_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"PARSE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"COMPILE_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"EXEC_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"COP_WAIT_TIME", mysql.TypeDouble, 22, 0, nil, nil},
+	{"ROWS_SENT", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"ROWS_EXAMINED", mysql.TypeLonglong, 21, 0, nil, nil},
+	{"PLAN_DIGEST", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"DIGEST", mysql.TypeVarchar, 64, 0, nil, nil},
+	{"QUERY", mysql.TypeLongBlob, types.UnspecifiedLength, 0, nil, nil},
+}
+
 func dataForCharacterSets() (records [][]types.Datum) {
 	records = append(records,
 		types.MakeDatums("ascii", "ascii_general_ci", "US ASCII", 1),
@@ -487,6 +527,81 @@ func dataForStatisticsInTable(schema *model.DBInfo, table *model.TableInfo) [][]
 	return rows
 }
 
+func dataForProcessList(ctx context.Context) ([][]types.Datum, error) {
+	processes, err := util.VisibleProcessList(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var rows [][]types.Datum
+	for _, pi := range processes {
+		rows = append(rows, types.MakeDatums(pi.ToRow(true)...))
+	}
+	return rows, nil
+}
+
+// dataForSlowQuery reads the rows from the slow query log file. Like the process list, the
+// slow queries of the other users need the PROCESS privilege.
+func dataForSlowQuery(ctx context.Context) ([][]types.Datum, error) {
+	hasProcessPriv, err := privilege.CheckGlobal(ctx, mysql.ProcessPriv)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	entries, err := slowlog.Read()
+	if err != nil {
+		log.Warnf("read slow query log error %v", err)
+		return nil, nil
+	}
+	rows := make([][]types.Datum, 0, len(entries))
+	for _, e := range entries {
+		if !hasProcessPriv && !util.IsCurrentUser(ctx, e.User) {
+			continue
+		}
+		startTime := mysql.Time{Time: e.StartTime, Type: mysql.TypeDatetime, Fsp: mysql.MaxFsp}
+		record := types.MakeDatums(
+			startTime,               // TIME
+			e.User,                  // USER
+			e.Host,                  // HOST
+			e.ConnID,                // CONN_ID
+			e.DB,                    // DB
+			e.QueryTime.Seconds(),   // QUERY_TIME
+			e.ParseTime.Seconds(),   // PARSE_TIME
+			e.CompileTime.Seconds(), // COMPILE_TIME
+			e.ExecTime.Seconds(),    // EXEC_TIME
+			e.CopWaitTime.Seconds(), // COP_WAIT_TIME
+			e.RowsSent,              // ROWS_SENT
+			e.RowsExamined,          // ROWS_EXAMINED
+			e.PlanDigest,            // PLAN_DIGEST
+			e.Digest,                // DIGEST
+			e.SQL,                   // QUERY
+		)
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// dynamicTables are the tables whose rows change all the time, so they are
+// generated every time the table is read instead of being filled when the schema is loaded.
+var dynamicTables = map[string]func(ctx context.Context) ([][]types.Datum, error){
+	strings.ToLower(tableProcessList): dataForProcessList,
+	strings.ToLower(tableSlowQuery):   dataForSlowQuery,
+}
+
+// IsDynamicTable checks whether the information_schema table is generated when it is read.
+func IsDynamicTable(tblName string) bool {
+	_, ok := dynamicTables[strings.ToLower(tblName)]
+	return ok
+}
+
+// DataForDynamicTable returns the current rows of the dynamic information_schema table.
+func DataForDynamicTable(ctx context.Context, tblName string) ([][]types.Datum, error) {
+	fn, ok := dynamicTables[strings.ToLower(tblName)]
+	if !ok {
+		return nil, nil
+	}
+	rows, err := fn(ctx)
+	return rows, errors.Trace(err)
+}
+
 var tableNameToColumns = map[string]([]columnInfo){
 	tableSchemata:      schemataCols,
 	tableTables:        tablesCols,
@@ -498,6 +613,8 @@ var tableNameToColumns = map[string]([]columnInfo){
 	tableProfiling:     profilingCols,
 	tablePartitions:    partitionsCols,
 	tableKeyColumm:     keyColumnUsageCols,
+	tableProcessList:   processListCols,
+	tableSlowQuery:     slowQueryCols,
 }
 
 func createMemoryTable(meta *model.TableInfo, alloc autoid.Allocator) (table.Table, error) {
@@ -300,6 +300,58 @@ func ScanSnapshotTableRecord(store kv.Storage, ver kv.Version, t table.Table, st
 	return records, nextHandle, errors.Trace(err)
 }
 
+// RowVersion is a committed version of a row.
+type RowVersion struct {
+	CommitTS uint64
+	// Values are the data of the public columns, it is nil if the row is deleted in this version.
+	Values []types.Datum
+}
+
+// GetRowVersions gets the versions of the row which are not compacted, the latest one first.
+// The store must implement kv.MvccInspector.
+func GetRowVersions(store kv.Storage, t table.Table, h int64) ([]*RowVersion, error) {
+	inspector, ok := store.(kv.MvccInspector)
+	if !ok {
+		return nil, errors.Errorf("the storage does not support reading MVCC versions")
+	}
+	versions, err := inspector.GetMvccVersions(t.RecordKey(h))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	cols := t.Cols()
+	colTps := make(map[int64]*types.FieldType, len(cols))
+	for _, col := range cols {
+		colTps[col.ID] = &col.FieldType
+	}
+	rows := make([]*RowVersion, 0, len(versions))
+	for _, ver := range versions {
+		row := &RowVersion{CommitTS: ver.CommitTS}
+		rows = append(rows, row)
+		if len(ver.Value) == 0 {
+			continue
+		}
+		data, err := tablecodec.DecodeRow(ver.Value, colTps)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		row.Values = make([]types.Datum, len(cols))
+		for i, col := range cols {
+			if col.IsPKHandleColumn(t.Meta()) {
+				if mysql.HasUnsignedFlag(col.Flag) {
+					row.Values[i].SetUint64(uint64(h))
+				} else {
+					row.Values[i].SetInt64(h)
+				}
+				continue
+			}
+			// The column which is added after this version is missing, it is null.
+			row.Values[i] = data[col.ID]
+		}
+	}
+	return rows, nil
+}
+
 // CompareTableRecord compares data and the corresponding table data one by one.
 // It returns nil if data is equal to the data that scans from table, otherwise
 // it returns an error with a different set of records. If exact is false, only compares handle.
@@ -106,6 +106,7 @@ const (
 	ReqSubTypeBasic   = 0
 	ReqSubTypeDesc    = 10000
 	ReqSubTypeGroupBy = 10001
+	ReqSubTypeTopN    = 10002
 )
 
 // KeyRange represents a range where StartKey <= key < EndKey.
@@ -177,6 +178,33 @@ type Storage interface {
 	CurrentVersion() (Version, error)
 }
 
+// RegionInfo is the key range of a region in the storage.
+type RegionInfo struct {
+	ID       uint64
+	StartKey Key
+	// EndKey is empty for the last region.
+	EndKey Key
+}
+
+// RegionLocator is implemented by the storages which split the keys into regions.
+type RegionLocator interface {
+	// LocateKey returns the region which contains the key.
+	LocateKey(key Key) (*RegionInfo, error)
+}
+
+// MvccVersion is a committed version of a key.
+type MvccVersion struct {
+	CommitTS uint64
+	// Value is empty if the key is deleted in this version.
+	Value []byte
+}
+
+// MvccInspector is implemented by the storages which can read all the versions of a key.
+type MvccInspector interface {
+	// GetMvccVersions returns the versions of the key which are not compacted, the latest one first.
+	GetMvccVersions(key Key) ([]*MvccVersion, error)
+}
+
 // FnKeyCmp is the function for iterator the keys
 type FnKeyCmp func(key Key) bool
 
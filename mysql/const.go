@@ -87,6 +87,51 @@ const (
 	ComResetConnection
 )
 
+// Command2Str is the command name map, the names are shown in the Command column of SHOW PROCESSLIST.
+var Command2Str = map[byte]string{
+	ComSleep:            "Sleep",
+	ComQuit:             "Quit",
+	ComInitDB:           "Init DB",
+	ComQuery:            "Query",
+	ComFieldList:        "Field List",
+	ComCreateDB:         "Create DB",
+	ComDropDB:           "Drop DB",
+	ComRefresh:          "Refresh",
+	ComShutdown:         "Shutdown",
+	ComStatistics:       "Statistics",
+	ComProcessInfo:      "Processlist",
+	ComConnect:          "Connect",
+	ComProcessKill:      "Kill",
+	ComDebug:            "Debug",
+	ComPing:             "Ping",
+	ComTime:             "Time",
+	ComDelayedInsert:    "Delayed Insert",
+	ComChangeUser:       "Change User",
+	ComBinlogDump:       "Binlog Dump",
+	ComTableDump:        "Table Dump",
+	ComConnectOut:       "Connect out",
+	ComRegisterSlave:    "Register Slave",
+	ComStmtPrepare:      "Prepare",
+	ComStmtExecute:      "Execute",
+	ComStmtSendLongData: "Long Data",
+	ComStmtClose:        "Close stmt",
+	ComStmtReset:        "Reset stmt",
+	ComSetOption:        "Set option",
+	ComStmtFetch:        "Fetch",
+	ComDaemon:           "Daemon",
+	ComBinlogDumpGtid:   "Binlog Dump",
+	ComResetConnection:  "Reset connect",
+}
+
+// Cursor type flags of COM_STMT_EXECUTE.
+// See https://dev.mysql.com/doc/internals/en/com-stmt-execute.html
+const (
+	CursorTypeNoCursor   byte = 0
+	CursorTypeReadOnly   byte = 1
+	CursorTypeForUpdate  byte = 2
+	CursorTypeScrollable byte = 4
+)
+
 // Client informations.
 const (
 	ClientLongPassword uint32 = 1 << iota
@@ -121,6 +166,8 @@ const (
 // Auth name informations.
 const (
 	AuthName = "mysql_native_password"
+	// AuthCachingSha2Password is the name of the caching_sha2_password authentication plugin.
+	AuthCachingSha2Password = "caching_sha2_password"
 )
 
 // MySQL database and tables.
@@ -141,6 +188,18 @@ const (
 	GlobalStatusTable = "GLOBAL_STATUS"
 	// TiDBTable is the table contains tidb info.
 	TiDBTable = "tidb"
+	// BindInfoTable is the table contains the global SQL bindings.
+	BindInfoTable = "bind_info"
+)
+
+// The values of the ssl_type column in mysql.user table, it is set by the REQUIRE clause.
+const (
+	// SSLTypeNone means the user has no TLS requirement.
+	SSLTypeNone = ""
+	// SSLTypeAny means the user must connect with TLS.
+	SSLTypeAny = "ANY"
+	// SSLTypeX509 means the user must connect with TLS and present a valid client certificate.
+	SSLTypeX509 = "X509"
 )
 
 // PrivilegeType  privilege
@@ -172,6 +231,10 @@ const (
 	ExecutePriv
 	// IndexPriv is the privilege to create/drop index.
 	IndexPriv
+	// ProcessPriv is the privilege to see the threads of other users.
+	ProcessPriv
+	// SuperPriv is the privilege to kill the threads of other users.
+	SuperPriv
 	// AllPriv is the privilege for all actions.
 	AllPriv
 )
@@ -190,6 +253,8 @@ var Priv2UserCol = map[PrivilegeType]string{
 	AlterPriv:      "Alter_priv",
 	ExecutePriv:    "Execute_priv",
 	IndexPriv:      "Index_priv",
+	ProcessPriv:    "Process_priv",
+	SuperPriv:      "Super_priv",
 }
 
 // Col2PrivType is the privilege tables column name to privilege type.
@@ -206,10 +271,12 @@ var Col2PrivType = map[string]PrivilegeType{
 	"Alter_priv":       AlterPriv,
 	"Execute_priv":     ExecutePriv,
 	"Index_priv":       IndexPriv,
+	"Process_priv":     ProcessPriv,
+	"Super_priv":       SuperPriv,
 }
 
 // AllGlobalPrivs is all the privileges in global scope.
-var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv}
+var AllGlobalPrivs = []PrivilegeType{SelectPriv, InsertPriv, UpdatePriv, DeletePriv, CreatePriv, DropPriv, GrantPriv, AlterPriv, ShowDBPriv, ExecutePriv, IndexPriv, CreateUserPriv, ProcessPriv, SuperPriv}
 
 // Priv2Str is the map for privilege to string.
 var Priv2Str = map[PrivilegeType]string{
@@ -225,6 +292,8 @@ var Priv2Str = map[PrivilegeType]string{
 	AlterPriv:      "Alter",
 	ExecutePriv:    "Execute",
 	IndexPriv:      "Index",
+	ProcessPriv:    "Process",
+	SuperPriv:      "Super",
 }
 
 // Priv2SetStr is the map for privilege to string.
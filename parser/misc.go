@@ -129,331 +129,344 @@ func init() {
 }
 
 var tokenMap = map[string]int{
-	"ABS":                 abs,
-	"ADD":                 add,
-	"ADDDATE":             addDate,
-	"ADMIN":               admin,
-	"AFTER":               after,
-	"ALL":                 all,
-	"ALTER":               alter,
-	"ANALYZE":             analyze,
-	"AND":                 and,
-	"ANY":                 any,
-	"AS":                  as,
-	"ASC":                 asc,
-	"ASCII":               ascii,
-	"AUTO_INCREMENT":      autoIncrement,
-	"AVG":                 avg,
-	"AVG_ROW_LENGTH":      avgRowLength,
-	"BEGIN":               begin,
-	"BETWEEN":             between,
-	"BINLOG":              binlog,
-	"BOTH":                both,
-	"BTREE":               btree,
-	"BY":                  by,
-	"BYTE":                byteType,
-	"CASE":                caseKwd,
-	"CAST":                cast,
-	"CEIL":                ceil,
-	"CEILING":             ceiling,
-	"CHARACTER":           character,
-	"CHARSET":             charsetKwd,
-	"CHECK":               check,
-	"CHECKSUM":            checksum,
-	"COALESCE":            coalesce,
-	"COLLATE":             collate,
-	"COLLATION":           collation,
-	"COLUMN":              column,
-	"COLUMNS":             columns,
-	"COMMENT":             comment,
-	"COMMIT":              commit,
-	"COMMITTED":           committed,
-	"COMPACT":             compact,
-	"COMPRESSED":          compressed,
-	"COMPRESSION":         compression,
-	"CONCAT":              concat,
-	"CONCAT_WS":           concatWs,
-	"CONNECTION":          connection,
-	"CONNECTION_ID":       connectionID,
-	"CONSTRAINT":          constraint,
-	"CONSISTENT":          consistent,
-	"CONVERT":             convert,
-	"COUNT":               count,
-	"CREATE":              create,
-	"CROSS":               cross,
-	"CURDATE":             curDate,
-	"UTC_DATE":            utcDate,
-	"CURRENT_DATE":        currentDate,
-	"CURTIME":             curTime,
-	"CURRENT_TIME":        currentTime,
-	"CURRENT_USER":        currentUser,
-	"DATA":                data,
-	"DATABASE":            database,
-	"DATABASES":           databases,
-	"DATE_ADD":            dateAdd,
-	"DATE_FORMAT":         dateFormat,
-	"DATE_SUB":            dateSub,
-	"DAY":                 day,
-	"DAYNAME":             dayname,
-	"DAYOFMONTH":          dayofmonth,
-	"DAYOFWEEK":           dayofweek,
-	"DAYOFYEAR":           dayofyear,
-	"DDL":                 ddl,
-	"DEALLOCATE":          deallocate,
-	"DEFAULT":             defaultKwd,
-	"DELAYED":             delayed,
-	"DELAY_KEY_WRITE":     delayKeyWrite,
-	"DELETE":              deleteKwd,
-	"DESC":                desc,
-	"DESCRIBE":            describe,
-	"DISABLE":             disable,
-	"DISTINCT":            distinct,
-	"DIV":                 div,
-	"DO":                  do,
-	"DROP":                drop,
-	"DUAL":                dual,
-	"DUPLICATE":           duplicate,
-	"DYNAMIC":             dynamic,
-	"ELSE":                elseKwd,
-	"ENABLE":              enable,
-	"ENCLOSED":            enclosed,
-	"END":                 end,
-	"ENGINE":              engine,
-	"ENGINES":             engines,
-	"ENUM":                enum,
-	"ESCAPE":              escape,
-	"ESCAPED":             escaped,
-	"EXECUTE":             execute,
-	"EXISTS":              exists,
-	"EXPLAIN":             explain,
-	"EXTRACT":             extract,
-	"FALSE":               falseKwd,
-	"FIELDS":              fields,
-	"FIRST":               first,
-	"FIXED":               fixed,
-	"FOREIGN":             foreign,
-	"FOR":                 forKwd,
-	"FORCE":               force,
-	"FOUND_ROWS":          foundRows,
-	"FROM":                from,
-	"FULL":                full,
-	"FULLTEXT":            fulltext,
-	"FUNCTION":            function,
-	"FLUSH":               flush,
-	"GET_LOCK":            getLock,
-	"GLOBAL":              global,
-	"GRANT":               grant,
-	"GRANTS":              grants,
-	"GREATEST":            greatest,
-	"GROUP":               group,
-	"GROUP_CONCAT":        groupConcat,
-	"HASH":                hash,
-	"HAVING":              having,
-	"HIGH_PRIORITY":       highPriority,
-	"HOUR":                hour,
-	"HEX":                 hex,
-	"UNHEX":               unhex,
-	"IDENTIFIED":          identified,
-	"IGNORE":              ignore,
-	"IF":                  ifKwd,
-	"IFNULL":              ifNull,
-	"IN":                  in,
-	"INDEX":               index,
-	"INFILE":              infile,
-	"INNER":               inner,
-	"INSERT":              insert,
-	"INTERVAL":            interval,
-	"INTO":                into,
-	"IS":                  is,
-	"ISNULL":              isNull,
-	"ISOLATION":           isolation,
-	"JOIN":                join,
-	"KEY":                 key,
-	"KEY_BLOCK_SIZE":      keyBlockSize,
-	"KEYS":                keys,
-	"LAST_INSERT_ID":      lastInsertID,
-	"LEADING":             leading,
-	"LEFT":                left,
-	"LENGTH":              length,
-	"LEVEL":               level,
-	"LIKE":                like,
-	"LIMIT":               limit,
-	"LINES":               lines,
-	"LOAD":                load,
-	"LOCAL":               local,
-	"LOCATE":              locate,
-	"LOCK":                lock,
-	"LOWER":               lower,
-	"LCASE":               lcase,
-	"LOW_PRIORITY":        lowPriority,
-	"LTRIM":               ltrim,
-	"MAX":                 max,
-	"MAX_ROWS":            maxRows,
-	"MICROSECOND":         microsecond,
-	"MIN":                 min,
-	"MINUTE":              minute,
-	"MIN_ROWS":            minRows,
-	"MOD":                 mod,
-	"MODE":                mode,
-	"MONTH":               month,
-	"MONTHNAME":           monthname,
-	"NAMES":               names,
-	"NATIONAL":            national,
-	"NOT":                 not,
-	"NO_WRITE_TO_BINLOG":  noWriteToBinLog,
-	"NULL":                null,
-	"NULLIF":              nullIf,
-	"OFFSET":              offset,
-	"ON":                  on,
-	"ONLY":                only,
-	"OPTION":              option,
-	"OR":                  or,
-	"ORDER":               order,
-	"OUTER":               outer,
-	"PASSWORD":            password,
-	"POW":                 pow,
-	"POWER":               power,
-	"PREPARE":             prepare,
-	"PRIMARY":             primary,
-	"PRIVILEGES":          privileges,
-	"PROCEDURE":           procedure,
-	"QUARTER":             quarter,
-	"QUICK":               quick,
-	"RAND":                rand,
-	"READ":                read,
-	"REDUNDANT":           redundant,
-	"REFERENCES":          references,
-	"REGEXP":              regexpKwd,
-	"RELEASE_LOCK":        releaseLock,
-	"REPEAT":              repeat,
-	"REPEATABLE":          repeatable,
-	"REPLACE":             replace,
-	"RIGHT":               right,
-	"RLIKE":               rlike,
-	"ROLLBACK":            rollback,
-	"ROUND":               round,
-	"ROW":                 row,
-	"ROW_FORMAT":          rowFormat,
-	"RTRIM":               rtrim,
-	"REVERSE":             reverse,
-	"SCHEMA":              schema,
-	"SCHEMAS":             schemas,
-	"SECOND":              second,
-	"SELECT":              selectKwd,
-	"SERIALIZABLE":        serializable,
-	"SESSION":             session,
-	"SET":                 set,
-	"SHARE":               share,
-	"SHOW":                show,
-	"SLEEP":               sleep,
-	"SIGNED":              signed,
-	"SNAPSHOT":            snapshot,
-	"SOME":                some,
-	"SPACE":               space,
-	"START":               start,
-	"STARTING":            starting,
-	"STATS_PERSISTENT":    statsPersistent,
-	"STATUS":              status,
-	"SUBDATE":             subDate,
-	"STRCMP":              strcmp,
-	"SUBSTR":              substring,
-	"SUBSTRING":           substring,
-	"SUBSTRING_INDEX":     substringIndex,
-	"SUM":                 sum,
-	"SYSDATE":             sysDate,
-	"TABLE":               tableKwd,
-	"TABLES":              tables,
-	"TERMINATED":          terminated,
-	"THEN":                then,
-	"TO":                  to,
-	"TRAILING":            trailing,
-	"TRANSACTION":         transaction,
-	"TRIGGERS":            triggers,
-	"TRIM":                trim,
-	"TRUE":                trueKwd,
-	"TRUNCATE":            truncate,
-	"UNCOMMITTED":         uncommitted,
-	"UNKNOWN":             unknown,
-	"UNION":               union,
-	"UNIQUE":              unique,
-	"UNLOCK":              unlock,
-	"UNSIGNED":            unsigned,
-	"UPDATE":              update,
-	"UPPER":               upper,
-	"UCASE":               ucase,
-	"USE":                 use,
-	"USER":                user,
-	"USING":               using,
-	"VALUE":               value,
-	"VALUES":              values,
-	"VARIABLES":           variables,
-	"VERSION":             version,
-	"WARNINGS":            warnings,
-	"WEEK":                week,
-	"WEEKDAY":             weekday,
-	"WEEKOFYEAR":          weekofyear,
-	"WHEN":                when,
-	"WHERE":               where,
-	"WITH":                with,
-	"WRITE":               write,
-	"XOR":                 xor,
-	"YEARWEEK":            yearweek,
-	"ZEROFILL":            zerofill,
-	"SQL_CALC_FOUND_ROWS": calcFoundRows,
-	"SQL_CACHE":           sqlCache,
-	"SQL_NO_CACHE":        sqlNoCache,
-	"CURRENT_TIMESTAMP":   currentTs,
-	"LOCALTIME":           localTime,
-	"LOCALTIMESTAMP":      localTs,
-	"NOW":                 now,
-	"TINY":                tinyIntType,
-	"TINYINT":             tinyIntType,
-	"SMALLINT":            smallIntType,
-	"MEDIUMINT":           mediumIntType,
-	"INT":                 intType,
-	"INTEGER":             integerType,
-	"BIGINT":              bigIntType,
-	"BIT":                 bitType,
-	"DECIMAL":             decimalType,
-	"NUMERIC":             numericType,
-	"FLOAT":               floatType,
-	"DOUBLE":              doubleType,
-	"PRECISION":           precisionType,
-	"REAL":                realType,
-	"DATE":                dateType,
-	"TIME":                timeType,
-	"DATETIME":            datetimeType,
-	"TIMESTAMP":           timestampType,
-	"YEAR":                yearType,
-	"CHAR":                charType,
-	"VARCHAR":             varcharType,
-	"BINARY":              binaryType,
-	"VARBINARY":           varbinaryType,
-	"TINYBLOB":            tinyblobType,
-	"BLOB":                blobType,
-	"MEDIUMBLOB":          mediumblobType,
-	"LONGBLOB":            longblobType,
-	"TINYTEXT":            tinytextType,
-	"TEXT":                textType,
-	"MEDIUMTEXT":          mediumtextType,
-	"LONGTEXT":            longtextType,
-	"BOOL":                boolType,
-	"BOOLEAN":             booleanType,
-	"SECOND_MICROSECOND":  secondMicrosecond,
-	"MINUTE_MICROSECOND":  minuteMicrosecond,
-	"MINUTE_SECOND":       minuteSecond,
-	"HOUR_MICROSECOND":    hourMicrosecond,
-	"HOUR_SECOND":         hourSecond,
-	"HOUR_MINUTE":         hourMinute,
-	"DAY_MICROSECOND":     dayMicrosecond,
-	"DAY_SECOND":          daySecond,
-	"DAY_MINUTE":          dayMinute,
-	"DAY_HOUR":            dayHour,
-	"YEAR_MONTH":          yearMonth,
-	"RESTRICT":            restrict,
-	"CASCADE":             cascade,
-	"NO":                  no,
-	"ACTION":              action,
+	"ABS":                  abs,
+	"ADD":                  add,
+	"ADDDATE":              addDate,
+	"ADMIN":                admin,
+	"AFTER":                after,
+	"ALL":                  all,
+	"ALTER":                alter,
+	"ANALYZE":              analyze,
+	"AND":                  and,
+	"ANY":                  any,
+	"AS":                   as,
+	"ASC":                  asc,
+	"ASCII":                ascii,
+	"AUTO_INCREMENT":       autoIncrement,
+	"AVG":                  avg,
+	"AVG_ROW_LENGTH":       avgRowLength,
+	"BEGIN":                begin,
+	"BINDING":              binding,
+	"BINDINGS":             bindings,
+	"BETWEEN":              between,
+	"BINLOG":               binlog,
+	"BOTH":                 both,
+	"BTREE":                btree,
+	"BY":                   by,
+	"BYTE":                 byteType,
+	"CASE":                 caseKwd,
+	"CAST":                 cast,
+	"CEIL":                 ceil,
+	"CEILING":              ceiling,
+	"CHARACTER":            character,
+	"CHARSET":              charsetKwd,
+	"CHECK":                check,
+	"CHECKSUM":             checksum,
+	"COALESCE":             coalesce,
+	"COLLATE":              collate,
+	"COLLATION":            collation,
+	"COLUMN":               column,
+	"COLUMNS":              columns,
+	"COMMENT":              comment,
+	"COMMIT":               commit,
+	"COMMITTED":            committed,
+	"COMPACT":              compact,
+	"COMPRESSED":           compressed,
+	"COMPRESSION":          compression,
+	"CONCAT":               concat,
+	"CONCAT_WS":            concatWs,
+	"CONNECTION":           connection,
+	"CONNECTION_ID":        connectionID,
+	"CONSTRAINT":           constraint,
+	"CONSISTENT":           consistent,
+	"CONVERT":              convert,
+	"COUNT":                count,
+	"CREATE":               create,
+	"CROSS":                cross,
+	"CURDATE":              curDate,
+	"UTC_DATE":             utcDate,
+	"CURRENT_DATE":         currentDate,
+	"CURTIME":              curTime,
+	"CURRENT_TIME":         currentTime,
+	"CURRENT_USER":         currentUser,
+	"DATA":                 data,
+	"DATABASE":             database,
+	"DATABASES":            databases,
+	"DATE_ADD":             dateAdd,
+	"DATE_FORMAT":          dateFormat,
+	"DATE_SUB":             dateSub,
+	"DAY":                  day,
+	"DAYNAME":              dayname,
+	"DAYOFMONTH":           dayofmonth,
+	"DAYOFWEEK":            dayofweek,
+	"DAYOFYEAR":            dayofyear,
+	"DDL":                  ddl,
+	"DEALLOCATE":           deallocate,
+	"DEFAULT":              defaultKwd,
+	"DELAYED":              delayed,
+	"DELAY_KEY_WRITE":      delayKeyWrite,
+	"DELETE":               deleteKwd,
+	"DESC":                 desc,
+	"DESCRIBE":             describe,
+	"DISABLE":              disable,
+	"DISTINCT":             distinct,
+	"DIV":                  div,
+	"DO":                   do,
+	"DROP":                 drop,
+	"DUAL":                 dual,
+	"DUPLICATE":            duplicate,
+	"DYNAMIC":              dynamic,
+	"ELSE":                 elseKwd,
+	"ENABLE":               enable,
+	"ENCLOSED":             enclosed,
+	"END":                  end,
+	"ENGINE":               engine,
+	"ENGINES":              engines,
+	"ENUM":                 enum,
+	"ESCAPE":               escape,
+	"ESCAPED":              escaped,
+	"EXECUTE":              execute,
+	"EXISTS":               exists,
+	"EXPLAIN":              explain,
+	"EXTRACT":              extract,
+	"FALSE":                falseKwd,
+	"FIELDS":               fields,
+	"FIRST":                first,
+	"FIXED":                fixed,
+	"FOREIGN":              foreign,
+	"FOR":                  forKwd,
+	"FORCE":                force,
+	"FOUND_ROWS":           foundRows,
+	"FROM":                 from,
+	"FULL":                 full,
+	"FULLTEXT":             fulltext,
+	"FUNCTION":             function,
+	"FLUSH":                flush,
+	"GET_LOCK":             getLock,
+	"GLOBAL":               global,
+	"GRANT":                grant,
+	"GRANTS":               grants,
+	"GREATEST":             greatest,
+	"GROUP":                group,
+	"GROUP_CONCAT":         groupConcat,
+	"HASH":                 hash,
+	"HAVING":               having,
+	"HIGH_PRIORITY":        highPriority,
+	"HOUR":                 hour,
+	"HEX":                  hex,
+	"UNHEX":                unhex,
+	"IDENTIFIED":           identified,
+	"IGNORE":               ignore,
+	"IF":                   ifKwd,
+	"IFNULL":               ifNull,
+	"IN":                   in,
+	"INDEX":                index,
+	"INFILE":               infile,
+	"INNER":                inner,
+	"INSERT":               insert,
+	"INTERVAL":             interval,
+	"INTO":                 into,
+	"IS":                   is,
+	"ISNULL":               isNull,
+	"ISOLATION":            isolation,
+	"JOIN":                 join,
+	"KEY":                  key,
+	"KEY_BLOCK_SIZE":       keyBlockSize,
+	"KEYS":                 keys,
+	"KILL":                 kill,
+	"LAST_INSERT_ID":       lastInsertID,
+	"LEADING":              leading,
+	"LEFT":                 left,
+	"LENGTH":               length,
+	"LEVEL":                level,
+	"LIKE":                 like,
+	"LIMIT":                limit,
+	"LINES":                lines,
+	"LOAD":                 load,
+	"LOCAL":                local,
+	"LOCATE":               locate,
+	"LOCK":                 lock,
+	"LOWER":                lower,
+	"LCASE":                lcase,
+	"LOW_PRIORITY":         lowPriority,
+	"LTRIM":                ltrim,
+	"MAX":                  max,
+	"MAX_ROWS":             maxRows,
+	"MAX_QUERIES_PER_HOUR": maxQueriesPerHour,
+	"MAX_USER_CONNECTIONS": maxUserConnections,
+	"MICROSECOND":          microsecond,
+	"MIN":                  min,
+	"MINUTE":               minute,
+	"MIN_ROWS":             minRows,
+	"MOD":                  mod,
+	"MODE":                 mode,
+	"MONTH":                month,
+	"MONTHNAME":            monthname,
+	"NAMES":                names,
+	"NATIONAL":             national,
+	"NOT":                  not,
+	"NO_WRITE_TO_BINLOG":   noWriteToBinLog,
+	"NULL":                 null,
+	"NULLIF":               nullIf,
+	"OFFSET":               offset,
+	"ON":                   on,
+	"ONLY":                 only,
+	"OPTION":               option,
+	"OR":                   or,
+	"ORDER":                order,
+	"OUTER":                outer,
+	"PASSWORD":             password,
+	"POW":                  pow,
+	"POWER":                power,
+	"PREPARE":              prepare,
+	"PRIMARY":              primary,
+	"PRIVILEGES":           privileges,
+	"PROCEDURE":            procedure,
+	"PROCESS":              process,
+	"PROCESSLIST":          processlist,
+	"QUARTER":              quarter,
+	"QUERY":                query,
+	"QUICK":                quick,
+	"RAND":                 rand,
+	"READ":                 read,
+	"REDUNDANT":            redundant,
+	"REFERENCES":           references,
+	"REGEXP":               regexpKwd,
+	"RELEASE_LOCK":         releaseLock,
+	"REPEAT":               repeat,
+	"REPEATABLE":           repeatable,
+	"REPLACE":              replace,
+	"RIGHT":                right,
+	"RLIKE":                rlike,
+	"ROLLBACK":             rollback,
+	"ROUND":                round,
+	"ROW":                  row,
+	"ROW_FORMAT":           rowFormat,
+	"RTRIM":                rtrim,
+	"REQUIRE":              require,
+	"REVERSE":              reverse,
+	"SCHEMA":               schema,
+	"SCHEMAS":              schemas,
+	"SECOND":               second,
+	"SELECT":               selectKwd,
+	"SERIALIZABLE":         serializable,
+	"SESSION":              session,
+	"SET":                  set,
+	"SHARE":                share,
+	"SHOW":                 show,
+	"SLEEP":                sleep,
+	"SIGNED":               signed,
+	"SNAPSHOT":             snapshot,
+	"SOME":                 some,
+	"SPACE":                space,
+	"START":                start,
+	"STARTING":             starting,
+	"STATS_PERSISTENT":     statsPersistent,
+	"STATUS":               status,
+	"SUBDATE":              subDate,
+	"STRCMP":               strcmp,
+	"SUBSTR":               substring,
+	"SUBSTRING":            substring,
+	"SUBSTRING_INDEX":      substringIndex,
+	"SUM":                  sum,
+	"SYSDATE":              sysDate,
+	"TABLE":                tableKwd,
+	"TABLES":               tables,
+	"TERMINATED":           terminated,
+	"THEN":                 then,
+	"TO":                   to,
+	"TRAILING":             trailing,
+	"TRANSACTION":          transaction,
+	"TRIGGERS":             triggers,
+	"TRIM":                 trim,
+	"TRUE":                 trueKwd,
+	"TRUNCATE":             truncate,
+	"UNCOMMITTED":          uncommitted,
+	"UNKNOWN":              unknown,
+	"UNION":                union,
+	"UNIQUE":               unique,
+	"UNLOCK":               unlock,
+	"UNSIGNED":             unsigned,
+	"UPDATE":               update,
+	"UPPER":                upper,
+	"UCASE":                ucase,
+	"USE":                  use,
+	"USER":                 user,
+	"USING":                using,
+	"VALUE":                value,
+	"VALUES":               values,
+	"VARIABLES":            variables,
+	"VERSION":              version,
+	"WARNINGS":             warnings,
+	"WEEK":                 week,
+	"WEEKDAY":              weekday,
+	"WEEKOFYEAR":           weekofyear,
+	"WHEN":                 when,
+	"WHERE":                where,
+	"WITH":                 with,
+	"WRITE":                write,
+	"XOR":                  xor,
+	"YEARWEEK":             yearweek,
+	"ZEROFILL":             zerofill,
+	"SQL_CALC_FOUND_ROWS":  calcFoundRows,
+	"SQL_CACHE":            sqlCache,
+	"SQL_NO_CACHE":         sqlNoCache,
+	"SSL":                  ssl,
+	"SUPER":                super,
+	"CURRENT_TIMESTAMP":    currentTs,
+	"LOCALTIME":            localTime,
+	"LOCALTIMESTAMP":       localTs,
+	"NOW":                  now,
+	"TINY":                 tinyIntType,
+	"TINYINT":              tinyIntType,
+	"SMALLINT":             smallIntType,
+	"MEDIUMINT":            mediumIntType,
+	"INT":                  intType,
+	"INTEGER":              integerType,
+	"BIGINT":               bigIntType,
+	"BIT":                  bitType,
+	"DECIMAL":              decimalType,
+	"NUMERIC":              numericType,
+	"FLOAT":                floatType,
+	"DOUBLE":               doubleType,
+	"PRECISION":            precisionType,
+	"REAL":                 realType,
+	"DATE":                 dateType,
+	"TIME":                 timeType,
+	"DATETIME":             datetimeType,
+	"TIMESTAMP":            timestampType,
+	"YEAR":                 yearType,
+	"X509":                 x509,
+	"CHAR":                 charType,
+	"VARCHAR":              varcharType,
+	"BINARY":               binaryType,
+	"VARBINARY":            varbinaryType,
+	"TINYBLOB":             tinyblobType,
+	"BLOB":                 blobType,
+	"MEDIUMBLOB":           mediumblobType,
+	"LONGBLOB":             longblobType,
+	"TINYTEXT":             tinytextType,
+	"TEXT":                 textType,
+	"MEDIUMTEXT":           mediumtextType,
+	"LONGTEXT":             longtextType,
+	"BOOL":                 boolType,
+	"BOOLEAN":              booleanType,
+	"SECOND_MICROSECOND":   secondMicrosecond,
+	"MINUTE_MICROSECOND":   minuteMicrosecond,
+	"MINUTE_SECOND":        minuteSecond,
+	"HOUR_MICROSECOND":     hourMicrosecond,
+	"HOUR_SECOND":          hourSecond,
+	"HOUR_MINUTE":          hourMinute,
+	"DAY_MICROSECOND":      dayMicrosecond,
+	"DAY_SECOND":           daySecond,
+	"DAY_MINUTE":           dayMinute,
+	"DAY_HOUR":             dayHour,
+	"YEAR_MONTH":           yearMonth,
+	"RESTRICT":             restrict,
+	"CASCADE":              cascade,
+	"NO":                   no,
+	"NONE":                 none,
+	"ACTION":               action,
 }
 
 func isTokenIdentifier(s string, buf *bytes.Buffer) int {
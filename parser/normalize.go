@@ -0,0 +1,99 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+)
+
+// Normalize returns the normalized text of a statement. The statements that differ only in literals, index hints,
+// letter case and white spaces have the same normalized text: the literals are replaced by "?", the lists of
+// literals in IN expressions are folded to one "?", the index hints are removed, the keywords and identifiers are
+// lower cased and the tokens are separated by one space.
+func Normalize(sql string) string {
+	s := NewScanner(sql)
+	var (
+		v      yySymType
+		tokens []string
+	)
+	for {
+		tok := s.Lex(&v)
+		end := s.r.pos().Offset
+		if tok == 0 || end <= v.offset {
+			break
+		}
+		switch tok {
+		case intLit, floatLit, hexLit, bitLit, stringLit:
+			tokens = append(tokens, "?")
+		case identifier:
+			tokens = append(tokens, strings.ToLower(v.ident))
+		default:
+			tokens = append(tokens, strings.ToLower(sql[v.offset:end]))
+		}
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return strings.Join(foldInLists(removeIndexHints(tokens)), " ")
+}
+
+// removeIndexHints removes the index hints like "use index (idx)" from the tokens.
+func removeIndexHints(tokens []string) []string {
+	result := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if !isIndexHint(tokens, i) {
+			result = append(result, tokens[i])
+			continue
+		}
+		for i < len(tokens) && tokens[i] != ")" {
+			i++
+		}
+		// Skip the comma between two index hints.
+		if i+1 < len(tokens) && tokens[i+1] == "," && isIndexHint(tokens, i+2) {
+			i++
+		}
+	}
+	return result
+}
+
+func isIndexHint(tokens []string, i int) bool {
+	if i+1 >= len(tokens) {
+		return false
+	}
+	switch tokens[i] {
+	case "use", "ignore", "force":
+		return tokens[i+1] == "index" || tokens[i+1] == "key"
+	}
+	return false
+}
+
+// foldInLists folds the lists of literals like "in ( ? , ? )" to "in ( ? )".
+func foldInLists(tokens []string) []string {
+	result := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		result = append(result, tokens[i])
+		if tokens[i] != "in" || i+3 >= len(tokens) || tokens[i+1] != "(" || tokens[i+2] != "?" {
+			continue
+		}
+		j := i + 3
+		for j+1 < len(tokens) && tokens[j] == "," && tokens[j+1] == "?" {
+			j += 2
+		}
+		if tokens[j] == ")" {
+			result = append(result, "(", "?", ")")
+			i = j
+		}
+	}
+	return result
+}
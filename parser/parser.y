@@ -124,6 +124,8 @@ import (
 	avgRowLength	"AVG_ROW_LENGTH"
 	avg		"AVG"
 	begin		"BEGIN"
+	binding		"BINDING"
+	bindings	"BINDINGS"
 	binlog		"BINLOG"
 	bitType		"BIT"
 	booleanType	"BOOLEAN"
@@ -166,24 +168,32 @@ import (
 	identified	"IDENTIFIED"
 	isolation	"ISOLATION"
 	keyBlockSize	"KEY_BLOCK_SIZE"
+	kill		"KILL"
 	local		"LOCAL"
 	level		"LEVEL"
 	mode		"MODE"
 	maxRows		"MAX_ROWS"
+	maxQueriesPerHour	"MAX_QUERIES_PER_HOUR"
+	maxUserConnections	"MAX_USER_CONNECTIONS"
 	minRows		"MIN_ROWS"
 	noWriteToBinLog "NO_WRITE_TO_BINLOG"
 	names		"NAMES"
 	national	"NATIONAL"
 	no		"NO"
+	none		"NONE"
 	offset		"OFFSET"
 	only		"ONLY"
 	password	"PASSWORD"
 	prepare		"PREPARE"
 	privileges	"PRIVILEGES"
+	process		"PROCESS"
+	processlist	"PROCESSLIST"
 	quarter		"QUARTER"
+	query		"QUERY"
 	quick		"QUICK"
 	redundant	"REDUNDANT"
 	repeatable	"REPEATABLE"
+	require		"REQUIRE"
 	reverse		"REVERSE"
 	rollback	"ROLLBACK"
 	row 		"ROW"
@@ -195,8 +205,10 @@ import (
 	space 		"SPACE"
 	sqlCache	"SQL_CACHE"
 	sqlNoCache	"SQL_NO_CACHE"
+	ssl		"SSL"
 	start		"START"
 	status		"STATUS"
+	super		"SUPER"
 	some 		"SOME"
 	global		"GLOBAL"
 	tables		"TABLES"
@@ -214,6 +226,7 @@ import (
 	warnings	"WARNINGS"
 	week		"WEEK"
 	yearType	"YEAR"
+	x509		"X509"
 
 %token	<item>
 
@@ -441,6 +454,7 @@ import (
 	Constraint		"table constraint"
 	ConstraintElem		"table constraint element"
 	ConstraintKeywordOpt	"Constraint Keyword or empty"
+	CreateBindingStmt	"CREATE BINDING statement"
 	CreateDatabaseStmt	"Create Database Statement"
 	CreateIndexStmt		"CREATE INDEX statement"
 	CreateIndexStmtUnique	"CREATE INDEX optional UNIQUE clause"
@@ -464,6 +478,7 @@ import (
 	DeleteFromStmt		"DELETE FROM statement"
 	DistinctOpt		"Distinct option"
 	DoStmt			"Do statement"
+	DropBindingStmt		"DROP BINDING statement"
 	DropDatabaseStmt	"DROP DATABASE statement"
 	DropIndexStmt		"DROP INDEX statement"
 	DropTableStmt		"DROP TABLE statement"
@@ -525,6 +540,8 @@ import (
 	JoinTable 		"join table"
 	JoinType		"join type"
 	KeyOrIndex		"{KEY|INDEX}"
+	KillStmt		"Kill statement"
+	KillType		"KILL [CONNECTION|QUERY]"
 	LikeEscapeOpt 		"like escape option"
 	LimitClause		"LIMIT clause"
 	Lines			"Lines clause"
@@ -573,6 +590,10 @@ import (
 	ReferOpt		"reference option"
 	RegexpSym		"REGEXP or RLIKE"
 	ReplaceIntoStmt		"REPLACE INTO statement"
+	RequireClauseOpt	"Require clause of user accounts"
+	ResourceOption		"resource limit of user accounts"
+	ResourceOptionList	"resource limit list of user accounts"
+	ResourceOptionListOpt	"optional WITH clause of resource limits"
 	ReplacePriority		"replace statement priority"
 	RollbackStmt		"ROLLBACK statement"
 	RowFormat		"Row format option"
@@ -1299,6 +1320,41 @@ IndexColNameList:
 
 
 
+/*******************************************************************
+ *
+ *  Create Binding Statement
+ *  CREATE [GLOBAL | SESSION] BINDING FOR select_stmt USING select_stmt_with_hints
+ *
+ *  Drop Binding Statement
+ *  DROP [GLOBAL | SESSION] BINDING FOR select_stmt
+ *
+ *******************************************************************/
+CreateBindingStmt:
+	"CREATE" GlobalScope "BINDING" "FOR" SelectStmt "USING" SelectStmt
+	{
+		originSel := $5.(*ast.SelectStmt)
+		hintedSel := $7.(*ast.SelectStmt)
+		// The lookahead token has been read to reduce the last SelectStmt, so it ends before the lookahead token.
+		originSel.SetText(strings.TrimSpace(parser.src[yyS[yypt-2].offset:yyS[yypt-1].offset]))
+		hintedSel.SetText(strings.TrimSpace(parser.src[yyS[yypt].offset:parser.yylval.offset]))
+		$$ = &ast.CreateBindingStmt{
+			GlobalScope:	$2.(bool),
+			OriginSel:	originSel,
+			HintedSel:	hintedSel,
+		}
+	}
+
+DropBindingStmt:
+	"DROP" GlobalScope "BINDING" "FOR" SelectStmt
+	{
+		originSel := $5.(*ast.SelectStmt)
+		originSel.SetText(strings.TrimSpace(parser.src[yyS[yypt].offset:parser.yylval.offset]))
+		$$ = &ast.DropBindingStmt{
+			GlobalScope:	$2.(bool),
+			OriginSel:	originSel,
+		}
+	}
+
 /*******************************************************************
  *
  *  Create Database Statement
@@ -1969,6 +2025,8 @@ UnReservedKeyword:
 |	"MIN_ROWS" | "NATIONAL" | "ROW" | "ROW_FORMAT" | "QUARTER" | "GRANTS" | "TRIGGERS" | "DELAY_KEY_WRITE" | "ISOLATION"
 |	"REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE"
 |	"SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION"
+|	"BINDING" | "BINDINGS" | "NONE" | "REQUIRE" | "SSL" | "X509" | "KILL" | "PROCESSLIST" | "QUERY"
+|	"MAX_QUERIES_PER_HOUR" | "MAX_USER_CONNECTIONS" | "PROCESS" | "SUPER"
 
 NotKeywordToken:
 	"ABS" | "ADDDATE" | "ADMIN" | "COALESCE" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CUR_TIME"| "COUNT" | "DAY"
@@ -3926,6 +3984,31 @@ AdminStmt:
 		}
 	}
 
+/********************************Kill Statement*****************************/
+KillStmt:
+	KillType LengthNum
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/kill.html
+		$$ = &ast.KillStmt{
+			Query:		$1.(bool),
+			ConnectionID:	$2.(uint64),
+		}
+	}
+
+KillType:
+	"KILL"
+	{
+		$$ = false
+	}
+|	"KILL" "CONNECTION"
+	{
+		$$ = false
+	}
+|	"KILL" "QUERY"
+	{
+		$$ = true
+	}
+
 /****************************Show Statement*******************************/
 ShowStmt:
 	"SHOW" ShowTargetFilterable ShowLikeOrWhereOpt
@@ -3952,6 +4035,14 @@ ShowStmt:
 		// See https://dev.mysql.com/doc/refman/5.7/en/show-grants.html
 		$$ = &ast.ShowStmt{Tp: ast.ShowGrants}
 	}
+|	"SHOW" OptFull "PROCESSLIST"
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/show-processlist.html
+		$$ = &ast.ShowStmt{
+			Tp:	ast.ShowProcessList,
+			Full:	$2.(bool),
+		}
+	}
 |	"SHOW" "GRANTS" "FOR" Username
 	{
 		// See https://dev.mysql.com/doc/refman/5.7/en/show-grants.html
@@ -4044,6 +4135,13 @@ ShowTargetFilterable:
 			GlobalScope: $1.(bool),
 		}
 	}
+|	GlobalScope "BINDINGS"
+	{
+		$$ = &ast.ShowStmt{
+			Tp: ast.ShowBindings,
+			GlobalScope: $1.(bool),
+		}
+	}
 |	"COLLATION"
 	{
 		$$ = &ast.ShowStmt{
@@ -4184,6 +4282,7 @@ Statement:
 |	BeginTransactionStmt
 |	BinlogStmt
 |	CommitStmt
+|	CreateBindingStmt
 |	DeallocateStmt
 |	DeleteFromStmt
 |	ExecuteStmt
@@ -4193,12 +4292,14 @@ Statement:
 |	CreateTableStmt
 |	CreateUserStmt
 |	DoStmt
+|	DropBindingStmt
 |	DropDatabaseStmt
 |	DropIndexStmt
 |	DropTableStmt
 |	FlushStmt
 |	GrantStmt
 |	InsertIntoStmt
+|	KillStmt
 |	LoadDataStmt
 |	PreparedStmt
 |	RollbackStmt
@@ -4933,12 +5034,14 @@ CommaOpt:
  *  https://dev.mysql.com/doc/refman/5.7/en/account-management-sql.html
  ************************************************************************************/
 CreateUserStmt:
-	"CREATE" "USER" IfNotExists UserSpecList
+	"CREATE" "USER" IfNotExists UserSpecList RequireClauseOpt ResourceOptionListOpt
 	{
  		// See https://dev.mysql.com/doc/refman/5.7/en/create-user.html
 		$$ = &ast.CreateUserStmt{
 			IfNotExists: $3.(bool),
 			Specs: $4.([]*ast.UserSpec),
+			Require: $5.(ast.RequireType),
+			ResourceOptions: $6.([]*ast.ResourceOption),
 		}
 	}
 
@@ -4981,6 +5084,27 @@ AuthOption:
 			HashString: $4.(string),
 		}
 	}
+|	"IDENTIFIED" "WITH" StringName
+	{
+		$$ = &ast.AuthOption{
+			AuthPlugin: $3.(string),
+		}
+	}
+|	"IDENTIFIED" "WITH" StringName "BY" AuthString
+	{
+		$$ = &ast.AuthOption{
+			AuthPlugin: $3.(string),
+			AuthString: $5.(string),
+			ByAuthString: true,
+		}
+	}
+|	"IDENTIFIED" "WITH" StringName "AS" HashString
+	{
+		$$ = &ast.AuthOption{
+			AuthPlugin: $3.(string),
+			HashString: $5.(string),
+		}
+	}
 
 HashString:
 	stringLit
@@ -4988,18 +5112,66 @@ HashString:
 		$$ = $1
 	}
 
+RequireClauseOpt:
+	{
+		$$ = ast.RequireUnspecified
+	}
+|	"REQUIRE" "NONE"
+	{
+		$$ = ast.RequireNone
+	}
+|	"REQUIRE" "SSL"
+	{
+		$$ = ast.RequireSSL
+	}
+|	"REQUIRE" "X509"
+	{
+		$$ = ast.RequireX509
+	}
+
+ResourceOptionListOpt:
+	{
+		$$ = []*ast.ResourceOption{}
+	}
+|	"WITH" ResourceOptionList
+	{
+		$$ = $2
+	}
+
+ResourceOptionList:
+	ResourceOption
+	{
+		$$ = []*ast.ResourceOption{$1.(*ast.ResourceOption)}
+	}
+|	ResourceOptionList ResourceOption
+	{
+		$$ = append($1.([]*ast.ResourceOption), $2.(*ast.ResourceOption))
+	}
+
+ResourceOption:
+	"MAX_QUERIES_PER_HOUR" LengthNum
+	{
+		$$ = &ast.ResourceOption{Type: ast.MaxQueriesPerHour, Count: $2.(uint64)}
+	}
+|	"MAX_USER_CONNECTIONS" LengthNum
+	{
+		$$ = &ast.ResourceOption{Type: ast.MaxUserConnections, Count: $2.(uint64)}
+	}
+
 /*************************************************************************************
  * Grant statement
  * See https://dev.mysql.com/doc/refman/5.7/en/grant.html
  *************************************************************************************/
 GrantStmt:
-	 "GRANT" PrivElemList "ON" ObjectType PrivLevel "TO" UserSpecList
+	 "GRANT" PrivElemList "ON" ObjectType PrivLevel "TO" UserSpecList RequireClauseOpt ResourceOptionListOpt
 	 {
 		$$ = &ast.GrantStmt{
 			Privs: $2.([]*ast.PrivElem),
 			ObjectType: $4.(ast.ObjectTypeType),
 			Level: $5.(*ast.GrantLevel),
 			Users: $7.([]*ast.UserSpec),
+			Require: $8.(ast.RequireType),
+			ResourceOptions: $9.([]*ast.ResourceOption),
 		}
 	 }
 
@@ -5085,6 +5257,14 @@ PrivType:
 	{
 		$$ = mysql.GrantPriv
 	}
+|	"PROCESS"
+	{
+		$$ = mysql.ProcessPriv
+	}
+|	"SUPER"
+	{
+		$$ = mysql.SuperPriv
+	}
 
 ObjectType:
 	{
@@ -49,7 +49,8 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"curtime", "variables", "dayname", "version", "btree", "hash", "row_format", "dynamic", "fixed", "compressed",
 		"compact", "redundant", "sql_no_cache sql_no_cache", "sql_cache sql_cache", "action", "round",
 		"enable", "disable", "reverse", "space", "privileges", "get_lock", "release_lock", "sleep", "no", "greatest",
-		"binlog", "hex", "unhex", "function",
+		"binlog", "hex", "unhex", "function", "binding", "bindings", "none", "require", "ssl", "x509",
+		"kill", "processlist", "query", "max_queries_per_hour", "max_user_connections",
 	}
 	for _, kw := range unreservedKws {
 		src := fmt.Sprintf("SELECT %s FROM tbl;", kw)
@@ -327,7 +328,13 @@ func (s *testParserSuite) TestDBAStmt(c *C) {
 		{`SHOW PROCEDURE STATUS WHERE Db='test'`, true},
 		{`SHOW FUNCTION STATUS WHERE Db='test'`, true},
 		{`SHOW INDEX FROM t;`, true},
+		{`SHOW PROCESSLIST`, true},
+		{`SHOW FULL PROCESSLIST`, true},
+		{`SHOW PROCESSLIST LIKE 'x'`, false},
 		{`SHOW KEYS FROM t;`, true},
+		{`SHOW BINDINGS`, true},
+		{`SHOW GLOBAL BINDINGS`, true},
+		{`SHOW SESSION BINDINGS LIKE 'select%'`, true},
 		// For show character set
 		{"show character set;", true},
 		// For show collation
@@ -397,6 +404,28 @@ func (s *testParserSuite) TestDBAStmt(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestKill(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{"kill 1", true},
+		{"kill connection 1", true},
+		{"kill query 1", true},
+		{"kill query", false},
+		{"kill 'a'", false},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("kill query 23", "", "")
+	c.Assert(err, IsNil)
+	kill := stmt.(*ast.KillStmt)
+	c.Assert(kill.Query, IsTrue)
+	c.Assert(kill.ConnectionID, Equals, uint64(23))
+	stmt, err = parser.ParseOneStmt("kill connection 23", "", "")
+	c.Assert(err, IsNil)
+	c.Assert(stmt.(*ast.KillStmt).Query, IsFalse)
+}
+
 func (s *testParserSuite) TestFlushTable(c *C) {
 	parser := New()
 	stmt, err := parser.Parse("flush local tables tbl1,tbl2 with read lock", "", "")
@@ -919,11 +948,28 @@ func (s *testParserSuite) TestPrivilege(c *C) {
 		{`CREATE USER 'root'@'localhost' IDENTIFIED BY 'new-password'`, true},
 		{`CREATE USER 'root'@'localhost' IDENTIFIED BY PASSWORD 'hashstring'`, true},
 		{`CREATE USER 'root'@'localhost' IDENTIFIED BY 'new-password', 'root'@'127.0.0.1' IDENTIFIED BY PASSWORD 'hashstring'`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED BY 'new-password' REQUIRE SSL`, true},
+		{`CREATE USER 'root'@'localhost', 'root'@'127.0.0.1' REQUIRE X509`, true},
+		{`CREATE USER 'root'@'localhost' WITH MAX_USER_CONNECTIONS 2`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED BY 'new-password' REQUIRE SSL WITH MAX_QUERIES_PER_HOUR 100 MAX_USER_CONNECTIONS 2`, true},
+		{`CREATE USER 'root'@'localhost' WITH MAX_USER_CONNECTIONS -1`, false},
+		{`CREATE USER 'root'@'localhost' REQUIRE NONE`, true},
+		{`CREATE USER 'root'@'localhost' REQUIRE CIPHER 'x'`, false},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH caching_sha2_password`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH 'caching_sha2_password' BY 'new-password'`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH mysql_native_password AS 'hashstring'`, true},
+		{`CREATE USER 'root'@'localhost' IDENTIFIED WITH BY 'new-password'`, false},
 
 		// For grant statement
 		{"GRANT ALL ON db1.* TO 'jeffrey'@'localhost';", true},
 		{"GRANT SELECT ON db2.invoice TO 'jeffrey'@'localhost';", true},
 		{"GRANT ALL ON *.* TO 'someuser'@'somehost';", true},
+		{"GRANT ALL ON *.* TO 'someuser'@'somehost' REQUIRE SSL;", true},
+		{"GRANT SELECT ON db1.* TO 'someuser'@'somehost' IDENTIFIED BY 'pwd' REQUIRE X509;", true},
+		{"GRANT SELECT ON db1.* TO 'someuser'@'somehost' WITH MAX_QUERIES_PER_HOUR 10;", true},
+		{"GRANT SELECT ON db1.* TO 'someuser'@'somehost' REQUIRE SSL WITH MAX_QUERIES_PER_HOUR 10 MAX_USER_CONNECTIONS 2;", true},
+		{"GRANT PROCESS, SUPER ON *.* TO 'someuser'@'somehost';", true},
+		{"GRANT SELECT ON db1.* TO 'someuser'@'somehost' WITH;", false},
 		{"GRANT SELECT, INSERT ON *.* TO 'someuser'@'somehost';", true},
 		{"GRANT ALL ON mydb.* TO 'someuser'@'somehost';", true},
 		{"GRANT SELECT, INSERT ON mydb.* TO 'someuser'@'somehost';", true},
@@ -1032,6 +1078,53 @@ func (s *testParserSuite) TestIndexHint(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestBinding(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`create binding for select * from t where a > 1 using select * from t use index (idx) where a > 1`, true},
+		{`create global binding for select * from t using select * from t ignore index (idx)`, true},
+		{`create session binding for select * from t using select * from t force index (idx);`, true},
+		{`create binding for select * from t`, false},
+		{`create binding for insert into t values (1) using insert into t values (1)`, false},
+		{`drop binding for select * from t where a > 1`, true},
+		{`drop global binding for select * from t`, true},
+		{`drop session binding for select * from t;`, true},
+	}
+	s.RunTest(c, table)
+
+	parser := New()
+	stmt, err := parser.ParseOneStmt("CREATE GLOBAL BINDING FOR select * from t where a > 1  USING  select * from t use index (idx) where a > 1;", "", "")
+	c.Assert(err, IsNil)
+	create := stmt.(*ast.CreateBindingStmt)
+	c.Assert(create.GlobalScope, IsTrue)
+	c.Assert(create.OriginSel.Text(), Equals, "select * from t where a > 1")
+	c.Assert(create.HintedSel.Text(), Equals, "select * from t use index (idx) where a > 1")
+	stmt, err = parser.ParseOneStmt("drop binding for select * from t where a = 'x'", "", "")
+	c.Assert(err, IsNil)
+	drop := stmt.(*ast.DropBindingStmt)
+	c.Assert(drop.GlobalScope, IsFalse)
+	c.Assert(drop.OriginSel.Text(), Equals, "select * from t where a = 'x'")
+}
+
+func (s *testParserSuite) TestNormalize(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql        string
+		normalized string
+	}{
+		{"select * from t where a > 1", "select * from t where a > ?"},
+		{"SELECT *  FROM `T` WHERE A>'x';", "select * from t where a > ?"},
+		{"select a from t use index (idx) where b = 1.5 and c = x'1f'", "select a from t where b = ? and c = ?"},
+		{"select * from t1 use index for join (i1), ignore key (i2) join t2 force index (i3) on t1.a = t2.a",
+			"select * from t1 join t2 on t1 . a = t2 . a"},
+		{"select * from t where a in (1, 2, 3) and b in (c, 1)", "select * from t where a in ( ? ) and b in ( c , ? )"},
+		{"select * from t where a = ? limit 10", "select * from t where a = ? limit ?"},
+	}
+	for _, ca := range cases {
+		c.Assert(Normalize(ca.sql), Equals, ca.normalized, Commentf("sql %s", ca.sql))
+	}
+}
+
 func (s *testParserSuite) TestEscape(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -0,0 +1,183 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// When the arguments of all the aggregate functions come from one side of a join, the aggregation can be split
+// into a partial aggregation below that side and a final aggregation above the join. The partial aggregation groups
+// the rows by the group by columns and the join key columns of that side. Every partial row matches the same rows
+// of the other side as the rows it aggregates, so the final aggregation merges the partial results to the same
+// result, e.g. select t1.a, sum(t1.b) from t1, t2 where t1.c = t2.c group by t1.a becomes
+// select t1.a, sum(s) from (select t1.a, t1.c, sum(t1.b) as s from t1 group by t1.a, t1.c) t1, t2
+// where t1.c = t2.c group by t1.a.
+
+// pushDownAggregation pushes the aggregations in p down through the joins.
+func pushDownAggregation(p LogicalPlan) error {
+	if agg, ok := p.(*Aggregation); ok {
+		if join, ok := agg.GetChildByIndex(0).(*Join); ok {
+			err := agg.pushDownThroughJoin(join)
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	for _, child := range p.GetChildren() {
+		err := pushDownAggregation(child.(LogicalPlan))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// pushDownThroughJoin tries to push a partial aggregation down to a child of the join.
+func (p *Aggregation) pushDownThroughJoin(join *Join) error {
+	if p.IsCorrelated() || join.IsCorrelated() {
+		return nil
+	}
+	for _, item := range p.GroupByItems {
+		if _, ok := item.(*expression.Column); !ok {
+			return nil
+		}
+	}
+	var argCols []*expression.Column
+	for _, aggFunc := range p.AggFuncs {
+		switch strings.ToLower(aggFunc.GetName()) {
+		case ast.AggFuncCount, ast.AggFuncSum, ast.AggFuncAvg, ast.AggFuncMax, ast.AggFuncMin, ast.AggFuncFirstRow:
+		default:
+			return nil
+		}
+		if aggFunc.IsDistinct() {
+			return nil
+		}
+		for _, arg := range aggFunc.GetArgs() {
+			argCols, _ = extractColumn(arg, argCols, nil)
+		}
+	}
+	for i, child := range join.GetChildren() {
+		// The inner side of an outer join may be extended by NULLs, the partial result can't be used then.
+		if (join.JoinType == LeftOuterJoin && i == 1) || (join.JoinType == RightOuterJoin && i == 0) ||
+			join.JoinType == SemiJoin || join.JoinType == SemiJoinWithAux {
+			continue
+		}
+		if !schemaContains(child.GetSchema(), argCols) {
+			continue
+		}
+		return errors.Trace(p.splitToJoinChild(join, child.(LogicalPlan)))
+	}
+	return nil
+}
+
+// splitToJoinChild inserts a partial aggregation between the join and its child, and turns p into the final
+// aggregation that merges the partial results.
+func (p *Aggregation) splitToJoinChild(join *Join, child LogicalPlan) error {
+	partial := &Aggregation{baseLogicalPlan: newBaseLogicalPlan(Agg, p.allocator), ctx: p.ctx}
+	partial.initID()
+	var schema expression.Schema
+	addPartialFunc := func(name string, args []expression.Expression, retType *types.FieldType) *expression.Column {
+		col := &expression.Column{
+			FromID:      partial.id,
+			ColName:     model.NewCIStr(fmt.Sprintf("%s_col_%d", partial.id, len(schema))),
+			Position:    len(schema),
+			IsAggOrSubq: true,
+			RetType:     retType,
+		}
+		partial.AggFuncs = append(partial.AggFuncs, expression.NewAggFunction(name, args, false))
+		schema = append(schema, col)
+		return col
+	}
+	finalFuncs := make([]expression.AggregationFunction, 0, len(p.AggFuncs))
+	for i, aggFunc := range p.AggFuncs {
+		args := make([]expression.Expression, 0, len(aggFunc.GetArgs()))
+		for _, arg := range aggFunc.GetArgs() {
+			args = append(args, arg.DeepCopy())
+		}
+		var finalFunc expression.AggregationFunction
+		switch name := strings.ToLower(aggFunc.GetName()); name {
+		case ast.AggFuncCount:
+			cnt := addPartialFunc(name, args, p.schema[i].RetType)
+			finalFunc = expression.NewAggFunction(name, []expression.Expression{cnt}, false)
+			finalFunc.SetMode(expression.FinalMode)
+		case ast.AggFuncAvg:
+			cnt := addPartialFunc(ast.AggFuncCount, args, types.NewFieldType(mysql.TypeLonglong))
+			sum := addPartialFunc(ast.AggFuncSum, args, p.schema[i].RetType)
+			finalFunc = expression.NewAggFunction(name, []expression.Expression{cnt, sum}, false)
+			finalFunc.SetMode(expression.FinalMode)
+		default:
+			col := addPartialFunc(name, args, p.schema[i].RetType)
+			finalFunc = expression.NewAggFunction(name, []expression.Expression{col}, false)
+		}
+		finalFuncs = append(finalFuncs, finalFunc)
+	}
+
+	// The group by columns and the join key columns of the child are grouped by and kept by the partial aggregation.
+	var usedCols []*expression.Column
+	for _, item := range p.GroupByItems {
+		usedCols, _ = extractColumn(item, usedCols, nil)
+	}
+	usedCols = append(usedCols, columnsUsedByPlan(join)...)
+	childSchema := child.GetSchema()
+	var groupByCols expression.Schema
+	for _, col := range usedCols {
+		if childSchema.GetIndex(col) == -1 || groupByCols.GetIndex(col) != -1 {
+			continue
+		}
+		newCol := col.DeepCopy().(*expression.Column)
+		groupByCols = append(groupByCols, newCol)
+		partial.AggFuncs = append(partial.AggFuncs,
+			expression.NewAggFunction(ast.AggFuncFirstRow, []expression.Expression{col.DeepCopy()}, false))
+		schema = append(schema, newCol)
+	}
+	for _, col := range groupByCols {
+		partial.GroupByItems = append(partial.GroupByItems, col.DeepCopy())
+	}
+	partial.SetSchema(schema)
+	// The join passes the columns of the partial aggregation instead of the columns of the child.
+	var joinSchema expression.Schema
+	if join.GetChildByIndex(0) == child {
+		joinSchema = append(joinSchema, schema...)
+		joinSchema = append(joinSchema, join.schema[len(childSchema):]...)
+	} else {
+		joinSchema = append(joinSchema, join.schema[:len(join.schema)-len(childSchema)]...)
+		joinSchema = append(joinSchema, schema...)
+	}
+	err := InsertPlan(join, child, partial)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	join.SetSchema(joinSchema)
+	p.AggFuncs = finalFuncs
+	return nil
+}
+
+// schemaContains checks whether all the columns are in the schema.
+func schemaContains(schema expression.Schema, cols []*expression.Column) bool {
+	for _, col := range cols {
+		if schema.GetIndex(col) == -1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/db"
+)
+
+// applyBinding makes a select statement use the index hints of its binding. The binding and the statement have
+// the same normalized text, so their tables are in the same order. The session bindings take precedence over the
+// global bindings. It returns a function that restores the index hints of the statement, which must be called after
+// the statement is optimized, because a prepared statement is optimized again after its binding is dropped.
+func applyBinding(ctx context.Context, node ast.Node) (restore func()) {
+	restore = func() {}
+	var sel *ast.SelectStmt
+	switch x := node.(type) {
+	case *ast.SelectStmt:
+		sel = x
+	case *ast.ExplainStmt:
+		sel, _ = x.Stmt.(*ast.SelectStmt)
+	}
+	if sel == nil || node.Text() == "" {
+		return restore
+	}
+	handles := make([]*bindinfo.Handle, 0, 2)
+	if h := bindinfo.GetSessionHandle(ctx); h != nil && h.Size() > 0 {
+		handles = append(handles, h)
+	}
+	if do := sessionctx.GetDomain(ctx); do != nil && do.BindHandle().Size() > 0 {
+		handles = append(handles, do.BindHandle())
+	}
+	if len(handles) == 0 {
+		return restore
+	}
+	normalized := parser.Normalize(node.Text())
+	if _, ok := node.(*ast.ExplainStmt); ok {
+		// Skip the explain keyword.
+		normalized = normalized[strings.Index(normalized, " ")+1:]
+	}
+	dbName := db.GetCurrentSchema(ctx)
+	for _, h := range handles {
+		if record := h.Get(normalized, dbName); record != nil {
+			return copyIndexHints(sel, record.BindStmt)
+		}
+	}
+	return restore
+}
+
+// copyIndexHints replaces the index hints of the tables in dst by the ones of the tables in src.
+// It returns a function that restores the original index hints of dst.
+func copyIndexHints(dst, src ast.Node) (restore func()) {
+	restore = func() {}
+	dstTables, srcTables := &tableNameCollector{}, &tableNameCollector{}
+	dst.Accept(dstTables)
+	src.Accept(srcTables)
+	if len(dstTables.names) != len(srcTables.names) {
+		return restore
+	}
+	for i, tn := range dstTables.names {
+		if tn.Name.L != srcTables.names[i].Name.L {
+			return restore
+		}
+	}
+	origin := make([][]*ast.IndexHint, len(dstTables.names))
+	for i, tn := range dstTables.names {
+		origin[i] = tn.IndexHints
+		tn.IndexHints = srcTables.names[i].IndexHints
+	}
+	return func() {
+		for i, tn := range dstTables.names {
+			tn.IndexHints = origin[i]
+		}
+	}
+}
+
+// BindingVersions returns the versions of the session bindings and the global bindings, a plan optimized with
+// the bindings of other versions may use other index hints.
+func BindingVersions(ctx context.Context) (sessionVersion, globalVersion uint64) {
+	if h := bindinfo.GetSessionHandle(ctx); h != nil {
+		sessionVersion = h.Version()
+	}
+	if do := sessionctx.GetDomain(ctx); do != nil {
+		globalVersion = do.BindHandle().Version()
+	}
+	return sessionVersion, globalVersion
+}
+
+// tableNameCollector collects the table names of a statement in the order they appear.
+type tableNameCollector struct {
+	names []*ast.TableName
+}
+
+func (c *tableNameCollector) Enter(in ast.Node) (ast.Node, bool) {
+	if tn, ok := in.(*ast.TableName); ok {
+		c.names = append(c.names, tn)
+	}
+	return in, false
+}
+
+func (c *tableNameCollector) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
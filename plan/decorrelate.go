@@ -0,0 +1,395 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// A correlated subquery is evaluated by an Apply, which executes the inner plan once for every outer row.
+// When the correlation is only in the filters of the inner plan, the correlated conditions can be pulled
+// up as join conditions, so the subquery is evaluated by one join with the outer plan:
+// exists (select ... where corr) becomes a semi join,
+// not exists (select ... where corr) becomes an anti semi join,
+// a in (select b ... where corr) becomes a semi join on a = b and corr,
+// (select max(b) ... where inner.c = outer.c) becomes a left outer join with an aggregation grouped by inner.c.
+
+// hasCorrelatedColumn checks if the expressions have correlated columns.
+func hasCorrelatedColumn(exprs ...expression.Expression) bool {
+	for _, expr := range exprs {
+		if _, outerCols := extractColumn(expr, nil, nil); len(outerCols) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// extractCorrelatedConds extracts the correlated conditions of the inner plan. The correlated conditions must
+// be in the selections that can be reached from the root of inner plan through selections and inner joins,
+// every other plan must not be correlated. It returns the selections and the correlated conditions in them.
+func extractCorrelatedConds(inner LogicalPlan) ([]*Selection, []expression.Expression, bool) {
+	var (
+		sels  []*Selection
+		conds []expression.Expression
+	)
+	var extract func(p LogicalPlan) bool
+	extract = func(p LogicalPlan) bool {
+		if !p.IsCorrelated() {
+			return true
+		}
+		switch x := p.(type) {
+		case *Selection:
+			found := false
+			for _, cond := range x.Conditions {
+				if hasCorrelatedColumn(cond) {
+					conds = append(conds, cond)
+					found = true
+				}
+			}
+			if found {
+				sels = append(sels, x)
+			}
+			return extract(x.GetChildByIndex(0).(LogicalPlan))
+		case *Join:
+			if x.JoinType != InnerJoin || hasCorrelatedColumn(expression.ScalarFuncs2Exprs(x.EqualConditions)...) ||
+				hasCorrelatedColumn(x.LeftConditions...) || hasCorrelatedColumn(x.RightConditions...) ||
+				hasCorrelatedColumn(x.OtherConditions...) {
+				return false
+			}
+			return extract(x.GetChildByIndex(0).(LogicalPlan)) && extract(x.GetChildByIndex(1).(LogicalPlan))
+		}
+		return false
+	}
+	if !extract(inner) || len(conds) == 0 {
+		return nil, nil, false
+	}
+	return sels, conds, true
+}
+
+// removeCorrelatedConds removes the correlated conditions from the selections under the root, the selection that
+// has no condition left is removed from the plan. Then the correlated flags of the plans are recomputed.
+func removeCorrelatedConds(root LogicalPlan, sels []*Selection) error {
+	for _, sel := range sels {
+		conds := sel.Conditions[:0]
+		for _, cond := range sel.Conditions {
+			if !hasCorrelatedColumn(cond) {
+				conds = append(conds, cond)
+			}
+		}
+		sel.Conditions = conds
+		if len(conds) == 0 {
+			if err := RemovePlan(sel); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	resetCorrelated(root)
+	return nil
+}
+
+// resetCorrelated recomputes the correlated flags of the plans whose correlated conditions are removed.
+func resetCorrelated(p LogicalPlan) {
+	if !p.IsCorrelated() {
+		return
+	}
+	correlated := false
+	for _, child := range p.GetChildren() {
+		resetCorrelated(child.(LogicalPlan))
+		correlated = correlated || child.IsCorrelated()
+	}
+	switch x := p.(type) {
+	case *Selection:
+		x.correlated = correlated || hasCorrelatedColumn(x.Conditions...)
+	case *Projection:
+		x.correlated = correlated || hasCorrelatedColumn(x.Exprs...)
+	case *Aggregation:
+		x.correlated = correlated || hasCorrelatedColumn(x.GroupByItems...)
+		for _, aggFunc := range x.AggFuncs {
+			x.correlated = x.correlated || hasCorrelatedColumn(aggFunc.GetArgs()...)
+		}
+	case *Join:
+		x.correlated = correlated
+	case *Exists:
+		x.correlated = correlated
+	}
+}
+
+// decorrelateExistsSubquery builds the semi join for the correlated exists subquery. It returns false if the subquery
+// can't be decorrelated, in which case the subquery is unchanged.
+func (er *expressionRewriter) decorrelateExistsSubquery(np LogicalPlan) bool {
+	sels, conds, ok := extractCorrelatedConds(np.GetChildByIndex(0).(LogicalPlan))
+	if !ok {
+		return false
+	}
+	if err := removeCorrelatedConds(np, sels); err != nil {
+		er.err = errors.Trace(err)
+		return true
+	}
+	inner := np.GetChildByIndex(0).(LogicalPlan)
+	inner.SetParents()
+	er.p = er.b.buildSemiJoin(er.p, inner, conds, er.asScalar, false, false)
+	return true
+}
+
+// decorrelateInSubquery builds the semi join for the correlated in subquery. The inner columns used by
+// the correlated conditions are added to the projection of the subquery. The join is not null aware, so the
+// "not in" and the scalar "in" are decorrelated only if neither side of the "in" can be NULL. It returns false if the
+// subquery can't be decorrelated, in which case the subquery is unchanged.
+func (er *expressionRewriter) decorrelateInSubquery(np LogicalPlan, lexpr, rexpr, checkCondition expression.Expression,
+	asScalar, not bool) bool {
+	if (not || asScalar) && (!isNotNullExpr(lexpr) || !isNotNullExpr(rexpr)) {
+		return false
+	}
+	proj, ok := np.(*Projection)
+	if !ok || hasCorrelatedColumn(proj.Exprs...) {
+		return false
+	}
+	child := proj.GetChildByIndex(0).(LogicalPlan)
+	sels, conds, ok := extractCorrelatedConds(child)
+	if !ok {
+		return false
+	}
+	if not && !asScalar {
+		// The anti semi join can't filter the outer rows by the conditions without inner columns.
+		for _, cond := range conds {
+			if innerCols, _ := extractColumn(cond, nil, nil); len(innerCols) == 0 {
+				return false
+			}
+		}
+	}
+	innerSchema := child.GetSchema()
+	if err := removeCorrelatedConds(proj, sels); err != nil {
+		er.err = errors.Trace(err)
+		return true
+	}
+	for i, cond := range conds {
+		conds[i] = projectInnerColumns(proj, innerSchema, cond)
+	}
+	conds = append(conds, expression.SplitCNFItems(checkCondition)...)
+	er.p = er.b.buildSemiJoin(er.p, proj, conds, asScalar, not, false)
+	return true
+}
+
+// projectInnerColumns replaces the inner columns in the condition with the columns of the projection, the inner
+// columns are added to the projection.
+func projectInnerColumns(proj *Projection, innerSchema expression.Schema, expr expression.Expression) expression.Expression {
+	switch v := expr.(type) {
+	case *expression.Column:
+		if innerSchema.GetIndex(v) == -1 {
+			return v
+		}
+		return addProjectionColumn(proj, v)
+	case *expression.ScalarFunction:
+		for i, arg := range v.Args {
+			v.Args[i] = projectInnerColumns(proj, innerSchema, arg)
+		}
+	}
+	return expr
+}
+
+// addProjectionColumn adds the expression to the projection and returns the new column of projection.
+func addProjectionColumn(proj *Projection, expr expression.Expression) *expression.Column {
+	col := &expression.Column{
+		FromID:      proj.id,
+		ColName:     model.NewCIStr(fmt.Sprintf("%s_col_%d", proj.id, len(proj.schema))),
+		RetType:     expr.GetType(),
+		Position:    len(proj.schema) + 1,
+		IsAggOrSubq: true,
+	}
+	proj.Exprs = append(proj.Exprs, expr)
+	proj.schema = append(proj.schema, col)
+	return col
+}
+
+// isNotNullExpr checks if the expression is a row or a column that can't be NULL.
+func isNotNullExpr(expr expression.Expression) bool {
+	switch v := expr.(type) {
+	case *expression.Column:
+		return mysql.HasNotNullFlag(v.RetType.Flag)
+	case *expression.Constant:
+		return !v.Value.IsNull()
+	case *expression.ScalarFunction:
+		if v.FuncName.L != ast.RowFunc {
+			return false
+		}
+		for _, arg := range v.Args {
+			if !isNotNullExpr(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// decorrelateScalarSubquery builds the left outer join for the correlated scalar subquery like
+// (select max(b) from t where t.c = outer.c). The aggregation is grouped by t.c, so the subquery returns one row
+// for every t.c, and the outer row that has no matched t.c gets NULL as the result of max(b), which is what the
+// aggregation returns on empty input. The aggregation functions that don't return NULL on empty input, such as
+// count, are not supported. It returns the columns of join that represent the result of subquery, or nil if the
+// subquery can't be decorrelated.
+func (er *expressionRewriter) decorrelateScalarSubquery(np LogicalPlan) []*expression.Column {
+	proj, ok := np.GetChildByIndex(0).(*Projection)
+	if !ok {
+		return nil
+	}
+	for _, expr := range proj.Exprs {
+		if _, ok := expr.(*expression.Column); !ok {
+			return nil
+		}
+	}
+	agg, ok := proj.GetChildByIndex(0).(*Aggregation)
+	if !ok || len(agg.GroupByItems) > 0 {
+		return nil
+	}
+	for _, aggFunc := range agg.AggFuncs {
+		switch aggFunc.GetName() {
+		case ast.AggFuncMax, ast.AggFuncMin, ast.AggFuncSum, ast.AggFuncAvg, ast.AggFuncFirstRow, ast.AggFuncGroupConcat:
+		default:
+			return nil
+		}
+		if hasCorrelatedColumn(aggFunc.GetArgs()...) {
+			return nil
+		}
+	}
+	child := agg.GetChildByIndex(0).(LogicalPlan)
+	sels, conds, ok := extractCorrelatedConds(child)
+	if !ok {
+		return nil
+	}
+	innerCols := make([]*expression.Column, 0, len(conds))
+	outerCols := make([]*expression.Column, 0, len(conds))
+	for _, cond := range conds {
+		f, ok := cond.(*expression.ScalarFunction)
+		if !ok || f.FuncName.L != ast.EQ {
+			return nil
+		}
+		lCol, lOK := f.Args[0].(*expression.Column)
+		rCol, rOK := f.Args[1].(*expression.Column)
+		if !lOK || !rOK || lCol.Correlated == rCol.Correlated {
+			return nil
+		}
+		if lCol.Correlated {
+			lCol, rCol = rCol, lCol
+		}
+		if er.p.GetSchema().GetIndex(rCol) == -1 || !sameComparisonClass(lCol, rCol) {
+			return nil
+		}
+		innerCols = append(innerCols, lCol)
+		outerCols = append(outerCols, rCol)
+	}
+	if err := removeCorrelatedConds(proj, sels); err != nil {
+		er.err = errors.Trace(err)
+		return nil
+	}
+	resultLen := len(proj.schema)
+	joinConds := make([]expression.Expression, 0, len(innerCols))
+	for i, innerCol := range innerCols {
+		agg.GroupByItems = append(agg.GroupByItems, innerCol.DeepCopy())
+		agg.AggFuncs = append(agg.AggFuncs, expression.NewAggFunction(ast.AggFuncFirstRow, []expression.Expression{innerCol.DeepCopy()}, false))
+		aggCol := &expression.Column{
+			FromID:      agg.id,
+			ColName:     model.NewCIStr(fmt.Sprintf("%s_col_%d", agg.id, len(agg.schema))),
+			Position:    len(agg.schema),
+			IsAggOrSubq: true,
+			RetType:     innerCol.RetType,
+		}
+		agg.schema = append(agg.schema, aggCol)
+		projCol := addProjectionColumn(proj, aggCol.DeepCopy())
+		cond, err := expression.NewFunction(ast.EQ, types.NewFieldType(mysql.TypeTiny), outerCols[i], projCol.DeepCopy())
+		if err != nil {
+			er.err = errors.Trace(err)
+			return nil
+		}
+		joinConds = append(joinConds, cond)
+	}
+	proj.SetParents()
+	er.p = er.b.buildLeftOuterJoin(er.p, proj, joinConds)
+	outerLen := len(er.p.GetSchema()) - len(proj.schema)
+	return er.p.GetSchema()[outerLen : outerLen+resultLen]
+}
+
+// sameComparisonClass checks whether the columns are compared in the comparison type of both of them. Otherwise
+// different groups of the inner column may equal the same outer value, like '1' and '1.0' for 1, and the outer
+// row is joined with every partial result of them.
+func sameComparisonClass(inner, outer *expression.Column) bool {
+	return comparisonClass(inner.RetType.Tp) == comparisonClass(outer.RetType.Tp)
+}
+
+// comparisonClass returns the type that the values of tp are compared in, the numeric types are compared as
+// numbers and the char types as strings.
+func comparisonClass(tp byte) byte {
+	switch tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong,
+		mysql.TypeFloat, mysql.TypeDouble, mysql.TypeDecimal, mysql.TypeNewDecimal:
+		return mysql.TypeNewDecimal
+	case mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString:
+		return mysql.TypeVarString
+	}
+	return tp
+}
+
+// buildLeftOuterJoin builds the left outer join for the decorrelated scalar subquery, the columns of inner plan are
+// marked as subquery columns.
+func (b *planBuilder) buildLeftOuterJoin(outerPlan, innerPlan LogicalPlan, onCondition []expression.Expression) LogicalPlan {
+	joinPlan := &Join{baseLogicalPlan: newBaseLogicalPlan(Jn, b.allocator)}
+	joinPlan.initID()
+	joinPlan.correlated = outerPlan.IsCorrelated() || innerPlan.IsCorrelated()
+	for _, expr := range onCondition {
+		joinPlan.correlated = joinPlan.correlated || tryDecorrelated(expr, outerPlan)
+	}
+	eqCond, leftCond, rightCond, otherCond := extractOnCondition(onCondition, outerPlan, innerPlan)
+	joinPlan.EqualConditions = eqCond
+	joinPlan.LeftConditions = leftCond
+	joinPlan.RightConditions = rightCond
+	joinPlan.OtherConditions = otherCond
+	innerSchema := innerPlan.GetSchema().DeepCopy()
+	for _, col := range innerSchema {
+		col.IsAggOrSubq = true
+	}
+	joinPlan.SetSchema(append(outerPlan.GetSchema().DeepCopy(), innerSchema...))
+	joinPlan.JoinType = LeftOuterJoin
+	joinPlan.SetChildren(outerPlan, innerPlan)
+	outerPlan.SetParents(joinPlan)
+	innerPlan.SetParents(joinPlan)
+	return joinPlan
+}
+
+// tryToAntiSemiJoin converts the filter "not exists (subq)" on the semi join with aux column to an anti semi join.
+// The semi join built for exists isn't null aware, so the aux column is never NULL.
+func tryToAntiSemiJoin(p LogicalPlan, cond expression.Expression) bool {
+	join, ok := p.(*Join)
+	if !ok || join.JoinType != SemiJoinWithAux || join.nullAware {
+		return false
+	}
+	f, ok := cond.(*expression.ScalarFunction)
+	if !ok || f.FuncName.L != ast.UnaryNot {
+		return false
+	}
+	schema := join.GetSchema()
+	if col, ok := f.Args[0].(*expression.Column); !ok || !col.Equal(schema[len(schema)-1]) {
+		return false
+	}
+	join.JoinType = SemiJoin
+	join.anti = !join.anti
+	join.SetSchema(schema[:len(schema)-1])
+	return true
+}
@@ -24,16 +24,19 @@ import (
 	"github.com/pingcap/tipb/go-tipb"
 )
 
-func expressionsToPB(exprs []expression.Expression, client kv.Client) (pbExpr *tipb.Expr, remained []expression.Expression, err error) {
+// expressionsToPB converts the expressions that can be pushed down to a pb expression,
+// the other expressions are returned as remained.
+func expressionsToPB(exprs []expression.Expression, client kv.Client) (pbExpr *tipb.Expr, pushed, remained []expression.Expression, err error) {
 	for _, expr := range exprs {
 		v, err := exprToPB(client, expr)
 		if err != nil {
-			return nil, nil, errors.Trace(err)
+			return nil, nil, nil, errors.Trace(err)
 		}
 		if v == nil {
 			remained = append(remained, expr)
 			continue
 		}
+		pushed = append(pushed, expr)
 		if pbExpr == nil {
 			pbExpr = v
 		} else {
@@ -157,7 +160,7 @@ func scalarFuncToPBExpr(client kv.Client, expr *expression.ScalarFunction) (*tip
 			return nil, nil
 		}
 		pattern, ok := expr.Args[1].(*expression.Constant)
-		if !ok || pattern.Value.Kind() != types.KindString {
+		if !ok || pattern.ParamMarker != nil || pattern.Value.Kind() != types.KindString {
 			return nil, nil
 		}
 		for i, b := range pattern.Value.GetString() {
@@ -172,6 +175,9 @@ func scalarFuncToPBExpr(client kv.Client, expr *expression.ScalarFunction) (*tip
 		}
 		tp = tipb.ExprType_Like
 	default:
+		if tp, ok := pushDownFuncs[expr.FuncName.L]; ok {
+			return funcToPBExpr(client, tp, expr.Args)
+		}
 		return nil, nil
 	}
 
@@ -198,6 +204,79 @@ func scalarFuncToPBExpr(client kv.Client, expr *expression.ScalarFunction) (*tip
 		Children: []*tipb.Expr{expr0, expr1}}, nil
 }
 
+// pushDownFuncs maps the arithmetic operators and builtin functions that the coprocessor
+// is able to evaluate to their pb types.
+var pushDownFuncs = map[string]tipb.ExprType{
+	// arithmetic operators
+	ast.Plus:   tipb.ExprType_Plus,
+	ast.Minus:  tipb.ExprType_Minus,
+	ast.Mul:    tipb.ExprType_Mul,
+	ast.Div:    tipb.ExprType_Div,
+	ast.IntDiv: tipb.ExprType_IntDiv,
+	ast.Mod:    tipb.ExprType_Mod,
+
+	// control functions
+	"if":       tipb.ExprType_If,
+	"ifnull":   tipb.ExprType_IfNull,
+	"nullif":   tipb.ExprType_NullIf,
+	ast.Case:   tipb.ExprType_Case,
+	"coalesce": tipb.ExprType_Coalesce,
+
+	// string functions
+	"concat":          tipb.ExprType_Concat,
+	"concat_ws":       tipb.ExprType_ConcatWS,
+	"left":            tipb.ExprType_Left,
+	"length":          tipb.ExprType_Length,
+	"lower":           tipb.ExprType_Lower,
+	"upper":           tipb.ExprType_Upper,
+	"repeat":          tipb.ExprType_Repeat,
+	"replace":         tipb.ExprType_Replace,
+	"strcmp":          tipb.ExprType_Strcmp,
+	"substring":       tipb.ExprType_Substring,
+	"substring_index": tipb.ExprType_SubstringIndex,
+	"locate":          tipb.ExprType_Locate,
+	"trim":            tipb.ExprType_Trim,
+
+	// time functions
+	"year":        tipb.ExprType_Year,
+	"month":       tipb.ExprType_Month,
+	"day":         tipb.ExprType_Day,
+	"dayofmonth":  tipb.ExprType_DayOfMonth,
+	"dayofweek":   tipb.ExprType_DayOfWeek,
+	"dayofyear":   tipb.ExprType_DayOfYear,
+	"weekday":     tipb.ExprType_Weekday,
+	"weekofyear":  tipb.ExprType_WeekOfYear,
+	"dayname":     tipb.ExprType_DayName,
+	"hour":        tipb.ExprType_Hour,
+	"minute":      tipb.ExprType_Minute,
+	"second":      tipb.ExprType_Second,
+	"microsecond": tipb.ExprType_Microsecond,
+}
+
+// PushDownFuncType returns the pb type of the function if it can be evaluated by the coprocessor.
+func PushDownFuncType(name string) (tipb.ExprType, bool) {
+	tp, ok := pushDownFuncs[name]
+	return tp, ok
+}
+
+func funcToPBExpr(client kv.Client, tp tipb.ExprType, args []expression.Expression) (*tipb.Expr, error) {
+	if !client.SupportRequestType(kv.ReqTypeSelect, int64(tp)) {
+		return nil, nil
+	}
+	children := make([]*tipb.Expr, 0, len(args))
+	for _, arg := range args {
+		pbArg, err := exprToPB(client, arg)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if pbArg == nil {
+			return nil, nil
+		}
+		children = append(children, pbArg)
+	}
+	return &tipb.Expr{Tp: tp, Children: children}, nil
+}
+
 func inToPBExpr(client kv.Client, expr *expression.ScalarFunction) (*tipb.Expr, error) {
 	if !client.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_In)) {
 		return nil, nil
@@ -227,9 +227,8 @@ func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (as
 	}
 	np = er.b.buildExists(np)
 	if np.IsCorrelated() {
-		if sel, ok := np.GetChildByIndex(0).(*Selection); ok && !sel.GetChildByIndex(0).IsCorrelated() {
-			er.p = er.b.buildSemiJoin(er.p, sel.GetChildByIndex(0).(LogicalPlan), sel.Conditions, er.asScalar, false)
-			if !er.asScalar {
+		if er.decorrelateExistsSubquery(np) {
+			if er.err != nil || !er.asScalar {
 				return v, true
 			}
 		} else {
@@ -306,31 +305,39 @@ func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node,
 	// a in (subq) will be rewrited as a = any(subq).
 	// a not in (subq) will be rewrited as a != all(subq).
 	checkCondition, err := constructBinaryOpFunction(lexpr, rexpr, ast.EQ)
-	if !np.IsCorrelated() {
-		er.p = er.b.buildSemiJoin(er.p, np, expression.SplitCNFItems(checkCondition), asScalar, v.Not)
-		if asScalar {
-			col := er.p.GetSchema()[len(er.p.GetSchema())-1]
-			er.ctxStack[len(er.ctxStack)-1] = col
-		} else {
-			er.ctxStack = er.ctxStack[:len(er.ctxStack)-1]
-		}
+	if err != nil {
+		er.err = errors.Trace(err)
 		return v, true
 	}
-	if v.Not {
-		checkCondition, _ = expression.NewFunction(ast.UnaryNot, &v.Type, checkCondition)
+	correlated := np.IsCorrelated()
+	if correlated && !er.decorrelateInSubquery(np, lexpr, rexpr, checkCondition, asScalar, v.Not) {
+		if v.Not {
+			checkCondition, _ = expression.NewFunction(ast.UnaryNot, &v.Type, checkCondition)
+		}
+		er.p = er.b.buildApply(er.p, np, outerSchema, &ApplyConditionChecker{Condition: checkCondition, All: v.Not})
+		if er.p.IsCorrelated() {
+			er.correlated = true
+		}
+		// The parent expression only use the last column in schema, which represents whether the condition is matched.
+		er.ctxStack[len(er.ctxStack)-1] = er.p.GetSchema()[len(er.p.GetSchema())-1]
+		return v, true
 	}
-	if err != nil {
-		er.err = errors.Trace(err)
+	if er.err != nil {
 		return v, true
 	}
-	er.p = er.b.buildApply(er.p, np, outerSchema, &ApplyConditionChecker{Condition: checkCondition, All: v.Not})
+	if !correlated {
+		er.p = er.b.buildSemiJoin(er.p, np, expression.SplitCNFItems(checkCondition), asScalar, v.Not, true)
+	}
 	if er.p.IsCorrelated() {
 		er.correlated = true
 	}
-	// The parent expression only use the last column in schema, which represents whether the condition is matched.
-	er.ctxStack[len(er.ctxStack)-1] = er.p.GetSchema()[len(er.p.GetSchema())-1]
+	if asScalar {
+		col := er.p.GetSchema()[len(er.p.GetSchema())-1]
+		er.ctxStack[len(er.ctxStack)-1] = col
+	} else {
+		er.ctxStack = er.ctxStack[:len(er.ctxStack)-1]
+	}
 	return v, true
-
 }
 
 func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Node, bool) {
@@ -340,13 +347,21 @@ func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Nod
 	}
 	np = er.b.buildMaxOneRow(np)
 	if np.IsCorrelated() {
-		er.p = er.b.buildApply(er.p, np, outerSchema, nil)
+		cols := er.decorrelateScalarSubquery(np)
+		if er.err != nil {
+			return v, true
+		}
+		if cols == nil {
+			er.p = er.b.buildApply(er.p, np, outerSchema, nil)
+			schema := er.p.GetSchema()
+			cols = schema[len(schema)-len(np.GetSchema()):]
+		}
 		if er.p.IsCorrelated() {
 			er.correlated = true
 		}
-		if len(np.GetSchema()) > 1 {
-			newCols := make([]expression.Expression, 0, len(np.GetSchema()))
-			for _, col := range np.GetSchema() {
+		if len(cols) > 1 {
+			newCols := make([]expression.Expression, 0, len(cols))
+			for _, col := range cols {
 				newCols = append(newCols, col.DeepCopy())
 			}
 			expr, err := expression.NewFunction(ast.RowFunc, nil, newCols...)
@@ -356,7 +371,7 @@ func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Nod
 			}
 			er.ctxStack = append(er.ctxStack, expr)
 		} else {
-			er.ctxStack = append(er.ctxStack, er.p.GetSchema()[len(er.p.GetSchema())-1])
+			er.ctxStack = append(er.ctxStack, cols[0])
 		}
 		return v, true
 	}
@@ -416,6 +431,10 @@ func (er *expressionRewriter) Leave(inNode ast.Node) (retNode ast.Node, ok bool)
 		er.ctxStack = append(er.ctxStack, value)
 	case *ast.ParamMarkerExpr:
 		value := &expression.Constant{Value: v.Datum, RetType: &v.Type}
+		if er.b.inPlanCache {
+			value.ParamMarker = v
+			er.b.params = append(er.b.params, value)
+		}
 		er.ctxStack = append(er.ctxStack, value)
 	case *ast.VariableExpr:
 		er.rewriteVariable(v)
@@ -0,0 +1,207 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+)
+
+// An outer join returns every row of its outer plan at least once. If the inner plan is joined on its unique key,
+// every outer row matches at most one inner row, so the join returns exactly the rows of the outer plan. When no
+// column of the inner plan is used by the rest of the plan, the outer join can be replaced by its outer plan, e.g.
+// select t1.a from t1 left join t2 on t1.b = t2.id becomes select t1.a from t1.
+
+// eliminateOuterJoin eliminates the outer joins in p. usedCols are the columns used by the ancestors of p.
+// It returns the plan that takes the place of p.
+func eliminateOuterJoin(p LogicalPlan, usedCols []*expression.Column) (LogicalPlan, error) {
+	if join, ok := p.(*Join); ok {
+		if outer := join.eliminableOuterPlan(usedCols); outer != nil {
+			err := removePlanWithChild(join, outer)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return eliminateOuterJoin(outer, usedCols)
+		}
+	}
+	selfUsedCols := columnsUsedByPlan(p)
+	cols := make([]*expression.Column, 0, len(usedCols)+len(selfUsedCols))
+	cols = append(append(cols, usedCols...), selfUsedCols...)
+	for _, child := range p.GetChildren() {
+		_, err := eliminateOuterJoin(child.(LogicalPlan), cols)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return p, nil
+}
+
+// removePlanWithChild removes a plan and puts one of its children in its place, the other children are dropped.
+func removePlanWithChild(p Plan, child Plan) error {
+	parents := p.GetParents()
+	if len(parents) > 1 {
+		return SystemInternalErrorType.Gen("can't remove this plan")
+	}
+	if len(parents) == 0 {
+		child.SetParents()
+		return nil
+	}
+	parent := parents[0]
+	err := parent.ReplaceChild(p, child)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(child.ReplaceParent(p, parent))
+}
+
+// columnsUsedByPlan returns the columns of the children that p uses. For the plans whose usage is not known,
+// all the columns of the children are returned.
+func columnsUsedByPlan(p LogicalPlan) []*expression.Column {
+	var exprs []expression.Expression
+	switch x := p.(type) {
+	case *Projection:
+		exprs = x.Exprs
+	case *Selection:
+		exprs = x.Conditions
+	case *Aggregation:
+		for _, aggFunc := range x.AggFuncs {
+			exprs = append(exprs, aggFunc.GetArgs()...)
+		}
+		exprs = append(exprs, x.GroupByItems...)
+	case *Sort:
+		for _, item := range x.ByItems {
+			exprs = append(exprs, item.Expr)
+		}
+	case *Join:
+		exprs = append(exprs, expression.ScalarFuncs2Exprs(x.EqualConditions)...)
+		exprs = append(exprs, x.LeftConditions...)
+		exprs = append(exprs, x.RightConditions...)
+		exprs = append(exprs, x.OtherConditions...)
+	case *Apply:
+		cols := append([]*expression.Column{}, x.OuterSchema...)
+		if x.Checker != nil {
+			cols, _ = extractColumn(x.Checker.Condition, cols, nil)
+		}
+		return cols
+	case *Trim, *Limit, *Exists, *MaxOneRow, *DataSource, *TableDual:
+		return nil
+	default:
+		var cols []*expression.Column
+		for _, child := range p.GetChildren() {
+			cols = append(cols, child.GetSchema()...)
+		}
+		return cols
+	}
+	var selfCols, outerCols []*expression.Column
+	for _, expr := range exprs {
+		selfCols, outerCols = extractColumn(expr, selfCols, outerCols)
+	}
+	return append(selfCols, outerCols...)
+}
+
+// eliminableOuterPlan returns the outer plan of the join if the join can be replaced by it.
+func (p *Join) eliminableOuterPlan(usedCols []*expression.Column) LogicalPlan {
+	var outer, inner LogicalPlan
+	var outerKeyIdx int
+	switch p.JoinType {
+	case LeftOuterJoin:
+		outer, inner = p.GetChildByIndex(0).(LogicalPlan), p.GetChildByIndex(1).(LogicalPlan)
+	case RightOuterJoin:
+		outer, inner = p.GetChildByIndex(1).(LogicalPlan), p.GetChildByIndex(0).(LogicalPlan)
+		outerKeyIdx = 1
+	default:
+		return nil
+	}
+	for _, col := range usedCols {
+		if inner.GetSchema().GetIndex(col) != -1 {
+			return nil
+		}
+	}
+	innerKeys := make([]*expression.Column, 0, len(p.EqualConditions))
+	for _, eqCond := range p.EqualConditions {
+		outerKey, ok1 := eqCond.Args[outerKeyIdx].(*expression.Column)
+		innerKey, ok2 := eqCond.Args[1-outerKeyIdx].(*expression.Column)
+		if !ok1 || !ok2 || !joinKeyComparable(outerKey, innerKey) {
+			continue
+		}
+		innerKeys = append(innerKeys, innerKey)
+	}
+	if !isUniqueKey(inner, innerKeys) {
+		return nil
+	}
+	return outer
+}
+
+// joinKeyComparable checks whether two join keys are compared without conversion, so that the rows matched by
+// a key value are exactly the rows having the same value.
+func joinKeyComparable(a, b *expression.Column) bool {
+	switch a.RetType.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		switch b.RetType.Tp {
+		case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+			return true
+		}
+		return false
+	}
+	return a.RetType.Tp == b.RetType.Tp
+}
+
+// isUniqueKey checks whether the columns contain the columns of a unique key of the plan. Only the data source
+// and the selections above it are checked.
+func isUniqueKey(p LogicalPlan, keys []*expression.Column) bool {
+	for {
+		sel, ok := p.(*Selection)
+		if !ok {
+			break
+		}
+		p = sel.GetChildByIndex(0).(LogicalPlan)
+	}
+	ds, ok := p.(*DataSource)
+	if !ok || len(keys) == 0 {
+		return false
+	}
+	hasKey := func(name model.CIStr) bool {
+		for _, key := range keys {
+			if key.ColName.L == name.L && ds.GetSchema().GetIndex(key) != -1 {
+				return true
+			}
+		}
+		return false
+	}
+	if ds.Table.PKIsHandle {
+		for _, col := range ds.Table.Columns {
+			if mysql.HasPriKeyFlag(col.Flag) && hasKey(col.Name) {
+				return true
+			}
+		}
+	}
+	for _, idx := range ds.Table.Indices {
+		if !idx.Unique || idx.State != model.StatePublic {
+			continue
+		}
+		covered := true
+		for _, idxCol := range idx.Columns {
+			if !hasKey(idxCol.Name) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,378 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/plan/statistics"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// joinReorderThreshold returns the max number of plans in a join group that are reordered by dynamic programming,
+// which is set by tidb_join_reorder_threshold. The larger join groups are reordered by the greedy solver, because
+// the cost of dynamic programming grows exponentially with the number of plans.
+func joinReorderThreshold(ctx context.Context) int {
+	vars := variable.GetSessionVars(ctx)
+	if vars == nil {
+		return variable.DefJoinReorderThreshold
+	}
+	return vars.JoinReorderThreshold
+}
+
+const (
+	// defaultEqualRate, defaultLessRate and defaultOtherRate are the selectivities of the conditions that can't be
+	// estimated by statistics.
+	defaultEqualRate = 0.1
+	defaultLessRate  = 0.3
+	defaultOtherRate = 0.9
+)
+
+// joinPredicate is a condition that refers to the plans in mask, it selects rate of the rows of their join.
+type joinPredicate struct {
+	mask uint
+	rate float64
+}
+
+// joinReorderDPSolver enumerates all the join trees of a join group without cartesian product by dynamic
+// programming, and picks the tree whose intermediate results have the least total row count.
+type joinReorderDPSolver struct {
+	allocator *idAllocator
+	group     []LogicalPlan
+	// rowCounts are the estimated row counts of the plans in the group after their own conditions are applied.
+	rowCounts []float64
+	// edges[i] is the mask of the plans joined with the plan i by equal conditions.
+	edges      []uint
+	predicates []joinPredicate
+}
+
+// dpJoinNode is the best join tree of a set of plans.
+type dpJoinNode struct {
+	cost     float64
+	rowCount float64
+	left     uint
+	right    uint
+}
+
+// reorderJoinByDP reorders the join group and returns the new join tree.
+func (e *joinReorderDPSolver) reorderJoinByDP(group []LogicalPlan, conds []expression.Expression) LogicalPlan {
+	e.group = group
+	e.rowCounts = make([]float64, len(group))
+	e.edges = make([]uint, len(group))
+	for i, p := range group {
+		e.rowCounts[i] = estimateRowCount(p)
+	}
+	for _, cond := range conds {
+		e.addCondition(cond)
+	}
+	components := e.connectedComponents()
+	best := make(map[uint]*dpJoinNode)
+	for _, component := range components {
+		e.solve(component, best)
+	}
+	// The components that are not connected by any equal condition are joined as a bushy tree, the smaller
+	// ones are joined first.
+	sort.Sort(&componentSorter{components: components, best: best})
+	plans := make([]LogicalPlan, 0, len(components))
+	for _, component := range components {
+		plans = append(plans, e.buildJoinTree(component, best))
+	}
+	solver := &joinReOrderSolver{allocator: e.allocator}
+	solver.makeBushyJoin(plans)
+	return solver.resultJoin
+}
+
+// addCondition records the selectivity of a condition of the join group.
+func (e *joinReorderDPSolver) addCondition(cond expression.Expression) {
+	f, ok := cond.(*expression.ScalarFunction)
+	if !ok {
+		return
+	}
+	if f.FuncName.L == ast.EQ {
+		lCol, lok := f.Args[0].(*expression.Column)
+		rCol, rok := f.Args[1].(*expression.Column)
+		if lok && rok && !lCol.Correlated && !rCol.Correlated {
+			lID := findColumnIndexByGroup(e.group, lCol)
+			rID := findColumnIndexByGroup(e.group, rCol)
+			if lID != -1 && rID != -1 && lID != rID {
+				e.edges[lID] |= 1 << uint(rID)
+				e.edges[rID] |= 1 << uint(lID)
+				ndv := math.Max(e.columnNDV(lID, lCol), e.columnNDV(rID, rCol))
+				e.predicates = append(e.predicates, joinPredicate{
+					mask: 1<<uint(lID) | 1<<uint(rID),
+					rate: 1 / math.Max(ndv, 1),
+				})
+				return
+			}
+		}
+	}
+	cols, _ := extractColumn(f, nil, nil)
+	var mask uint
+	for _, col := range cols {
+		idx := findColumnIndexByGroup(e.group, col)
+		if idx == -1 {
+			return
+		}
+		mask |= 1 << uint(idx)
+	}
+	if mask == 0 {
+		return
+	}
+	if len(cols) == 1 {
+		idx := findColumnIndexByGroup(e.group, cols[0])
+		e.rowCounts[idx] *= e.conditionRate(idx, f)
+		return
+	}
+	e.predicates = append(e.predicates, joinPredicate{mask: mask, rate: defaultRate(f.FuncName.L)})
+}
+
+// columnNDV returns the number of distinct values of a column of the plan idx. If there are no statistics of the
+// column, every row is assumed to be distinct.
+func (e *joinReorderDPSolver) columnNDV(idx int, col *expression.Column) float64 {
+	if colStats := columnStatistics(e.group[idx], col); colStats != nil && colStats.NDV > 0 {
+		return math.Min(float64(colStats.NDV), math.Max(e.rowCounts[idx], 1))
+	}
+	return e.rowCounts[idx]
+}
+
+// conditionRate estimates the selectivity of a condition on one plan of the group. The comparisons between a
+// column and a constant are estimated by the histogram of the column.
+func (e *joinReorderDPSolver) conditionRate(idx int, f *expression.ScalarFunction) float64 {
+	rate := defaultRate(f.FuncName.L)
+	col, con, funcName := extractColumnComparison(f)
+	if col == nil {
+		return rate
+	}
+	ds := findDataSource(e.group[idx])
+	colStats := columnStatistics(e.group[idx], col)
+	if ds == nil || colStats == nil || ds.statisticTable.Count <= 0 {
+		return rate
+	}
+	var (
+		count int64
+		err   error
+	)
+	switch funcName {
+	case ast.EQ:
+		count, err = colStats.EqualRowCount(con.Value)
+	case ast.LT, ast.LE:
+		count, err = colStats.LessRowCount(con.Value)
+	case ast.GT, ast.GE:
+		count, err = colStats.GreaterRowCount(con.Value)
+	}
+	if err != nil {
+		return rate
+	}
+	return math.Min(float64(count)/float64(ds.statisticTable.Count), 1)
+}
+
+// connectedComponents groups the plans connected by equal conditions, every component is returned as a mask.
+func (e *joinReorderDPSolver) connectedComponents() []uint {
+	var (
+		components []uint
+		visited    uint
+	)
+	for i := range e.group {
+		if visited&(1<<uint(i)) != 0 {
+			continue
+		}
+		component := uint(1) << uint(i)
+		for {
+			next := component
+			for j := range e.group {
+				if component&(1<<uint(j)) != 0 {
+					next |= e.edges[j]
+				}
+			}
+			if next == component {
+				break
+			}
+			component = next
+		}
+		visited |= component
+		components = append(components, component)
+	}
+	return components
+}
+
+// solve finds the best join tree of every connected subset of the component.
+func (e *joinReorderDPSolver) solve(component uint, best map[uint]*dpJoinNode) {
+	// Every subset is larger than its proper subsets, so they are solved before it in the increasing order.
+	for set := uint(1); set <= component; set++ {
+		if set&component != set {
+			continue
+		}
+		rowCount := e.joinRowCount(set)
+		if set&(set-1) == 0 {
+			best[set] = &dpJoinNode{rowCount: rowCount}
+			continue
+		}
+		var node *dpJoinNode
+		// The subsets are enumerated in the increasing order, so the plans keep their original order when the
+		// costs are equal.
+		for left := (0 - set) & set; left != set; left = (left - set) & set {
+			right := set ^ left
+			leftNode, rightNode := best[left], best[right]
+			if leftNode == nil || rightNode == nil || !e.connected(left, right) {
+				continue
+			}
+			cost := leftNode.cost + rightNode.cost + rowCount
+			if node == nil || cost < node.cost {
+				node = &dpJoinNode{cost: cost, rowCount: rowCount, left: left, right: right}
+			}
+		}
+		if node != nil {
+			best[set] = node
+		}
+	}
+}
+
+// joinRowCount estimates the row count of the join of the plans in set.
+func (e *joinReorderDPSolver) joinRowCount(set uint) float64 {
+	rowCount := 1.0
+	for i := range e.group {
+		if set&(1<<uint(i)) != 0 {
+			rowCount *= e.rowCounts[i]
+		}
+	}
+	for _, pred := range e.predicates {
+		if pred.mask&set == pred.mask {
+			rowCount *= pred.rate
+		}
+	}
+	return rowCount
+}
+
+// connected checks whether two sets of plans are joined by an equal condition.
+func (e *joinReorderDPSolver) connected(left, right uint) bool {
+	for i := range e.group {
+		if left&(1<<uint(i)) != 0 && e.edges[i]&right != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// buildJoinTree builds the join tree of the set from the solved best trees.
+func (e *joinReorderDPSolver) buildJoinTree(set uint, best map[uint]*dpJoinNode) LogicalPlan {
+	node := best[set]
+	if node.left == 0 {
+		for i := range e.group {
+			if set == 1<<uint(i) {
+				return e.group[i]
+			}
+		}
+	}
+	solver := &joinReOrderSolver{allocator: e.allocator}
+	return solver.newJoin(e.buildJoinTree(node.left, best), e.buildJoinTree(node.right, best))
+}
+
+type componentSorter struct {
+	components []uint
+	best       map[uint]*dpJoinNode
+}
+
+func (s *componentSorter) Len() int {
+	return len(s.components)
+}
+
+func (s *componentSorter) Less(i, j int) bool {
+	return s.best[s.components[i]].rowCount < s.best[s.components[j]].rowCount
+}
+
+func (s *componentSorter) Swap(i, j int) {
+	s.components[i], s.components[j] = s.components[j], s.components[i]
+}
+
+// defaultRate returns the selectivity of a condition that can't be estimated by statistics.
+func defaultRate(funcName string) float64 {
+	switch funcName {
+	case ast.EQ:
+		return defaultEqualRate
+	case ast.LT, ast.LE, ast.GE, ast.GT:
+		return defaultLessRate
+	}
+	return defaultOtherRate
+}
+
+// estimateRowCount estimates the row count of a logical plan by the statistics of its data sources.
+func estimateRowCount(p LogicalPlan) float64 {
+	switch x := p.(type) {
+	case *DataSource:
+		return float64(x.statisticTable.Count)
+	case *TableDual:
+		if x.Empty {
+			return 0
+		}
+		return 1
+	case *Selection:
+		return estimateRowCount(x.GetChildByIndex(0).(LogicalPlan)) * selectionFactor
+	case *Aggregation:
+		return estimateRowCount(x.GetChildByIndex(0).(LogicalPlan)) * distinctFactor
+	case *Limit:
+		return math.Min(estimateRowCount(x.GetChildByIndex(0).(LogicalPlan)), float64(x.Count))
+	case *Union:
+		var rowCount float64
+		for _, child := range x.GetChildren() {
+			rowCount += estimateRowCount(child.(LogicalPlan))
+		}
+		return rowCount
+	case *Join:
+		lCount := estimateRowCount(x.GetChildByIndex(0).(LogicalPlan))
+		rCount := estimateRowCount(x.GetChildByIndex(1).(LogicalPlan))
+		if len(x.EqualConditions) > 0 {
+			return math.Max(lCount, rCount)
+		}
+		return lCount * rCount
+	}
+	if len(p.GetChildren()) == 1 {
+		return estimateRowCount(p.GetChildByIndex(0).(LogicalPlan))
+	}
+	return 1
+}
+
+// findDataSource returns the data source under the selections of p.
+func findDataSource(p LogicalPlan) *DataSource {
+	for {
+		switch x := p.(type) {
+		case *DataSource:
+			return x
+		case *Selection:
+			p = x.GetChildByIndex(0).(LogicalPlan)
+		default:
+			return nil
+		}
+	}
+}
+
+// columnStatistics returns the statistics of a column of p if p reads the column from a data source directly.
+func columnStatistics(p LogicalPlan, col *expression.Column) *statistics.Column {
+	ds := findDataSource(p)
+	if ds == nil || ds.statisticTable == nil {
+		return nil
+	}
+	idx := ds.GetSchema().GetIndex(col)
+	if idx == -1 || idx >= len(ds.Columns) {
+		return nil
+	}
+	offset := ds.Columns[idx].Offset
+	if offset >= len(ds.statisticTable.Columns) {
+		return nil
+	}
+	return ds.statisticTable.Columns[offset]
+}
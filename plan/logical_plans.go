@@ -46,11 +46,16 @@ type Join struct {
 	anti          bool
 	reordered     bool
 	cartesianJoin bool
+	// nullAware means the semi join follows the semantics of IN, the result is NULL instead of false
+	// if the join key is NULL or the inner side has NULL join keys.
+	nullAware bool
 
 	EqualConditions []*expression.ScalarFunction
 	LeftConditions  []expression.Expression
 	RightConditions []expression.Expression
 	OtherConditions []expression.Expression
+
+	ctx context.Context
 }
 
 // Projection represents a select fields plan.
@@ -62,9 +67,12 @@ type Projection struct {
 // Aggregation represents an aggregate plan.
 type Aggregation struct {
 	baseLogicalPlan
-	// TODO: implement hash aggregation and streamed aggregation
 	AggFuncs     []expression.AggregationFunction
 	GroupByItems []expression.Expression
+	// Streamed means the child returns the rows in the order of the group by items, so the rows of a group are
+	// adjacent and the aggregation is executed by streaming instead of hashing.
+	Streamed bool
+	ctx      context.Context
 }
 
 // Selection means a filter.
@@ -101,6 +109,9 @@ type MaxOneRow struct {
 // TableDual represents a dual table plan.
 type TableDual struct {
 	baseLogicalPlan
+
+	// Empty is true if the dual replaces a plan that returns nothing, it returns no rows then.
+	Empty bool
 }
 
 // DataSource represents a tablescan without condition push down.
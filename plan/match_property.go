@@ -42,6 +42,11 @@ func (is *PhysicalIndexScan) matchProperty(prop requiredProperty, rowCounts []ui
 	cost := rowCount * netWorkFactor
 	if is.DoubleRead {
 		cost *= 2
+	} else if len(is.AccessCondition) > 0 {
+		// A covering index is read without table lookup, and its entries only contain the index columns and
+		// the handle, so they are cheaper to scan than the table rows. A full index scan is not preferred, so
+		// the tables without conditions are still read in the handle order.
+		cost *= float64(len(is.Index.Columns)+1) / float64(len(is.Table.Columns)+1)
 	}
 	if len(prop) == 0 {
 		return &physicalPlanInfo{p: is, cost: cost}
@@ -69,7 +74,12 @@ func (is *PhysicalIndexScan) matchProperty(prop requiredProperty, rowCounts []ui
 		}
 	}
 	if matched == len(prop) {
-		sortedCost := cost + rowCount*math.Log2(rowCount)*cpuFactor
+		// A covering index is read in order by merging the ordered results, but the double read has to look up
+		// the table rows in the order of the index, which is much slower.
+		sortedCost := cost + rowCount*cpuFactor
+		if is.DoubleRead {
+			sortedCost = cost + rowCount*math.Log2(rowCount)*cpuFactor
+		}
 		if allDesc {
 			sortedIs := *is
 			sortedIs.Desc = true
@@ -85,6 +95,23 @@ func (is *PhysicalIndexScan) matchProperty(prop requiredProperty, rowCounts []ui
 	return &physicalPlanInfo{p: is, cost: math.MaxFloat64}
 }
 
+// matchProperty implements PhysicalPlan matchProperty interface.
+func (p *PhysicalIndexMerge) matchProperty(prop requiredProperty, rowCounts []uint64, _ ...*physicalPlanInfo) *physicalPlanInfo {
+	// The handles are merged out of order, so no property can be matched.
+	if len(prop) != 0 {
+		return &physicalPlanInfo{p: p, cost: math.MaxFloat64}
+	}
+	// The partial index scans are executed in parallel, so the slowest one decides the cost of reading handles.
+	var maxPartialCount uint64
+	for _, cnt := range p.partialRowCounts {
+		if cnt > maxPartialCount {
+			maxPartialCount = cnt
+		}
+	}
+	cost := float64(maxPartialCount)*netWorkFactor + float64(rowCounts[0])*netWorkFactor
+	return &physicalPlanInfo{p: p, cost: cost}
+}
+
 // matchProperty implements PhysicalPlan matchProperty interface.
 func (p *PhysicalHashSemiJoin) matchProperty(prop requiredProperty, _ []uint64, childPlanInfo ...*physicalPlanInfo) *physicalPlanInfo {
 	lRes, rRes := childPlanInfo[0], childPlanInfo[1]
@@ -26,40 +26,89 @@ import (
 // Optimize does optimization and creates a Plan.
 // The node must be prepared first.
 func Optimize(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (Plan, error) {
-	// We have to infer type again because after parameter is set, the expression type may change.
-	if err := InferType(node); err != nil {
-		return nil, errors.Trace(err)
-	}
 	builder := &planBuilder{
 		ctx:       ctx,
 		is:        is,
 		colMapper: make(map[*ast.ColumnNameExpr]int),
 		allocator: new(idAllocator)}
+	return optimize(builder, node)
+}
+
+// OptimizeForCache is like Optimize, but the parameter markers of the node are kept as
+// placeholders in the plan, so the plan can be cached and executed again with other
+// parameter values. The node must be checked by Cacheable first.
+func OptimizeForCache(ctx context.Context, node ast.Node, is infoschema.InfoSchema) (*CachedPlan, error) {
+	builder := &planBuilder{
+		ctx:         ctx,
+		is:          is,
+		colMapper:   make(map[*ast.ColumnNameExpr]int),
+		allocator:   new(idAllocator),
+		inPlanCache: true}
+	p, err := optimize(builder, node)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &CachedPlan{Plan: p, params: builder.params}, nil
+}
+
+func optimize(builder *planBuilder, node ast.Node) (Plan, error) {
+	defer applyBinding(builder.ctx, node)()
+	// We have to infer type again because after parameter is set, the expression type may change.
+	if err := InferType(node); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !builder.inPlanCache {
+		if p := TryFastPlan(builder.ctx, node, builder.is); p != nil {
+			log.Debugf("[PLAN] %s", ToString(p))
+			return p, nil
+		}
+	}
 	p := builder.build(node)
 	if builder.err != nil {
 		return nil, errors.Trace(builder.err)
 	}
 	if logic, ok := p.(LogicalPlan); ok {
-		var err error
-		_, logic, err = logic.PredicatePushDown(nil)
+		phy, err := doOptimize(logic)
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		_, err = logic.PruneColumnsAndResolveIndices(p.GetSchema())
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		_, res, _, err := logic.convert2PhysicalPlan(nil)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		p = res.p.PushLimit(nil)
-		log.Debugf("[PLAN] %s", ToString(p))
-		return p, nil
+		log.Debugf("[PLAN] %s", ToString(phy))
+		return phy, nil
 	}
 	return p, nil
 }
 
+// doOptimize rewrites the logical plan and converts it to the physical plan.
+// EXPLAIN uses it too, so it shows the plan which is executed.
+func doOptimize(logic LogicalPlan) (PhysicalPlan, error) {
+	schema := logic.GetSchema()
+	_, logic, err := logic.PredicatePushDown(nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	logic, err = simplifyPredicates(logic)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	logic, err = eliminateOuterJoin(logic, schema)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = pushDownAggregation(logic)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	_, err = logic.PruneColumnsAndResolveIndices(schema)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	_, res, _, err := logic.convert2PhysicalPlan(nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return res.p.PushLimit(nil), nil
+}
+
 // PrepareStmt prepares a raw statement parsed from parser.
 // The statement must be prepared before it can be passed to optimize function.
 // We pass InfoSchema instead of getting from Context in case it is changed after resolving name.
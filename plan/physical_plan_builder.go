@@ -15,8 +15,11 @@ package plan
 
 import (
 	"math"
+	"sort"
+	"strings"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
@@ -108,7 +111,7 @@ func (p *DataSource) handleTableScan(prop requiredProperty) (*physicalPlanInfo,
 				memDB = true
 			}
 			if !memDB && client.SupportRequestType(kv.ReqTypeSelect, 0) {
-				ts.ConditionPBExpr, newSel.Conditions, err = expressionsToPB(newSel.Conditions, client)
+				ts.ConditionPBExpr, ts.pushedConditions, newSel.Conditions, err = expressionsToPB(newSel.Conditions, client)
 			}
 			if err != nil {
 				return nil, nil, errors.Trace(err)
@@ -207,7 +210,7 @@ func (p *DataSource) handleIndexScan(prop requiredProperty, index *model.IndexIn
 				memDB = true
 			}
 			if !memDB && client.SupportRequestType(kv.ReqTypeSelect, 0) {
-				is.ConditionPBExpr, newSel.Conditions, err = expressionsToPB(newSel.Conditions, client)
+				is.ConditionPBExpr, is.pushedConditions, newSel.Conditions, err = expressionsToPB(newSel.Conditions, client)
 			}
 			if err != nil {
 				return nil, nil, errors.Trace(err)
@@ -244,6 +247,201 @@ func (p *DataSource) handleIndexScan(prop requiredProperty, index *model.IndexIn
 	return resultPlan.matchProperty(prop, rowCounts), resultPlan.matchProperty(nil, rowCounts), nil
 }
 
+// buildPartialIndexScan builds an index scan that only reads handles for index merge.
+// It returns nil if the index can't be used to access the conditions.
+func (p *DataSource) buildPartialIndexScan(index *model.IndexInfo, conditions []expression.Expression) (*PhysicalIndexScan, uint64, error) {
+	is := &PhysicalIndexScan{
+		Index:       index,
+		Table:       p.Table,
+		Columns:     p.Columns,
+		TableAsName: p.TableAsName,
+		OutOfOrder:  true,
+		DoubleRead:  true,
+		DBName:      p.DBName,
+	}
+	is.SetSchema(p.schema)
+	conds := make([]expression.Expression, 0, len(conditions))
+	for _, cond := range conditions {
+		conds = append(conds, cond.DeepCopy())
+	}
+	is.AccessCondition, _ = detachIndexScanConditions(conds, is)
+	if len(is.AccessCondition) == 0 {
+		return nil, 0, nil
+	}
+	err := buildIndexRange(is)
+	if err != nil {
+		return nil, 0, errors.Trace(err)
+	}
+	var rowCount uint64
+	for _, idxRange := range is.Ranges {
+		cnt, err := getRowCountByIndexRange(p.statisticTable, idxRange, is.Index)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		rowCount += cnt
+	}
+	return is, rowCount, nil
+}
+
+// buildIndexMergeUnion tries to access every item of a DNF condition by an index, the handles read
+// from the partial index scans are unioned.
+func (p *DataSource) buildIndexMergeUnion(cond expression.Expression, indices []*model.IndexInfo) (*PhysicalIndexMerge, uint64, error) {
+	dnfItems := expression.SplitDNFItems(cond)
+	if len(dnfItems) < 2 {
+		return nil, 0, nil
+	}
+	merge := &PhysicalIndexMerge{}
+	var rowCount uint64
+	for _, item := range dnfItems {
+		var (
+			bestScan  *PhysicalIndexScan
+			bestCount uint64
+		)
+		for _, index := range indices {
+			is, cnt, err := p.buildPartialIndexScan(index, expression.SplitCNFItems(item))
+			if err != nil {
+				return nil, 0, errors.Trace(err)
+			}
+			if is != nil && (bestScan == nil || cnt < bestCount) {
+				bestScan, bestCount = is, cnt
+			}
+		}
+		if bestScan == nil {
+			return nil, 0, nil
+		}
+		merge.IndexScans = append(merge.IndexScans, bestScan)
+		merge.partialRowCounts = append(merge.partialRowCounts, bestCount)
+		rowCount += bestCount
+	}
+	if tblCount := uint64(p.statisticTable.Count); rowCount > tblCount {
+		rowCount = tblCount
+	}
+	return merge, rowCount, nil
+}
+
+// buildIndexMergeIntersection accesses the CNF conditions by several indices, the handles read
+// from the partial index scans are intersected.
+func (p *DataSource) buildIndexMergeIntersection(conds []expression.Expression, indices []*model.IndexInfo) (*PhysicalIndexMerge, uint64, error) {
+	merge := &PhysicalIndexMerge{Intersection: true}
+	for _, index := range indices {
+		is, cnt, err := p.buildPartialIndexScan(index, conds)
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		if is != nil {
+			merge.IndexScans = append(merge.IndexScans, is)
+			merge.partialRowCounts = append(merge.partialRowCounts, cnt)
+		}
+	}
+	if len(merge.IndexScans) < 2 {
+		return nil, 0, nil
+	}
+	sort.Sort(partialScanSorter{merge})
+	tblCount := float64(p.statisticTable.Count)
+	if tblCount == 0 {
+		return nil, 0, nil
+	}
+	// Add the partial scans in the order of row count until the cost is not reduced any more. The partial scans
+	// are executed in parallel, so adding a scan costs the difference between its row count and the largest one.
+	rowCount := float64(merge.partialRowCounts[0])
+	maxCount := rowCount
+	n := 1
+	for ; n < len(merge.IndexScans); n++ {
+		partialCount := float64(merge.partialRowCounts[n])
+		newCount := rowCount * partialCount / tblCount
+		if partialCount+newCount >= maxCount+rowCount {
+			break
+		}
+		rowCount, maxCount = newCount, partialCount
+	}
+	if n < 2 {
+		return nil, 0, nil
+	}
+	merge.IndexScans = merge.IndexScans[:n]
+	merge.partialRowCounts = merge.partialRowCounts[:n]
+	return merge, uint64(rowCount), nil
+}
+
+type partialScanSorter struct {
+	merge *PhysicalIndexMerge
+}
+
+func (s partialScanSorter) Len() int {
+	return len(s.merge.IndexScans)
+}
+
+func (s partialScanSorter) Less(i, j int) bool {
+	return s.merge.partialRowCounts[i] < s.merge.partialRowCounts[j]
+}
+
+func (s partialScanSorter) Swap(i, j int) {
+	s.merge.IndexScans[i], s.merge.IndexScans[j] = s.merge.IndexScans[j], s.merge.IndexScans[i]
+	s.merge.partialRowCounts[i], s.merge.partialRowCounts[j] = s.merge.partialRowCounts[j], s.merge.partialRowCounts[i]
+}
+
+// handleIndexMerge builds the cheapest index merge plan for the conditions of the parent selection.
+// It returns nil if no index merge plan can be built.
+func (p *DataSource) handleIndexMerge(indices []*model.IndexInfo) (*physicalPlanInfo, error) {
+	sel, ok := p.GetParentByIndex(0).(*Selection)
+	if !ok || len(indices) < 2 {
+		return nil, nil
+	}
+	var (
+		bestMerge *PhysicalIndexMerge
+		bestCount uint64
+		bestInfo  *physicalPlanInfo
+	)
+	tryMerge := func(merge *PhysicalIndexMerge, rowCount uint64) {
+		if merge == nil {
+			return
+		}
+		info := merge.matchProperty(nil, []uint64{rowCount})
+		if bestInfo == nil || info.cost < bestInfo.cost {
+			bestMerge, bestCount, bestInfo = merge, rowCount, info
+		}
+	}
+	for _, cond := range sel.Conditions {
+		merge, rowCount, err := p.buildIndexMergeUnion(cond, indices)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tryMerge(merge, rowCount)
+	}
+	merge, rowCount, err := p.buildIndexMergeIntersection(sel.Conditions, indices)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tryMerge(merge, rowCount)
+	if bestMerge == nil {
+		return nil, nil
+	}
+	bestMerge.Table = p.Table
+	bestMerge.Columns = p.Columns
+	bestMerge.DBName = p.DBName
+	bestMerge.TableAsName = p.TableAsName
+	bestMerge.SetSchema(p.schema)
+	var resultPlan PhysicalPlan = bestMerge
+	txn, err := p.ctx.GetTxn(false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if txn != nil && !txn.IsReadOnly() {
+		us := &PhysicalUnionScan{
+			Condition: expression.ComposeCNFCondition(sel.Conditions),
+		}
+		us.SetChildren(resultPlan)
+		resultPlan = us
+	}
+	// The partial index scans only access the rows, all the conditions are evaluated again on the merged rows.
+	newSel := *sel
+	newSel.Conditions = make([]expression.Expression, 0, len(sel.Conditions))
+	for _, cond := range sel.Conditions {
+		newSel.Conditions = append(newSel.Conditions, cond.DeepCopy())
+	}
+	newSel.SetChildren(resultPlan)
+	return newSel.matchProperty(nil, []uint64{bestCount}), nil
+}
+
 func isCoveringIndex(columns []*model.ColumnInfo, indexColumns []*model.IndexColumn, pkIsHandle bool) bool {
 	for _, colInfo := range columns {
 		if pkIsHandle && mysql.HasPriKeyFlag(colInfo.Flag) {
@@ -273,7 +471,7 @@ func (p *DataSource) convert2PhysicalPlan(prop requiredProperty) (*physicalPlanI
 	var err error
 	if isSel {
 		for _, cond := range sel.Conditions {
-			if con, ok := cond.(*expression.Constant); ok {
+			if con, ok := cond.(*expression.Constant); ok && con.ParamMarker == nil {
 				var result bool
 				result, err = expression.EvalBool(con, nil, nil)
 				if err != nil {
@@ -308,6 +506,16 @@ func (p *DataSource) convert2PhysicalPlan(prop requiredProperty) (*physicalPlanI
 			unsortedRes = unsortedIsRes
 		}
 	}
+	mergeRes, err := p.handleIndexMerge(indices)
+	if err != nil {
+		return nil, nil, 0, errors.Trace(err)
+	}
+	if mergeRes != nil && (unsortedRes == nil || mergeRes.cost < unsortedRes.cost) {
+		unsortedRes = mergeRes
+		if len(prop) == 0 {
+			sortedRes = mergeRes
+		}
+	}
 	statsTbl := p.statisticTable
 	p.storePlanInfo(prop, sortedRes, unsortedRes, uint64(statsTbl.Count))
 	return sortedRes, unsortedRes, uint64(statsTbl.Count), nil
@@ -451,6 +659,7 @@ func (p *Join) convert2PhysicalPlan(prop requiredProperty) (*physicalPlanInfo, *
 			RightConditions: p.RightConditions,
 			OtherConditions: p.OtherConditions,
 			Anti:            p.anti,
+			NullAware:       p.nullAware,
 		}
 		join.SetSchema(p.schema)
 		if !allLeft {
@@ -510,18 +719,182 @@ func (p *Join) convert2PhysicalPlan(prop requiredProperty) (*physicalPlanInfo, *
 
 // convert2PhysicalPlan implements LogicalPlan convert2PhysicalPlan interface.
 func (p *Aggregation) convert2PhysicalPlan(prop requiredProperty) (*physicalPlanInfo, *physicalPlanInfo, uint64, error) {
-	var err error
-	_, planInfo, cnt := p.getPlanInfo(prop)
-	if planInfo != nil {
-		return planInfo, planInfo, cnt, nil
+	sortedPlanInfo, unSortedPlanInfo, cnt := p.getPlanInfo(prop)
+	if sortedPlanInfo != nil {
+		return sortedPlanInfo, unSortedPlanInfo, cnt, nil
 	}
-	_, planInfo, cnt, err = p.GetChildByIndex(0).(LogicalPlan).convert2PhysicalPlan(nil)
-	if len(prop) != 0 {
-		return &physicalPlanInfo{cost: math.MaxFloat64}, addPlanToResponse(p, planInfo), cnt / 3, errors.Trace(err)
+	child := p.GetChildByIndex(0).(LogicalPlan)
+	_, childPlanInfo, cnt, err := child.convert2PhysicalPlan(nil)
+	if err != nil {
+		return nil, nil, 0, errors.Trace(err)
+	}
+	// The hash aggregation keeps all the groups in memory.
+	unSortedPlanInfo = addPlanToResponse(p, childPlanInfo)
+	pushed := p.canPushToCop(childPlanInfo.p)
+	if pushed {
+		// The executor pushes the aggregation down to the coprocessor, only the partial results of the groups
+		// are sent back instead of all the scanned rows.
+		unSortedPlanInfo.cost += float64(cnt/3)*(cpuFactor+memoryFactor) - float64(cnt-cnt/3)*netWorkFactor
+	} else {
+		unSortedPlanInfo.cost += float64(cnt)*cpuFactor + float64(cnt/3)*memoryFactor
 	}
-	planInfo = addPlanToResponse(p, planInfo)
-	p.storePlanInfo(prop, planInfo, planInfo, cnt/3)
-	return planInfo, planInfo, cnt / 3, errors.Trace(err)
+	sortedPlanInfo = &physicalPlanInfo{cost: math.MaxFloat64}
+	if p.canStream() {
+		// The stream aggregation returns the groups in the order of the group by items, so it can provide the
+		// order required by the parent if the order is on the group by items.
+		if childProp, ok := p.streamProperty(prop); ok {
+			sortedPlanInfo, err = p.handleStreamAgg(childProp)
+			if err != nil {
+				return nil, nil, 0, errors.Trace(err)
+			}
+		}
+		// The stream aggregation can't be pushed down, so it only replaces the pushed down aggregation
+		// when the parent requires its order.
+		if !pushed && len(prop) > 0 {
+			childProp, _ := p.streamProperty(nil)
+			streamPlanInfo, err := p.handleStreamAgg(childProp)
+			if err != nil {
+				return nil, nil, 0, errors.Trace(err)
+			}
+			if streamPlanInfo.cost < unSortedPlanInfo.cost {
+				unSortedPlanInfo = streamPlanInfo
+			}
+		} else if !pushed && sortedPlanInfo.cost < unSortedPlanInfo.cost {
+			unSortedPlanInfo = sortedPlanInfo
+		}
+	}
+	if len(prop) == 0 {
+		sortedPlanInfo = unSortedPlanInfo
+	}
+	p.storePlanInfo(prop, sortedPlanInfo, unSortedPlanInfo, cnt/3)
+	return sortedPlanInfo, unSortedPlanInfo, cnt / 3, nil
+}
+
+// canStream checks whether the aggregation can be executed by streaming. The group by items must be columns, and
+// the aggregate functions must accept the original rows.
+func (p *Aggregation) canStream() bool {
+	if len(p.GroupByItems) == 0 {
+		return false
+	}
+	for _, item := range p.GroupByItems {
+		if _, ok := item.(*expression.Column); !ok {
+			return false
+		}
+	}
+	for _, aggFunc := range p.AggFuncs {
+		if aggFunc.GetMode() != expression.CompleteMode {
+			return false
+		}
+	}
+	return true
+}
+
+// streamProperty returns the order of the child that makes the rows of a group adjacent and also satisfies prop.
+// prop is satisfied if it's on the columns that are grouped by, and they are all sorted in the same direction.
+func (p *Aggregation) streamProperty(prop requiredProperty) (requiredProperty, bool) {
+	childProp := make(requiredProperty, 0, len(p.GroupByItems))
+	desc := false
+	for i, c := range prop {
+		idx := p.schema.GetIndex(c.col)
+		if idx == -1 || strings.ToLower(p.AggFuncs[idx].GetName()) != ast.AggFuncFirstRow {
+			return nil, false
+		}
+		col, ok := p.AggFuncs[idx].GetArgs()[0].(*expression.Column)
+		if !ok || !p.isGroupByColumn(col) {
+			return nil, false
+		}
+		if i == 0 {
+			desc = c.desc
+		} else if c.desc != desc {
+			return nil, false
+		}
+		if !propContains(childProp, col) {
+			childProp = append(childProp, &columnProp{col: col, desc: desc})
+		}
+	}
+	for _, item := range p.GroupByItems {
+		col := item.(*expression.Column)
+		if !propContains(childProp, col) {
+			childProp = append(childProp, &columnProp{col: col, desc: desc})
+		}
+	}
+	return childProp, true
+}
+
+func (p *Aggregation) isGroupByColumn(col *expression.Column) bool {
+	for _, item := range p.GroupByItems {
+		if col.Equal(item) {
+			return true
+		}
+	}
+	return false
+}
+
+func propContains(prop requiredProperty, col *expression.Column) bool {
+	for _, c := range prop {
+		if c.col.Equal(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// canPushToCop checks whether the executor can push the aggregation down to the coprocessor that
+// returns the rows of child. It follows the checks of the executor builder.
+func (p *Aggregation) canPushToCop(child PhysicalPlan) bool {
+	var dbName model.CIStr
+	var reqType int64
+	switch x := child.(type) {
+	case *PhysicalTableScan:
+		dbName, reqType = *x.DBName, kv.ReqTypeSelect
+	case *PhysicalIndexScan:
+		dbName, reqType = *x.DBName, kv.ReqTypeIndex
+	default:
+		return false
+	}
+	switch dbName.L {
+	case "information_schema", "performance_schema":
+		return false
+	}
+	if p.ctx == nil {
+		return false
+	}
+	client := p.ctx.GetClient()
+	if client == nil || !client.SupportRequestType(reqType, 0) {
+		return false
+	}
+	if len(p.GroupByItems) > 0 && !client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeGroupBy) {
+		return false
+	}
+	for _, aggFunc := range p.AggFuncs {
+		if aggFunc.IsDistinct() {
+			return false
+		}
+		if pb, err := aggFuncToPBExpr(client, aggFunc); err != nil || pb == nil {
+			return false
+		}
+	}
+	for _, item := range p.GroupByItems {
+		if pb, err := groupByItemToPB(client, item); err != nil || pb == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// handleStreamAgg builds the stream aggregation over the child that is sorted by childProp.
+func (p *Aggregation) handleStreamAgg(childProp requiredProperty) (*physicalPlanInfo, error) {
+	childPlanInfo, _, cnt, err := p.GetChildByIndex(0).(LogicalPlan).convert2PhysicalPlan(childProp)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if childPlanInfo.cost == math.MaxFloat64 {
+		return childPlanInfo, nil
+	}
+	planInfo := addPlanToResponse(p, childPlanInfo)
+	planInfo.p.(*Aggregation).Streamed = true
+	planInfo.cost += float64(cnt) * cpuFactor
+	return planInfo, nil
 }
 
 // convert2PhysicalPlan implements LogicalPlan convert2PhysicalPlan interface.
@@ -717,6 +1090,10 @@ func (p *Distinct) convert2PhysicalPlan(prop requiredProperty) (*physicalPlanInf
 
 // convert2PhysicalPlan implements LogicalPlan convert2PhysicalPlan interface.
 func (p *TableDual) convert2PhysicalPlan(prop requiredProperty) (*physicalPlanInfo, *physicalPlanInfo, uint64, error) {
+	if p.Empty {
+		planInfo := &physicalPlanInfo{p: p}
+		return planInfo, planInfo, 0, nil
+	}
 	planInfo := &physicalPlanInfo{p: p, cost: 1.0}
 	return planInfo, planInfo, 1, nil
 }
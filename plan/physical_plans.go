@@ -43,6 +43,8 @@ type PhysicalIndexScan struct {
 	AccessCondition  []expression.Expression
 	// ConditionPBExpr is the pb structure of conditions that pushed down.
 	ConditionPBExpr *tipb.Expr
+	// pushedConditions are the conditions converted to ConditionPBExpr.
+	pushedConditions []expression.Expression
 
 	TableAsName *model.CIStr
 
@@ -63,6 +65,8 @@ type PhysicalTableScan struct {
 	AccessCondition []expression.Expression
 	// ConditionPBExpr is the pb structure of conditions that pushed down.
 	ConditionPBExpr *tipb.Expr
+	// pushedConditions are the conditions converted to ConditionPBExpr.
+	pushedConditions []expression.Expression
 
 	TableAsName *model.CIStr
 
@@ -72,6 +76,25 @@ type PhysicalTableScan struct {
 	KeepOrder bool
 }
 
+// PhysicalIndexMerge represents an index merge plan. It reads the handles from several index scans
+// in parallel, unions or intersects the handle sets, and then reads the rows by the merged handles.
+type PhysicalIndexMerge struct {
+	basePlan
+
+	Table       *model.TableInfo
+	Columns     []*model.ColumnInfo
+	DBName      *model.CIStr
+	TableAsName *model.CIStr
+
+	// IndexScans are the partial index scans, only the handles are read from them.
+	IndexScans []*PhysicalIndexScan
+	// Intersection means the handle sets are intersected, otherwise they are unioned.
+	Intersection bool
+
+	// partialRowCounts are the estimated row counts of the partial index scans.
+	partialRowCounts []uint64
+}
+
 // PhysicalDummyScan is a dummy table that returns nothing.
 type PhysicalDummyScan struct {
 	basePlan
@@ -104,8 +127,9 @@ type PhysicalHashJoin struct {
 type PhysicalHashSemiJoin struct {
 	basePlan
 
-	WithAux bool
-	Anti    bool
+	WithAux   bool
+	Anti      bool
+	NullAware bool
 
 	EqualConditions []*expression.ScalarFunction
 	LeftConditions  []expression.Expression
@@ -179,6 +203,28 @@ func (p *PhysicalTableScan) MarshalJSON() ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// Copy implements the PhysicalPlan Copy interface.
+func (p *PhysicalIndexMerge) Copy() PhysicalPlan {
+	np := *p
+	return &np
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (p *PhysicalIndexMerge) MarshalJSON() ([]byte, error) {
+	partials, err := json.Marshal(p.IndexScans)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	buffer := bytes.NewBufferString("{")
+	buffer.WriteString(fmt.Sprintf("\"type\": \"IndexMerge\",\n"+
+		" \"db\": \"%s\","+
+		"\n \"table\": \"%s\","+
+		"\n \"intersection\": %v,"+
+		"\n \"partial scans\": %s}",
+		p.DBName.O, p.Table.Name.O, p.Intersection, partials))
+	return buffer.Bytes(), nil
+}
+
 // Copy implements the PhysicalPlan Copy interface.
 func (p *PhysicalApply) Copy() PhysicalPlan {
 	np := *p
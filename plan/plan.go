@@ -63,6 +63,8 @@ const (
 	Up = "Update"
 	// Del is the type of Delete.
 	Del = "Delete"
+	// PointGet is the type of PointGetPlan.
+	PointGet = "PointGet"
 )
 
 // Plan is a description of an execution flow.
@@ -0,0 +1,76 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+)
+
+// CachedPlan is the plan of a prepared statement that can be executed again with other
+// parameter values, the parameter markers are kept as placeholders in the plan.
+type CachedPlan struct {
+	Plan   Plan
+	params []*expression.Constant
+}
+
+// Rebuild refreshes the placeholders with the current values of the parameter markers,
+// then rebuilds the ranges and the pushed down conditions of the scans.
+func (cp *CachedPlan) Rebuild(ctx context.Context) error {
+	for _, param := range cp.params {
+		param.Value = param.ParamMarker.Datum
+	}
+	return errors.Trace(rebuildRange(cp.Plan, ctx.GetClient()))
+}
+
+// Cacheable checks whether the plan of the node can be cached. Only the select statements
+// without subqueries and variables are cached, the plans of the other statements depend
+// on the values of the parameters.
+func Cacheable(node ast.Node) bool {
+	sel, ok := node.(*ast.SelectStmt)
+	if !ok {
+		return false
+	}
+	checker := cacheableChecker{root: sel, cacheable: true}
+	node.Accept(&checker)
+	return checker.cacheable
+}
+
+// cacheableChecker checks whether a select statement can be cached.
+type cacheableChecker struct {
+	root      *ast.SelectStmt
+	cacheable bool
+}
+
+// Enter implements ast.Visitor interface.
+func (checker *cacheableChecker) Enter(in ast.Node) (out ast.Node, skipChildren bool) {
+	switch x := in.(type) {
+	case *ast.SelectStmt:
+		if x != checker.root {
+			checker.cacheable = false
+			return in, true
+		}
+	case *ast.UnionStmt, *ast.SubqueryExpr, *ast.ExistsSubqueryExpr, *ast.CompareSubqueryExpr, *ast.VariableExpr:
+		checker.cacheable = false
+		return in, true
+	}
+	return in, false
+}
+
+// Leave implements ast.Visitor interface.
+func (checker *cacheableChecker) Leave(in ast.Node) (out ast.Node, ok bool) {
+	return in, checker.cacheable
+}
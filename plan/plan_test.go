@@ -218,12 +218,12 @@ func (s *testPlanSuite) TestPredicatePushDown(c *C) {
 		},
 		{
 			sql:   "select a from t where exists(select 1 from t as x where x.a = t.a and t.a < 1 and x.a < 1)",
-			first: "Join{DataScan(t)->DataScan(t)}->Projection",
+			first: "Join{DataScan(t)->DataScan(t)->Selection}->Projection",
 			best:  "Join{DataScan(t)->Selection->DataScan(t)->Selection}->Projection",
 		},
 		{
 			sql:   "select a from t where exists(select 1 from t as x where x.a = t.a and x.a < 1) and a < 1",
-			first: "Join{DataScan(t)->DataScan(t)}->Selection->Projection",
+			first: "Join{DataScan(t)->DataScan(t)->Selection}->Selection->Projection",
 			best:  "Join{DataScan(t)->Selection->DataScan(t)->Selection}->Projection",
 		},
 		{
@@ -266,15 +266,15 @@ func (s *testPlanSuite) TestJoinReOrder(c *C) {
 	}{
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6 where t1.a = t2.b and t2.a = t3.b and t3.c = t4.a and t4.d = t2.c and t5.d = t6.d",
-			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}(t1.a,t2.b)->Table(t)}(t2.a,t3.b)->Table(t)}(t3.c,t4.a)(t2.c,t4.d)->LeftHashJoin{Table(t)->Table(t)}(t5.d,t6.d)}->Projection",
+			best: "LeftHashJoin{RightHashJoin{Table(t)->RightHashJoin{Table(t)->LeftHashJoin{Table(t)->Table(t)}(t3.c,t4.a)}(t2.a,t3.b)(t2.c,t4.d)}(t1.a,t2.b)->LeftHashJoin{Table(t)->Table(t)}(t5.d,t6.d)}->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5, t t6, t t7, t t8 where t1.a = t8.a",
-			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}(t1.a,t8.a)->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}->LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Table(t)}}->Projection",
+			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->LeftHashJoin{Table(t)->Table(t)}}->LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->LeftHashJoin{Table(t)->Table(t)}(t1.a,t8.a)}}->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5 where t1.a = t5.a and t5.a = t4.a and t4.a = t3.a and t3.a = t2.a and t2.a = t1.a and t1.a = t3.a and t2.a = t4.a and t5.b < 8",
-			best: "LeftHashJoin{LeftHashJoin{LeftHashJoin{RightHashJoin{Table(t)->Selection->Table(t)}(t5.a,t1.a)->Table(t)}(t1.a,t2.a)->Table(t)}(t2.a,t3.a)(t1.a,t3.a)->Table(t)}(t5.a,t4.a)(t3.a,t4.a)(t2.a,t4.a)->Projection",
+			best: "RightHashJoin{Table(t)->RightHashJoin{Table(t)->RightHashJoin{Table(t)->LeftHashJoin{Table(t)->Table(t)->Selection}(t4.a,t5.a)}(t3.a,t4.a)}(t2.a,t3.a)(t2.a,t4.a)}(t1.a,t5.a)(t1.a,t2.a)(t1.a,t3.a)->Projection",
 		},
 		{
 			sql:  "select * from t t1, t t2, t t3, t t4, t t5 where t1.a = t5.a and t5.a = t4.a and t4.a = t3.a and t3.a = t2.a and t2.a = t1.a and t1.a = t3.a and t2.a = t4.a and t3.b = 1 and t4.a = 1",
@@ -282,11 +282,11 @@ func (s *testPlanSuite) TestJoinReOrder(c *C) {
 		},
 		{
 			sql:  "select * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a)",
-			best: "Table(t)->Apply(LeftHashJoin{RightHashJoin{Table(t)->Selection->Table(t)}(t2.a,t3.a)->Table(t)}(t3.a,t1.a)->Projection)->Selection->Projection",
+			best: "SemiJoin{Table(t)->RightHashJoin{Table(t)->LeftHashJoin{Table(t)->Table(t)}(t2.a,t3.a)}(t1.a,t3.a)->Projection}->Projection",
 		},
 		{
 			sql:  "select * from t o where o.b in (select t3.c from t t1, t t2, t t3 where t1.a = t3.a and t2.a = t3.a and t2.a = o.a and t1.a = 1)",
-			best: "Table(t)->Apply(LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Table(t)->Selection}->Projection)->Selection->Projection",
+			best: "SemiJoin{Table(t)->LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}->Table(t)}->Projection}->Projection",
 		},
 	}
 	for _, ca := range cases {
@@ -318,6 +318,88 @@ func (s *testPlanSuite) TestJoinReOrder(c *C) {
 	}
 }
 
+func (s *testPlanSuite) TestJoinReOrderByStatistics(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql       string
+		counts    map[string]int64
+		threshold int
+		best      string
+	}{
+		// The small tables are joined first.
+		{
+			sql:       "select * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b",
+			counts:    map[string]int64{"t1": 1000000, "t2": 1000, "t3": 10},
+			threshold: 8,
+			best:      "LeftHashJoin{Table(t)->LeftHashJoin{Table(t)->Table(t)}(t2.b,t3.b)}(t1.a,t2.a)->Projection",
+		},
+		{
+			sql:       "select * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b",
+			counts:    map[string]int64{"t1": 10, "t2": 1000, "t3": 1000000},
+			threshold: 8,
+			best:      "RightHashJoin{RightHashJoin{Table(t)->Table(t)}(t1.a,t2.a)->Table(t)}(t2.b,t3.b)->Projection",
+		},
+		// The selective conditions make the table small.
+		{
+			sql:       "select * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b and t3.c = 1 and t3.d = 1",
+			counts:    map[string]int64{"t1": 1000, "t2": 1000, "t3": 1000},
+			threshold: 8,
+			best:      "RightHashJoin{Table(t)->LeftHashJoin{Table(t)->Index(t.c_d_e)[[1 1,1 1]]}(t2.b,t3.b)}(t1.a,t2.a)->Projection",
+		},
+		// The join group is larger than the threshold, it's reordered by the greedy solver.
+		{
+			sql:       "select * from t t1, t t2, t t3 where t1.a = t2.a and t2.b = t3.b",
+			counts:    map[string]int64{"t1": 1000000, "t2": 1000, "t3": 10},
+			threshold: 2,
+			best:      "LeftHashJoin{LeftHashJoin{Table(t)->Table(t)}(t1.a,t2.a)->Table(t)}(t2.b,t3.b)->Projection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		ast.SetFlag(stmt)
+
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+
+		ctx := mock.NewContext()
+		variable.BindSessionVars(ctx)
+		err = variable.GetSessionVars(ctx).SetSystemVar("tidb_join_reorder_threshold", types.NewIntDatum(int64(ca.threshold)))
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       ctx,
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		setTableCounts(lp, ca.counts)
+
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		_, err = lp.PruneColumnsAndResolveIndices(lp.GetSchema())
+		c.Assert(err, IsNil)
+		_, res, _, err := lp.convert2PhysicalPlan(nil)
+		c.Assert(err, IsNil)
+		p = res.p.PushLimit(nil)
+		c.Assert(ToString(p), Equals, ca.best, comment)
+	}
+}
+
+// setTableCounts sets the row counts in the statistics of the data sources by their alias names.
+func setTableCounts(p LogicalPlan, counts map[string]int64) {
+	if ds, ok := p.(*DataSource); ok && ds.TableAsName != nil {
+		if count, ok := counts[ds.TableAsName.L]; ok {
+			ds.statisticTable.Count = count
+		}
+	}
+	for _, child := range p.GetChildren() {
+		setTableCounts(child.(LogicalPlan), counts)
+	}
+}
+
 func (s *testPlanSuite) TestCBO(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
@@ -1124,3 +1206,268 @@ func (s *testPlanSuite) TestConstantPropagation(c *C) {
 		c.Assert(strings.Join(result, ", "), Equals, ca.after, Commentf("for %s", ca.sql))
 	}
 }
+
+func (s *testPlanSuite) TestOuterJoinElimination(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select t1.b from t t1 left join t t2 on t1.c = t2.a",
+			best: "DataScan(t)->Projection",
+		},
+		{
+			sql:  "select t2.b from t t1 right join t t2 on t1.a = t2.c and t1.d > 1",
+			best: "DataScan(t)->Projection",
+		},
+		{
+			sql:  "select count(*) from t t1 left join t t2 on t1.c = t2.a",
+			best: "DataScan(t)->Aggr->Projection",
+		},
+		{
+			sql:  "select t1.b from t t1 left join t t2 on t1.c = t2.a left join t t3 on t2.b = t3.a",
+			best: "DataScan(t)->Projection",
+		},
+		{
+			sql:  "select t2.c from t t1 left join t t2 on t1.c = t2.a left join t t3 on t2.b = t3.a",
+			best: "Join{DataScan(t)->DataScan(t)}->Projection",
+		},
+		// The inner plan contributes columns.
+		{
+			sql:  "select t1.b, t2.c from t t1 left join t t2 on t1.c = t2.a",
+			best: "Join{DataScan(t)->DataScan(t)}->Projection",
+		},
+		{
+			sql:  "select t1.b from t t1 left join t t2 on t1.c = t2.a where t2.b is null",
+			best: "Join{DataScan(t)->DataScan(t)}->Selection->Projection",
+		},
+		// The inner plan isn't joined on a unique key.
+		{
+			sql:  "select t1.b from t t1 left join t t2 on t1.c = t2.b",
+			best: "Join{DataScan(t)->DataScan(t)}->Projection",
+		},
+		{
+			sql:  "select t1.b from t t1 join t t2 on t1.c = t2.a",
+			best: "Join{DataScan(t)->DataScan(t)}->Projection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		ast.SetFlag(stmt)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp, err = eliminateOuterJoin(lp, lp.GetSchema())
+		c.Assert(err, IsNil)
+		_, err = lp.PruneColumnsAndResolveIndices(lp.GetSchema())
+		c.Assert(err, IsNil)
+		c.Assert(ToString(lp), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestAggPushDownThroughJoin(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "select sum(t1.b) from t t1, t t2 where t1.c = t2.c",
+			best: "Join{DataScan(t)->Aggr->DataScan(t)}->Aggr->Projection",
+		},
+		{
+			sql:  "select t2.d, count(*), avg(t2.b) from t t1 join t t2 on t1.c = t2.c group by t2.d",
+			best: "Join{DataScan(t)->DataScan(t)->Aggr}->Aggr->Projection",
+		},
+		{
+			sql:  "select max(t1.b), min(t1.e) from t t1 left join t t2 on t1.c = t2.c and t2.d > 1 group by t2.e",
+			best: "Join{DataScan(t)->Aggr->DataScan(t)->Selection}->Aggr->Projection",
+		},
+		// The arguments come from both sides.
+		{
+			sql:  "select sum(t1.b), sum(t2.b) from t t1, t t2 where t1.c = t2.c",
+			best: "Join{DataScan(t)->DataScan(t)}->Aggr->Projection",
+		},
+		// The inner side of an outer join.
+		{
+			sql:  "select sum(t2.b) from t t1 left join t t2 on t1.c = t2.c",
+			best: "Join{DataScan(t)->DataScan(t)}->Aggr->Projection",
+		},
+		{
+			sql:  "select count(distinct t1.b) from t t1, t t2 where t1.c = t2.c",
+			best: "Join{DataScan(t)->DataScan(t)}->Aggr->Projection",
+		},
+		{
+			sql:  "select sum(t1.b) from t t1, t t2 where t1.c = t2.c group by t1.d + 1",
+			best: "Join{DataScan(t)->DataScan(t)}->Aggr->Projection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		ast.SetFlag(stmt)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		err = pushDownAggregation(lp)
+		c.Assert(err, IsNil)
+		_, err = lp.PruneColumnsAndResolveIndices(lp.GetSchema())
+		c.Assert(err, IsNil)
+		c.Assert(ToString(lp), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestExplainPlan(c *C) {
+	defer testleak.AfterTest(c)()
+	// EXPLAIN shows the plan optimized by the same rules as the executed one.
+	cases := []struct {
+		sql  string
+		best string
+	}{
+		{
+			sql:  "explain select t1.b from t t1 left join t t2 on t1.c = t2.a",
+			best: "Table(t)->Projection",
+		},
+		{
+			sql:  "explain select sum(t1.b) from t t1, t t2 where t1.c = t2.c",
+			best: "RightHashJoin{Table(t)->Aggr->Table(t)}(t1.c,t2.c)->Aggr->Projection",
+		},
+		{
+			sql:  "explain select * from t where c > 10 and c < 5",
+			best: "*plan.TableDual->Projection",
+		},
+		{
+			sql:  "explain select * from t where c = b and b = 5 and c >= 5",
+			best: "Index(t.c_d_e)[[5,5]]->Selection->Projection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		ast.SetFlag(stmt)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		c.Assert(ToString(p.(*Explain).StmtPlan), Equals, ca.best, comment)
+	}
+}
+
+func (s *testPlanSuite) TestPredicateSimplification(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql   string
+		conds string
+		best  string
+	}{
+		{
+			sql:   "select * from t where c > 5 and c > 10 and 10 < c and b < 3 and c < 20",
+			conds: "gt(test.t.c, 10), lt(test.t.c, 20), lt(test.t.b, 3)",
+			best:  "Index(t.c_d_e)[(10,20)]->Selection->Projection",
+		},
+		{
+			sql:   "select * from t where c = b and b = 5 and c >= 5 and 1 = 1",
+			conds: "eq(test.t.c, 5), eq(test.t.b, 5)",
+			best:  "Index(t.c_d_e)[[5,5]]->Selection->Projection",
+		},
+		{
+			sql:   "select * from t where (c > 1 and c < 0) or b = 1",
+			conds: "eq(test.t.b, 1)",
+			best:  "Table(t)->Selection->Projection",
+		},
+		{
+			sql:   "select * from t where c > 1 or (b = 1 and 1 = 1)",
+			conds: "or(gt(test.t.c, 1), eq(test.t.b, 1))",
+			best:  "Table(t)->Selection->Projection",
+		},
+		{
+			sql:  "select * from t where c > 10 and c < 5",
+			best: "*plan.TableDual->Projection",
+		},
+		{
+			sql:  "select * from t where c = b and b = 5 and c > 5",
+			best: "*plan.TableDual->Projection",
+		},
+		{
+			sql:  "select * from t where c = 1 and c = 2",
+			best: "*plan.TableDual->Projection",
+		},
+		{
+			sql:  "select * from t where c = null",
+			best: "*plan.TableDual->Projection",
+		},
+		{
+			sql:  "select * from t where (c > 1 and c < 0) or 1 = 0",
+			best: "*plan.TableDual->Projection",
+		},
+		{
+			// The string constant of a numeric column is compared as a number after the column is replaced.
+			sql:   "select * from t where d = 1 and d = '1.0' and d > 0",
+			conds: "eq(test.t.d, 1)",
+			best:  "Table(t)->Selection->Projection",
+		},
+		{
+			sql:  "select count(*) from t t1, t t2 where t1.a = t2.a and t1.c > 3 and t2.c < 3 and t1.c = t2.c",
+			best: "LeftHashJoin{*plan.TableDual->*plan.TableDual}(t1.a,t2.a)(t1.c,t2.c)->Aggr->Projection",
+		},
+	}
+	for _, ca := range cases {
+		comment := Commentf("for %s", ca.sql)
+		stmt, err := s.ParseOneStmt(ca.sql, "", "")
+		c.Assert(err, IsNil, comment)
+		ast.SetFlag(stmt)
+		err = mockResolve(stmt)
+		c.Assert(err, IsNil)
+		builder := &planBuilder{
+			allocator: new(idAllocator),
+			ctx:       mock.NewContext(),
+			colMapper: make(map[*ast.ColumnNameExpr]int),
+		}
+		p := builder.build(stmt)
+		c.Assert(builder.err, IsNil)
+		lp := p.(LogicalPlan)
+		_, lp, err = lp.PredicatePushDown(nil)
+		c.Assert(err, IsNil)
+		lp, err = simplifyPredicates(lp)
+		c.Assert(err, IsNil)
+		if ca.conds != "" {
+			var result []string
+			for _, cond := range lp.GetChildByIndex(0).(*Selection).Conditions {
+				result = append(result, cond.String())
+			}
+			c.Assert(strings.Join(result, ", "), Equals, ca.conds, comment)
+		}
+		_, err = lp.PruneColumnsAndResolveIndices(lp.GetSchema())
+		c.Assert(err, IsNil)
+		_, res, _, err := lp.convert2PhysicalPlan(nil)
+		c.Assert(err, IsNil)
+		p = res.p.PushLimit(nil)
+		c.Assert(ToString(p), Equals, ca.best, comment)
+	}
+}
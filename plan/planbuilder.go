@@ -59,6 +59,10 @@ type planBuilder struct {
 	outerSchemas []expression.Schema
 	// colMapper stores the column that must be pre-resolved.
 	colMapper map[*ast.ColumnNameExpr]int
+	// inPlanCache means the plan is built to be cached, the parameter markers are kept as placeholders.
+	inPlanCache bool
+	// params stores the placeholders of the parameter markers.
+	params []*expression.Constant
 }
 
 func (b *planBuilder) build(node ast.Node) Plan {
@@ -71,6 +75,8 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildSimple(x)
 	case *ast.BinlogStmt:
 		return b.buildSimple(x)
+	case *ast.CreateBindingStmt:
+		return b.buildSimple(x)
 	case *ast.CreateDatabaseStmt:
 		return b.buildDDL(x)
 	case *ast.CreateIndexStmt:
@@ -81,6 +87,8 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return &Deallocate{Name: x.Name}
 	case *ast.DeleteStmt:
 		return b.buildDelete(x)
+	case *ast.DropBindingStmt:
+		return b.buildSimple(x)
 	case *ast.DropDatabaseStmt:
 		return b.buildDDL(x)
 	case *ast.DropIndexStmt:
@@ -107,6 +115,8 @@ func (b *planBuilder) build(node ast.Node) Plan {
 		return b.buildUpdate(x)
 	case *ast.UseStmt:
 		return b.buildSimple(x)
+	case *ast.KillStmt:
+		return b.buildSimple(x)
 	case *ast.SetStmt:
 		return b.buildSimple(x)
 	case *ast.ShowStmt:
@@ -400,6 +410,9 @@ func (b *planBuilder) buildShow(show *ast.ShowStmt) Plan {
 		User:   show.User,
 	}
 	switch show.Tp {
+	case ast.ShowBindings:
+		p.(*Show).GlobalScope = show.GlobalScope
+		p.SetFields(show.GetResultFields())
 	case ast.ShowProcedureStatus:
 		p.SetFields(buildShowProcedureFields())
 	case ast.ShowTriggers:
@@ -473,23 +486,12 @@ func (b *planBuilder) buildExplain(explain *ast.ExplainStmt) Plan {
 		return nil
 	}
 	if logic, ok := targetPlan.(LogicalPlan); ok {
-		var err error
-		_, logic, err = logic.PredicatePushDown(nil)
-		if err != nil {
-			b.err = errors.Trace(err)
-			return nil
-		}
-		_, err = logic.PruneColumnsAndResolveIndices(logic.GetSchema())
-		if err != nil {
-			b.err = errors.Trace(err)
-			return nil
-		}
-		_, res, _, err := logic.convert2PhysicalPlan(nil)
+		phy, err := doOptimize(logic)
 		if err != nil {
 			b.err = errors.Trace(err)
 			return nil
 		}
-		targetPlan = res.p.PushLimit(nil)
+		targetPlan = phy
 	}
 	p := &Explain{StmtPlan: targetPlan}
 	addChild(p, targetPlan)
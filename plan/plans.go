@@ -169,7 +169,7 @@ type Show struct {
 	Full   bool
 	User   string // Used for show grants.
 
-	// Used by show variables
+	// Used by show variables and show bindings.
 	GlobalScope bool
 }
 
@@ -0,0 +1,444 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// PointGetPlan reads the rows of a table by the integer handles or by the values of
+// a unique index directly from the kv storage, the rows are not filtered any more.
+type PointGetPlan struct {
+	basePlan
+
+	Table       *model.TableInfo
+	TableAsName *model.CIStr
+	// IndexInfo is the unique index used to get the rows, it is nil if the rows
+	// are got by Handles.
+	IndexInfo   *model.IndexInfo
+	Handles     []int64
+	IndexValues [][]types.Datum
+	// Columns are the columns of the output rows, a column may appear more than once.
+	Columns []*model.ColumnInfo
+}
+
+// TryFastPlan tries to build a point get plan for the statements that only read or
+// write the rows with the given handles or unique index values, most of the optimizer
+// is skipped for them. It returns nil if the statement doesn't have such a shape.
+// The node must be prepared and its types must be inferred.
+func TryFastPlan(ctx context.Context, node ast.Node, is infoschema.InfoSchema) Plan {
+	builder := &planBuilder{
+		ctx:       ctx,
+		is:        is,
+		colMapper: make(map[*ast.ColumnNameExpr]int),
+		allocator: new(idAllocator)}
+	var p Plan
+	switch x := node.(type) {
+	case *ast.SelectStmt:
+		p = builder.tryPointGetSelect(x)
+	case *ast.UpdateStmt:
+		p = builder.tryPointGetUpdate(x)
+	case *ast.DeleteStmt:
+		p = builder.tryPointGetDelete(x)
+	}
+	if builder.err != nil {
+		return nil
+	}
+	return p
+}
+
+func (b *planBuilder) tryPointGetSelect(sel *ast.SelectStmt) Plan {
+	if sel.Distinct || sel.GroupBy != nil || sel.Having != nil || sel.OrderBy != nil ||
+		sel.Limit != nil || sel.LockTp != ast.SelectLockNone || sel.From == nil {
+		return nil
+	}
+	ds := b.buildPointGetSource(sel.From.TableRefs)
+	if ds == nil {
+		return nil
+	}
+	p := b.newPointGetPlan(ds, sel.Where)
+	if p == nil {
+		return nil
+	}
+	schema := make(expression.Schema, 0, len(sel.Fields.Fields))
+	for _, field := range sel.Fields.Fields {
+		if field.WildCard != nil {
+			n := len(schema)
+			for i, col := range ds.GetSchema() {
+				if (field.WildCard.Schema.L == "" || field.WildCard.Schema.L == col.DBName.L) &&
+					(field.WildCard.Table.L == "" || field.WildCard.Table.L == col.TblName.L) {
+					p.Columns = append(p.Columns, ds.Columns[i])
+					schema = append(schema, &expression.Column{
+						FromID:  p.id,
+						TblName: col.TblName,
+						ColName: col.ColName,
+						RetType: col.RetType})
+				}
+			}
+			if len(schema) == n {
+				// Let the normal path report the unknown table.
+				return nil
+			}
+			continue
+		}
+		astCol, ok := getInnerFromParentheses(field.Expr).(*ast.ColumnNameExpr)
+		if !ok {
+			return nil
+		}
+		col, err := ds.GetSchema().FindColumn(astCol.Name)
+		if err != nil || col == nil {
+			return nil
+		}
+		colName := astCol.Name.Name
+		if field.AsName.L != "" {
+			colName = field.AsName
+		}
+		p.Columns = append(p.Columns, ds.Columns[ds.GetSchema().GetIndex(col)])
+		schema = append(schema, &expression.Column{
+			FromID:  p.id,
+			TblName: astCol.Name.Table,
+			ColName: colName,
+			RetType: col.RetType})
+	}
+	for i, col := range schema {
+		col.Position = i
+	}
+	schema.InitIndices()
+	p.SetSchema(schema)
+	return p
+}
+
+func (b *planBuilder) tryPointGetUpdate(update *ast.UpdateStmt) Plan {
+	if update.MultipleTable || update.Order != nil || update.Limit != nil {
+		return nil
+	}
+	ds := b.buildPointGetSource(update.TableRefs.TableRefs)
+	if ds == nil {
+		return nil
+	}
+	p := b.newPointGetPlan(ds, update.Where)
+	if p == nil {
+		return nil
+	}
+	orderedList, np := b.buildUpdateLists(update.List, ds)
+	if b.err != nil || np != ds {
+		// The assignments have subqueries.
+		return nil
+	}
+	p.Columns = ds.Columns
+	schema := ds.GetSchema()
+	schema.InitIndices()
+	p.SetSchema(schema)
+	for _, assign := range orderedList {
+		if assign == nil {
+			continue
+		}
+		assign.Col.Index = schema.GetIndex(assign.Col)
+		initColumnIndexInExpr(assign.Expr, schema)
+	}
+	updt := &Update{OrderedList: orderedList, SelectPlan: p, baseLogicalPlan: newBaseLogicalPlan(Up, b.allocator)}
+	updt.initID()
+	addChild(updt, p)
+	updt.SetSchema(schema)
+	return updt
+}
+
+func (b *planBuilder) tryPointGetDelete(delete *ast.DeleteStmt) Plan {
+	if delete.IsMultiTable || delete.Order != nil || delete.Limit != nil {
+		return nil
+	}
+	ds := b.buildPointGetSource(delete.TableRefs.TableRefs)
+	if ds == nil {
+		return nil
+	}
+	p := b.newPointGetPlan(ds, delete.Where)
+	if p == nil {
+		return nil
+	}
+	p.Columns = ds.Columns
+	schema := ds.GetSchema()
+	schema.InitIndices()
+	p.SetSchema(schema)
+	del := &Delete{SelectPlan: p, baseLogicalPlan: newBaseLogicalPlan(Del, b.allocator)}
+	del.initID()
+	addChild(del, p)
+	return del
+}
+
+// buildPointGetSource builds the data source if the statement reads a single table
+// of the kv storage.
+func (b *planBuilder) buildPointGetSource(refs *ast.Join) *DataSource {
+	if refs == nil || refs.Right != nil {
+		return nil
+	}
+	ts, ok := refs.Left.(*ast.TableSource)
+	if !ok {
+		return nil
+	}
+	tn, ok := ts.Source.(*ast.TableName)
+	if !ok {
+		return nil
+	}
+	switch tn.Schema.L {
+	case "information_schema", "performance_schema":
+		return nil
+	}
+	ds, _ := b.buildResultSetNode(ts).(*DataSource)
+	if b.err != nil {
+		return nil
+	}
+	return ds
+}
+
+// newPointGetPlan returns the point get plan of the data source if the where condition only
+// has equal or in conditions on the integer handle or on all the columns of a unique index.
+func (b *planBuilder) newPointGetPlan(ds *DataSource, where ast.ExprNode) *PointGetPlan {
+	conds := extractPointConds(where)
+	if len(conds) == 0 {
+		return nil
+	}
+	p := &PointGetPlan{
+		basePlan:    basePlan{tp: PointGet, allocator: b.allocator},
+		Table:       ds.Table,
+		TableAsName: ds.TableAsName,
+	}
+	p.initID()
+	if ds.Table.PKIsHandle && len(conds) == 1 && mysql.HasPriKeyFlag(conds[0].col.Flag) {
+		handles := make([]int64, 0, len(conds[0].values))
+		seen := make(map[int64]struct{}, len(conds[0].values))
+		for _, v := range conds[0].values {
+			d, ok := pointValue(conds[0].col, v)
+			if !ok {
+				// No row can match the value.
+				continue
+			}
+			h := d.GetInt64()
+			if mysql.HasUnsignedFlag(conds[0].col.Flag) {
+				h = int64(d.GetUint64())
+			}
+			if _, ok := seen[h]; ok {
+				continue
+			}
+			seen[h] = struct{}{}
+			handles = append(handles, h)
+		}
+		sort.Sort(int64Slice(handles))
+		p.Handles = handles
+		return p
+	}
+	idx := findPointGetIndex(ds.Table, conds)
+	if idx == nil {
+		return nil
+	}
+	if len(idx.Columns) == 1 {
+		for _, v := range conds[0].values {
+			d, ok := pointValue(conds[0].col, v)
+			if !ok {
+				continue
+			}
+			p.IndexValues = append(p.IndexValues, []types.Datum{d})
+		}
+	} else {
+		values := make([]types.Datum, len(idx.Columns))
+		for _, cond := range conds {
+			d, ok := pointValue(cond.col, cond.values[0])
+			if !ok {
+				// The where condition is always false.
+				p.IndexInfo = idx
+				return p
+			}
+			for i, ic := range idx.Columns {
+				if ic.Name.L == cond.col.Name.L {
+					values[i] = d
+				}
+			}
+		}
+		p.IndexValues = [][]types.Datum{values}
+	}
+	p.IndexInfo = idx
+	return p
+}
+
+// findPointGetIndex finds the public unique index whose columns are exactly the columns of conds.
+// Only a single column index can be used with an in condition.
+func findPointGetIndex(tbl *model.TableInfo, conds []pointCond) *model.IndexInfo {
+	if len(conds) > 1 {
+		for _, cond := range conds {
+			if cond.in {
+				return nil
+			}
+		}
+	}
+	for _, idx := range tbl.Indices {
+		if !idx.Unique || idx.State != model.StatePublic || len(idx.Columns) != len(conds) {
+			continue
+		}
+		matched := true
+		for _, ic := range idx.Columns {
+			if ic.Length != types.UnspecifiedLength {
+				matched = false
+				break
+			}
+			found := false
+			for _, cond := range conds {
+				if cond.col.Name.L == ic.Name.L {
+					found = true
+					break
+				}
+			}
+			if !found {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return idx
+		}
+	}
+	return nil
+}
+
+// pointCond is an equal condition or an in condition on a column.
+type pointCond struct {
+	col    *model.ColumnInfo
+	values []types.Datum
+	in     bool
+}
+
+// extractPointConds extracts the conditions of the where clause, it returns nil if any
+// of the conditions is not an equal or in condition between a column and constants, or
+// a column appears in more than one condition.
+func extractPointConds(where ast.ExprNode) []pointCond {
+	conditions := splitWhere(where)
+	conds := make([]pointCond, 0, len(conditions))
+	for _, expr := range conditions {
+		var cond pointCond
+		switch x := getInnerFromParentheses(expr).(type) {
+		case *ast.BinaryOperationExpr:
+			if x.Op != opcode.EQ {
+				return nil
+			}
+			col, val := pointColumn(x.L), pointConst(x.R)
+			if col == nil || val == nil {
+				col, val = pointColumn(x.R), pointConst(x.L)
+			}
+			if col == nil || val == nil || !pointComparable(col, *val) {
+				return nil
+			}
+			cond = pointCond{col: col, values: []types.Datum{*val}}
+		case *ast.PatternInExpr:
+			col := pointColumn(x.Expr)
+			if x.Not || x.Sel != nil || col == nil {
+				return nil
+			}
+			cond = pointCond{col: col, in: true}
+			for _, item := range x.List {
+				val := pointConst(item)
+				if val == nil || !pointComparable(col, *val) {
+					return nil
+				}
+				cond.values = append(cond.values, *val)
+			}
+		default:
+			return nil
+		}
+		for _, c := range conds {
+			if c.col.Name.L == cond.col.Name.L {
+				return nil
+			}
+		}
+		conds = append(conds, cond)
+	}
+	return conds
+}
+
+func pointColumn(expr ast.ExprNode) *model.ColumnInfo {
+	col, ok := getInnerFromParentheses(expr).(*ast.ColumnNameExpr)
+	if !ok || col.Refer == nil {
+		return nil
+	}
+	return col.Refer.Column
+}
+
+func pointConst(expr ast.ExprNode) *types.Datum {
+	switch x := getInnerFromParentheses(expr).(type) {
+	case *ast.ValueExpr:
+		return x.GetDatum()
+	case *ast.ParamMarkerExpr:
+		return x.GetDatum()
+	}
+	return nil
+}
+
+// pointComparable checks whether the constant can be compared with the values of the column
+// without conversion, only the integer and the variable length string columns are supported.
+func pointComparable(col *model.ColumnInfo, val types.Datum) bool {
+	switch col.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		return val.Kind() == types.KindInt64 || val.Kind() == types.KindUint64
+	case mysql.TypeVarchar, mysql.TypeVarString:
+		return val.Kind() == types.KindString || val.Kind() == types.KindBytes
+	}
+	return false
+}
+
+// pointValue converts the constant to the value stored for the column. The second return
+// value is false if no row can match the constant. The constant must be checked by
+// pointComparable first.
+func pointValue(col *model.ColumnInfo, val types.Datum) (types.Datum, bool) {
+	var d types.Datum
+	switch col.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		unsigned := mysql.HasUnsignedFlag(col.Flag)
+		switch val.Kind() {
+		case types.KindInt64:
+			if unsigned {
+				if val.GetInt64() < 0 {
+					return d, false
+				}
+				d.SetUint64(uint64(val.GetInt64()))
+			} else {
+				d.SetInt64(val.GetInt64())
+			}
+		case types.KindUint64:
+			if unsigned {
+				d.SetUint64(val.GetUint64())
+			} else {
+				if val.GetUint64() > math.MaxInt64 {
+					return d, false
+				}
+				d.SetInt64(int64(val.GetUint64()))
+			}
+		}
+	case mysql.TypeVarchar, mysql.TypeVarString:
+		d.SetString(val.GetString())
+	}
+	return d, true
+}
+
+type int64Slice []int64
+
+func (s int64Slice) Len() int           { return len(s) }
+func (s int64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -111,6 +111,11 @@ func propagateConstant(conditions []expression.Expression) []expression.Expressi
 				} else {
 					continue
 				}
+				// The constant takes the place of the column only if they are compared in the same way,
+				// e.g. "s = 1 and s = '1.0'" must not become "s = 1 and 1 = '1.0'" for a string column s.
+				if !comparableWithColumn(col, val) {
+					continue
+				}
 				equalities[string(col.HashCode())] = val
 				isSource[i] = true
 				getOneEquality = true
@@ -307,9 +312,15 @@ func (p *Join) PredicatePushDown(predicates []expression.Expression) (ret []expr
 	}
 	groups, valid := tryToGetJoinGroup(p)
 	if valid {
-		e := joinReOrderSolver{allocator: p.allocator}
-		e.reorderJoin(groups, predicates)
-		newJoin := e.resultJoin
+		var newJoin LogicalPlan
+		if len(groups) <= joinReorderThreshold(p.ctx) {
+			e := joinReorderDPSolver{allocator: p.allocator}
+			newJoin = e.reorderJoinByDP(groups, predicates)
+		} else {
+			e := joinReOrderSolver{allocator: p.allocator}
+			e.reorderJoin(groups, predicates)
+			newJoin = e.resultJoin
+		}
 		parent := p.parents[0]
 		newJoin.SetParents(parent)
 		parent.ReplaceChild(p, newJoin)
@@ -353,9 +364,14 @@ func (p *Join) PredicatePushDown(predicates []expression.Expression) (ret []expr
 		ret = append(ret, leftPushCond...)
 	case SemiJoin:
 		equalCond, leftPushCond, rightPushCond, otherCond = extractOnCondition(predicates, leftPlan, rightPlan)
-		leftCond = propagateConstant(append(p.LeftConditions, leftPushCond...))
+		if p.anti {
+			// The left conditions of anti semi join decide which rows are not matched, so they can't be pushed down.
+			leftCond = propagateConstant(leftPushCond)
+		} else {
+			leftCond = propagateConstant(append(p.LeftConditions, leftPushCond...))
+			p.LeftConditions = nil
+		}
 		rightCond = propagateConstant(append(p.RightConditions, rightPushCond...))
-		p.LeftConditions = nil
 		p.RightConditions = nil
 	case InnerJoin:
 		p.LeftConditions = nil
@@ -454,7 +470,7 @@ func isNullRejected(schema expression.Schema, expr expression.Expression) (bool,
 		return false, errors.Trace(err)
 	}
 	x, ok := result.(*expression.Constant)
-	if !ok {
+	if !ok || x.ParamMarker != nil {
 		return false, nil
 	}
 	if x.Value.IsNull() {
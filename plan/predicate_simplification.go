@@ -0,0 +1,331 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// After the predicates are pushed down and the constants are propagated, the conditions of a selection may contain
+// constant conditions and several comparisons between one column and constants. The constant conditions that are
+// always true are removed, and the comparisons of a column are folded to the tightest ones, e.g. a > 5 and a > 10
+// becomes a > 10. If the conditions can never be satisfied, e.g. a > 10 and a < 5, the selection and its child are
+// replaced by a dual table that returns nothing. This runs before the ranges are built, so the access conditions
+// of the scans are as simple as possible.
+
+// simplifyPredicates simplifies the conditions of the selections and inner joins in p.
+// It returns the plan that takes the place of p.
+func simplifyPredicates(p LogicalPlan) (LogicalPlan, error) {
+	switch x := p.(type) {
+	case *Selection:
+		conditions, alwaysFalse, err := simplifyConditions(x.Conditions)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if alwaysFalse {
+			return replaceWithEmptyDual(x, x.allocator)
+		}
+		if len(conditions) == 0 {
+			child := x.GetChildByIndex(0).(LogicalPlan)
+			err = RemovePlan(x)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return simplifyPredicates(child)
+		}
+		x.Conditions = conditions
+	case *Join:
+		if x.JoinType == InnerJoin {
+			conditions, alwaysFalse, err := simplifyConditions(x.OtherConditions)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if alwaysFalse {
+				return replaceWithEmptyDual(x, x.allocator)
+			}
+			x.OtherConditions = conditions
+		}
+	}
+	for _, child := range p.GetChildren() {
+		_, err := simplifyPredicates(child.(LogicalPlan))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return p, nil
+}
+
+// replaceWithEmptyDual replaces p and its children by a dual table that has the same schema and returns no rows.
+func replaceWithEmptyDual(p LogicalPlan, allocator *idAllocator) (LogicalPlan, error) {
+	dual := &TableDual{baseLogicalPlan: newBaseLogicalPlan(Dual, allocator), Empty: true}
+	dual.initID()
+	dual.SetSchema(p.GetSchema())
+	dual.correlated = p.IsCorrelated()
+	parents := p.GetParents()
+	if len(parents) > 1 {
+		return nil, SystemInternalErrorType.Gen("can't replace this plan")
+	}
+	if len(parents) == 1 {
+		err := parents[0].ReplaceChild(p, dual)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		dual.SetParents(parents[0])
+	}
+	return dual, nil
+}
+
+// columnBound is a comparison between a column and a constant, the column is always the first argument.
+type columnBound struct {
+	cond  expression.Expression
+	value types.Datum
+	// strict is true for < and >.
+	strict bool
+}
+
+// columnBounds records the tightest comparisons of a column.
+type columnBounds struct {
+	eq    *columnBound
+	lower *columnBound
+	upper *columnBound
+}
+
+// reversedCompareFuncs maps a comparison to the one that gets the same result after swapping the arguments.
+var reversedCompareFuncs = map[string]string{
+	ast.EQ: ast.EQ,
+	ast.LT: ast.GT,
+	ast.LE: ast.GE,
+	ast.GT: ast.LT,
+	ast.GE: ast.LE,
+}
+
+// simplifyConditions removes the conditions that are always true and folds the comparisons between a column and
+// constants in the CNF conditions. alwaysFalse is true if the conditions can never be satisfied.
+func simplifyConditions(conditions []expression.Expression) (result []expression.Expression, alwaysFalse bool, err error) {
+	var (
+		colOrder []string
+		bounds   = make(map[string]*columnBounds)
+	)
+	for _, cond := range conditions {
+		switch x := cond.(type) {
+		case *expression.Constant:
+			if x.ParamMarker != nil {
+				result = append(result, cond)
+				continue
+			}
+			var ok bool
+			ok, err = expression.EvalBool(x, nil, nil)
+			if err != nil {
+				return nil, false, errors.Trace(err)
+			}
+			if !ok {
+				return nil, true, nil
+			}
+			continue
+		case *expression.ScalarFunction:
+			if x.FuncName.L == ast.OrOr {
+				var newCond expression.Expression
+				newCond, alwaysFalse, err = simplifyDNFCondition(x)
+				if err != nil || alwaysFalse {
+					return nil, alwaysFalse, errors.Trace(err)
+				}
+				if newCond != nil {
+					result = append(result, newCond)
+				}
+				continue
+			}
+			col, con, funcName := extractColumnComparison(x)
+			if col == nil {
+				break
+			}
+			// A comparison with NULL is never true.
+			if con.Value.IsNull() {
+				return nil, true, nil
+			}
+			if !comparableWithColumn(col, con) {
+				break
+			}
+			key := string(col.HashCode())
+			b, ok := bounds[key]
+			if !ok {
+				b = &columnBounds{}
+				bounds[key] = b
+				colOrder = append(colOrder, key)
+			}
+			alwaysFalse, err = b.add(&columnBound{cond: cond, value: con.Value, strict: funcName == ast.LT || funcName == ast.GT}, funcName)
+			if err != nil || alwaysFalse {
+				return nil, alwaysFalse, errors.Trace(err)
+			}
+			continue
+		}
+		result = append(result, cond)
+	}
+	for _, key := range colOrder {
+		var conds []expression.Expression
+		conds, alwaysFalse, err = bounds[key].conditions()
+		if err != nil || alwaysFalse {
+			return nil, alwaysFalse, errors.Trace(err)
+		}
+		result = append(result, conds...)
+	}
+	return result, false, nil
+}
+
+// simplifyDNFCondition simplifies every item of a DNF condition. It returns nil if the condition is always true.
+func simplifyDNFCondition(cond expression.Expression) (expression.Expression, bool, error) {
+	var items []expression.Expression
+	for _, item := range expression.SplitDNFItems(cond) {
+		conds, alwaysFalse, err := simplifyConditions(expression.SplitCNFItems(item))
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if alwaysFalse {
+			continue
+		}
+		if len(conds) == 0 {
+			return nil, false, nil
+		}
+		items = append(items, expression.ComposeCNFCondition(conds))
+	}
+	if len(items) == 0 {
+		return nil, true, nil
+	}
+	return expression.ComposeDNFCondition(items), false, nil
+}
+
+// extractColumnComparison extracts the column and the constant of a comparison like "column op constant" or
+// "constant op column". The returned function name is the comparison when the column is the first argument.
+func extractColumnComparison(expr *expression.ScalarFunction) (*expression.Column, *expression.Constant, string) {
+	reversed, ok := reversedCompareFuncs[expr.FuncName.L]
+	if !ok {
+		return nil, nil, ""
+	}
+	if col, ok := expr.Args[0].(*expression.Column); ok && !col.Correlated {
+		if con, ok := expr.Args[1].(*expression.Constant); ok && con.ParamMarker == nil {
+			return col, con, expr.FuncName.L
+		}
+	}
+	if col, ok := expr.Args[1].(*expression.Column); ok && !col.Correlated {
+		if con, ok := expr.Args[0].(*expression.Constant); ok && con.ParamMarker == nil {
+			return col, con, reversed
+		}
+	}
+	return nil, nil, ""
+}
+
+// comparableWithColumn checks whether the constant is compared with the column in the column's comparison type, so
+// that the constants of the column can be compared with each other to fold the comparisons. Only numeric constants
+// of numeric columns and string constants of string columns are folded, because a string column compared with a
+// number is compared as a number, e.g. '1.0' = 1 is true but '1.0' = '1' is false.
+func comparableWithColumn(col *expression.Column, con *expression.Constant) bool {
+	switch con.Value.Kind() {
+	case types.KindInt64, types.KindUint64, types.KindFloat32, types.KindFloat64, types.KindMysqlDecimal:
+		switch col.RetType.Tp {
+		case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong,
+			mysql.TypeFloat, mysql.TypeDouble, mysql.TypeDecimal, mysql.TypeNewDecimal:
+			return true
+		}
+	case types.KindString:
+		switch col.RetType.Tp {
+		case mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString:
+			return true
+		}
+	}
+	return false
+}
+
+// add adds a comparison to the bounds, funcName is the comparison when the column is the first argument.
+// It returns true if the comparisons of the column can never be satisfied.
+func (b *columnBounds) add(bound *columnBound, funcName string) (bool, error) {
+	switch funcName {
+	case ast.EQ:
+		if b.eq == nil {
+			b.eq = bound
+			return false, nil
+		}
+		cmp, err := b.eq.value.CompareDatum(bound.value)
+		return cmp != 0, errors.Trace(err)
+	case ast.GT, ast.GE:
+		if b.lower == nil {
+			b.lower = bound
+			return false, nil
+		}
+		cmp, err := bound.value.CompareDatum(b.lower.value)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if cmp > 0 || (cmp == 0 && bound.strict && !b.lower.strict) {
+			b.lower = bound
+		}
+	case ast.LT, ast.LE:
+		if b.upper == nil {
+			b.upper = bound
+			return false, nil
+		}
+		cmp, err := bound.value.CompareDatum(b.upper.value)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if cmp < 0 || (cmp == 0 && bound.strict && !b.upper.strict) {
+			b.upper = bound
+		}
+	}
+	return false, nil
+}
+
+// conditions returns the folded comparisons of the column. It returns true if they can never be satisfied.
+func (b *columnBounds) conditions() ([]expression.Expression, bool, error) {
+	if b.eq != nil {
+		if b.lower != nil {
+			cmp, err := b.eq.value.CompareDatum(b.lower.value)
+			if err != nil {
+				return nil, false, errors.Trace(err)
+			}
+			if cmp < 0 || (cmp == 0 && b.lower.strict) {
+				return nil, true, nil
+			}
+		}
+		if b.upper != nil {
+			cmp, err := b.eq.value.CompareDatum(b.upper.value)
+			if err != nil {
+				return nil, false, errors.Trace(err)
+			}
+			if cmp > 0 || (cmp == 0 && b.upper.strict) {
+				return nil, true, nil
+			}
+		}
+		return []expression.Expression{b.eq.cond}, false, nil
+	}
+	var conds []expression.Expression
+	if b.lower != nil {
+		conds = append(conds, b.lower.cond)
+	}
+	if b.upper != nil {
+		conds = append(conds, b.upper.cond)
+	}
+	if b.lower != nil && b.upper != nil {
+		cmp, err := b.lower.value.CompareDatum(b.upper.value)
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if cmp > 0 || (cmp == 0 && (b.lower.strict || b.upper.strict)) {
+			return nil, true, nil
+		}
+	}
+	return conds, false, nil
+}
@@ -214,6 +214,15 @@ func (p *PhysicalIndexScan) PushLimit(l *Limit) PhysicalPlan {
 	return p
 }
 
+// PushLimit implements PhysicalPlan PushLimit interface.
+func (p *PhysicalIndexMerge) PushLimit(l *Limit) PhysicalPlan {
+	// The limit can't be pushed to the partial index scans, every handle is needed to merge.
+	if l != nil {
+		return insertLimit(p, l)
+	}
+	return p
+}
+
 // PushLimit implements PhysicalPlan PushLimit interface.
 func (p *PhysicalTableScan) PushLimit(l *Limit) PhysicalPlan {
 	if l != nil {
@@ -19,9 +19,11 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
 )
 
 var fullRange = []rangePoint{
@@ -219,6 +221,51 @@ func buildTableRange(p *PhysicalTableScan) error {
 	return errors.Trace(rb.err)
 }
 
+// rebuildRange rebuilds the ranges and the pushed down conditions of the scans in a
+// cached plan, whose parameters have been refreshed. The access conditions and pushed
+// down conditions are kept in the scans, so the optimization doesn't need to be done again.
+func rebuildRange(p Plan, client kv.Client) error {
+	var err error
+	switch x := p.(type) {
+	case *PhysicalTableScan:
+		err = buildTableRange(x)
+		if err == nil && len(x.pushedConditions) > 0 {
+			x.ConditionPBExpr, err = rebuildConditionPBExpr(x.pushedConditions, client)
+		}
+	case *PhysicalIndexScan:
+		err = buildIndexRange(x)
+		if err == nil && len(x.pushedConditions) > 0 {
+			x.ConditionPBExpr, err = rebuildConditionPBExpr(x.pushedConditions, client)
+		}
+	case *PhysicalIndexMerge:
+		for _, is := range x.IndexScans {
+			if err = buildIndexRange(is); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, child := range p.GetChildren() {
+		if err = rebuildRange(child, client); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func rebuildConditionPBExpr(conditions []expression.Expression, client kv.Client) (*tipb.Expr, error) {
+	pbExpr, _, remained, err := expressionsToPB(conditions, client)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(remained) > 0 {
+		return nil, errors.Errorf("condition %s can not be pushed down with the new parameters", remained[0])
+	}
+	return pbExpr, nil
+}
+
 // conditionChecker checks if this condition can be pushed to index plan.
 type conditionChecker struct {
 	tableName     model.CIStr
@@ -282,7 +329,8 @@ func (c *conditionChecker) checkLikeFunc(scalar *expression.ScalarFunction) bool
 		return false
 	}
 	pattern, ok := scalar.Args[1].(*expression.Constant)
-	if !ok {
+	// The pattern of a cached plan may change, whether it is an access condition can't be decided.
+	if !ok || pattern.ParamMarker != nil {
 		return false
 	}
 	if pattern.Value.IsNull() {
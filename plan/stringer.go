@@ -16,6 +16,8 @@ package plan
 import (
 	"fmt"
 	"strings"
+
+	"github.com/pingcap/tidb/util/types"
 )
 
 // ToString explains a Plan, returns description string.
@@ -42,8 +44,28 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 		str = fmt.Sprintf("Index(%s.%s)%v", x.Table.Name.L, x.Index.Name.L, x.Ranges)
 	case *PhysicalTableScan:
 		str = fmt.Sprintf("Table(%s)", x.Table.Name.L)
+	case *PhysicalIndexMerge:
+		partials := make([]string, 0, len(x.IndexScans))
+		for _, is := range x.IndexScans {
+			partials = append(partials, ToString(is))
+		}
+		if x.Intersection {
+			str = "IndexMergeIntersection{" + strings.Join(partials, ",") + "}"
+		} else {
+			str = "IndexMergeUnion{" + strings.Join(partials, ",") + "}"
+		}
 	case *PhysicalDummyScan:
 		str = "Dummy"
+	case *PointGetPlan:
+		if x.IndexInfo != nil {
+			values := make([][]interface{}, 0, len(x.IndexValues))
+			for _, vals := range x.IndexValues {
+				values = append(values, types.DatumsToInterfaces(vals))
+			}
+			str = fmt.Sprintf("PointGet(%s.%s)%v", x.Table.Name.L, x.IndexInfo.Name.L, values)
+		} else {
+			str = fmt.Sprintf("PointGet(%s)%v", x.Table.Name.L, x.Handles)
+		}
 	case *PhysicalHashJoin:
 		last := len(idxs) - 1
 		idx := idxs[last]
@@ -113,7 +135,11 @@ func toString(in Plan, strs []string, idxs []int) ([]string, []int) {
 	case *Projection:
 		str = "Projection"
 	case *Aggregation:
-		str = "Aggr"
+		if x.Streamed {
+			str = "StreamAgg"
+		} else {
+			str = "Aggr"
+		}
 	case *Distinct:
 		str = "Distinct"
 	case *Trim:
@@ -54,7 +54,10 @@ func (v *typeInferrer) Leave(in ast.Node) (out ast.Node, ok bool) {
 	case *ast.CaseExpr:
 		v.handleCaseExpr(x)
 	case *ast.ColumnNameExpr:
-		x.SetType(&x.Refer.Column.FieldType)
+		// The columns unfolded from the wildcard by the plan builder are not resolved, but their types are set.
+		if x.Refer != nil {
+			x.SetType(&x.Refer.Column.FieldType)
+		}
 	case *ast.CompareSubqueryExpr:
 		x.SetType(types.NewFieldType(mysql.TypeLonglong))
 		x.Type.Charset = charset.CharsetBin
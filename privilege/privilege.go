@@ -49,3 +49,13 @@ func GetPrivilegeChecker(ctx context.Context) Checker {
 	}
 	return nil
 }
+
+// CheckGlobal checks whether the current user has the global scope privilege.
+// A context without a Checker is not restricted.
+func CheckGlobal(ctx context.Context, privilege mysql.PrivilegeType) (bool, error) {
+	checker := GetPrivilegeChecker(ctx)
+	if checker == nil {
+		return true, nil
+	}
+	return checker.Check(ctx, nil, nil, privilege)
+}
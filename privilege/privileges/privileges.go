@@ -195,6 +195,9 @@ func (p *UserPrivileges) Check(ctx context.Context, db *model.DBInfo, tbl *model
 	if ok {
 		return true, nil
 	}
+	if db == nil {
+		return false, nil
+	}
 	// Check db scope privileges.
 	dbp, ok := p.privs.DBPrivs[db.Name.O]
 	if ok {
@@ -272,6 +275,10 @@ func (p *UserPrivileges) loadGlobalPrivileges(ctx context.Context) error {
 			break
 		}
 		for i := userTablePrivColumnStartIndex; i < len(fs); i++ {
+			// Skip the columns that are not privileges, like ssl_type and plugin.
+			if !strings.HasSuffix(fs[i].ColumnAsName.L, "_priv") {
+				continue
+			}
 			d := row.Data[i]
 			if d.Kind() != types.KindMysqlEnum {
 				return errInvalidPrivilegeType.Gen("Privilege should be mysql.Enum: %v(%T)", d, d)
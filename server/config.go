@@ -21,4 +21,28 @@ type Config struct {
 	StatusAddr   string `json:"status_addr" toml:"status_addr"`
 	Socket       string `json:"socket" toml:"socket"`
 	ReportStatus bool   `json:"report_status" toml:"report_status"`
+	// SSLCert and SSLKey are the certificate and private key files, TLS is enabled if both of them are set.
+	SSLCert string `json:"ssl_cert" toml:"ssl_cert"`
+	SSLKey  string `json:"ssl_key" toml:"ssl_key"`
+	// SSLCA is the CA file to verify the client certificates with.
+	SSLCA string `json:"ssl_ca" toml:"ssl_ca"`
+	// AuthRSAKey is the RSA private key file used by caching_sha2_password to exchange the password
+	// on insecure connections, a key is generated when it's first used if it is not set.
+	AuthRSAKey string `json:"auth_rsa_key" toml:"auth_rsa_key"`
+	// TokenLimit is the number of the commands executed concurrently, 100 is used if it's not set.
+	TokenLimit int `json:"token_limit" toml:"token_limit"`
+	// MaxConnections is the maximum number of the client connections, 0 means no limit.
+	MaxConnections int `json:"max_connections" toml:"max_connections"`
+	// PGAddr is the address of the PostgreSQL protocol server, it is disabled if it's not set.
+	PGAddr string `json:"pg_addr" toml:"pg_addr"`
+	// PGAuthMethod is the authentication method of the PostgreSQL protocol, "md5" or "password".
+	// The MD5 digest of a user is only known after the user logs in with the cleartext password,
+	// so the first login of the user must be on a TLS connection.
+	PGAuthMethod string `json:"pg_auth_method" toml:"pg_auth_method"`
+	// PGAllowInsecurePassword allows the cleartext password of the PostgreSQL protocol to be sent
+	// on the connections without TLS.
+	PGAllowInsecurePassword bool `json:"pg_allow_insecure_password" toml:"pg_allow_insecure_password"`
+	// EnableDebugAPI enables the MVCC and settings REST API on the status port. The status port
+	// isn't authenticated, so they are disabled by default.
+	EnableDebugAPI bool `json:"enable_debug_api" toml:"enable_debug_api"`
 }
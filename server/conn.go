@@ -36,28 +36,33 @@ package server
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"net"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/arena"
 	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/types"
 )
 
 var defaultCapability = mysql.ClientLongPassword | mysql.ClientLongFlag |
 	mysql.ClientConnectWithDB | mysql.ClientProtocol41 |
 	mysql.ClientTransactions | mysql.ClientSecureConnection | mysql.ClientFoundRows |
 	mysql.ClientMultiStatements | mysql.ClientMultiResults |
-	mysql.ClientConnectAtts
+	mysql.ClientConnectAtts | mysql.ClientCompress | mysql.ClientPluginAuth
 
 type clientConn struct {
 	pkg          *packetIO
@@ -74,6 +79,65 @@ type clientConn struct {
 	lastCmd      string
 	ctx          IContext
 	attrs        map[string]string
+	// tlsState is the TLS state of the connection, it is nil if the connection is not encrypted.
+	tlsState *tls.ConnectionState
+	// maxQuestions is the MAX_QUERIES_PER_HOUR limit of the user, 0 means no limit.
+	maxQuestions uint64
+	// userConnCounted is true if the connection is counted for the MAX_USER_CONNECTIONS limit.
+	userConnCounted bool
+
+	// mu protects the process info of the connection, it is read by SHOW PROCESSLIST in other connections.
+	mu struct {
+		sync.Mutex
+		command   byte
+		db        string
+		info      string
+		startTime time.Time
+	}
+}
+
+// setProcessInfo records the command the connection is running.
+func (cc *clientConn) setProcessInfo(command byte, info string) {
+	db := cc.ctx.CurrentDB()
+	cc.mu.Lock()
+	cc.mu.command = command
+	cc.mu.db = db
+	cc.mu.info = info
+	cc.mu.startTime = time.Now()
+	cc.mu.Unlock()
+}
+
+func (cc *clientConn) processInfo() util.ProcessInfo {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	pi := util.ProcessInfo{
+		ID:      uint64(cc.connectionID),
+		User:    cc.user,
+		Host:    cc.conn.RemoteAddr().String(),
+		DB:      cc.mu.db,
+		Command: mysql.Command2Str[cc.mu.command],
+		Time:    cc.mu.startTime,
+		Info:    cc.mu.info,
+	}
+	if cc.mu.command != mysql.ComSleep {
+		pi.State = "executing"
+	}
+	return pi
+}
+
+// isIdle returns whether the connection is waiting for the next command.
+func (cc *clientConn) isIdle() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.mu.command == mysql.ComSleep
+}
+
+// releaseUserConn releases the connection counted for the MAX_USER_CONNECTIONS limit.
+func (cc *clientConn) releaseUserConn() {
+	if cc.userConnCounted {
+		cc.server.releaseUserConn(cc.user)
+		cc.userConnCounted = false
+	}
 }
 
 func (cc *clientConn) String() string {
@@ -99,18 +163,24 @@ func (cc *clientConn) handshake() error {
 	}
 
 	err := cc.writePacket(data)
-	cc.pkg.sequence = 0
+	cc.pkg.resetSequence()
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	return errors.Trace(cc.flush())
+	if err = cc.flush(); err != nil {
+		return errors.Trace(err)
+	}
+	// The packets after the handshake are compressed if the client asks for it.
+	cc.pkg.compress = cc.capability&mysql.ClientCompress > 0
+	return nil
 }
 
 func (cc *clientConn) Close() error {
 	cc.server.rwlock.Lock()
 	delete(cc.server.clients, cc.connectionID)
 	cc.server.rwlock.Unlock()
+	cc.releaseUserConn()
 	cc.conn.Close()
 	connGauge.Dec()
 	if cc.ctx != nil {
@@ -133,15 +203,16 @@ func (cc *clientConn) writeInitialHandshake() error {
 	data = append(data, cc.salt[0:8]...)
 	// filler [00]
 	data = append(data, 0)
-	// capability flag lower 2 bytes, using default capability here
-	data = append(data, byte(defaultCapability), byte(defaultCapability>>8))
+	// capability flag lower 2 bytes, using server capability here
+	capability := cc.server.capability
+	data = append(data, byte(capability), byte(capability>>8))
 	// charset, utf-8 default
 	data = append(data, uint8(mysql.DefaultCollationID))
 	//status
 	data = append(data, dumpUint16(mysql.ServerStatusAutocommit)...)
 	// below 13 byte may not be used
-	// capability flag upper 2 bytes, using default capability here
-	data = append(data, byte(defaultCapability>>16), byte(defaultCapability>>24))
+	// capability flag upper 2 bytes, using server capability here
+	data = append(data, byte(capability>>16), byte(capability>>24))
 	// filler [0x15], for wireshark dump, value is 0x15
 	data = append(data, 0x15)
 	// reserved 10 [00]
@@ -150,6 +221,11 @@ func (cc *clientConn) writeInitialHandshake() error {
 	data = append(data, cc.salt[8:]...)
 	// filler [00]
 	data = append(data, 0)
+	// auth-plugin name
+	if capability&mysql.ClientPluginAuth > 0 {
+		data = append(data, mysql.AuthName...)
+		data = append(data, 0)
+	}
 	err := cc.writePacket(data)
 	if err != nil {
 		return errors.Trace(err)
@@ -171,6 +247,7 @@ type handshakeResponse41 struct {
 	User       string
 	DBName     string
 	Auth       []byte
+	AuthPlugin string
 	Attrs      map[string]string
 }
 
@@ -192,8 +269,8 @@ func handshakeResponseFromData(packet *handshakeResponse41, data []byte) error {
 	pos += len(packet.User) + 1
 
 	if capability&mysql.ClientPluginAuthLenencClientData > 0 {
-		// TODO: Support mysql.ClientPluginAuthLenencClientData, skip it now
 		if num, null, off := parseLengthEncodedInt(data[pos:]); !null {
+			packet.Auth = data[pos+off : pos+off+int(num)]
 			pos = pos + off + int(num)
 		}
 	} else if capability&mysql.ClientSecureConnection > 0 {
@@ -216,9 +293,11 @@ func handshakeResponseFromData(packet *handshakeResponse41, data []byte) error {
 	}
 
 	if capability&mysql.ClientPluginAuth > 0 {
-		// TODO: Support mysql.ClientPluginAuth, skip it now
 		idx := bytes.IndexByte(data[pos:], 0)
-		pos = pos + idx + 1
+		if idx >= 0 {
+			packet.AuthPlugin = string(data[pos : pos+idx])
+			pos = pos + idx + 1
+		}
 	}
 
 	if capability&mysql.ClientConnectAtts > 0 {
@@ -256,11 +335,42 @@ func parseAttrs(data []byte) (map[string]string, error) {
 	return attrs, nil
 }
 
+// sslRequestLen is the length of the SSL request packet, which is the fixed
+// header of the handshake response, the client sends it to upgrade to TLS.
+const sslRequestLen = 32
+
+// upgradeToTLS does the TLS handshake on the connection, then the packets are
+// sent and received through the encrypted connection.
+func (cc *clientConn) upgradeToTLS() error {
+	if cc.server.tlsConfig == nil {
+		return errors.New("the client requests a secure connection, but TLS is not enabled on the server")
+	}
+	// The client may send the TLS handshake right after the SSL request, it may have been buffered.
+	tlsConn := tls.Server(bufferedReadConn{Conn: cc.conn, rb: cc.pkg.rb}, cc.server.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return errors.Trace(err)
+	}
+	cc.conn = tlsConn
+	cc.pkg.setConn(tlsConn)
+	state := tlsConn.ConnectionState()
+	cc.tlsState = &state
+	return nil
+}
+
 func (cc *clientConn) readHandshakeResponse() error {
 	data, err := cc.readPacket()
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if len(data) == sslRequestLen && binary.LittleEndian.Uint32(data[:4])&mysql.ClientSSL > 0 {
+		if err = cc.upgradeToTLS(); err != nil {
+			return errors.Trace(err)
+		}
+		data, err = cc.readPacket()
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
 
 	var p handshakeResponse41
 	if err = handshakeResponseFromData(&p, data); err != nil {
@@ -278,6 +388,10 @@ func (cc *clientConn) readHandshakeResponse() error {
 		cc.Close()
 		return errors.Trace(err)
 	}
+	if cc.tlsState != nil {
+		cc.ctx.SetTLSState(cc.tlsState)
+	}
+	cc.ctx.SetSessionManager(cc.server)
 	if !cc.server.skipAuth() {
 		// Do Auth
 		addr := cc.conn.RemoteAddr().String()
@@ -286,9 +400,19 @@ func (cc *clientConn) readHandshakeResponse() error {
 			return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, cc.user, addr, "Yes"))
 		}
 		user := fmt.Sprintf("%s@%s", cc.user, host)
-		if !cc.ctx.Auth(user, p.Auth, cc.salt) {
+		ok, err1 := cc.authenticate(user, p.AuthPlugin, p.Auth)
+		if err1 != nil {
+			return errors.Trace(err1)
+		}
+		if !ok {
 			return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, cc.user, host, "Yes"))
 		}
+		var maxUserConnections uint64
+		cc.maxQuestions, maxUserConnections = cc.ctx.UserResourceLimits(user)
+		if err1 = cc.server.acquireUserConn(cc.user, maxUserConnections); err1 != nil {
+			return errors.Trace(err1)
+		}
+		cc.userConnCounted = true
 	}
 	return nil
 }
@@ -308,10 +432,14 @@ func (cc *clientConn) Run() {
 	connGauge.Inc()
 
 	for {
+		// The connection exits before reading the next command if the server is shutting down.
+		if cc.server.isShuttingDown() {
+			return
+		}
 		cc.alloc.Reset()
 		data, err := cc.readPacket()
 		if err != nil {
-			if terror.ErrorNotEqual(err, io.EOF) {
+			if terror.ErrorNotEqual(err, io.EOF) && !cc.server.isShuttingDown() {
 				log.Error(errors.ErrorStack(err))
 			}
 			return
@@ -326,7 +454,7 @@ func (cc *clientConn) Run() {
 			cc.writeError(err)
 		}
 
-		cc.pkg.sequence = 0
+		cc.pkg.resetSequence()
 	}
 }
 
@@ -335,11 +463,24 @@ func (cc *clientConn) dispatch(data []byte) error {
 	data = data[1:]
 	cc.lastCmd = hack.String(data)
 
+	if cmd == mysql.ComQuery || cmd == mysql.ComStmtExecute {
+		if err := cc.server.useQuestion(cc.user, cc.maxQuestions); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	token := cc.server.getToken()
 
+	var info string
+	if cmd == mysql.ComQuery {
+		info = hack.String(data)
+	}
+	cc.setProcessInfo(cmd, info)
+
 	startTs := time.Now()
 	defer func() {
 		cc.server.releaseToken(token)
+		cc.setProcessInfo(mysql.ComSleep, "")
 		log.Debugf("[TIME_CMD] %v %d", time.Since(startTs), cmd)
 	}()
 
@@ -367,6 +508,8 @@ func (cc *clientConn) dispatch(data []byte) error {
 		return cc.handleStmtPrepare(hack.String(data))
 	case mysql.ComStmtExecute:
 		return cc.handleStmtExecute(data)
+	case mysql.ComStmtFetch:
+		return cc.handleStmtFetch(data)
 	case mysql.ComStmtClose:
 		return cc.handleStmtClose(data)
 	case mysql.ComStmtSendLongData:
@@ -394,12 +537,17 @@ func (cc *clientConn) flush() error {
 }
 
 func (cc *clientConn) writeOK() error {
+	return cc.writeOKWithStatus(0)
+}
+
+// writeOKWithStatus writes the OK packet with the status ORed to the server status.
+func (cc *clientConn) writeOKWithStatus(status uint16) error {
 	data := cc.alloc.AllocWithLen(4, 32)
 	data = append(data, mysql.OKHeader)
 	data = append(data, dumpLengthEncodedInt(uint64(cc.ctx.AffectedRows()))...)
 	data = append(data, dumpLengthEncodedInt(uint64(cc.ctx.LastInsertID()))...)
 	if cc.capability&mysql.ClientProtocol41 > 0 {
-		data = append(data, dumpUint16(cc.ctx.Status())...)
+		data = append(data, dumpUint16(cc.ctx.Status()|status)...)
 		data = append(data, dumpUint16(cc.ctx.WarningCount())...)
 	}
 
@@ -420,7 +568,7 @@ func (cc *clientConn) writeError(e error) error {
 	originErr := errors.Cause(e)
 	if te, ok = originErr.(*terror.Error); ok {
 		m = te.ToSQLError()
-	} else {
+	} else if m, ok = originErr.(*mysql.SQLError); !ok {
 		m = mysql.NewErrf(mysql.ErrUnknown, e.Error())
 	}
 
@@ -447,16 +595,22 @@ func (cc *clientConn) writeError(e error) error {
 // If "more" is true, a mysql.ServerMoreResultsExists bit would be set
 // in the packet.
 func (cc *clientConn) writeEOF(more bool) error {
+	var status uint16
+	if more {
+		status = mysql.ServerMoreResultsExists
+	}
+	return cc.writeEOFWithStatus(status)
+}
+
+// writeEOFWithStatus writes an EOF packet with the extra status flags, it's used
+// to set the cursor flags in the mysql protocol.
+func (cc *clientConn) writeEOFWithStatus(status uint16) error {
 	data := cc.alloc.AllocWithLen(4, 9)
 
 	data = append(data, mysql.EOFHeader)
 	if cc.capability&mysql.ClientProtocol41 > 0 {
 		data = append(data, dumpUint16(cc.ctx.WarningCount())...)
-		status := cc.ctx.Status()
-		if more {
-			status |= mysql.ServerMoreResultsExists
-		}
-		data = append(data, dumpUint16(cc.ctx.Status())...)
+		data = append(data, dumpUint16(cc.ctx.Status()|status)...)
 	}
 
 	err := cc.writePacket(data)
@@ -542,25 +696,23 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 		queryCounter.WithLabelValues(label).Inc()
 	}()
 
-	rs, err := cc.ctx.Execute(sql)
+	stmts, err := cc.ctx.Parse(sql)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	if rs != nil {
-		if len(rs) == 1 {
-			err = cc.writeResultset(rs[0], false, false)
-		} else {
-			err = cc.writeMultiResultset(rs, false)
-		}
-	} else {
-		loadDataInfo := cc.ctx.Value(executor.LoadDataVarKey)
-		if loadDataInfo != nil {
-			if err = cc.handleLoadData(loadDataInfo.(*executor.LoadDataInfo)); err != nil {
-				return errors.Trace(err)
-			}
-		}
+	if len(stmts) > 1 && cc.capability&mysql.ClientMultiStatements == 0 {
+		// Like MySQL, the second statement is a syntax error if multiple statements are not enabled.
+		return mysql.NewErr(mysql.ErrParse, "You have an error in your SQL syntax; multiple statements are not enabled", strings.TrimSpace(stmts[1].Text()), 1)
+	}
+	if len(stmts) == 0 {
 		err = cc.writeOK()
 	}
+	// The result of each statement is written once it finishes, the statements after an error are not executed.
+	for i, stmt := range stmts {
+		if err = cc.handleStmt(stmt, i < len(stmts)-1); err != nil {
+			break
+		}
+	}
 	costTime := time.Since(startTs)
 	if len(sql) > 1024 {
 		sql = sql[:1024]
@@ -573,6 +725,29 @@ func (cc *clientConn) handleQuery(sql string) (err error) {
 	return errors.Trace(err)
 }
 
+// handleStmt executes a statement of COM_QUERY and writes its result. SERVER_MORE_RESULTS_EXISTS is
+// set in the result if more is true, which means there are more statements to execute.
+func (cc *clientConn) handleStmt(stmt ast.StmtNode, more bool) error {
+	rs, err := cc.ctx.ExecuteStmt(stmt)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if rs != nil {
+		return errors.Trace(cc.writeResultset(rs, false, more))
+	}
+	loadDataInfo := cc.ctx.Value(executor.LoadDataVarKey)
+	if loadDataInfo != nil {
+		if err = cc.handleLoadData(loadDataInfo.(*executor.LoadDataInfo)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	var status uint16
+	if more {
+		status = mysql.ServerMoreResultsExists
+	}
+	return errors.Trace(cc.writeOKWithStatus(status))
+}
+
 func (cc *clientConn) handleFieldList(sql string) (err error) {
 	parts := strings.Split(sql, "\x00")
 	columns, err := cc.ctx.FieldList(parts[0])
@@ -610,25 +785,14 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 	if err != nil {
 		return errors.Trace(err)
 	}
-	columnLen := dumpLengthEncodedInt(uint64(len(columns)))
-	data := cc.alloc.AllocWithLen(4, 1024)
-	data = append(data, columnLen...)
-	if err = cc.writePacket(data); err != nil {
+	if err = cc.writeColumnInfo(columns); err != nil {
 		return errors.Trace(err)
 	}
-
-	for _, v := range columns {
-		data = data[0:4]
-		data = append(data, v.Dump(cc.alloc)...)
-		if err = cc.writePacket(data); err != nil {
-			return errors.Trace(err)
-		}
-	}
-
 	if err = cc.writeEOF(false); err != nil {
 		return errors.Trace(err)
 	}
 
+	data := cc.alloc.AllocWithLen(4, 1024)
 	for {
 		if err != nil {
 			return errors.Trace(err)
@@ -636,29 +800,10 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 		if row == nil {
 			break
 		}
-		data = data[0:4]
-		if binary {
-			var rowData []byte
-			rowData, err = dumpRowValuesBinary(cc.alloc, columns, row)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			data = append(data, rowData...)
-		} else {
-			for i, value := range row {
-				if value.IsNull() {
-					data = append(data, 0xfb)
-					continue
-				}
-				var valData []byte
-				valData, err = dumpTextValue(columns[i].Type, value)
-				if err != nil {
-					return errors.Trace(err)
-				}
-				data = append(data, dumpLengthEncodedString(valData, cc.alloc)...)
-			}
+		data, err = dumpRow(data[0:4], cc.alloc, columns, row, binary)
+		if err != nil {
+			return errors.Trace(err)
 		}
-
 		if err = cc.writePacket(data); err != nil {
 			return errors.Trace(err)
 		}
@@ -673,11 +818,42 @@ func (cc *clientConn) writeResultset(rs ResultSet, binary bool, more bool) error
 	return errors.Trace(cc.flush())
 }
 
-func (cc *clientConn) writeMultiResultset(rss []ResultSet, binary bool) error {
-	for _, rs := range rss {
-		if err := cc.writeResultset(rs, binary, true); err != nil {
+// writeColumnInfo writes the column count and the column definitions of a resultset.
+func (cc *clientConn) writeColumnInfo(columns []*ColumnInfo) error {
+	data := cc.alloc.AllocWithLen(4, 1024)
+	data = append(data, dumpLengthEncodedInt(uint64(len(columns)))...)
+	if err := cc.writePacket(data); err != nil {
+		return errors.Trace(err)
+	}
+	for _, v := range columns {
+		data = data[0:4]
+		data = append(data, v.Dump(cc.alloc)...)
+		if err := cc.writePacket(data); err != nil {
 			return errors.Trace(err)
 		}
 	}
-	return cc.writeOK()
+	return nil
+}
+
+// dumpRow appends the row to data in BINARY format if binary is true, or in TEXT format otherwise.
+func dumpRow(data []byte, alloc arena.Allocator, columns []*ColumnInfo, row []types.Datum, binary bool) ([]byte, error) {
+	if binary {
+		rowData, err := dumpRowValuesBinary(alloc, columns, row)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return append(data, rowData...), nil
+	}
+	for i, value := range row {
+		if value.IsNull() {
+			data = append(data, 0xfb)
+			continue
+		}
+		valData, err := dumpTextValue(columns[i].Type, value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		data = append(data, dumpLengthEncodedString(valData, alloc)...)
+	}
+	return data, nil
 }
@@ -0,0 +1,162 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/mysql"
+)
+
+// The packets of the caching_sha2_password authentication exchange.
+// See https://dev.mysql.com/doc/dev/mysql-server/latest/page_caching_sha2_authentication_exchanges.html
+const (
+	authMoreDataHeader byte = 0x01
+	// authRequestPublicKey is sent by the client to request the RSA public key of the server.
+	authRequestPublicKey byte = 0x02
+	// authFastAuthSuccess tells the client that the fast authentication succeeds.
+	authFastAuthSuccess byte = 0x03
+	// authPerformFullAuth asks the client to send the password for the full authentication.
+	authPerformFullAuth byte = 0x04
+)
+
+// authenticate verifies the user with the authentication plugin of the user, the client is asked to
+// switch to the plugin if it's not the one used in the handshake response.
+func (cc *clientConn) authenticate(user string, clientPlugin string, auth []byte) (bool, error) {
+	// The client without CLIENT_PLUGIN_AUTH uses mysql_native_password.
+	if len(clientPlugin) == 0 {
+		clientPlugin = mysql.AuthName
+	}
+	plugin := cc.ctx.AuthPlugin(user)
+	if clientPlugin != plugin {
+		if cc.capability&mysql.ClientPluginAuth == 0 {
+			log.Warnf("User [%s] uses %s, but the client doesn't support authentication plugins", user, plugin)
+			return false, nil
+		}
+		var err error
+		auth, err = cc.switchAuthPlugin(plugin)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+	}
+	if plugin == mysql.AuthCachingSha2Password {
+		ok, err := cc.authCachingSha2(user, auth)
+		return ok, errors.Trace(err)
+	}
+	return cc.ctx.Auth(user, auth, cc.salt), nil
+}
+
+// switchAuthPlugin sends the AuthSwitchRequest packet and returns the auth data of the plugin from the client.
+// See https://dev.mysql.com/doc/internals/en/connection-phase-packets.html#packet-Protocol::AuthSwitchRequest
+func (cc *clientConn) switchAuthPlugin(plugin string) ([]byte, error) {
+	data := make([]byte, 4, 6+len(plugin)+len(cc.salt))
+	data = append(data, mysql.EOFHeader)
+	data = append(data, plugin...)
+	data = append(data, 0)
+	data = append(data, cc.salt...)
+	data = append(data, 0)
+	if err := cc.writePacket(data); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := cc.flush(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	auth, err := cc.readPacket()
+	return auth, errors.Trace(err)
+}
+
+// writeAuthMoreData writes the AuthMoreData packet.
+func (cc *clientConn) writeAuthMoreData(payload []byte) error {
+	data := make([]byte, 4, 5+len(payload))
+	data = append(data, authMoreDataHeader)
+	data = append(data, payload...)
+	if err := cc.writePacket(data); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
+// authCachingSha2 verifies the user with caching_sha2_password. The scramble is verified with
+// the cached digest at first, if it fails, the client is asked to send the password, which is
+// in cleartext on TLS connections, or encrypted by the RSA public key of the server otherwise.
+func (cc *clientConn) authCachingSha2(user string, scramble []byte) (bool, error) {
+	// The client sends nothing if the password is empty.
+	if len(scramble) == 0 {
+		return cc.ctx.AuthCachingSha2Full(user, nil), nil
+	}
+	if cc.ctx.AuthCachingSha2Fast(user, scramble, cc.salt) {
+		// The OK packet follows it.
+		return true, errors.Trace(cc.writeAuthMoreData([]byte{authFastAuthSuccess}))
+	}
+	if err := cc.writeAuthMoreData([]byte{authPerformFullAuth}); err != nil {
+		return false, errors.Trace(err)
+	}
+	data, err := cc.readPacket()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if cc.tlsState == nil {
+		data, err = cc.readRSAEncryptedPassword(data)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if data == nil {
+			return false, nil
+		}
+	}
+	// The password is terminated by 0.
+	if n := len(data); n > 0 && data[n-1] == 0 {
+		data = data[:n-1]
+	}
+	return cc.ctx.AuthCachingSha2Full(user, data), nil
+}
+
+// readRSAEncryptedPassword sends the RSA public key if the client requests it, then decrypts the
+// password from the client. The password is XORed with the salt before encrypted.
+// It returns nil if the password can't be decrypted.
+func (cc *clientConn) readRSAEncryptedPassword(data []byte) ([]byte, error) {
+	key, err := cc.server.getRSAKey()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(data) == 1 && data[0] == authRequestPublicKey {
+		der, err1 := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err1 != nil {
+			return nil, errors.Trace(err1)
+		}
+		pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		if err = cc.writeAuthMoreData(pub); err != nil {
+			return nil, errors.Trace(err)
+		}
+		data, err = cc.readPacket()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	password, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, data, nil)
+	if err != nil {
+		log.Warnf("Decrypt the password of caching_sha2_password error %v", err)
+		return nil, nil
+	}
+	for i := range password {
+		password[i] ^= cc.salt[i%len(cc.salt)]
+	}
+	return password, nil
+}
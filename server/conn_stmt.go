@@ -42,6 +42,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/types"
 )
 
 func (cc *clientConn) handleStmtPrepare(sql string) error {
@@ -118,8 +119,8 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 
 	flag := data[pos]
 	pos++
-	//now we only support CURSOR_TYPE_NO_CURSOR flag
-	if flag != 0 {
+	//now we only support CURSOR_TYPE_NO_CURSOR and CURSOR_TYPE_READ_ONLY flag
+	if flag != mysql.CursorTypeNoCursor && flag != mysql.CursorTypeReadOnly {
 		return mysql.NewErrf(mysql.ErrUnknown, "unsupported flag %d", flag)
 	}
 
@@ -158,6 +159,8 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 			return errors.Trace(err)
 		}
 	}
+	// Executing the statement again closes the cursor opened before.
+	stmt.StoreResultSet(nil)
 	rs, err := stmt.Execute(args...)
 	if err != nil {
 		return errors.Trace(err)
@@ -165,10 +168,91 @@ func (cc *clientConn) handleStmtExecute(data []byte) (err error) {
 	if rs == nil {
 		return errors.Trace(cc.writeOK())
 	}
+	if flag == mysql.CursorTypeReadOnly {
+		return errors.Trace(cc.openCursor(stmt, rs))
+	}
 
 	return errors.Trace(cc.writeResultset(rs, true, false))
 }
 
+// cursorResultSet is the result set kept by a cursor, the next row is read in
+// advance to know whether the last row is sent.
+type cursorResultSet struct {
+	ResultSet
+	columns []*ColumnInfo
+	row     []types.Datum
+}
+
+// openCursor writes the column definitions of the result set and keeps it on
+// the statement, the rows are sent by COM_STMT_FETCH.
+func (cc *clientConn) openCursor(stmt IStatement, rs ResultSet) error {
+	// We need to call Next before we get columns.
+	row, err := rs.Next()
+	if err != nil {
+		rs.Close()
+		return errors.Trace(err)
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		rs.Close()
+		return errors.Trace(err)
+	}
+	stmt.StoreResultSet(&cursorResultSet{ResultSet: rs, columns: columns, row: row})
+	if err = cc.writeColumnInfo(columns); err != nil {
+		return errors.Trace(err)
+	}
+	if err = cc.writeEOFWithStatus(mysql.ServerStatusCursorExists); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
+// See https://dev.mysql.com/doc/internals/en/com-stmt-fetch.html
+func (cc *clientConn) handleStmtFetch(data []byte) (err error) {
+	if len(data) < 8 {
+		return mysql.ErrMalformPacket
+	}
+
+	stmtID := binary.LittleEndian.Uint32(data[0:4])
+	fetchSize := binary.LittleEndian.Uint32(data[4:8])
+
+	stmt := cc.ctx.GetStatement(int(stmtID))
+	if stmt == nil {
+		return mysql.NewErr(mysql.ErrUnknownStmtHandler,
+			strconv.FormatUint(uint64(stmtID), 10), "stmt_fetch")
+	}
+	cursor, ok := stmt.GetResultSet().(*cursorResultSet)
+	if !ok {
+		return mysql.NewErrf(mysql.ErrStmtHasNoOpenCursor, "The statement (%d) has no open cursor.", stmtID)
+	}
+
+	data = cc.alloc.AllocWithLen(4, 1024)
+	for i := uint32(0); i < fetchSize && cursor.row != nil; i++ {
+		data, err = dumpRow(data[0:4], cc.alloc, cursor.columns, cursor.row, true)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = cc.writePacket(data); err != nil {
+			return errors.Trace(err)
+		}
+		cursor.row, err = cursor.Next()
+		if err != nil {
+			stmt.StoreResultSet(nil)
+			return errors.Trace(err)
+		}
+	}
+
+	status := mysql.ServerStatusCursorExists
+	if cursor.row == nil {
+		status |= mysql.ServerStatusLastRowSend
+		stmt.StoreResultSet(nil)
+	}
+	if err = cc.writeEOFWithStatus(status); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cc.flush())
+}
+
 func parseStmtArgs(args []interface{}, boundParams [][]byte, nullBitmap, paramTypes, paramValues []byte) (err error) {
 	pos := 0
 	var v []byte
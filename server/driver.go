@@ -14,8 +14,11 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -57,6 +60,12 @@ type IContext interface {
 	// Execute executes a SQL statement.
 	Execute(sql string) ([]ResultSet, error)
 
+	// Parse parses the SQL text into statements.
+	Parse(sql string) ([]ast.StmtNode, error)
+
+	// ExecuteStmt executes a statement returned by Parse, the result set is nil if the statement has no result.
+	ExecuteStmt(stmt ast.StmtNode) (ResultSet, error)
+
 	// SetClientCapability sets client capability flags
 	SetClientCapability(uint32)
 
@@ -74,6 +83,36 @@ type IContext interface {
 
 	// Auth verifies user's authentication.
 	Auth(user string, auth []byte, salt []byte) bool
+
+	// AuthPlugin returns the authentication plugin of the user.
+	AuthPlugin(user string) string
+
+	// AuthCachingSha2Fast verifies the scramble of caching_sha2_password fast authentication.
+	AuthCachingSha2Fast(user string, scramble []byte, salt []byte) bool
+
+	// AuthCachingSha2Full verifies the cleartext password of caching_sha2_password full authentication.
+	AuthCachingSha2Full(user string, password []byte) bool
+
+	// AuthPassword verifies the cleartext password with the authentication plugin of the user.
+	AuthPassword(user string, password []byte) bool
+
+	// HasMD5Digest checks whether the MD5 digest of the user is known for the PostgreSQL MD5 authentication.
+	HasMD5Digest(user string) bool
+
+	// AuthMD5 verifies the response of the PostgreSQL MD5 authentication.
+	AuthMD5(user string, response []byte, salt []byte) bool
+
+	// UserResourceLimits returns the MAX_QUERIES_PER_HOUR and MAX_USER_CONNECTIONS limits of the user, 0 means no limit.
+	UserResourceLimits(user string) (maxQuestions, maxUserConnections uint64)
+
+	// SetTLSState sets the TLS state of the connection, it is called before Auth if the connection is encrypted.
+	SetTLSState(state *tls.ConnectionState)
+
+	// SetSessionManager sets the session manager used by SHOW PROCESSLIST and KILL.
+	SetSessionManager(sm util.SessionManager)
+
+	// KillQuery interrupts the running statement, it can be called from other goroutines.
+	KillQuery()
 }
 
 // IStatement is the interface to use a prepared statement.
@@ -93,9 +132,15 @@ type IStatement interface {
 	// BoundParams returns bound parameters.
 	BoundParams() [][]byte
 
-	// Reset removes all bound parameters.
+	// Reset removes all bound parameters and closes the stored result set.
 	Reset()
 
+	// StoreResultSet stores the result set opened by a cursor, the previous one is closed.
+	StoreResultSet(rs ResultSet)
+
+	// GetResultSet gets the result set stored by StoreResultSet, it returns nil if no cursor is open.
+	GetResultSet() ResultSet
+
 	// Close closes the statement.
 	Close() error
 }
@@ -14,13 +14,17 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/db"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -40,7 +44,6 @@ func NewTiDBDriver(store kv.Storage) *TiDBDriver {
 // TiDBContext implements IContext.
 type TiDBContext struct {
 	session      tidb.Session
-	currentDB    string
 	warningCount uint16
 	stmts        map[int]*TiDBStatement
 }
@@ -51,6 +54,7 @@ type TiDBStatement struct {
 	numParams   int
 	boundParams [][]byte
 	ctx         *TiDBContext
+	rs          ResultSet
 }
 
 // ID implements IStatement ID method.
@@ -97,11 +101,26 @@ func (ts *TiDBStatement) Reset() {
 	for i := range ts.boundParams {
 		ts.boundParams[i] = nil
 	}
+	ts.StoreResultSet(nil)
+}
+
+// StoreResultSet implements IStatement StoreResultSet method.
+func (ts *TiDBStatement) StoreResultSet(rs ResultSet) {
+	if ts.rs != nil {
+		ts.rs.Close()
+	}
+	ts.rs = rs
+}
+
+// GetResultSet implements IStatement GetResultSet method.
+func (ts *TiDBStatement) GetResultSet() ResultSet {
+	return ts.rs
 }
 
 // Close implements IStatement Close method.
 func (ts *TiDBStatement) Close() error {
 	//TODO close at tidb level
+	ts.StoreResultSet(nil)
 	err := ts.ctx.session.DropPreparedStmt(ts.id)
 	if err != nil {
 		return errors.Trace(err)
@@ -122,9 +141,8 @@ func (qd *TiDBDriver) OpenCtx(connID uint64, capability uint32, collation uint8,
 		}
 	}
 	tc := &TiDBContext{
-		session:   session,
-		currentDB: dbname,
-		stmts:     make(map[int]*TiDBStatement),
+		session: session,
+		stmts:   make(map[int]*TiDBStatement),
 	}
 	return tc, nil
 }
@@ -166,7 +184,7 @@ func (tc *TiDBContext) AffectedRows() uint64 {
 
 // CurrentDB implements IContext CurrentDB method.
 func (tc *TiDBContext) CurrentDB() string {
-	return tc.currentDB
+	return db.GetCurrentSchema(tc.session.(context.Context))
 }
 
 // WarningCount implements IContext WarningCount method.
@@ -192,6 +210,20 @@ func (tc *TiDBContext) Execute(sql string) (rs []ResultSet, err error) {
 	return
 }
 
+// Parse implements IContext Parse method.
+func (tc *TiDBContext) Parse(sql string) ([]ast.StmtNode, error) {
+	return tc.session.Parse(sql)
+}
+
+// ExecuteStmt implements IContext ExecuteStmt method.
+func (tc *TiDBContext) ExecuteStmt(stmt ast.StmtNode) (ResultSet, error) {
+	rs, err := tc.session.ExecuteStmt(stmt)
+	if err != nil || rs == nil {
+		return nil, errors.Trace(err)
+	}
+	return &tidbResultSet{recordSet: rs}, nil
+}
+
 // SetClientCapability implements IContext SetClientCapability method.
 func (tc *TiDBContext) SetClientCapability(flags uint32) {
 	tc.session.SetClientCapability(flags)
@@ -199,6 +231,9 @@ func (tc *TiDBContext) SetClientCapability(flags uint32) {
 
 // Close implements IContext Close method.
 func (tc *TiDBContext) Close() (err error) {
+	for _, stmt := range tc.stmts {
+		stmt.StoreResultSet(nil)
+	}
 	return tc.session.Close()
 }
 
@@ -207,6 +242,56 @@ func (tc *TiDBContext) Auth(user string, auth []byte, salt []byte) bool {
 	return tc.session.Auth(user, auth, salt)
 }
 
+// AuthPlugin implements IContext AuthPlugin method.
+func (tc *TiDBContext) AuthPlugin(user string) string {
+	return tc.session.AuthPlugin(user)
+}
+
+// AuthCachingSha2Fast implements IContext AuthCachingSha2Fast method.
+func (tc *TiDBContext) AuthCachingSha2Fast(user string, scramble []byte, salt []byte) bool {
+	return tc.session.AuthCachingSha2Fast(user, scramble, salt)
+}
+
+// AuthCachingSha2Full implements IContext AuthCachingSha2Full method.
+func (tc *TiDBContext) AuthCachingSha2Full(user string, password []byte) bool {
+	return tc.session.AuthCachingSha2Full(user, password)
+}
+
+// AuthPassword implements IContext AuthPassword method.
+func (tc *TiDBContext) AuthPassword(user string, password []byte) bool {
+	return tc.session.AuthPassword(user, password)
+}
+
+// HasMD5Digest implements IContext HasMD5Digest method.
+func (tc *TiDBContext) HasMD5Digest(user string) bool {
+	return tc.session.HasMD5Digest(user)
+}
+
+// AuthMD5 implements IContext AuthMD5 method.
+func (tc *TiDBContext) AuthMD5(user string, response []byte, salt []byte) bool {
+	return tc.session.AuthMD5(user, response, salt)
+}
+
+// UserResourceLimits implements IContext UserResourceLimits method.
+func (tc *TiDBContext) UserResourceLimits(user string) (maxQuestions, maxUserConnections uint64) {
+	return tc.session.UserResourceLimits(user)
+}
+
+// SetTLSState implements IContext SetTLSState method.
+func (tc *TiDBContext) SetTLSState(state *tls.ConnectionState) {
+	tc.session.SetTLSState(state)
+}
+
+// SetSessionManager implements IContext SetSessionManager method.
+func (tc *TiDBContext) SetSessionManager(sm util.SessionManager) {
+	tc.session.SetSessionManager(sm)
+}
+
+// KillQuery implements IContext KillQuery method.
+func (tc *TiDBContext) KillQuery() {
+	tc.session.KillQuery()
+}
+
 // FieldList implements IContext FieldList method.
 func (tc *TiDBContext) FieldList(table string) (colums []*ColumnInfo, err error) {
 	rs, err := tc.Execute("SELECT * FROM " + table + " LIMIT 0")
@@ -0,0 +1,406 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb"
+	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/inspectkv"
+	"github.com/pingcap/tidb/kv"
+	"github.com/pingcap/tidb/meta"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/perfschema"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/tablecodec"
+)
+
+// The REST API on the status port:
+//
+//	GET  /schema                          all the databases
+//	GET  /schema/{db}                     the tables of the database
+//	GET  /schema/{db}/{table}             the table
+//	GET  /tables/{db}/{table}/regions     the regions of the records and the indices of the table
+//	GET  /ddl/history                     the finished DDL jobs
+//	GET  /mvcc/key/{db}/{table}/{handle}  the MVCC versions of the row, the system schemas are refused,
+//	                                      only served by localstore, other storages return 501
+//	GET  /settings                        the settings which can be changed
+//	POST /settings                        changes the settings by the form values, e.g. log_level=warn
+//
+// The MVCC and settings API is only served if Config.EnableDebugAPI is set.
+const (
+	pathSchema     = "/schema"
+	pathTables     = "/tables/"
+	pathDDLHistory = "/ddl/history"
+	pathMvccKey    = "/mvcc/key/"
+	pathSettings   = "/settings"
+)
+
+// logLevels are the log levels which can be set by /settings.
+var logLevels = []struct {
+	name  string
+	level log.LogLevel
+}{
+	{"fatal", log.LOG_LEVEL_FATAL},
+	{"error", log.LOG_LEVEL_ERROR},
+	{"warn", log.LOG_LEVEL_WARN},
+	{"info", log.LOG_LEVEL_INFO},
+	{"debug", log.LOG_LEVEL_DEBUG},
+}
+
+// systemSchemas are the schemas whose rows can't be read by the MVCC API, e.g. mysql.user has the
+// password hashes.
+var systemSchemas = []string{mysql.SystemDB, infoschema.Name, perfschema.Name}
+
+// apiHandler serves the REST API on the status port.
+type apiHandler struct {
+	store    kv.Storage
+	debugAPI bool
+}
+
+// regionItem is a region which a key range overlaps with.
+type regionItem struct {
+	ID       uint64 `json:"region_id"`
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+}
+
+// indexRegions is the regions of an index.
+type indexRegions struct {
+	Name    string       `json:"name"`
+	ID      int64        `json:"id"`
+	Regions []regionItem `json:"regions"`
+}
+
+// tableRegions is the regions of the records and the indices of a table.
+type tableRegions struct {
+	Name          string         `json:"name"`
+	ID            int64          `json:"id"`
+	RecordRegions []regionItem   `json:"record_regions"`
+	Indices       []indexRegions `json:"indices"`
+}
+
+// mvccVersion is a version of a row, the values are nil if the row is deleted.
+type mvccVersion struct {
+	CommitTS uint64                 `json:"commit_ts"`
+	Values   map[string]interface{} `json:"values"`
+}
+
+// rowMvcc is the MVCC versions of a row.
+type rowMvcc struct {
+	Key      string        `json:"key"`
+	Versions []mvccVersion `json:"versions"`
+}
+
+// settings is the settings which can be changed on the fly.
+type settings struct {
+	LogLevel string `json:"log_level"`
+}
+
+// registerAPIHandlers registers the REST API if the driver is TiDBDriver.
+func (s *Server) registerAPIHandlers() {
+	driver, ok := s.driver.(*TiDBDriver)
+	if !ok {
+		return
+	}
+	h := &apiHandler{store: driver.store, debugAPI: s.cfg.EnableDebugAPI}
+	http.HandleFunc(pathSchema, h.handleSchema)
+	http.HandleFunc(pathSchema+"/", h.handleSchema)
+	http.HandleFunc(pathTables, h.handleTableRegions)
+	http.HandleFunc(pathDDLHistory, h.handleDDLHistory)
+	http.HandleFunc(pathMvccKey, h.handleMvccKey)
+	http.HandleFunc(pathSettings, h.handleSettings)
+}
+
+// splitPath returns the elements of the path after the prefix.
+func splitPath(path, prefix string) []string {
+	path = strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	if len(path) == 0 {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, errors.Trace(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+func writeHTTPError(w http.ResponseWriter, code int, err error) {
+	if code == http.StatusInternalServerError {
+		log.Errorf("[http] %v", errors.ErrorStack(err))
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// checkDebugAPI writes the error if the MVCC and settings API is disabled.
+func (h *apiHandler) checkDebugAPI(w http.ResponseWriter) bool {
+	if !h.debugAPI {
+		writeHTTPError(w, http.StatusForbidden, errors.New("the debug API is disabled, set enable_debug_api to enable it"))
+		return false
+	}
+	return true
+}
+
+func (h *apiHandler) infoSchema() (infoschema.InfoSchema, error) {
+	dom, err := tidb.GetDomain(h.store)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return dom.InfoSchema(), nil
+}
+
+// getTable gets the table by the names, the error is written if it fails.
+func (h *apiHandler) getTable(w http.ResponseWriter, dbName, tableName string) (table.Table, bool) {
+	is, err := h.infoSchema()
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	tbl, err := is.TableByName(model.NewCIStr(dbName), model.NewCIStr(tableName))
+	if err != nil {
+		writeHTTPError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	return tbl, true
+}
+
+func (h *apiHandler) handleSchema(w http.ResponseWriter, req *http.Request) {
+	params := splitPath(req.URL.Path, pathSchema)
+	switch len(params) {
+	case 0:
+		is, err := h.infoSchema()
+		if err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, is.AllSchemas())
+	case 1:
+		is, err := h.infoSchema()
+		if err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, err)
+			return
+		}
+		dbName := model.NewCIStr(params[0])
+		if !is.SchemaExists(dbName) {
+			writeHTTPError(w, http.StatusNotFound, infoschema.ErrDatabaseNotExists.Gen("database %s not exists", params[0]))
+			return
+		}
+		tables := is.SchemaTables(dbName)
+		infos := make([]*model.TableInfo, 0, len(tables))
+		for _, tbl := range tables {
+			infos = append(infos, tbl.Meta())
+		}
+		writeJSON(w, infos)
+	case 2:
+		tbl, ok := h.getTable(w, params[0], params[1])
+		if ok {
+			writeJSON(w, tbl.Meta())
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// getRegions returns the regions which the key range [startKey, endKey) overlaps with.
+func (h *apiHandler) getRegions(locator kv.RegionLocator, startKey, endKey kv.Key) ([]regionItem, error) {
+	var regions []regionItem
+	for key := startKey; ; {
+		region, err := locator.LocateKey(key)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		regions = append(regions, regionItem{
+			ID:       region.ID,
+			StartKey: hex.EncodeToString(region.StartKey),
+			EndKey:   hex.EncodeToString(region.EndKey),
+		})
+		if len(region.EndKey) == 0 || region.EndKey.Cmp(endKey) >= 0 {
+			return regions, nil
+		}
+		key = region.EndKey
+	}
+}
+
+func (h *apiHandler) handleTableRegions(w http.ResponseWriter, req *http.Request) {
+	params := splitPath(req.URL.Path, pathTables)
+	if len(params) != 3 || params[2] != "regions" {
+		http.NotFound(w, req)
+		return
+	}
+	locator, ok := h.store.(kv.RegionLocator)
+	if !ok {
+		writeHTTPError(w, http.StatusNotImplemented, errors.New("the storage does not support locating regions"))
+		return
+	}
+	tbl, ok := h.getTable(w, params[0], params[1])
+	if !ok {
+		return
+	}
+
+	tableID := tbl.Meta().ID
+	result := &tableRegions{
+		Name: tbl.Meta().Name.O,
+		ID:   tableID,
+	}
+	var err error
+	recordPrefix := tablecodec.GenTableRecordPrefix(tableID)
+	result.RecordRegions, err = h.getRegions(locator, recordPrefix, recordPrefix.PrefixNext())
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, idx := range tbl.Indices() {
+		indexPrefix := tablecodec.EncodeTableIndexPrefix(tableID, idx.Meta().ID)
+		regions, err := h.getRegions(locator, indexPrefix, indexPrefix.PrefixNext())
+		if err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, err)
+			return
+		}
+		result.Indices = append(result.Indices, indexRegions{
+			Name:    idx.Meta().Name.O,
+			ID:      idx.Meta().ID,
+			Regions: regions,
+		})
+	}
+	writeJSON(w, result)
+}
+
+func (h *apiHandler) handleDDLHistory(w http.ResponseWriter, req *http.Request) {
+	var jobs []*model.Job
+	err := kv.RunInNewTxn(h.store, false, func(txn kv.Transaction) error {
+		var err error
+		jobs, err = meta.NewMeta(txn).GetAllHistoryDDLJobs()
+		return errors.Trace(err)
+	})
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, jobs)
+}
+
+// handleMvccKey writes the MVCC versions of a row. Only localstore implements kv.MvccInspector, TiKV doesn't
+// expose the versions of a key yet, so the request returns 501 Not Implemented on the TiKV storage.
+func (h *apiHandler) handleMvccKey(w http.ResponseWriter, req *http.Request) {
+	if !h.checkDebugAPI(w) {
+		return
+	}
+	params := splitPath(req.URL.Path, pathMvccKey)
+	if len(params) != 3 {
+		http.NotFound(w, req)
+		return
+	}
+	for _, name := range systemSchemas {
+		if strings.EqualFold(params[0], name) {
+			writeHTTPError(w, http.StatusForbidden, errors.Errorf("the MVCC versions of the system schema %s can't be read", params[0]))
+			return
+		}
+	}
+	handle, err := strconv.ParseInt(params[2], 10, 64)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, errors.Errorf("invalid handle %s", params[2]))
+		return
+	}
+	if _, ok := h.store.(kv.MvccInspector); !ok {
+		writeHTTPError(w, http.StatusNotImplemented, errors.New("only localstore supports reading MVCC versions"))
+		return
+	}
+	tbl, ok := h.getTable(w, params[0], params[1])
+	if !ok {
+		return
+	}
+
+	rows, err := inspectkv.GetRowVersions(h.store, tbl, handle)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	result := &rowMvcc{
+		Key:      hex.EncodeToString(tbl.RecordKey(handle)),
+		Versions: make([]mvccVersion, 0, len(rows)),
+	}
+	cols := tbl.Cols()
+	for _, row := range rows {
+		ver := mvccVersion{CommitTS: row.CommitTS}
+		if row.Values != nil {
+			ver.Values = make(map[string]interface{}, len(cols))
+			for i, col := range cols {
+				d := row.Values[i]
+				if d.IsNull() {
+					ver.Values[col.Name.O] = nil
+					continue
+				}
+				str, err := d.ToString()
+				if err != nil {
+					writeHTTPError(w, http.StatusInternalServerError, errors.Trace(err))
+					return
+				}
+				ver.Values[col.Name.O] = str
+			}
+		}
+		result.Versions = append(result.Versions, ver)
+	}
+	writeJSON(w, result)
+}
+
+func (h *apiHandler) handleSettings(w http.ResponseWriter, req *http.Request) {
+	if !h.checkDebugAPI(w) {
+		return
+	}
+	if req.Method == "POST" {
+		err := req.ParseForm()
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		if levelName := req.Form.Get("log_level"); len(levelName) > 0 {
+			found := false
+			for _, l := range logLevels {
+				if l.name == strings.ToLower(levelName) {
+					log.SetLevel(l.level)
+					log.Infof("[http] set log level to %s", l.name)
+					found = true
+					break
+				}
+			}
+			if !found {
+				writeHTTPError(w, http.StatusBadRequest, errors.Errorf("invalid log level %s", levelName))
+				return
+			}
+		}
+	}
+
+	s := settings{}
+	level := log.GetLogLevel()
+	for _, l := range logLevels {
+		if l.level == level {
+			s.LogLevel = l.name
+		}
+	}
+	writeJSON(w, s)
+}
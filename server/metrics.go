@@ -14,6 +14,7 @@
 package server
 
 import (
+	"github.com/pingcap/tidb/executor"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -46,10 +47,43 @@ var (
 			Name:      "connections",
 			Help:      "Number of connections.",
 		})
+
+	planCacheHitCounter = prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_hit_total",
+			Help:      "Counter of prepared statement executions that reuse a cached plan.",
+		}, func() float64 {
+			return float64(executor.PlanCacheHits())
+		})
+
+	planCacheMissCounter = prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "plan_cache_miss_total",
+			Help:      "Counter of prepared statement executions whose plan is not found in the plan cache.",
+		}, func() float64 {
+			return float64(executor.PlanCacheMisses())
+		})
+
+	spillFileCounter = prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Namespace: "tidb",
+			Subsystem: "server",
+			Name:      "spill_file_total",
+			Help:      "Counter of spill files created by executors exceeding the query memory quota.",
+		}, func() float64 {
+			return float64(executor.SpillFiles())
+		})
 )
 
 func init() {
 	prometheus.MustRegister(queryHistgram)
 	prometheus.MustRegister(queryCounter)
 	prometheus.MustRegister(connGauge)
+	prometheus.MustRegister(planCacheHitCounter)
+	prometheus.MustRegister(planCacheMissCounter)
+	prometheus.MustRegister(spillFileCounter)
 }
@@ -36,6 +36,8 @@ package server
 
 import (
 	"bufio"
+	"bytes"
+	"compress/zlib"
 	"io"
 	"net"
 
@@ -46,6 +48,13 @@ import (
 const (
 	defaultReaderSize = 16 * 1024
 	defaultWriterSize = 16 * 1024
+
+	// compressedHeaderLen is the length of the compressed packet header, it is made of
+	// the 3 bytes compressed payload length, the 1 byte compressed sequence and the 3 bytes
+	// uncompressed payload length.
+	compressedHeaderLen = 7
+	// minCompressLen is the min payload length to compress, smaller payloads are sent as is.
+	minCompressLen = 50
 )
 
 type packetIO struct {
@@ -53,6 +62,16 @@ type packetIO struct {
 	wb *bufio.Writer
 
 	sequence uint8
+
+	// compress is set after the handshake if the client negotiates CLIENT_COMPRESS,
+	// then the packets are sent and received inside compressed packets.
+	// See https://dev.mysql.com/doc/internals/en/compressed-packet-header.html
+	compress           bool
+	compressedSequence uint8
+	// compressedReadBuf holds the uncompressed payload that has not been read yet.
+	compressedReadBuf bytes.Buffer
+	// compressedWriteBuf holds the packets that have not been compressed and sent yet.
+	compressedWriteBuf bytes.Buffer
 }
 
 func newPacketIO(conn net.Conn) *packetIO {
@@ -64,10 +83,148 @@ func newPacketIO(conn net.Conn) *packetIO {
 	return p
 }
 
+// bufferedReadConn is a net.Conn that reads through the buffered reader, so the
+// data that is already buffered is not lost when the connection is wrapped.
+type bufferedReadConn struct {
+	net.Conn
+	rb *bufio.Reader
+}
+
+// Read implements net.Conn Read interface.
+func (conn bufferedReadConn) Read(b []byte) (int, error) {
+	return conn.rb.Read(b)
+}
+
+// setConn replaces the connection to read from and write to, it is used after the connection is upgraded to TLS.
+func (p *packetIO) setConn(conn net.Conn) {
+	p.rb = bufio.NewReaderSize(conn, defaultReaderSize)
+	p.wb = bufio.NewWriterSize(conn, defaultWriterSize)
+}
+
+// resetSequence resets the sequences when a new command starts.
+func (p *packetIO) resetSequence() {
+	p.sequence = 0
+	p.compressedSequence = 0
+}
+
+// readFull reads exactly len(b) bytes of the packets.
+func (p *packetIO) readFull(b []byte) error {
+	if !p.compress {
+		_, err := io.ReadFull(p.rb, b)
+		return errors.Trace(err)
+	}
+	for p.compressedReadBuf.Len() < len(b) {
+		if err := p.readCompressedPacket(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	p.compressedReadBuf.Read(b)
+	return nil
+}
+
+// readCompressedPacket reads a compressed packet and appends its uncompressed payload to compressedReadBuf.
+func (p *packetIO) readCompressedPacket() error {
+	var header [compressedHeaderLen]byte
+	if _, err := io.ReadFull(p.rb, header[:]); err != nil {
+		return errors.Trace(err)
+	}
+	compressedLength := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+	sequence := uint8(header[3])
+	uncompressedLength := int(uint32(header[4]) | uint32(header[5])<<8 | uint32(header[6])<<16)
+	if sequence != p.compressedSequence {
+		return errInvalidSequence.Gen("invalid compressed sequence %d != %d", sequence, p.compressedSequence)
+	}
+	p.compressedSequence++
+
+	data := make([]byte, compressedLength)
+	if _, err := io.ReadFull(p.rb, data); err != nil {
+		return errors.Trace(err)
+	}
+	// The payload is not compressed if the uncompressed length is 0.
+	if uncompressedLength == 0 {
+		p.compressedReadBuf.Write(data)
+		return nil
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer r.Close()
+	p.compressedReadBuf.Grow(uncompressedLength)
+	// Read at most one byte more than declared, so a frame that inflates past its
+	// length is rejected without decompressing all of it.
+	n, err := p.compressedReadBuf.ReadFrom(io.LimitReader(r, int64(uncompressedLength)+1))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if int(n) != uncompressedLength {
+		return errInvalidPayloadLen.Gen("invalid compressed payload length %d != %d", n, uncompressedLength)
+	}
+	return nil
+}
+
+// write writes the packets to the connection buffer, in compressed protocol the
+// packets are buffered and compressed together.
+func (p *packetIO) write(data []byte) (int, error) {
+	if !p.compress {
+		return p.wb.Write(data)
+	}
+	n, _ := p.compressedWriteBuf.Write(data)
+	if p.compressedWriteBuf.Len() >= defaultWriterSize {
+		if err := p.writeCompressedPackets(); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	return n, nil
+}
+
+// writeCompressedPackets sends the buffered packets in compressed packets.
+func (p *packetIO) writeCompressedPackets() error {
+	var compressed bytes.Buffer
+	for p.compressedWriteBuf.Len() > 0 {
+		payload := p.compressedWriteBuf.Next(mysql.MaxPayloadLen)
+		compressed.Reset()
+		compressed.Write(make([]byte, compressedHeaderLen))
+		uncompressedLength := len(payload)
+		if uncompressedLength >= minCompressLen {
+			w := zlib.NewWriter(&compressed)
+			_, err := w.Write(payload)
+			if err == nil {
+				err = w.Close()
+			}
+			if err != nil {
+				return errors.Trace(err)
+			}
+		}
+		// Send the payload as is if it is not worth compressing.
+		if uncompressedLength < minCompressLen || compressed.Len()-compressedHeaderLen >= uncompressedLength {
+			compressed.Truncate(compressedHeaderLen)
+			compressed.Write(payload)
+			uncompressedLength = 0
+		}
+
+		data := compressed.Bytes()
+		length := len(data) - compressedHeaderLen
+		data[0] = byte(length)
+		data[1] = byte(length >> 8)
+		data[2] = byte(length >> 16)
+		data[3] = p.compressedSequence
+		data[4] = byte(uncompressedLength)
+		data[5] = byte(uncompressedLength >> 8)
+		data[6] = byte(uncompressedLength >> 16)
+		if _, err := p.wb.Write(data); err != nil {
+			return errors.Trace(mysql.ErrBadConn)
+		}
+		p.compressedSequence++
+	}
+	p.compressedWriteBuf.Reset()
+	return nil
+}
+
 func (p *packetIO) readOnePacket() ([]byte, error) {
 	var header [4]byte
 
-	if _, err := io.ReadFull(p.rb, header[:]); err != nil {
+	if err := p.readFull(header[:]); err != nil {
 		return nil, errors.Trace(err)
 	}
 
@@ -81,7 +238,7 @@ func (p *packetIO) readOnePacket() ([]byte, error) {
 	length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
 
 	data := make([]byte, length)
-	if _, err := io.ReadFull(p.rb, data); err != nil {
+	if err := p.readFull(data); err != nil {
 		return nil, errors.Trace(err)
 	}
 	return data, nil
@@ -125,7 +282,7 @@ func (p *packetIO) writePacket(data []byte) error {
 
 		data[3] = p.sequence
 
-		if n, err := p.wb.Write(data[:4+mysql.MaxPayloadLen]); err != nil {
+		if n, err := p.write(data[:4+mysql.MaxPayloadLen]); err != nil {
 			return mysql.ErrBadConn
 		} else if n != (4 + mysql.MaxPayloadLen) {
 			return mysql.ErrBadConn
@@ -141,7 +298,7 @@ func (p *packetIO) writePacket(data []byte) error {
 	data[2] = byte(length >> 16)
 	data[3] = p.sequence
 
-	if n, err := p.wb.Write(data); err != nil {
+	if n, err := p.write(data); err != nil {
 		return errors.Trace(mysql.ErrBadConn)
 	} else if n != len(data) {
 		return errors.Trace(mysql.ErrBadConn)
@@ -152,5 +309,10 @@ func (p *packetIO) writePacket(data []byte) error {
 }
 
 func (p *packetIO) flush() error {
+	if p.compress {
+		if err := p.writeCompressedPackets(); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	return p.wb.Flush()
 }
@@ -0,0 +1,115 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+type PacketIOTestSuite struct{}
+
+var _ = Suite(PacketIOTestSuite{})
+
+func newBufferPacketIO(buf *bytes.Buffer, compress bool) *packetIO {
+	return &packetIO{
+		rb:       bufio.NewReaderSize(buf, defaultReaderSize),
+		wb:       bufio.NewWriterSize(buf, defaultWriterSize),
+		compress: compress,
+	}
+}
+
+func (ts PacketIOTestSuite) TestCompressedPacket(c *C) {
+	defer testleak.AfterTest(c)()
+	payloads := [][]byte{
+		[]byte("select 1"),
+		bytes.Repeat([]byte("a"), 1000),
+		bytes.Repeat([]byte("abcdefg"), 3*defaultWriterSize),
+		bytes.Repeat([]byte("b"), mysql.MaxPayloadLen+100),
+		{},
+	}
+	var buf bytes.Buffer
+	w := newBufferPacketIO(&buf, true)
+	for _, payload := range payloads {
+		data := append(make([]byte, 4), payload...)
+		c.Assert(w.writePacket(data), IsNil)
+	}
+	c.Assert(w.flush(), IsNil)
+	// The payloads are compressed.
+	c.Assert(buf.Len(), Less, 1000+3*defaultWriterSize)
+
+	r := newBufferPacketIO(&buf, true)
+	for _, payload := range payloads {
+		data, err := r.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(bytes.Equal(data, payload), IsTrue)
+	}
+	c.Assert(r.sequence, Equals, w.sequence)
+	c.Assert(r.compressedSequence, Equals, w.compressedSequence)
+	c.Assert(buf.Len(), Equals, 0)
+
+	w.resetSequence()
+	c.Assert(w.sequence, Equals, uint8(0))
+	c.Assert(w.compressedSequence, Equals, uint8(0))
+}
+
+func (ts PacketIOTestSuite) TestUncompressedPayload(c *C) {
+	defer testleak.AfterTest(c)()
+	// A compressed packet with uncompressed length 0 carries the packet as is.
+	packet := []byte{0x03, 0x00, 0x00, 0x00, mysql.ComQuery, 0x31, 0x32}
+	header := []byte{byte(len(packet)), 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	buf := bytes.NewBuffer(append(header, packet...))
+	r := newBufferPacketIO(buf, true)
+	data, err := r.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, []byte{mysql.ComQuery, 0x31, 0x32})
+
+	// Small packets are sent without compression.
+	var out bytes.Buffer
+	w := newBufferPacketIO(&out, true)
+	c.Assert(w.writePacket(append(make([]byte, 4), mysql.ComQuery, 0x31, 0x32)), IsNil)
+	c.Assert(w.flush(), IsNil)
+	c.Assert(out.Bytes(), DeepEquals, append(header, packet...))
+
+	// The compressed sequence is checked.
+	header[3] = 0x01
+	buf = bytes.NewBuffer(append(header, packet...))
+	r = newBufferPacketIO(buf, true)
+	_, err = r.readPacket()
+	c.Assert(terror.ErrorEqual(err, errInvalidSequence), IsTrue)
+}
+
+func (ts PacketIOTestSuite) TestCompressedPayloadLength(c *C) {
+	defer testleak.AfterTest(c)()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write(bytes.Repeat([]byte("a"), 1<<20))
+	c.Assert(err, IsNil)
+	c.Assert(zw.Close(), IsNil)
+
+	// A frame inflating past its declared uncompressed length is rejected.
+	l := compressed.Len()
+	header := []byte{byte(l), byte(l >> 8), byte(l >> 16), 0x00, 0x00, 0x01, 0x00}
+	buf := bytes.NewBuffer(append(header, compressed.Bytes()...))
+	r := newBufferPacketIO(buf, true)
+	_, err = r.readPacket()
+	c.Assert(terror.ErrorEqual(err, errInvalidPayloadLen), IsTrue)
+	c.Assert(r.compressedReadBuf.Len(), Equals, 0x100+1)
+}
@@ -0,0 +1,1090 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The codes of the startup packets of the PostgreSQL v3 protocol.
+// See https://www.postgresql.org/docs/9.6/static/protocol-message-formats.html
+const (
+	pgProtocolVersion3  uint32 = 196608
+	pgSSLRequestCode    uint32 = 80877103
+	pgCancelRequestCode uint32 = 80877102
+)
+
+// The messages sent by the client.
+const (
+	pgMsgQuery     byte = 'Q'
+	pgMsgParse     byte = 'P'
+	pgMsgBind      byte = 'B'
+	pgMsgDescribe  byte = 'D'
+	pgMsgExecute   byte = 'E'
+	pgMsgSync      byte = 'S'
+	pgMsgFlush     byte = 'H'
+	pgMsgClose     byte = 'C'
+	pgMsgTerminate byte = 'X'
+	pgMsgPassword  byte = 'p'
+)
+
+// The messages sent by the server.
+const (
+	pgMsgAuthentication       byte = 'R'
+	pgMsgParameterStatus      byte = 'S'
+	pgMsgBackendKeyData       byte = 'K'
+	pgMsgReadyForQuery        byte = 'Z'
+	pgMsgRowDescription       byte = 'T'
+	pgMsgDataRow              byte = 'D'
+	pgMsgCommandComplete      byte = 'C'
+	pgMsgErrorResponse        byte = 'E'
+	pgMsgEmptyQueryResponse   byte = 'I'
+	pgMsgParseComplete        byte = '1'
+	pgMsgBindComplete         byte = '2'
+	pgMsgCloseComplete        byte = '3'
+	pgMsgNoData               byte = 'n'
+	pgMsgParameterDescription byte = 't'
+	pgMsgPortalSuspended      byte = 's'
+)
+
+// The authentication requests.
+const (
+	pgAuthOK                int32 = 0
+	pgAuthCleartextPassword int32 = 3
+	pgAuthMD5Password       int32 = 5
+)
+
+// The authentication methods of the PostgreSQL protocol server.
+const (
+	// PGAuthMD5 uses the MD5 authentication if the MD5 digest of the user is known, which is
+	// cached after the user is verified with the cleartext password. It's the default method.
+	PGAuthMD5 = "md5"
+	// PGAuthPassword always uses the cleartext password authentication.
+	PGAuthPassword = "password"
+)
+
+// The maximum lengths of the messages from the client. The messages before the authentication
+// succeeds are limited like PostgreSQL, so an unauthenticated client can't make the server
+// allocate large buffers.
+const (
+	pgMaxMessageLen = 1 << 30
+	// pgMaxStartupPacketLen is MAX_STARTUP_PACKET_LENGTH of PostgreSQL.
+	pgMaxStartupPacketLen = 10000
+	// pgMaxPasswordMessageLen is the limit of the password message in PostgreSQL.
+	pgMaxPasswordMessageLen = 1000
+)
+
+// pgCapability is the client capability of the sessions of the PostgreSQL protocol,
+// UPDATE reports the matched rows like PostgreSQL.
+const pgCapability = mysql.ClientProtocol41 | mysql.ClientTransactions | mysql.ClientFoundRows
+
+// pgStatement is a prepared statement created by Parse.
+type pgStatement struct {
+	query string
+	// stmt is nil if the statement is empty or local.
+	stmt  IStatement
+	local *pgLocalStmt
+	// paramOIDs are the types of the parameters specified by the client, 0 means unspecified.
+	paramOIDs []uint32
+	// paramOrder is the index of the parameter of each placeholder of stmt.
+	paramOrder []int
+	columns    []*ColumnInfo
+}
+
+// pgPortal is a statement with the bound parameters created by Bind.
+type pgPortal struct {
+	stmt          *pgStatement
+	args          []interface{}
+	resultFormats []int16
+
+	// The fields below are set when the portal is executed.
+	executed bool
+	rs       ResultSet
+	columns  []*ColumnInfo
+	// row is the next row of rs to send.
+	row      []types.Datum
+	rowsSent uint64
+	affected uint64
+}
+
+func (p *pgPortal) close() {
+	if p.rs != nil {
+		p.rs.Close()
+		p.rs = nil
+	}
+}
+
+// pgConn is a connection of the PostgreSQL protocol.
+type pgConn struct {
+	conn         net.Conn
+	rb           *bufio.Reader
+	wb           *bufio.Writer
+	server       *Server
+	connectionID uint32
+	// secretKey is sent in BackendKeyData, it's required by the CancelRequest of the connection.
+	secretKey uint32
+	user      string
+	dbname    string
+	ctx       IContext
+	params    map[string]*pgParam
+	stmts     map[string]*pgStatement
+	portals   map[string]*pgPortal
+	// tlsState is the TLS state of the connection, it is nil if the connection is not encrypted.
+	tlsState *tls.ConnectionState
+	// maxQuestions is the MAX_QUERIES_PER_HOUR limit of the user, 0 means no limit.
+	maxQuestions uint64
+	// userConnCounted is true if the connection is counted for the MAX_USER_CONNECTIONS limit.
+	userConnCounted bool
+	// ignoreTillSync is set when an error occurs in the extended query, the messages are
+	// discarded until Sync like PostgreSQL.
+	ignoreTillSync bool
+	// maxMessageLen is the maximum length of the messages read by readMessage, it's raised to
+	// pgMaxMessageLen after the authentication succeeds.
+	maxMessageLen uint32
+
+	// mu protects the process info of the connection, it is read by SHOW PROCESSLIST in other connections.
+	mu struct {
+		sync.Mutex
+		command   byte
+		db        string
+		info      string
+		startTime time.Time
+	}
+}
+
+func (s *Server) newPGConn(conn net.Conn) *pgConn {
+	log.Info("newPGConn", conn.RemoteAddr().String())
+	pc := &pgConn{
+		conn:          conn,
+		server:        s,
+		connectionID:  atomic.AddUint32(&baseConnID, 1),
+		secretKey:     rand.Uint32(),
+		params:        newPGParams(),
+		stmts:         make(map[string]*pgStatement),
+		portals:       make(map[string]*pgPortal),
+		maxMessageLen: pgMaxPasswordMessageLen,
+	}
+	pc.setConn(conn)
+	return pc
+}
+
+func (pc *pgConn) setConn(conn net.Conn) {
+	pc.conn = conn
+	pc.rb = bufio.NewReaderSize(conn, defaultReaderSize)
+	pc.wb = bufio.NewWriterSize(conn, defaultWriterSize)
+}
+
+// setProcessInfo records the command the connection is running.
+func (pc *pgConn) setProcessInfo(command byte, info string) {
+	db := pc.ctx.CurrentDB()
+	pc.mu.Lock()
+	pc.mu.command = command
+	pc.mu.db = db
+	pc.mu.info = info
+	pc.mu.startTime = time.Now()
+	pc.mu.Unlock()
+}
+
+func (pc *pgConn) processInfo() util.ProcessInfo {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pi := util.ProcessInfo{
+		ID:      uint64(pc.connectionID),
+		User:    pc.user,
+		Host:    pc.conn.RemoteAddr().String(),
+		DB:      pc.mu.db,
+		Command: mysql.Command2Str[pc.mu.command],
+		Time:    pc.mu.startTime,
+		Info:    pc.mu.info,
+	}
+	if pc.mu.command != mysql.ComSleep {
+		pi.State = "executing"
+	}
+	return pi
+}
+
+// isIdle returns whether the connection is waiting for the next message.
+func (pc *pgConn) isIdle() bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.mu.command == mysql.ComSleep
+}
+
+func (pc *pgConn) String() string {
+	return fmt.Sprintf("pg conn: %s, user: %s", pc.conn.RemoteAddr(), pc.user)
+}
+
+// readStartupMessage reads the startup packet, which has no message type.
+func (pc *pgConn) readStartupMessage() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(pc.rb, header[:]); err != nil {
+		return nil, errors.Trace(err)
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length < 8 || length > pgMaxStartupPacketLen {
+		return nil, errInvalidPayloadLen.Gen("invalid startup packet length %d", length)
+	}
+	data := make([]byte, length-4)
+	_, err := io.ReadFull(pc.rb, data)
+	return data, errors.Trace(err)
+}
+
+// readMessage reads a message, which is the message type, the length including itself and the payload.
+func (pc *pgConn) readMessage() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(pc.rb, header[:]); err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length < 4 || length > pc.maxMessageLen {
+		return 0, nil, errInvalidPayloadLen.Gen("invalid message length %d", length)
+	}
+	data := make([]byte, length-4)
+	_, err := io.ReadFull(pc.rb, data)
+	return header[0], data, errors.Trace(err)
+}
+
+// writeMessage writes a message to the buffer, it's sent when the buffer is flushed.
+func (pc *pgConn) writeMessage(tp byte, payload []byte) error {
+	var header [5]byte
+	header[0] = tp
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)+4))
+	if _, err := pc.wb.Write(header[:]); err != nil {
+		return errors.Trace(err)
+	}
+	_, err := pc.wb.Write(payload)
+	return errors.Trace(err)
+}
+
+func (pc *pgConn) flush() error {
+	return errors.Trace(pc.wb.Flush())
+}
+
+func appendPGInt16(data []byte, v int16) []byte {
+	return append(data, byte(v>>8), byte(v))
+}
+
+func appendPGInt32(data []byte, v int32) []byte {
+	return append(data, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendPGString(data []byte, s string) []byte {
+	return append(append(data, s...), 0)
+}
+
+// pgReader reads the fields of a message, the error is recorded and the following reads return zero values.
+type pgReader struct {
+	data []byte
+	err  error
+}
+
+func (r *pgReader) fail() {
+	if r.err == nil {
+		r.err = errInvalidPayloadLen.Gen("malformed message")
+	}
+	r.data = nil
+}
+
+func (r *pgReader) int16() int16 {
+	if len(r.data) < 2 {
+		r.fail()
+		return 0
+	}
+	v := int16(binary.BigEndian.Uint16(r.data))
+	r.data = r.data[2:]
+	return v
+}
+
+// count reads the int16 number of the following fields, the message is malformed if it's negative.
+func (r *pgReader) count() int {
+	n := r.int16()
+	if n < 0 {
+		r.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (r *pgReader) int32() int32 {
+	if len(r.data) < 4 {
+		r.fail()
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(r.data))
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *pgReader) string() string {
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.fail()
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+func (r *pgReader) bytes(n int) []byte {
+	if n < 0 || len(r.data) < n {
+		r.fail()
+		return nil
+	}
+	v := r.data[:n]
+	r.data = r.data[n:]
+	return v
+}
+
+// handshake handles the startup packets and the authentication.
+func (pc *pgConn) handshake() error {
+	var startup map[string]string
+	for startup == nil {
+		data, err := pc.readStartupMessage()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		r := &pgReader{data: data}
+		switch code := uint32(r.int32()); code {
+		case pgSSLRequestCode:
+			if err = pc.handleSSLRequest(); err != nil {
+				return errors.Trace(err)
+			}
+		case pgCancelRequestCode:
+			pid, key := uint32(r.int32()), uint32(r.int32())
+			if r.err == nil {
+				pc.server.cancelPGQuery(pid, key)
+			}
+			// The connection of CancelRequest is closed without any response.
+			return io.EOF
+		case pgProtocolVersion3:
+			startup = make(map[string]string)
+			for len(r.data) > 1 && r.err == nil {
+				startup[r.string()] = r.string()
+			}
+			if r.err != nil {
+				return errors.Trace(r.err)
+			}
+		default:
+			return errors.Errorf("unsupported frontend protocol %d.%d", code>>16, code&0xffff)
+		}
+	}
+	pc.user = startup["user"]
+	pc.dbname = startup["database"]
+	for name, value := range startup {
+		if p, ok := pc.params[strings.ToLower(name)]; ok {
+			if err := checkPGParam(p, value); err != nil {
+				return errors.Trace(err)
+			}
+			p.value = value
+		}
+	}
+
+	var err error
+	pc.ctx, err = pc.server.driver.OpenCtx(uint64(pc.connectionID), pgCapability, mysql.DefaultCollationID, pc.dbname)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if pc.tlsState != nil {
+		pc.ctx.SetTLSState(pc.tlsState)
+	}
+	pc.ctx.SetSessionManager(pc.server)
+	if !pc.server.skipAuth() {
+		if err = pc.authenticate(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	pc.maxMessageLen = pgMaxMessageLen
+
+	if err = pc.writeMessage(pgMsgAuthentication, appendPGInt32(nil, pgAuthOK)); err != nil {
+		return errors.Trace(err)
+	}
+	for _, p := range pc.params {
+		if p.reported {
+			if err = pc.writeParameterStatus(p); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	data := appendPGInt32(nil, int32(pc.connectionID))
+	data = appendPGInt32(data, int32(pc.secretKey))
+	if err = pc.writeMessage(pgMsgBackendKeyData, data); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(pc.writeReadyForQuery())
+}
+
+// handleSSLRequest replies 'S' and does the TLS handshake if TLS is enabled, or replies 'N' otherwise.
+func (pc *pgConn) handleSSLRequest() error {
+	if pc.server.tlsConfig == nil || pc.tlsState != nil {
+		if _, err := pc.conn.Write([]byte{'N'}); err != nil {
+			return errors.Trace(err)
+		}
+		return nil
+	}
+	if _, err := pc.conn.Write([]byte{'S'}); err != nil {
+		return errors.Trace(err)
+	}
+	tlsConn := tls.Server(bufferedReadConn{Conn: pc.conn, rb: pc.rb}, pc.server.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return errors.Trace(err)
+	}
+	pc.setConn(tlsConn)
+	state := tlsConn.ConnectionState()
+	pc.tlsState = &state
+	return nil
+}
+
+// authenticate asks the client for the password. The MD5 authentication is used if the MD5 digest
+// of the user is known, otherwise the cleartext password is asked, which is refused on the connections
+// without TLS unless PGAllowInsecurePassword is set. The digest is cached when the user logs in with
+// the cleartext password, so a user must log in with TLS once before the MD5 authentication works.
+func (pc *pgConn) authenticate() error {
+	addr := pc.conn.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, pc.user, addr, "Yes"))
+	}
+	user := fmt.Sprintf("%s@%s", pc.user, host)
+	useMD5 := pc.server.cfg.PGAuthMethod != PGAuthPassword && pc.ctx.HasMD5Digest(user)
+	if !useMD5 && pc.tlsState == nil && !pc.server.cfg.PGAllowInsecurePassword {
+		return errors.Trace(mysql.NewErrf(mysql.ErrAccessDenied,
+			"Access denied for user '%s'@'%s', log in with TLS to send the password", pc.user, host))
+	}
+	var salt []byte
+	data := appendPGInt32(nil, pgAuthCleartextPassword)
+	if useMD5 {
+		salt = randomBuf(4)
+		data = append(appendPGInt32(nil, pgAuthMD5Password), salt...)
+	}
+	if err = pc.writeMessage(pgMsgAuthentication, data); err != nil {
+		return errors.Trace(err)
+	}
+	if err = pc.flush(); err != nil {
+		return errors.Trace(err)
+	}
+	tp, data, err := pc.readMessage()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if tp != pgMsgPassword {
+		return errors.Errorf("expected password response, got message type %c", tp)
+	}
+	password := bytes.TrimSuffix(data, []byte{0})
+	var ok bool
+	if useMD5 {
+		ok = pc.ctx.AuthMD5(user, password, salt)
+	} else {
+		ok = pc.ctx.AuthPassword(user, password)
+	}
+	if !ok {
+		return errors.Trace(mysql.NewErr(mysql.ErrAccessDenied, pc.user, host, "Yes"))
+	}
+	var maxUserConnections uint64
+	pc.maxQuestions, maxUserConnections = pc.ctx.UserResourceLimits(user)
+	if err = pc.server.acquireUserConn(pc.user, maxUserConnections); err != nil {
+		return errors.Trace(err)
+	}
+	pc.userConnCounted = true
+	return nil
+}
+
+// releaseUserConn releases the connection counted for the MAX_USER_CONNECTIONS limit.
+func (pc *pgConn) releaseUserConn() {
+	if pc.userConnCounted {
+		pc.server.releaseUserConn(pc.user)
+		pc.userConnCounted = false
+	}
+}
+
+func (pc *pgConn) Close() error {
+	pc.server.rwlock.Lock()
+	delete(pc.server.pgClients, pc.connectionID)
+	pc.server.rwlock.Unlock()
+	pc.releaseUserConn()
+	for _, p := range pc.portals {
+		p.close()
+	}
+	pc.conn.Close()
+	connGauge.Dec()
+	if pc.ctx != nil {
+		return pc.ctx.Close()
+	}
+	return nil
+}
+
+// Run reads the messages and dispatches them until the connection is closed.
+func (pc *pgConn) Run() {
+	defer func() {
+		r := recover()
+		if r != nil {
+			const size = 4096
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Errorf("pg conn %d, %v, %s", pc.connectionID, r, buf)
+		}
+		pc.Close()
+	}()
+
+	connGauge.Inc()
+
+	for {
+		if pc.server.isShuttingDown() {
+			return
+		}
+		tp, data, err := pc.readMessage()
+		if err != nil {
+			if terror.ErrorNotEqual(err, io.EOF) && !pc.server.isShuttingDown() {
+				log.Error(errors.ErrorStack(err))
+			}
+			return
+		}
+		if err = pc.dispatch(tp, data); err != nil {
+			if terror.ErrorEqual(err, io.EOF) {
+				return
+			}
+			log.Warnf("dispatch error %s, %s", errors.ErrorStack(err), pc)
+			return
+		}
+	}
+}
+
+// dispatch handles a message. The errors of the statements are sent to the client, the error
+// returned closes the connection.
+func (pc *pgConn) dispatch(tp byte, data []byte) error {
+	switch tp {
+	case pgMsgTerminate:
+		return io.EOF
+	case pgMsgSync:
+		pc.ignoreTillSync = false
+		return errors.Trace(pc.writeReadyForQuery())
+	case pgMsgFlush:
+		return errors.Trace(pc.flush())
+	case pgMsgQuery:
+		r := &pgReader{data: data}
+		sql := r.string()
+		if r.err != nil {
+			return errors.Trace(r.err)
+		}
+		if err := pc.server.useQuestion(pc.user, pc.maxQuestions); err != nil {
+			pc.writeError(err)
+			return errors.Trace(pc.writeReadyForQuery())
+		}
+		pc.runCommand(sql, func() error {
+			return pc.handleQuery(sql)
+		})
+		return errors.Trace(pc.writeReadyForQuery())
+	case pgMsgParse, pgMsgBind, pgMsgDescribe, pgMsgExecute, pgMsgClose:
+		if pc.ignoreTillSync {
+			return nil
+		}
+		err := pc.runCommand("", func() error {
+			return pc.dispatchExtended(tp, data)
+		})
+		if err != nil {
+			pc.ignoreTillSync = true
+		}
+		return nil
+	default:
+		pc.writeError(errors.Errorf("unsupported message type %c", tp))
+		pc.flush()
+		return io.EOF
+	}
+}
+
+// runCommand runs fn with the token and the process info, the error of fn is sent to the client.
+func (pc *pgConn) runCommand(info string, fn func() error) error {
+	token := pc.server.getToken()
+	pc.setProcessInfo(mysql.ComQuery, info)
+	defer func() {
+		pc.server.releaseToken(token)
+		pc.setProcessInfo(mysql.ComSleep, "")
+	}()
+	err := fn()
+	if err != nil {
+		log.Warnf("pg command error %s, %s", errors.ErrorStack(err), pc)
+		pc.writeError(err)
+	}
+	return err
+}
+
+func (pc *pgConn) dispatchExtended(tp byte, data []byte) error {
+	r := &pgReader{data: data}
+	switch tp {
+	case pgMsgParse:
+		return pc.handleParse(r)
+	case pgMsgBind:
+		return pc.handleBind(r)
+	case pgMsgDescribe:
+		return pc.handleDescribe(r)
+	case pgMsgExecute:
+		return pc.handleExecute(r)
+	default:
+		return pc.handleClose(r)
+	}
+}
+
+// handleQuery handles the simple query, the statements after an error are not executed.
+func (pc *pgConn) handleQuery(sql string) error {
+	queries := pgSplitQuery(sql)
+	if len(queries) == 0 {
+		return errors.Trace(pc.writeMessage(pgMsgEmptyQueryResponse, nil))
+	}
+	for _, q := range queries {
+		if err := pc.handleSimpleStmt(q); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (pc *pgConn) handleSimpleStmt(sql string) error {
+	if local := parsePGLocalStmt(sql, pc.params); local != nil {
+		rs, err := pc.execLocal(local)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(pc.writeResult(sql, rs, nil, true))
+	}
+	query, paramOrder, err := pgRewrite(sql)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(paramOrder) > 0 {
+		return mysql.NewErrf(mysql.ErrParse, "there is no parameter $%d", paramOrder[0]+1)
+	}
+	stmts, err := pc.ctx.Parse(query)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, stmt := range stmts {
+		rs, err := pc.ctx.ExecuteStmt(stmt)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err = pc.checkLoadData(); err != nil {
+			return errors.Trace(err)
+		}
+		if err = pc.writeResult(stmt.Text(), rs, nil, true); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// checkLoadData rejects LOAD DATA LOCAL, which needs the file transfer of the MySQL protocol.
+func (pc *pgConn) checkLoadData() error {
+	if pc.ctx.Value(executor.LoadDataVarKey) == nil {
+		return nil
+	}
+	pc.ctx.SetValue(executor.LoadDataVarKey, nil)
+	return mysql.NewErrf(mysql.ErrNotSupportedYet, "LOAD DATA LOCAL is not supported by the PostgreSQL protocol")
+}
+
+// writeResult writes RowDescription if describe is true, then the rows and CommandComplete.
+func (pc *pgConn) writeResult(sql string, rs ResultSet, formats []int16, describe bool) error {
+	if rs == nil {
+		return errors.Trace(pc.writeCommandComplete(pgCommandTag(sql, false, pc.ctx.AffectedRows())))
+	}
+	p := &pgPortal{resultFormats: formats}
+	if err := p.open(rs); err != nil {
+		return errors.Trace(err)
+	}
+	defer p.close()
+	if describe {
+		if err := pc.writeRowDescription(p.columns, formats); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	_, err := pc.writeRows(sql, p, 0)
+	return errors.Trace(err)
+}
+
+// open stores the result set to the portal, the first row is read before the columns since the
+// columns may be incorrect before it.
+func (p *pgPortal) open(rs ResultSet) error {
+	p.rs = rs
+	row, err := rs.Next()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	p.row = row
+	p.columns, err = rs.Columns()
+	return errors.Trace(err)
+}
+
+// writeRows writes at most maxRows rows of the portal, 0 means no limit. It writes PortalSuspended
+// and returns true if there are more rows, or CommandComplete otherwise.
+func (pc *pgConn) writeRows(sql string, p *pgPortal, maxRows int) (suspended bool, err error) {
+	oids := make([]uint32, len(p.columns))
+	for i, col := range p.columns {
+		oids[i] = pgTypeOID(col)
+	}
+	for n := 0; p.row != nil; n++ {
+		if maxRows > 0 && n == maxRows {
+			return true, errors.Trace(pc.writeMessage(pgMsgPortalSuspended, nil))
+		}
+		data := appendPGInt16(nil, int16(len(p.row)))
+		for i, value := range p.row {
+			if value.IsNull() {
+				data = appendPGInt32(data, -1)
+				continue
+			}
+			v, err1 := dumpPGValue(oids[i], pgFormat(p.resultFormats, i), p.columns[i], value)
+			if err1 != nil {
+				return false, errors.Trace(err1)
+			}
+			data = appendPGInt32(data, int32(len(v)))
+			data = append(data, v...)
+		}
+		if err = pc.writeMessage(pgMsgDataRow, data); err != nil {
+			return false, errors.Trace(err)
+		}
+		p.rowsSent++
+		if p.row, err = p.rs.Next(); err != nil {
+			return false, errors.Trace(err)
+		}
+	}
+	p.close()
+	return false, errors.Trace(pc.writeCommandComplete(pgCommandTag(sql, true, p.rowsSent)))
+}
+
+// pgFormat returns the format of the i-th field, there may be no format for all the fields,
+// one format for all the fields or one format for each field.
+func pgFormat(formats []int16, i int) int16 {
+	switch len(formats) {
+	case 0:
+		return pgFormatText
+	case 1:
+		return formats[0]
+	}
+	if i < len(formats) {
+		return formats[i]
+	}
+	return pgFormatText
+}
+
+func (pc *pgConn) writeRowDescription(columns []*ColumnInfo, formats []int16) error {
+	if len(columns) == 0 {
+		return errors.Trace(pc.writeMessage(pgMsgNoData, nil))
+	}
+	data := appendPGInt16(nil, int16(len(columns)))
+	for i, col := range columns {
+		oid := pgTypeOID(col)
+		data = appendPGString(data, col.Name)
+		// The table OID and the column attribute number.
+		data = appendPGInt32(data, 0)
+		data = appendPGInt16(data, 0)
+		data = appendPGInt32(data, int32(oid))
+		data = appendPGInt16(data, pgTypeSize(oid))
+		// The type modifier.
+		data = appendPGInt32(data, -1)
+		data = appendPGInt16(data, pgFormat(formats, i))
+	}
+	return errors.Trace(pc.writeMessage(pgMsgRowDescription, data))
+}
+
+func (pc *pgConn) writeCommandComplete(tag string) error {
+	return errors.Trace(pc.writeMessage(pgMsgCommandComplete, appendPGString(nil, tag)))
+}
+
+func (pc *pgConn) writeParameterStatus(p *pgParam) error {
+	return errors.Trace(pc.writeMessage(pgMsgParameterStatus, appendPGString(appendPGString(nil, p.name), p.value)))
+}
+
+// writeReadyForQuery writes ReadyForQuery with the transaction status and flushes the buffer.
+func (pc *pgConn) writeReadyForQuery() error {
+	status := byte('I')
+	if pc.ctx.Status()&mysql.ServerStatusInTrans > 0 {
+		status = 'T'
+	}
+	if err := pc.writeMessage(pgMsgReadyForQuery, []byte{status}); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(pc.flush())
+}
+
+// writeError writes ErrorResponse with the SQLSTATE code mapped from the MySQL error.
+func (pc *pgConn) writeError(e error) error {
+	return errors.Trace(pc.writeErrorWithSeverity(e, "ERROR"))
+}
+
+func (pc *pgConn) writeErrorWithSeverity(e error, severity string) error {
+	var (
+		m  *mysql.SQLError
+		te *terror.Error
+		ok bool
+	)
+	originErr := errors.Cause(e)
+	if te, ok = originErr.(*terror.Error); ok {
+		m = te.ToSQLError()
+	} else if m, ok = originErr.(*mysql.SQLError); !ok {
+		m = mysql.NewErrf(mysql.ErrUnknown, "%s", e.Error())
+	}
+	data := appendPGString([]byte{'S'}, severity)
+	data = appendPGString(append(data, 'V'), severity)
+	data = appendPGString(append(data, 'C'), pgErrorCode(m))
+	data = appendPGString(append(data, 'M'), m.Message)
+	data = append(data, 0)
+	return errors.Trace(pc.writeMessage(pgMsgErrorResponse, data))
+}
+
+// execLocal executes the SET or SHOW of a run-time parameter, the result set of SET is nil.
+func (pc *pgConn) execLocal(local *pgLocalStmt) (ResultSet, error) {
+	p := pc.params[local.name]
+	if local.show {
+		return newPGLocalResultSet(local.name, p.value), nil
+	}
+	value := local.value
+	if strings.EqualFold(value, "default") {
+		value = newPGParams()[local.name].value
+	}
+	if err := checkPGParam(p, value); err != nil {
+		return nil, errors.Trace(err)
+	}
+	p.value = value
+	if p.reported {
+		return nil, errors.Trace(pc.writeParameterStatus(p))
+	}
+	return nil, nil
+}
+
+func (pc *pgConn) handleParse(r *pgReader) error {
+	name, sql := r.string(), r.string()
+	paramOIDs := make([]uint32, r.count())
+	for i := range paramOIDs {
+		paramOIDs[i] = uint32(r.int32())
+	}
+	if r.err != nil {
+		return errors.Trace(r.err)
+	}
+	if _, ok := pc.stmts[name]; ok {
+		if len(name) > 0 {
+			return mysql.NewErrf(mysql.ErrUnknown, "prepared statement \"%s\" already exists", name)
+		}
+		pc.closeStmt(name)
+	}
+	stmt := &pgStatement{query: sql, paramOIDs: paramOIDs}
+	sql = strings.TrimSuffix(strings.TrimSpace(sql), ";")
+	if local := parsePGLocalStmt(sql, pc.params); local != nil {
+		stmt.local = local
+		if local.show {
+			stmt.columns = newPGLocalResultSet(local.name, "").columns
+		}
+	} else if len(sql) > 0 {
+		query, paramOrder, err := pgRewrite(sql)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		stmt.stmt, stmt.columns, _, err = pc.ctx.Prepare(query)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		stmt.paramOrder = paramOrder
+		for _, i := range paramOrder {
+			for i >= len(stmt.paramOIDs) {
+				stmt.paramOIDs = append(stmt.paramOIDs, 0)
+			}
+		}
+	}
+	pc.stmts[name] = stmt
+	return errors.Trace(pc.writeMessage(pgMsgParseComplete, nil))
+}
+
+func (pc *pgConn) closeStmt(name string) {
+	stmt, ok := pc.stmts[name]
+	if !ok {
+		return
+	}
+	if stmt.stmt != nil {
+		stmt.stmt.Close()
+	}
+	delete(pc.stmts, name)
+}
+
+func (pc *pgConn) handleBind(r *pgReader) error {
+	portalName, stmtName := r.string(), r.string()
+	paramFormats := make([]int16, r.count())
+	for i := range paramFormats {
+		paramFormats[i] = r.int16()
+	}
+	values := make([][]byte, r.count())
+	for i := range values {
+		if n := r.int32(); n >= 0 {
+			values[i] = r.bytes(int(n))
+		}
+	}
+	resultFormats := make([]int16, r.count())
+	for i := range resultFormats {
+		resultFormats[i] = r.int16()
+	}
+	if r.err != nil {
+		return errors.Trace(r.err)
+	}
+	stmt, ok := pc.stmts[stmtName]
+	if !ok {
+		return mysql.NewErrf(mysql.ErrUnknownStmtHandler, "prepared statement \"%s\" does not exist", stmtName)
+	}
+	if len(values) != len(stmt.paramOIDs) {
+		return mysql.NewErrf(mysql.ErrWrongArguments, "bind message supplies %d parameters, but prepared statement \"%s\" requires %d",
+			len(values), stmtName, len(stmt.paramOIDs))
+	}
+	params := make([]interface{}, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		var err error
+		if params[i], err = parsePGValue(stmt.paramOIDs[i], pgFormat(paramFormats, i), v); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	args := make([]interface{}, len(stmt.paramOrder))
+	for i, idx := range stmt.paramOrder {
+		args[i] = params[idx]
+	}
+	if old, ok := pc.portals[portalName]; ok {
+		old.close()
+	}
+	pc.portals[portalName] = &pgPortal{stmt: stmt, args: args, resultFormats: resultFormats}
+	return errors.Trace(pc.writeMessage(pgMsgBindComplete, nil))
+}
+
+// execute executes the statement of the portal if it's not executed. It's executed by the
+// Describe of the portal to get the exact columns, then Execute sends the result.
+func (pc *pgConn) execute(p *pgPortal) error {
+	if p.executed {
+		return nil
+	}
+	p.executed = true
+	var (
+		rs  ResultSet
+		err error
+	)
+	switch {
+	case p.stmt.local != nil:
+		rs, err = pc.execLocal(p.stmt.local)
+	case p.stmt.stmt != nil:
+		pc.setProcessInfo(mysql.ComQuery, p.stmt.query)
+		rs, err = p.stmt.stmt.Execute(p.args...)
+		if err == nil {
+			err = pc.checkLoadData()
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if rs == nil {
+		p.affected = pc.ctx.AffectedRows()
+		return nil
+	}
+	return errors.Trace(p.open(rs))
+}
+
+func (pc *pgConn) handleDescribe(r *pgReader) error {
+	kind, name := r.bytes(1), r.string()
+	if r.err != nil {
+		return errors.Trace(r.err)
+	}
+	if kind[0] == 'S' {
+		stmt, ok := pc.stmts[name]
+		if !ok {
+			return mysql.NewErrf(mysql.ErrUnknownStmtHandler, "prepared statement \"%s\" does not exist", name)
+		}
+		data := appendPGInt16(nil, int16(len(stmt.paramOIDs)))
+		for _, oid := range stmt.paramOIDs {
+			if oid == 0 {
+				// The parameters are sent to TiDB as strings if the type is unspecified.
+				oid = pgTypeText
+			}
+			data = appendPGInt32(data, int32(oid))
+		}
+		if err := pc.writeMessage(pgMsgParameterDescription, data); err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(pc.writeRowDescription(stmt.columns, nil))
+	}
+	p, ok := pc.portals[name]
+	if !ok {
+		return mysql.NewErrf(mysql.ErrUnknownStmtHandler, "portal \"%s\" does not exist", name)
+	}
+	if err := pc.execute(p); err != nil {
+		return errors.Trace(err)
+	}
+	if p.rs == nil {
+		return errors.Trace(pc.writeMessage(pgMsgNoData, nil))
+	}
+	return errors.Trace(pc.writeRowDescription(p.columns, p.resultFormats))
+}
+
+func (pc *pgConn) handleExecute(r *pgReader) error {
+	name, maxRows := r.string(), r.int32()
+	if r.err != nil {
+		return errors.Trace(r.err)
+	}
+	p, ok := pc.portals[name]
+	if !ok {
+		return mysql.NewErrf(mysql.ErrUnknownStmtHandler, "portal \"%s\" does not exist", name)
+	}
+	if err := pc.server.useQuestion(pc.user, pc.maxQuestions); err != nil {
+		return errors.Trace(err)
+	}
+	if p.stmt.stmt == nil && p.stmt.local == nil {
+		return errors.Trace(pc.writeMessage(pgMsgEmptyQueryResponse, nil))
+	}
+	if err := pc.execute(p); err != nil {
+		return errors.Trace(err)
+	}
+	if p.rs == nil && p.columns == nil {
+		return errors.Trace(pc.writeCommandComplete(pgCommandTag(p.stmt.query, false, p.affected)))
+	}
+	_, err := pc.writeRows(p.stmt.query, p, int(maxRows))
+	return errors.Trace(err)
+}
+
+func (pc *pgConn) handleClose(r *pgReader) error {
+	kind, name := r.bytes(1), r.string()
+	if r.err != nil {
+		return errors.Trace(r.err)
+	}
+	if kind[0] == 'S' {
+		pc.closeStmt(name)
+	} else if p, ok := pc.portals[name]; ok {
+		p.close()
+		delete(pc.portals, name)
+	}
+	return errors.Trace(pc.writeMessage(pgMsgCloseComplete, nil))
+}
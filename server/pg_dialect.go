@@ -0,0 +1,525 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The dialect layer translates the SQL of PostgreSQL clients to the SQL of TiDB. It handles
+// the $n parameters, the double-quoted identifiers, the standard conforming strings, the
+// minimal pg_catalog and the SET/SHOW of the PostgreSQL run-time parameters.
+
+type pgTokenKind int
+
+const (
+	// pgTokenSpace is the whitespaces and the comments.
+	pgTokenSpace pgTokenKind = iota
+	// pgTokenWord is the identifiers and the keywords.
+	pgTokenWord
+	// pgTokenIdent is the double-quoted identifiers.
+	pgTokenIdent
+	// pgTokenString is the string constants, including the escape string constants like E'\n'.
+	pgTokenString
+	// pgTokenParam is the parameters like $1.
+	pgTokenParam
+	// pgTokenOther is the other characters.
+	pgTokenOther
+)
+
+type pgToken struct {
+	kind pgTokenKind
+	text string
+}
+
+func isPGWordChar(c byte, first bool) bool {
+	if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 {
+		return true
+	}
+	return !first && (c >= '0' && c <= '9' || c == '$')
+}
+
+// pgLex splits the SQL into tokens, the unterminated literals are left to the parser to report.
+func pgLex(sql string) []pgToken {
+	var tokens []pgToken
+	for i := 0; i < len(sql); {
+		start := i
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			for i < len(sql) && strings.IndexByte(" \t\n\r\f", sql[i]) >= 0 {
+				i++
+			}
+			tokens = append(tokens, pgToken{pgTokenSpace, sql[start:i]})
+		case strings.HasPrefix(sql[i:], "--"):
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, pgToken{pgTokenSpace, sql[start:i]})
+		case strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(sql)
+			}
+			tokens = append(tokens, pgToken{pgTokenSpace, sql[start:i]})
+		case (c == 'E' || c == 'e') && i+1 < len(sql) && sql[i+1] == '\'':
+			i = scanPGQuoted(sql, i+1, '\'', true)
+			tokens = append(tokens, pgToken{pgTokenString, sql[start:i]})
+		case c == '\'':
+			i = scanPGQuoted(sql, i, '\'', false)
+			tokens = append(tokens, pgToken{pgTokenString, sql[start:i]})
+		case c == '"':
+			i = scanPGQuoted(sql, i, '"', false)
+			tokens = append(tokens, pgToken{pgTokenIdent, sql[start:i]})
+		case c == '$' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			i++
+			for i < len(sql) && sql[i] >= '0' && sql[i] <= '9' {
+				i++
+			}
+			tokens = append(tokens, pgToken{pgTokenParam, sql[start:i]})
+		case isPGWordChar(c, true):
+			for i < len(sql) && isPGWordChar(sql[i], false) {
+				i++
+			}
+			tokens = append(tokens, pgToken{pgTokenWord, sql[start:i]})
+		default:
+			i++
+			tokens = append(tokens, pgToken{pgTokenOther, sql[start:i]})
+		}
+	}
+	return tokens
+}
+
+// scanPGQuoted returns the end of the literal quoted by quote starting at i, the quote is escaped
+// by doubling it, and by the backslash if escape is true.
+func scanPGQuoted(sql string, i int, quote byte, escape bool) int {
+	for i++; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if escape {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// pgStringLiteral converts the string constant to the string literal of MySQL. The escape string
+// constant like E'\n' is the same as the MySQL one, and the backslashes are ordinary characters
+// in the standard conforming strings.
+func pgStringLiteral(text string) string {
+	if text[0] == 'E' || text[0] == 'e' {
+		return text[1:]
+	}
+	return strings.Replace(text, `\`, `\\`, -1)
+}
+
+// pgStringValue returns the value of the string constant.
+func pgStringValue(text string) string {
+	escape := text[0] == 'E' || text[0] == 'e'
+	if escape {
+		text = text[1:]
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "'"), "'")
+	if escape {
+		text = strings.Replace(text, `\'`, "'", -1)
+	}
+	return strings.Replace(text, "''", "'", -1)
+}
+
+// pgIdentName returns the name of the double-quoted identifier.
+func pgIdentName(text string) string {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, `"`), `"`)
+	return strings.Replace(text, `""`, `"`, -1)
+}
+
+// pgSplitQuery splits the query string of the simple query protocol into statements, the empty ones are removed.
+func pgSplitQuery(sql string) []string {
+	var (
+		queries []string
+		buf     bytes.Buffer
+	)
+	flush := func() {
+		if q := strings.TrimSpace(buf.String()); len(q) > 0 {
+			queries = append(queries, q)
+		}
+		buf.Reset()
+	}
+	for _, tk := range pgLex(sql) {
+		if tk.kind == pgTokenOther && tk.text == ";" {
+			flush()
+			continue
+		}
+		buf.WriteString(tk.text)
+	}
+	flush()
+	return queries
+}
+
+// pgCatalogTables are the tables of the minimal pg_catalog, they are translated to the derived tables.
+var pgCatalogTables = map[string]string{
+	"pg_type":      pgTypeTableSQL(),
+	"pg_namespace": "SELECT 11 AS oid, 'pg_catalog' AS nspname UNION ALL SELECT 2200, 'public'",
+	"pg_database":  "SELECT 0 AS oid, SCHEMA_NAME AS datname, 6 AS encoding, 'C' AS datcollate FROM information_schema.SCHEMATA",
+	"pg_tables":    "SELECT TABLE_SCHEMA AS schemaname, TABLE_NAME AS tablename, '' AS tableowner FROM information_schema.TABLES",
+}
+
+func pgTypeTableSQL() string {
+	var buf bytes.Buffer
+	for i, t := range pgTypes {
+		if i == 0 {
+			fmt.Fprintf(&buf, "SELECT %d AS oid, '%s' AS typname, 11 AS typnamespace, %d AS typlen, 'b' AS typtype, 0 AS typelem, 0 AS typbasetype", t.oid, t.name, t.size)
+			continue
+		}
+		fmt.Fprintf(&buf, " UNION ALL SELECT %d, '%s', 11, %d, 'b', 0, 0", t.oid, t.name, t.size)
+	}
+	return buf.String()
+}
+
+// pgAliasStoppers are the keywords following a table reference that are not its alias.
+var pgAliasStoppers = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "cross": true,
+	"natural": true, "full": true, "on": true, "using": true, "group": true, "order": true,
+	"having": true, "limit": true, "offset": true, "union": true, "for": true,
+}
+
+// pgRewrite translates the SQL of PostgreSQL to the SQL of TiDB. The $n parameters are replaced
+// with ?, paramOrder is the index of the parameter of each ? in order.
+func pgRewrite(sql string) (query string, paramOrder []int, err error) {
+	tokens := pgLex(sql)
+	var (
+		buf      bytes.Buffer
+		prevWord string
+	)
+	// next returns the index of the next token which is not a space.
+	next := func(i int) int {
+		for i++; i < len(tokens) && tokens[i].kind == pgTokenSpace; i++ {
+		}
+		return i
+	}
+	writeCatalogTable := func(name string, i int) {
+		buf.WriteString("(" + pgCatalogTables[name] + ")")
+		j := next(i)
+		if j < len(tokens) && (tokens[j].kind == pgTokenIdent ||
+			tokens[j].kind == pgTokenWord && !pgAliasStoppers[strings.ToLower(tokens[j].text)]) {
+			// The table has an alias.
+			return
+		}
+		buf.WriteString(" AS " + name)
+	}
+	for i := 0; i < len(tokens); i++ {
+		tk := tokens[i]
+		switch tk.kind {
+		case pgTokenParam:
+			n, err1 := strconv.Atoi(tk.text[1:])
+			if err1 != nil || n < 1 {
+				return "", nil, mysql.NewErrf(mysql.ErrParse, "there is no parameter %s", tk.text)
+			}
+			paramOrder = append(paramOrder, n-1)
+			buf.WriteByte('?')
+			continue
+		case pgTokenWord:
+			lower := strings.ToLower(tk.text)
+			if lower == "pg_catalog" && i+2 < len(tokens) && tokens[i+1].text == "." && tokens[i+2].kind == pgTokenWord {
+				name := strings.ToLower(tokens[i+2].text)
+				if _, ok := pgCatalogTables[name]; ok {
+					i += 2
+					writeCatalogTable(name, i)
+				} else {
+					// The functions like pg_catalog.version() are the builtin functions.
+					i++
+				}
+				prevWord = name
+				continue
+			}
+			if _, ok := pgCatalogTables[lower]; ok && (prevWord == "from" || prevWord == "join") {
+				writeCatalogTable(lower, i)
+				prevWord = lower
+				continue
+			}
+			if (lower == "current_database" || lower == "current_schema") && next(i) < len(tokens) && tokens[next(i)].text == "(" {
+				buf.WriteString("database")
+				prevWord = lower
+				continue
+			}
+			prevWord = lower
+		case pgTokenString:
+			buf.WriteString(pgStringLiteral(tk.text))
+			prevWord = ""
+			continue
+		case pgTokenIdent:
+			buf.WriteString("`" + strings.Replace(pgIdentName(tk.text), "`", "``", -1) + "`")
+			prevWord = ""
+			continue
+		case pgTokenSpace:
+			// TiDB only takes "-- " followed by a space as a comment, so the comments are written as a space.
+			if strings.HasPrefix(tk.text, "--") || strings.HasPrefix(tk.text, "/*") {
+				buf.WriteByte(' ')
+				continue
+			}
+		default:
+			prevWord = ""
+			// # is XOR in PostgreSQL, but starts a comment in TiDB.
+			if tk.text == "#" {
+				buf.WriteByte('^')
+				continue
+			}
+		}
+		buf.WriteString(tk.text)
+	}
+	return buf.String(), paramOrder, nil
+}
+
+// pgParam is a run-time parameter of PostgreSQL, the reported ones are sent to the client by ParameterStatus.
+type pgParam struct {
+	name     string
+	value    string
+	reported bool
+	// fixed is true if the parameter can't be changed by the client.
+	fixed bool
+}
+
+// pgServerVersion is the PostgreSQL version reported to the clients.
+const pgServerVersion = "9.6.0"
+
+// newPGParams returns the run-time parameters of a new connection, they are keyed by the lowercase names.
+func newPGParams() map[string]*pgParam {
+	params := []*pgParam{
+		{name: "server_version", value: pgServerVersion, reported: true, fixed: true},
+		{name: "server_encoding", value: "UTF8", reported: true, fixed: true},
+		{name: "client_encoding", value: "UTF8", reported: true},
+		{name: "DateStyle", value: "ISO, MDY", reported: true},
+		{name: "TimeZone", value: "UTC", reported: true},
+		{name: "integer_datetimes", value: "on", reported: true, fixed: true},
+		{name: "standard_conforming_strings", value: "on", reported: true},
+		{name: "IntervalStyle", value: "postgres", reported: true},
+		{name: "is_superuser", value: "off", reported: true, fixed: true},
+		{name: "application_name", value: "", reported: true},
+		{name: "extra_float_digits", value: "0"},
+		{name: "search_path", value: `"$user", public`},
+		{name: "statement_timeout", value: "0"},
+		{name: "client_min_messages", value: "notice"},
+		{name: "bytea_output", value: "hex"},
+		{name: "transaction_isolation", value: "repeatable read", fixed: true},
+	}
+	m := make(map[string]*pgParam, len(params))
+	for _, p := range params {
+		m[strings.ToLower(p.name)] = p
+	}
+	return m
+}
+
+// pgSupportedValues are the only values supported by the parameters, the SQL is translated with them.
+var pgSupportedValues = map[string][]string{
+	"client_encoding":             {"UTF8", "UNICODE"},
+	"standard_conforming_strings": {"on"},
+	"bytea_output":                {"hex"},
+}
+
+// checkPGParam checks whether the parameter can be set to the value.
+func checkPGParam(p *pgParam, value string) error {
+	if p.fixed {
+		return mysql.NewErrf(mysql.ErrUnknown, "parameter \"%s\" cannot be changed", p.name)
+	}
+	values, ok := pgSupportedValues[strings.ToLower(p.name)]
+	if !ok {
+		return nil
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return nil
+		}
+	}
+	return mysql.NewErrf(mysql.ErrUnknown, "parameter \"%s\" only supports %s", p.name, values[0])
+}
+
+// pgLocalStmt is the SET or SHOW statement of a run-time parameter, it's handled without TiDB.
+type pgLocalStmt struct {
+	show  bool
+	name  string
+	value string
+}
+
+// parsePGLocalStmt returns the pgLocalStmt of the SQL, it returns nil if the SQL isn't a SET or
+// SHOW statement of a known run-time parameter.
+func parsePGLocalStmt(sql string, params map[string]*pgParam) *pgLocalStmt {
+	var tokens []pgToken
+	for _, tk := range pgLex(strings.TrimSuffix(strings.TrimSpace(sql), ";")) {
+		if tk.kind != pgTokenSpace {
+			tokens = append(tokens, tk)
+		}
+	}
+	word := func(i int) string {
+		if i < len(tokens) && tokens[i].kind == pgTokenWord {
+			return strings.ToLower(tokens[i].text)
+		}
+		if i < len(tokens) && tokens[i].kind == pgTokenIdent {
+			return strings.ToLower(pgIdentName(tokens[i].text))
+		}
+		return ""
+	}
+	switch word(0) {
+	case "show":
+		name := word(1)
+		if name == "transaction" && word(2) == "isolation" && word(3) == "level" && len(tokens) == 4 {
+			name = "transaction_isolation"
+		} else if len(tokens) != 2 {
+			return nil
+		}
+		if _, ok := params[name]; !ok {
+			return nil
+		}
+		return &pgLocalStmt{show: true, name: name}
+	case "set":
+		i := 1
+		if w := word(i); w == "session" || w == "local" {
+			i++
+		}
+		var name string
+		if word(i) == "time" && word(i+1) == "zone" {
+			name, i = "timezone", i+2
+		} else {
+			name = word(i)
+			i++
+			if word(i) != "to" && (i >= len(tokens) || tokens[i].text != "=") {
+				return nil
+			}
+			i++
+		}
+		if _, ok := params[name]; !ok || i >= len(tokens) {
+			return nil
+		}
+		var values []string
+		for _, tk := range tokens[i:] {
+			if tk.text == "," {
+				continue
+			}
+			v := tk.text
+			if tk.kind == pgTokenString {
+				v = pgStringValue(v)
+			}
+			values = append(values, v)
+		}
+		return &pgLocalStmt{name: name, value: strings.Join(values, ", ")}
+	}
+	return nil
+}
+
+// pgLocalResultSet is the result set of SHOW of a run-time parameter.
+type pgLocalResultSet struct {
+	columns []*ColumnInfo
+	rows    [][]types.Datum
+}
+
+func newPGLocalResultSet(name, value string) *pgLocalResultSet {
+	return &pgLocalResultSet{
+		columns: []*ColumnInfo{{Name: name, Type: mysql.TypeBlob, Charset: uint16(mysql.CharsetIDs["utf8"])}},
+		rows:    [][]types.Datum{types.MakeDatums(value)},
+	}
+}
+
+// Columns implements ResultSet Columns interface.
+func (rs *pgLocalResultSet) Columns() ([]*ColumnInfo, error) {
+	return rs.columns, nil
+}
+
+// Next implements ResultSet Next interface.
+func (rs *pgLocalResultSet) Next() ([]types.Datum, error) {
+	if len(rs.rows) == 0 {
+		return nil, nil
+	}
+	row := rs.rows[0]
+	rs.rows = rs.rows[1:]
+	return row, nil
+}
+
+// Close implements ResultSet Close interface.
+func (rs *pgLocalResultSet) Close() error {
+	return nil
+}
+
+// pgCommandTag returns the tag of CommandComplete of the SQL. The rows is the number of the rows
+// returned if hasResult is true, or the affected rows otherwise.
+func pgCommandTag(sql string, hasResult bool, rows uint64) string {
+	if hasResult {
+		return fmt.Sprintf("SELECT %d", rows)
+	}
+	var words []string
+	for _, tk := range pgLex(sql) {
+		if tk.kind == pgTokenWord {
+			words = append(words, strings.ToUpper(tk.text))
+			if len(words) == 2 {
+				break
+			}
+		} else if tk.kind != pgTokenSpace {
+			break
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	switch words[0] {
+	case "INSERT", "REPLACE":
+		return fmt.Sprintf("INSERT 0 %d", rows)
+	case "UPDATE", "DELETE":
+		return fmt.Sprintf("%s %d", words[0], rows)
+	case "START":
+		return "BEGIN"
+	case "USE":
+		return "SET"
+	case "CREATE", "DROP", "ALTER", "TRUNCATE":
+		return strings.Join(words, " ")
+	}
+	return words[0]
+}
+
+// pgErrorCodes maps the MySQL error codes to the SQLSTATE codes of PostgreSQL.
+var pgErrorCodes = map[uint16]string{
+	mysql.ErrParse:             "42601",
+	mysql.ErrSyntax:            "42601",
+	mysql.ErrNoSuchTable:       "42P01",
+	mysql.ErrBadDB:             "3D000",
+	mysql.ErrDupEntry:          "23505",
+	mysql.ErrBadField:          "42703",
+	mysql.ErrTableExists:       "42P07",
+	mysql.ErrDBCreateExists:    "42P04",
+	mysql.ErrAccessDenied:      "28P01",
+	mysql.ErrDBaccessDenied:    "42501",
+	mysql.ErrTableaccessDenied: "42501",
+	mysql.ErrQueryInterrupted:  "57014",
+}
+
+// pgErrorCode returns the SQLSTATE code of PostgreSQL of the MySQL error.
+func pgErrorCode(m *mysql.SQLError) string {
+	if code, ok := pgErrorCodes[m.Code]; ok {
+		return code
+	}
+	if len(m.State) == 5 && m.State != mysql.DefaultMySQLState {
+		return m.State
+	}
+	return "XX000"
+}
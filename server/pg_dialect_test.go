@@ -0,0 +1,194 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+var _ = Suite(&testPGDialectSuite{})
+
+type testPGDialectSuite struct {
+}
+
+func (s *testPGDialectSuite) TestRewrite(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		sql   string
+		query string
+		order []int
+	}{
+		{"select 1", "select 1", nil},
+		{"select * from t where a = $2 and b = $1 or c = $2", "select * from t where a = ? and b = ? or c = ?", []int{1, 0, 1}},
+		{`select "Name", "a""b" from "T"`, "select `Name`, `a\"b` from `T`", nil},
+		{`select 'a\b', 'it''s', E'a\nb', '$1'`, `select 'a\\b', 'it''s', 'a\nb', '$1'`, nil},
+		{"select $1 -- $2\n", "select ?  \n", []int{0}},
+		{"select 1 --1", "select 1  ", nil},
+		{"select 1 --note\n, 2", "select 1  \n, 2", nil},
+		{"select 1/*note*/+ 2", "select 1 + 2", nil},
+		{"select 5 # 3", "select 5 ^ 3", nil},
+		{"select pg_catalog.version(), current_database()", "select version(), database()", nil},
+		{"select nspname from pg_catalog.pg_namespace",
+			"select nspname from (" + pgCatalogTables["pg_namespace"] + ") AS pg_namespace", nil},
+		{"select n.nspname from pg_namespace n where n.oid = 11",
+			"select n.nspname from (" + pgCatalogTables["pg_namespace"] + ") n where n.oid = 11", nil},
+		{"select datname from pg_database where datname = 'test'",
+			"select datname from (" + pgCatalogTables["pg_database"] + ") AS pg_database where datname = 'test'", nil},
+		// The catalog table name is only translated in the FROM clause.
+		{"select pg_type from t", "select pg_type from t", nil},
+	}
+	for _, t := range tbl {
+		query, order, err := pgRewrite(t.sql)
+		c.Assert(err, IsNil)
+		c.Assert(query, Equals, t.query, Commentf("%s", t.sql))
+		c.Assert(order, DeepEquals, t.order, Commentf("%s", t.sql))
+	}
+	_, _, err := pgRewrite("select $0")
+	c.Assert(err, NotNil)
+}
+
+func (s *testPGDialectSuite) TestSplitQuery(c *C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(pgSplitQuery(" ; ;"), HasLen, 0)
+	c.Assert(pgSplitQuery("select 1; select ';' ;select \"a;b\" -- ;\n"), DeepEquals,
+		[]string{"select 1", "select ';'", "select \"a;b\" -- ;"})
+}
+
+func (s *testPGDialectSuite) TestLocalStmt(c *C) {
+	defer testleak.AfterTest(c)()
+	params := newPGParams()
+	tbl := []struct {
+		sql   string
+		local *pgLocalStmt
+	}{
+		{"SET extra_float_digits = 3", &pgLocalStmt{name: "extra_float_digits", value: "3"}},
+		{"set session application_name to 'it''s';", &pgLocalStmt{name: "application_name", value: "it's"}},
+		{"SET TIME ZONE 'UTC'", &pgLocalStmt{name: "timezone", value: "UTC"}},
+		{`SET search_path TO "$user", public`, &pgLocalStmt{name: "search_path", value: `"$user", public`}},
+		{"SHOW standard_conforming_strings", &pgLocalStmt{show: true, name: "standard_conforming_strings"}},
+		{"show transaction isolation level", &pgLocalStmt{show: true, name: "transaction_isolation"}},
+		{"SET autocommit = 1", nil},
+		{"SET NAMES utf8", nil},
+		{"SHOW TABLES", nil},
+		{"SHOW DateStyle like 'a'", nil},
+		{"select 1", nil},
+	}
+	for _, t := range tbl {
+		c.Assert(parsePGLocalStmt(t.sql, params), DeepEquals, t.local, Commentf("%s", t.sql))
+	}
+
+	c.Assert(checkPGParam(params["client_encoding"], "utf8"), IsNil)
+	c.Assert(checkPGParam(params["client_encoding"], "LATIN1"), NotNil)
+	c.Assert(checkPGParam(params["server_version"], "10"), NotNil)
+	c.Assert(checkPGParam(params["application_name"], "psql"), IsNil)
+}
+
+func (s *testPGDialectSuite) TestCommandTag(c *C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(pgCommandTag("select * from t", true, 3), Equals, "SELECT 3")
+	c.Assert(pgCommandTag("show databases", true, 0), Equals, "SELECT 0")
+	c.Assert(pgCommandTag("insert into t values (1)", false, 1), Equals, "INSERT 0 1")
+	c.Assert(pgCommandTag(" update t set a = 1", false, 2), Equals, "UPDATE 2")
+	c.Assert(pgCommandTag("delete from t", false, 0), Equals, "DELETE 0")
+	c.Assert(pgCommandTag("create table t (a int)", false, 0), Equals, "CREATE TABLE")
+	c.Assert(pgCommandTag("begin", false, 0), Equals, "BEGIN")
+	c.Assert(pgCommandTag("start transaction", false, 0), Equals, "BEGIN")
+	c.Assert(pgCommandTag("use test", false, 0), Equals, "SET")
+
+	c.Assert(pgErrorCode(mysql.NewErr(mysql.ErrNoSuchTable, "test", "t")), Equals, "42P01")
+	c.Assert(pgErrorCode(mysql.NewErr(mysql.ErrNoDB)), Equals, "3D000")
+	c.Assert(pgErrorCode(mysql.NewErrf(mysql.ErrUnknown, "unknown")), Equals, "XX000")
+}
+
+func (s *testPGDialectSuite) TestTypeOID(c *C) {
+	defer testleak.AfterTest(c)()
+	binaryCharset := uint16(mysql.CharsetIDs["binary"])
+	utf8Charset := uint16(mysql.CharsetIDs["utf8"])
+	tbl := []struct {
+		col *ColumnInfo
+		oid uint32
+	}{
+		{&ColumnInfo{Type: mysql.TypeTiny}, pgTypeInt2},
+		{&ColumnInfo{Type: mysql.TypeLong}, pgTypeInt4},
+		{&ColumnInfo{Type: mysql.TypeLong, Flag: mysql.UnsignedFlag}, pgTypeInt8},
+		{&ColumnInfo{Type: mysql.TypeLonglong}, pgTypeInt8},
+		{&ColumnInfo{Type: mysql.TypeLonglong, Flag: mysql.UnsignedFlag}, pgTypeNumeric},
+		{&ColumnInfo{Type: mysql.TypeDouble}, pgTypeFloat8},
+		{&ColumnInfo{Type: mysql.TypeNewDecimal}, pgTypeNumeric},
+		{&ColumnInfo{Type: mysql.TypeVarString, Charset: utf8Charset}, pgTypeVarchar},
+		{&ColumnInfo{Type: mysql.TypeVarString, Charset: binaryCharset}, pgTypeBytea},
+		{&ColumnInfo{Type: mysql.TypeString, Charset: utf8Charset}, pgTypeBpchar},
+		{&ColumnInfo{Type: mysql.TypeBlob, Charset: utf8Charset}, pgTypeText},
+		{&ColumnInfo{Type: mysql.TypeBlob, Charset: binaryCharset}, pgTypeBytea},
+		{&ColumnInfo{Type: mysql.TypeDate}, pgTypeDate},
+		{&ColumnInfo{Type: mysql.TypeDatetime}, pgTypeTimestamp},
+		{&ColumnInfo{Type: mysql.TypeDuration}, pgTypeTime},
+		{&ColumnInfo{Type: mysql.TypeNull}, pgTypeText},
+	}
+	for _, t := range tbl {
+		c.Assert(pgTypeOID(t.col), Equals, t.oid, Commentf("%d", t.col.Type))
+	}
+}
+
+func (s *testPGDialectSuite) TestValue(c *C) {
+	defer testleak.AfterTest(c)()
+	col := &ColumnInfo{Type: mysql.TypeLong}
+	d, err := dumpPGValue(pgTypeInt4, pgFormatBinary, col, types.NewIntDatum(-2))
+	c.Assert(err, IsNil)
+	c.Assert(d, DeepEquals, []byte{0xff, 0xff, 0xff, 0xfe})
+	v, err := parsePGValue(pgTypeInt4, pgFormatBinary, d)
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, int64(-2))
+	d, err = dumpPGValue(pgTypeInt4, pgFormatText, col, types.NewIntDatum(-2))
+	c.Assert(err, IsNil)
+	c.Assert(string(d), Equals, "-2")
+
+	col = &ColumnInfo{Type: mysql.TypeBlob, Charset: uint16(mysql.CharsetIDs["binary"])}
+	d, err = dumpPGValue(pgTypeBytea, pgFormatText, col, types.NewBytesDatum([]byte{0, 0xab}))
+	c.Assert(err, IsNil)
+	c.Assert(string(d), Equals, `\x00ab`)
+	v, err = parsePGValue(pgTypeBytea, pgFormatText, d)
+	c.Assert(err, IsNil)
+	c.Assert(v, DeepEquals, []byte{0, 0xab})
+
+	t, err := mysql.ParseDatetime("2000-01-02 00:00:01")
+	c.Assert(err, IsNil)
+	d, err = dumpPGValue(pgTypeTimestamp, pgFormatBinary, &ColumnInfo{Type: mysql.TypeDatetime}, types.NewDatum(t))
+	c.Assert(err, IsNil)
+	c.Assert(d, DeepEquals, []byte{0, 0, 0, 0x14, 0x1d, 0xe6, 0xa2, 0x40})
+	v, err = parsePGValue(pgTypeTimestamp, pgFormatBinary, d)
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "2000-01-02 00:00:01")
+
+	for _, s := range []string{"0", "1", "-1", "12345.678", "0.0001", "-0.00500", "100000000", "123456789012345678901234567890.1"} {
+		d, err = dumpPGNumeric(s)
+		c.Assert(err, IsNil, Commentf("%s", s))
+		v, err = parsePGNumeric(d)
+		c.Assert(err, IsNil, Commentf("%s", s))
+		c.Assert(v, Equals, s)
+	}
+	d, err = dumpPGNumeric("12345.678")
+	c.Assert(err, IsNil)
+	// ndigits 3, weight 1, sign +, dscale 3, digits 1 2345 6780.
+	c.Assert(d, DeepEquals, []byte{0, 3, 0, 1, 0, 0, 0, 3, 0, 1, 0x09, 0x29, 0x1a, 0x7c})
+	_, err = dumpPGNumeric("1e5")
+	c.Assert(err, NotNil)
+	_, err = parsePGValue(pgTypeInt4, pgFormatBinary, []byte{1, 2, 3})
+	c.Assert(strings.Contains(err.Error(), "invalid integer parameter length"), IsTrue)
+}
@@ -0,0 +1,387 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// The OIDs of the PostgreSQL types, see src/include/catalog/pg_type.h of PostgreSQL.
+const (
+	pgTypeBool      uint32 = 16
+	pgTypeBytea     uint32 = 17
+	pgTypeInt8      uint32 = 20
+	pgTypeInt2      uint32 = 21
+	pgTypeInt4      uint32 = 23
+	pgTypeText      uint32 = 25
+	pgTypeFloat4    uint32 = 700
+	pgTypeFloat8    uint32 = 701
+	pgTypeUnknown   uint32 = 705
+	pgTypeBpchar    uint32 = 1042
+	pgTypeVarchar   uint32 = 1043
+	pgTypeDate      uint32 = 1082
+	pgTypeTime      uint32 = 1083
+	pgTypeTimestamp uint32 = 1114
+	pgTypeNumeric   uint32 = 1700
+)
+
+// The format codes of the parameters and the result columns.
+const (
+	pgFormatText   int16 = 0
+	pgFormatBinary int16 = 1
+)
+
+// pgTypeInfo is the information of a PostgreSQL type exposed by the minimal pg_catalog.
+type pgTypeInfo struct {
+	oid  uint32
+	name string
+	// size is the typlen of the type, -1 means it's variable length.
+	size int16
+}
+
+var pgTypes = []pgTypeInfo{
+	{pgTypeBool, "bool", 1},
+	{pgTypeBytea, "bytea", -1},
+	{pgTypeInt8, "int8", 8},
+	{pgTypeInt2, "int2", 2},
+	{pgTypeInt4, "int4", 4},
+	{pgTypeText, "text", -1},
+	{pgTypeFloat4, "float4", 4},
+	{pgTypeFloat8, "float8", 8},
+	{pgTypeUnknown, "unknown", -2},
+	{pgTypeBpchar, "bpchar", -1},
+	{pgTypeVarchar, "varchar", -1},
+	{pgTypeDate, "date", 4},
+	{pgTypeTime, "time", 8},
+	{pgTypeTimestamp, "timestamp", 8},
+	{pgTypeNumeric, "numeric", -1},
+}
+
+// pgTypeSize returns the typlen of the type.
+func pgTypeSize(oid uint32) int16 {
+	for _, t := range pgTypes {
+		if t.oid == oid {
+			return t.size
+		}
+	}
+	return -1
+}
+
+// pgTypeOID maps the column to the PostgreSQL type by the MySQL type of its types.FieldType.
+// The unsigned integers are mapped to the wider types since PostgreSQL has no unsigned types,
+// and the strings with the binary charset are mapped to bytea.
+func pgTypeOID(col *ColumnInfo) uint32 {
+	unsigned := col.Flag&mysql.UnsignedFlag > 0
+	switch col.Type {
+	case mysql.TypeTiny, mysql.TypeYear:
+		return pgTypeInt2
+	case mysql.TypeShort:
+		if unsigned {
+			return pgTypeInt4
+		}
+		return pgTypeInt2
+	case mysql.TypeInt24:
+		return pgTypeInt4
+	case mysql.TypeLong:
+		if unsigned {
+			return pgTypeInt8
+		}
+		return pgTypeInt4
+	case mysql.TypeLonglong:
+		if unsigned {
+			return pgTypeNumeric
+		}
+		return pgTypeInt8
+	case mysql.TypeFloat:
+		return pgTypeFloat4
+	case mysql.TypeDouble:
+		return pgTypeFloat8
+	case mysql.TypeDecimal, mysql.TypeNewDecimal:
+		return pgTypeNumeric
+	case mysql.TypeDate, mysql.TypeNewDate:
+		return pgTypeDate
+	case mysql.TypeDatetime, mysql.TypeTimestamp:
+		return pgTypeTimestamp
+	case mysql.TypeDuration:
+		return pgTypeTime
+	case mysql.TypeVarchar, mysql.TypeVarString:
+		if col.Charset == uint16(mysql.CharsetIDs["binary"]) {
+			return pgTypeBytea
+		}
+		return pgTypeVarchar
+	case mysql.TypeString:
+		if col.Charset == uint16(mysql.CharsetIDs["binary"]) {
+			return pgTypeBytea
+		}
+		return pgTypeBpchar
+	case mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		if col.Charset == uint16(mysql.CharsetIDs["binary"]) {
+			return pgTypeBytea
+		}
+		return pgTypeText
+	default:
+		return pgTypeText
+	}
+}
+
+// pgEpoch is the epoch of the binary format of the PostgreSQL date and time types.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// dumpPGValue encodes the value of the PostgreSQL type oid in the format.
+func dumpPGValue(oid uint32, format int16, col *ColumnInfo, value types.Datum) ([]byte, error) {
+	if format == pgFormatText {
+		data, err := dumpTextValue(col.Type, value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if oid == pgTypeBytea {
+			return append([]byte(`\x`), hex.EncodeToString(data)...), nil
+		}
+		return data, nil
+	}
+	switch oid {
+	case pgTypeInt2, pgTypeInt4, pgTypeInt8:
+		v, err := value.ToInt64()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(v))
+		return data[8-pgTypeSize(oid):], nil
+	case pgTypeFloat4:
+		v, err := value.ToFloat64()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		data := make([]byte, 4)
+		binary.BigEndian.PutUint32(data, math.Float32bits(float32(v)))
+		return data, nil
+	case pgTypeFloat8:
+		v, err := value.ToFloat64()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, math.Float64bits(v))
+		return data, nil
+	case pgTypeNumeric:
+		s, err := value.ToString()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return dumpPGNumeric(s)
+	case pgTypeDate, pgTypeTimestamp:
+		if value.Kind() != types.KindMysqlTime {
+			return nil, errInvalidType.Gen("invalid type %T for %d", value.GetValue(), oid)
+		}
+		t := value.GetMysqlTime().Time
+		d := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Sub(pgEpoch)
+		if oid == pgTypeDate {
+			data := make([]byte, 4)
+			binary.BigEndian.PutUint32(data, uint32(int32(d/(24*time.Hour))))
+			return data, nil
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(int64(d/time.Microsecond)))
+		return data, nil
+	case pgTypeTime:
+		if value.Kind() != types.KindMysqlDuration {
+			return nil, errInvalidType.Gen("invalid type %T for %d", value.GetValue(), oid)
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(int64(value.GetMysqlDuration().Duration/time.Microsecond)))
+		return data, nil
+	default:
+		// The binary format of the string types is the same as the text format.
+		return dumpTextValue(col.Type, value)
+	}
+}
+
+// parsePGValue decodes the parameter of the PostgreSQL type oid in the format to the argument of IStatement.
+func parsePGValue(oid uint32, format int16, data []byte) (interface{}, error) {
+	if format == pgFormatText {
+		if oid == pgTypeBytea && strings.HasPrefix(string(data), `\x`) {
+			v, err := hex.DecodeString(string(data[2:]))
+			return v, errors.Trace(err)
+		}
+		return string(data), nil
+	}
+	switch oid {
+	case pgTypeBool:
+		if len(data) != 1 {
+			return nil, errInvalidPayloadLen.Gen("invalid bool parameter length %d", len(data))
+		}
+		return int64(data[0]), nil
+	case pgTypeInt2, pgTypeInt4, pgTypeInt8:
+		switch len(data) {
+		case 2:
+			return int64(int16(binary.BigEndian.Uint16(data))), nil
+		case 4:
+			return int64(int32(binary.BigEndian.Uint32(data))), nil
+		case 8:
+			return int64(binary.BigEndian.Uint64(data)), nil
+		}
+		return nil, errInvalidPayloadLen.Gen("invalid integer parameter length %d", len(data))
+	case pgTypeFloat4:
+		if len(data) != 4 {
+			return nil, errInvalidPayloadLen.Gen("invalid float4 parameter length %d", len(data))
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case pgTypeFloat8:
+		if len(data) != 8 {
+			return nil, errInvalidPayloadLen.Gen("invalid float8 parameter length %d", len(data))
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case pgTypeNumeric:
+		return parsePGNumeric(data)
+	case pgTypeDate:
+		if len(data) != 4 {
+			return nil, errInvalidPayloadLen.Gen("invalid date parameter length %d", len(data))
+		}
+		days := int32(binary.BigEndian.Uint32(data))
+		return pgEpoch.AddDate(0, 0, int(days)).Format("2006-01-02"), nil
+	case pgTypeTimestamp:
+		if len(data) != 8 {
+			return nil, errInvalidPayloadLen.Gen("invalid timestamp parameter length %d", len(data))
+		}
+		us := int64(binary.BigEndian.Uint64(data))
+		return pgEpoch.Add(time.Duration(us) * time.Microsecond).Format("2006-01-02 15:04:05.999999"), nil
+	case pgTypeBytea:
+		return data, nil
+	default:
+		// The binary format of the string types is the same as the text format.
+		return string(data), nil
+	}
+}
+
+// The signs of the binary format of numeric.
+const (
+	pgNumericPos uint16 = 0x0000
+	pgNumericNeg uint16 = 0x4000
+	pgNumericNaN uint16 = 0xC000
+)
+
+// dumpPGNumeric encodes the decimal string in the binary format of numeric, which is
+// ndigits, weight, sign, dscale and the digits in base 10000, all of them are int16.
+func dumpPGNumeric(s string) ([]byte, error) {
+	sign := pgNumericPos
+	if strings.HasPrefix(s, "-") {
+		sign = pgNumericNeg
+		s = s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if strings.Trim(intPart+fracPart, "0123456789") != "" {
+		return nil, errInvalidType.Gen("invalid numeric %s", s)
+	}
+	dscale := len(fracPart)
+	// Align the integer part and the fraction part to the groups of 4 digits.
+	if n := len(intPart) % 4; n > 0 {
+		intPart = strings.Repeat("0", 4-n) + intPart
+	}
+	if n := len(fracPart) % 4; n > 0 {
+		fracPart += strings.Repeat("0", 4-n)
+	}
+	all := intPart + fracPart
+	digits := make([]uint16, 0, len(all)/4)
+	for i := 0; i < len(all); i += 4 {
+		d, err := strconv.ParseUint(all[i:i+4], 10, 16)
+		if err != nil {
+			return nil, errInvalidType.Gen("invalid numeric %s", s)
+		}
+		digits = append(digits, uint16(d))
+	}
+	weight := len(intPart)/4 - 1
+	for len(digits) > 0 && digits[0] == 0 {
+		digits = digits[1:]
+		weight--
+	}
+	for len(digits) > 0 && digits[len(digits)-1] == 0 {
+		digits = digits[:len(digits)-1]
+	}
+	if len(digits) == 0 {
+		weight, sign = 0, pgNumericPos
+	}
+	data := make([]byte, 8, 8+2*len(digits))
+	binary.BigEndian.PutUint16(data, uint16(len(digits)))
+	binary.BigEndian.PutUint16(data[2:], uint16(int16(weight)))
+	binary.BigEndian.PutUint16(data[4:], sign)
+	binary.BigEndian.PutUint16(data[6:], uint16(dscale))
+	for _, d := range digits {
+		data = append(data, byte(d>>8), byte(d))
+	}
+	return data, nil
+}
+
+// parsePGNumeric decodes the binary format of numeric to the decimal string.
+func parsePGNumeric(data []byte) (interface{}, error) {
+	if len(data) < 8 {
+		return nil, errInvalidPayloadLen.Gen("invalid numeric parameter length %d", len(data))
+	}
+	ndigits := int(binary.BigEndian.Uint16(data))
+	weight := int(int16(binary.BigEndian.Uint16(data[2:])))
+	sign := binary.BigEndian.Uint16(data[4:])
+	dscale := int(binary.BigEndian.Uint16(data[6:]))
+	if len(data) != 8+2*ndigits {
+		return nil, errInvalidPayloadLen.Gen("invalid numeric parameter length %d", len(data))
+	}
+	if sign == pgNumericNaN {
+		return nil, errInvalidType.Gen("NaN is not supported")
+	}
+	digit := func(i int) uint16 {
+		if i < 0 || i >= ndigits {
+			return 0
+		}
+		return binary.BigEndian.Uint16(data[8+2*i:])
+	}
+	var buf []byte
+	if sign == pgNumericNeg {
+		buf = append(buf, '-')
+	}
+	// The digit i is the group of 10000^(weight-i).
+	if weight < 0 {
+		buf = append(buf, '0')
+	} else {
+		buf = strconv.AppendUint(buf, uint64(digit(0)), 10)
+		for i := 1; i <= weight; i++ {
+			buf = append(buf, groupDigits(digit(i))...)
+		}
+	}
+	if dscale > 0 {
+		var frac []byte
+		for i := weight + 1; len(frac) < dscale; i++ {
+			frac = append(frac, groupDigits(digit(i))...)
+		}
+		buf = append(buf, '.')
+		buf = append(buf, frac[:dscale]...)
+	}
+	return string(buf), nil
+}
+
+// groupDigits formats the digit in base 10000 to 4 decimal digits.
+func groupDigits(d uint16) string {
+	s := strconv.Itoa(int(d))
+	return strings.Repeat("0", 4-len(s)) + s
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/pingcap/tidb/mysql"
+)
+
+// userResource tracks the resources used by a user, which are limited by the
+// MAX_USER_CONNECTIONS and MAX_QUERIES_PER_HOUR options of the user account.
+// The resources are counted by the user name, the accounts of the same name on
+// different hosts share them.
+type userResource struct {
+	connections int
+	// questions is the number of the statements executed since hourStart.
+	questions uint64
+	hourStart time.Time
+}
+
+// acquireUserConn counts a connection of the user, it fails if the user already has
+// maxUserConnections connections.
+func (s *Server) acquireUserConn(user string, maxUserConnections uint64) error {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	res, ok := s.users[user]
+	if !ok {
+		res = &userResource{hourStart: time.Now()}
+		s.users[user] = res
+	}
+	if maxUserConnections > 0 && uint64(res.connections) >= maxUserConnections {
+		return mysql.NewErr(mysql.ErrTooManyUserConnections, user)
+	}
+	res.connections++
+	return nil
+}
+
+// releaseUserConn releases a connection counted by acquireUserConn.
+func (s *Server) releaseUserConn(user string) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	res, ok := s.users[user]
+	if !ok {
+		return
+	}
+	res.connections--
+	if res.connections <= 0 && res.questions == 0 {
+		delete(s.users, user)
+	}
+}
+
+// useQuestion counts a statement of the user, it fails if the user has executed maxQuestions
+// statements in the current hour.
+func (s *Server) useQuestion(user string, maxQuestions uint64) error {
+	if maxQuestions == 0 {
+		return nil
+	}
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	res, ok := s.users[user]
+	if !ok {
+		res = &userResource{hourStart: time.Now()}
+		s.users[user] = res
+	}
+	if time.Since(res.hourStart) >= time.Hour {
+		res.hourStart = time.Now()
+		res.questions = 0
+	}
+	if res.questions >= maxQuestions {
+		return mysql.NewErrf(mysql.ErrUserLimitReached, "User '%s' has exceeded the 'max_questions' resource (current value: %d)", user, res.questions)
+	}
+	res.questions++
+	return nil
+}
@@ -29,10 +29,18 @@
 package server
 
 import (
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -43,6 +51,7 @@ import (
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/arena"
 	"github.com/pingcap/tidb/util/printer"
 	"github.com/prometheus/client_golang/prometheus"
@@ -61,23 +70,104 @@ var (
 
 // Server is the MySQL protocol server
 type Server struct {
-	cfg               *Config
-	driver            IDriver
-	listener          net.Listener
+	cfg      *Config
+	driver   IDriver
+	listener net.Listener
+	// pgListener is the listener of the PostgreSQL protocol, it is nil if PGAddr is not set.
+	pgListener        net.Listener
 	rwlock            *sync.RWMutex
 	concurrentLimiter *TokenLimiter
 	clients           map[uint32]*clientConn
+	pgClients         map[uint32]*pgConn
+	// tlsConfig is nil if TLS is not enabled.
+	tlsConfig *tls.Config
+	// capability is the server capability flags advertised in the initial handshake.
+	capability uint32
+
+	// rsaKey is the RSA key of caching_sha2_password, it is loaded or generated by rsaKeyOnce.
+	rsaKeyOnce sync.Once
+	rsaKey     *rsa.PrivateKey
+	rsaKeyErr  error
+
+	// connCount is the number of the connections including the ones in handshake, it's
+	// limited by cfg.MaxConnections.
+	connCount int32
+	// inShutdown is set to 1 when the server is shutting down gracefully.
+	inShutdown int32
+
+	// users tracks the resources used by each user, it's protected by usersMu.
+	usersMu sync.Mutex
+	users   map[string]*userResource
 }
 
 // ConnectionCount gets current connection count.
 func (s *Server) ConnectionCount() int {
 	var cnt int
 	s.rwlock.RLock()
-	cnt = len(s.clients)
+	cnt = len(s.clients) + len(s.pgClients)
 	s.rwlock.RUnlock()
 	return cnt
 }
 
+// ShowProcessList implements the SessionManager interface.
+func (s *Server) ShowProcessList() []util.ProcessInfo {
+	s.rwlock.RLock()
+	rs := make([]util.ProcessInfo, 0, len(s.clients)+len(s.pgClients))
+	for _, client := range s.clients {
+		rs = append(rs, client.processInfo())
+	}
+	for _, client := range s.pgClients {
+		rs = append(rs, client.processInfo())
+	}
+	s.rwlock.RUnlock()
+	sort.Sort(processInfoByID(rs))
+	return rs
+}
+
+type processInfoByID []util.ProcessInfo
+
+func (p processInfoByID) Len() int           { return len(p) }
+func (p processInfoByID) Less(i, j int) bool { return p[i].ID < p[j].ID }
+func (p processInfoByID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// Kill implements the SessionManager interface.
+func (s *Server) Kill(connectionID uint64, query bool) bool {
+	s.rwlock.RLock()
+	var (
+		ctx  IContext
+		conn net.Conn
+	)
+	if client, ok := s.clients[uint32(connectionID)]; ok {
+		ctx, conn = client.ctx, client.conn
+	} else if client, ok := s.pgClients[uint32(connectionID)]; ok {
+		ctx, conn = client.ctx, client.conn
+	}
+	s.rwlock.RUnlock()
+	if ctx == nil {
+		return false
+	}
+	ctx.KillQuery()
+	if !query {
+		// Closing the connection makes the blocked read in Run fail, then the
+		// connection is cleaned up there.
+		conn.Close()
+	}
+	return true
+}
+
+// cancelPGQuery handles the CancelRequest of the PostgreSQL protocol, the query is interrupted
+// if the secret key matches the one sent to the connection.
+func (s *Server) cancelPGQuery(connectionID, secretKey uint32) {
+	s.rwlock.RLock()
+	client, ok := s.pgClients[connectionID]
+	s.rwlock.RUnlock()
+	if !ok || client.secretKey != secretKey {
+		log.Warnf("Invalid cancel request for connection %d", connectionID)
+		return
+	}
+	client.ctx.KillQuery()
+}
+
 func (s *Server) getToken() *Token {
 	return s.concurrentLimiter.Get()
 }
@@ -118,17 +208,42 @@ func (s *Server) skipAuth() bool {
 	return s.cfg.SkipAuth
 }
 
+// defaultTokenLimit is the number of the commands executed concurrently if it's not configured.
+const defaultTokenLimit = 100
+
 // NewServer creates a new Server.
 func NewServer(cfg *Config, driver IDriver) (*Server, error) {
+	tokenLimit := cfg.TokenLimit
+	if tokenLimit <= 0 {
+		tokenLimit = defaultTokenLimit
+	}
 	s := &Server{
 		cfg:               cfg,
 		driver:            driver,
-		concurrentLimiter: NewTokenLimiter(100),
+		concurrentLimiter: NewTokenLimiter(tokenLimit),
 		rwlock:            &sync.RWMutex{},
 		clients:           make(map[uint32]*clientConn),
+		pgClients:         make(map[uint32]*pgConn),
+		capability:        defaultCapability,
+		users:             make(map[string]*userResource),
 	}
 
 	var err error
+	s.tlsConfig, err = loadTLSConfig(cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if s.tlsConfig != nil {
+		s.capability |= mysql.ClientSSL
+		log.Infof("Secure connection is enabled")
+	}
+	// Load the configured RSA key early to report the error on startup.
+	if len(cfg.AuthRSAKey) > 0 {
+		if _, err = s.getRSAKey(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
 	if cfg.Socket != "" {
 		cfg.SkipAuth = true
 		s.listener, err = net.Listen("unix", cfg.Socket)
@@ -139,12 +254,90 @@ func NewServer(cfg *Config, driver IDriver) (*Server, error) {
 		return nil, errors.Trace(err)
 	}
 
+	if len(cfg.PGAddr) > 0 {
+		s.pgListener, err = net.Listen("tcp", cfg.PGAddr)
+		if err != nil {
+			s.listener.Close()
+			return nil, errors.Trace(err)
+		}
+		log.Infof("Server run PostgreSQL Protocol Listen at [%s]", cfg.PGAddr)
+	}
+
 	// Init rand seed for randomBuf()
 	rand.Seed(time.Now().UTC().UnixNano())
 	log.Infof("Server run MySql Protocol Listen at [%s]", s.cfg.Addr)
 	return s, nil
 }
 
+// loadTLSConfig loads the certificate, private key and CA files of cfg.
+// It returns nil if the certificate or private key is not set.
+func loadTLSConfig(cfg *Config) (*tls.Config, error) {
+	if len(cfg.SSLCert) == 0 || len(cfg.SSLKey) == 0 {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.SSLCert, cfg.SSLKey)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	if len(cfg.SSLCA) > 0 {
+		caCert, err := ioutil.ReadFile(cfg.SSLCA)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.Errorf("no certificate is found in %s", cfg.SSLCA)
+		}
+		// The client certificate is optional, users with REQUIRE X509 are checked in authentication.
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// rsaKeyBits is the size of the RSA key generated for caching_sha2_password.
+const rsaKeyBits = 2048
+
+// getRSAKey returns the RSA key of caching_sha2_password. It's loaded from the AuthRSAKey file,
+// or generated if the file is not set, only once.
+func (s *Server) getRSAKey() (*rsa.PrivateKey, error) {
+	s.rsaKeyOnce.Do(func() {
+		if len(s.cfg.AuthRSAKey) == 0 {
+			s.rsaKey, s.rsaKeyErr = rsa.GenerateKey(cryptorand.Reader, rsaKeyBits)
+			return
+		}
+		s.rsaKey, s.rsaKeyErr = loadRSAKey(s.cfg.AuthRSAKey)
+	})
+	return s.rsaKey, errors.Trace(s.rsaKeyErr)
+}
+
+// loadRSAKey loads the RSA private key in PKCS #1 or PKCS #8 PEM format.
+func loadRSAKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data is found in %s", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("the key in %s is not an RSA private key", path)
+	}
+	return rsaKey, nil
+}
+
 // Run runs the server.
 func (s *Server) Run() error {
 
@@ -152,6 +345,9 @@ func (s *Server) Run() error {
 	if s.cfg.ReportStatus {
 		s.startStatusHTTP()
 	}
+	if s.pgListener != nil {
+		go s.runPG(s.pgListener)
+	}
 
 	for {
 		conn, err := s.listener.Accept()
@@ -169,6 +365,24 @@ func (s *Server) Run() error {
 	}
 }
 
+// runPG accepts the connections of the PostgreSQL protocol until the listener is closed.
+func (s *Server) runPG(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if opErr, ok := err.(*net.OpError); ok {
+				if opErr.Err.Error() == "use of closed network connection" {
+					return
+				}
+			}
+			log.Errorf("accept error %s", err.Error())
+			return
+		}
+
+		go s.onPGConn(conn)
+	}
+}
+
 // Close closes the server.
 func (s *Server) Close() {
 	s.rwlock.Lock()
@@ -178,6 +392,58 @@ func (s *Server) Close() {
 		s.listener.Close()
 		s.listener = nil
 	}
+	if s.pgListener != nil {
+		s.pgListener.Close()
+		s.pgListener = nil
+	}
+}
+
+// gracefulCheckInterval is the interval to check whether all the connections exit in graceful shutdown.
+const gracefulCheckInterval = 100 * time.Millisecond
+
+// GracefulDown stops accepting new connections and lets the connections exit after their
+// running statements finish. The connections still running after timeout are closed.
+func (s *Server) GracefulDown(timeout time.Duration) {
+	log.Infof("Graceful shutdown, wait %v for %d connections to exit.", timeout, s.ConnectionCount())
+	atomic.StoreInt32(&s.inShutdown, 1)
+	s.Close()
+
+	// The connections exit before reading the next command, the idle ones are waked up by the
+	// read deadline.
+	s.rwlock.RLock()
+	for _, client := range s.clients {
+		if client.isIdle() {
+			client.conn.SetReadDeadline(time.Now())
+		}
+	}
+	for _, client := range s.pgClients {
+		if client.isIdle() {
+			client.conn.SetReadDeadline(time.Now())
+		}
+	}
+	s.rwlock.RUnlock()
+
+	deadline := time.Now().Add(timeout)
+	for s.ConnectionCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(gracefulCheckInterval)
+	}
+
+	s.rwlock.RLock()
+	for _, client := range s.clients {
+		log.Warnf("Close connection %d of user %s in graceful shutdown.", client.connectionID, client.user)
+		client.ctx.KillQuery()
+		client.conn.Close()
+	}
+	for _, client := range s.pgClients {
+		log.Warnf("Close connection %d of user %s in graceful shutdown.", client.connectionID, client.user)
+		client.ctx.KillQuery()
+		client.conn.Close()
+	}
+	s.rwlock.RUnlock()
+}
+
+func (s *Server) isShuttingDown() bool {
+	return atomic.LoadInt32(&s.inShutdown) == 1
 }
 
 func (s *Server) onConn(c net.Conn) {
@@ -186,15 +452,25 @@ func (s *Server) onConn(c net.Conn) {
 		log.Errorf("newConn error %s", errors.ErrorStack(err))
 		return
 	}
+	defer atomic.AddInt32(&s.connCount, -1)
+	if n := atomic.AddInt32(&s.connCount, 1); s.cfg.MaxConnections > 0 && int(n) > s.cfg.MaxConnections {
+		log.Warnf("Reject connection from %s, the connections exceed max connections %d.", c.RemoteAddr(), s.cfg.MaxConnections)
+		// The error packet is sent instead of the initial handshake.
+		conn.writeError(mysql.NewErr(mysql.ErrConCount))
+		c.Close()
+		return
+	}
 	if err := conn.handshake(); err != nil {
 		log.Errorf("handshake error %s", errors.ErrorStack(err))
 		c.Close()
+		conn.releaseUserConn()
 		return
 	}
 	defer func() {
 		log.Infof("close %s", conn)
 	}()
 
+	conn.setProcessInfo(mysql.ComSleep, "")
 	s.rwlock.Lock()
 	s.clients[conn.connectionID] = conn
 	s.rwlock.Unlock()
@@ -202,6 +478,41 @@ func (s *Server) onConn(c net.Conn) {
 	conn.Run()
 }
 
+func (s *Server) onPGConn(c net.Conn) {
+	conn := s.newPGConn(c)
+	defer atomic.AddInt32(&s.connCount, -1)
+	if n := atomic.AddInt32(&s.connCount, 1); s.cfg.MaxConnections > 0 && int(n) > s.cfg.MaxConnections {
+		log.Warnf("Reject connection from %s, the connections exceed max connections %d.", c.RemoteAddr(), s.cfg.MaxConnections)
+		conn.writeErrorWithSeverity(mysql.NewErr(mysql.ErrConCount), "FATAL")
+		conn.flush()
+		c.Close()
+		return
+	}
+	if err := conn.handshake(); err != nil {
+		if terror.ErrorNotEqual(err, io.EOF) {
+			log.Errorf("pg handshake error %s", errors.ErrorStack(err))
+			conn.writeErrorWithSeverity(err, "FATAL")
+			conn.flush()
+		}
+		conn.conn.Close()
+		conn.releaseUserConn()
+		if conn.ctx != nil {
+			conn.ctx.Close()
+		}
+		return
+	}
+	defer func() {
+		log.Infof("close %s", conn)
+	}()
+
+	conn.setProcessInfo(mysql.ComSleep, "")
+	s.rwlock.Lock()
+	s.pgClients[conn.connectionID] = conn
+	s.rwlock.Unlock()
+
+	conn.Run()
+}
+
 var once sync.Once
 
 const defaultStatusAddr = ":10080"
@@ -227,6 +538,7 @@ func (s *Server) startStatusHTTP() {
 			})
 			// HTTP path for prometheus.
 			http.Handle("/metrics", prometheus.Handler())
+			s.registerAPIHandlers()
 			addr := s.cfg.StatusAddr
 			if len(addr) == 0 {
 				addr = defaultStatusAddr
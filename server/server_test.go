@@ -14,17 +14,35 @@
 package server
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
+	"encoding/binary"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/model"
 	tmysql "github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/printer"
 )
 
@@ -457,6 +475,104 @@ func runTestStatusAPI(c *C) {
 	c.Assert(data.GitHash, Equals, printer.TiDBGitHash)
 }
 
+// getStatusJSON gets the url on the status port and decodes the JSON response into v.
+func getStatusJSON(c *C, path string, v interface{}) int {
+	resp, err := http.Get("http://127.0.0.1:10090" + path)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(v)
+		c.Assert(err, IsNil)
+	}
+	return resp.StatusCode
+}
+
+func runTestRestAPI(c *C) {
+	runTests(c, dsn, func(dbt *DBTest) {
+		dbt.mustExec("create table test (id int primary key, v varchar(10), index idx_v (v))")
+		dbt.mustExec("insert test values (1, 'a')")
+		dbt.mustExec("update test set v = 'b' where id = 1")
+
+		var dbs []*model.DBInfo
+		c.Assert(getStatusJSON(c, "/schema", &dbs), Equals, http.StatusOK)
+		var names []string
+		for _, db := range dbs {
+			names = append(names, db.Name.L)
+		}
+		c.Assert(strings.Join(names, ","), Matches, ".*test.*")
+		var tables []*model.TableInfo
+		c.Assert(getStatusJSON(c, "/schema/test", &tables), Equals, http.StatusOK)
+		var tbl model.TableInfo
+		c.Assert(getStatusJSON(c, "/schema/test/test", &tbl), Equals, http.StatusOK)
+		c.Assert(tbl.Name.O, Equals, "test")
+		c.Assert(tbl.Columns, HasLen, 2)
+		c.Assert(tbl.Indices, HasLen, 1)
+		c.Assert(getStatusJSON(c, "/schema/no_such_db", &tables), Equals, http.StatusNotFound)
+		c.Assert(getStatusJSON(c, "/schema/test/no_such_table", &tbl), Equals, http.StatusNotFound)
+
+		var regions tableRegions
+		c.Assert(getStatusJSON(c, "/tables/test/test/regions", &regions), Equals, http.StatusOK)
+		c.Assert(regions.ID, Equals, tbl.ID)
+		c.Assert(regions.RecordRegions, HasLen, 1)
+		c.Assert(regions.Indices, HasLen, 1)
+		c.Assert(regions.Indices[0].Name, Equals, "idx_v")
+		c.Assert(regions.Indices[0].Regions, HasLen, 1)
+
+		var jobs []*model.Job
+		c.Assert(getStatusJSON(c, "/ddl/history", &jobs), Equals, http.StatusOK)
+		found := false
+		for _, job := range jobs {
+			if job.TableID == tbl.ID && job.Type == model.ActionCreateTable {
+				found = true
+			}
+		}
+		c.Assert(found, IsTrue)
+
+		var mvcc rowMvcc
+		c.Assert(getStatusJSON(c, "/mvcc/key/test/test/1", &mvcc), Equals, http.StatusOK)
+		c.Assert(mvcc.Versions, HasLen, 2)
+		c.Assert(mvcc.Versions[0].CommitTS > mvcc.Versions[1].CommitTS, IsTrue)
+		c.Assert(mvcc.Versions[0].Values, DeepEquals, map[string]interface{}{"id": "1", "v": "b"})
+		c.Assert(mvcc.Versions[1].Values, DeepEquals, map[string]interface{}{"id": "1", "v": "a"})
+		dbt.mustExec("delete from test where id = 1")
+		c.Assert(getStatusJSON(c, "/mvcc/key/test/test/1", &mvcc), Equals, http.StatusOK)
+		c.Assert(mvcc.Versions, HasLen, 3)
+		c.Assert(mvcc.Versions[0].Values, IsNil)
+		c.Assert(getStatusJSON(c, "/mvcc/key/test/test/x", &mvcc), Equals, http.StatusBadRequest)
+		// The rows of the system schemas aren't exposed, e.g. the password hashes in mysql.user.
+		c.Assert(getStatusJSON(c, "/mvcc/key/mysql/user/1", &mvcc), Equals, http.StatusForbidden)
+		c.Assert(getStatusJSON(c, "/mvcc/key/information_schema/tables/1", &mvcc), Equals, http.StatusForbidden)
+	})
+
+	var s settings
+	c.Assert(getStatusJSON(c, "/settings", &s), Equals, http.StatusOK)
+	oldLevel := s.LogLevel
+	resp, err := http.PostForm("http://127.0.0.1:10090/settings", url.Values{"log_level": {"debug"}})
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(log.GetLogLevel(), Equals, log.LOG_LEVEL_DEBUG)
+	resp, err = http.PostForm("http://127.0.0.1:10090/settings", url.Values{"log_level": {"verbose"}})
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	resp, err = http.PostForm("http://127.0.0.1:10090/settings", url.Values{"log_level": {oldLevel}})
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(getStatusJSON(c, "/settings", &s), Equals, http.StatusOK)
+	c.Assert(s.LogLevel, Equals, oldLevel)
+
+	// The MVCC and settings API is disabled by default.
+	h := &apiHandler{}
+	w := httptest.NewRecorder()
+	h.handleMvccKey(w, httptest.NewRequest("GET", "/mvcc/key/test/test/1", nil))
+	c.Assert(w.Code, Equals, http.StatusForbidden)
+	w = httptest.NewRecorder()
+	h.handleSettings(w, httptest.NewRequest("POST", "/settings?log_level=debug", nil))
+	c.Assert(w.Code, Equals, http.StatusForbidden)
+	c.Assert(log.GetLogLevel(), Not(Equals), log.LOG_LEVEL_DEBUG)
+}
+
 func runTestMultiPacket(c *C) {
 	runTests(c, dsn, func(dbt *DBTest) {
 		dbt.mustExec(fmt.Sprintf("set global max_allowed_packet=%d", 1024*1024*160)) // 160M
@@ -482,18 +598,18 @@ func runTestMultiStatements(c *C) {
 		c.Assert(err, IsNil, Commentf("res.RowsAffected() returned error"))
 		c.Assert(count, Equals, int64(1))
 
-		// Update
-		res = dbt.mustExec("UPDATE test SET value = 3 WHERE id = 1; UPDATE test SET value = 4 WHERE id = 1; UPDATE test SET value = 5 WHERE id = 1;")
-		count, err = res.RowsAffected()
-		c.Assert(err, IsNil, Commentf("res.RowsAffected() returned error"))
-		c.Assert(count, Equals, int64(1))
+		// The driver doesn't set CLIENT_MULTI_STATEMENTS.
+		_, err = dbt.db.Exec("UPDATE test SET value = 3 WHERE id = 1; UPDATE test SET value = 4 WHERE id = 1;")
+		checkErrorCode(c, err, tmysql.ErrParse)
+
+		runTestMultiStatementsRaw(c)
 
 		// Read
 		var out int
 		rows := dbt.mustQuery("SELECT value FROM test WHERE id=1;")
 		if rows.Next() {
 			rows.Scan(&out)
-			c.Assert(out, Equals, 5)
+			c.Assert(out, Equals, 6)
 
 			if rows.Next() {
 				dbt.Error("unexpected data")
@@ -503,3 +619,784 @@ func runTestMultiStatements(c *C) {
 		}
 	})
 }
+
+func runTestMultiStatementsRaw(c *C) {
+	conn, pkg := dialRawConn(c, 0)
+	defer conn.Close()
+
+	readPacket := func() []byte {
+		data, err := pkg.readPacket()
+		c.Assert(err, IsNil)
+		return data
+	}
+	// readOK returns the affected rows and the status of the OK packet.
+	readOK := func() (uint64, uint16) {
+		data := readPacket()
+		c.Assert(data[0], Equals, tmysql.OKHeader, Commentf("%q", data))
+		affectedRows, _, n := parseLengthEncodedInt(data[1:])
+		_, _, m := parseLengthEncodedInt(data[1+n:])
+		return affectedRows, binary.LittleEndian.Uint16(data[1+n+m:])
+	}
+	readErrorCode := func() uint16 {
+		data := readPacket()
+		c.Assert(data[0], Equals, tmysql.ErrHeader)
+		return binary.LittleEndian.Uint16(data[1:3])
+	}
+	setOption := func(option uint16) {
+		writeRawCommand(c, pkg, tmysql.ComSetOption, dumpUint16(option))
+		c.Assert(readPacket()[0], Equals, tmysql.EOFHeader)
+	}
+	const more = tmysql.ServerMoreResultsExists
+
+	// Multiple statements are enabled by COM_SET_OPTION.
+	multiUpdate := "UPDATE test SET value = 3 WHERE id = 1; UPDATE test SET value = 4 WHERE id = 1"
+	writeRawCommand(c, pkg, tmysql.ComQuery, []byte(multiUpdate))
+	c.Assert(readErrorCode(), Equals, uint16(tmysql.ErrParse))
+	setOption(0)
+	writeRawCommand(c, pkg, tmysql.ComQuery, []byte(multiUpdate))
+	affectedRows, status := readOK()
+	c.Assert(affectedRows, Equals, uint64(1))
+	c.Assert(status&more, Equals, more)
+	affectedRows, status = readOK()
+	c.Assert(affectedRows, Equals, uint64(1))
+	c.Assert(status&more, Equals, uint16(0))
+	setOption(1)
+	writeRawCommand(c, pkg, tmysql.ComQuery, []byte(multiUpdate))
+	c.Assert(readErrorCode(), Equals, uint16(tmysql.ErrParse))
+	setOption(0)
+
+	// Each statement has its own result, the result of SELECT sees the UPDATE before it.
+	writeRawCommand(c, pkg, tmysql.ComQuery, []byte("UPDATE test SET value = 5 WHERE id = 1; SELECT value FROM test WHERE id = 1; UPDATE test SET value = 5 WHERE id = 2"))
+	affectedRows, status = readOK()
+	c.Assert(affectedRows, Equals, uint64(1))
+	c.Assert(status&more, Equals, more)
+	c.Assert(readPacket(), DeepEquals, []byte{1})
+	readPacket()
+	c.Assert(readPacket()[0], Equals, tmysql.EOFHeader)
+	c.Assert(readPacket(), DeepEquals, []byte("\x015"))
+	data := readPacket()
+	c.Assert(data[0], Equals, tmysql.EOFHeader)
+	c.Assert(binary.LittleEndian.Uint16(data[3:5])&more, Equals, more)
+	affectedRows, status = readOK()
+	c.Assert(affectedRows, Equals, uint64(0))
+	c.Assert(status&more, Equals, uint16(0))
+
+	// The statements after an error are not executed.
+	writeRawCommand(c, pkg, tmysql.ComQuery, []byte("UPDATE test SET value = 6 WHERE id = 1; SELECT * FROM not_exist; UPDATE test SET value = 7 WHERE id = 1"))
+	_, status = readOK()
+	c.Assert(status&more, Equals, more)
+	c.Assert(readErrorCode(), Equals, uint16(tmysql.ErrNoSuchTable))
+	writeRawCommand(c, pkg, tmysql.ComPing, nil)
+	readOK()
+}
+
+func runTestProcessListAndKill(c *C) {
+	runTests(c, dsn, func(dbt *DBTest) {
+		// Use a dedicated pool with a single connection so that it can be killed.
+		db, err := sql.Open("mysql", dsn)
+		c.Assert(err, IsNil)
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+		var id uint64
+		err = db.QueryRow("select connection_id()").Scan(&id)
+		c.Assert(err, IsNil)
+		_, err = db.Exec("use mysql")
+		c.Assert(err, IsNil)
+
+		findProcess := func() (found bool, dbName sql.NullString, command string) {
+			rows := dbt.mustQuery("show processlist")
+			defer rows.Close()
+			for rows.Next() {
+				var (
+					pid         uint64
+					user, host  string
+					state, info sql.NullString
+					elapsed     int
+					db          sql.NullString
+					cmd         string
+				)
+				err := rows.Scan(&pid, &user, &host, &db, &cmd, &elapsed, &state, &info)
+				c.Assert(err, IsNil)
+				if pid == id {
+					found, dbName, command = true, db, cmd
+				}
+			}
+			return
+		}
+		found, dbName, command := findProcess()
+		c.Assert(found, IsTrue)
+		c.Assert(dbName.String, Equals, "mysql")
+		c.Assert(command, Equals, "Sleep")
+		dbt.mustQueryRows(fmt.Sprintf("select id from information_schema.processlist where id = %d", id))
+
+		// Killing the idle query keeps the connection.
+		dbt.mustExec(fmt.Sprintf("kill query %d", id))
+		var newID uint64
+		err = db.QueryRow("select connection_id()").Scan(&newID)
+		c.Assert(err, IsNil)
+		c.Assert(newID, Equals, id)
+
+		dbt.mustExec(fmt.Sprintf("kill %d", id))
+		for i := 0; i < 50; i++ {
+			if found, _, _ = findProcess(); !found {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		c.Assert(found, IsFalse)
+
+		_, err = dbt.db.Exec("kill 1")
+		checkErrorCode(c, err, tmysql.ErrNoSuchThread)
+	})
+}
+
+// dialRawConn connects to the test server and finishes the handshake with the
+// capability, the packets are read and written by packetIO.
+func dialRawConn(c *C, capability uint32) (net.Conn, *packetIO) {
+	conn, pkg, _ := rawHandshake(c, "localhost:4001", nil, capability, "root", "", nil)
+	data, err := pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, tmysql.OKHeader)
+	return conn, pkg
+}
+
+// rawHandshake connects to the server at addr and sends the handshake response with the user,
+// the auth plugin and the auth data calculated from the salt. The connection is upgraded to TLS
+// if tlsConfig is not nil. It returns the salt of the server.
+func rawHandshake(c *C, addr string, tlsConfig *tls.Config, capability uint32, user, plugin string, auth func(salt []byte) []byte) (net.Conn, *packetIO, []byte) {
+	conn, err := net.Dial("tcp", addr)
+	c.Assert(err, IsNil)
+	pkg := newPacketIO(conn)
+
+	// Initial handshake.
+	data, err := pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, byte(10))
+	pos := 1 + bytes.IndexByte(data[1:], 0) + 1 + 4
+	salt := append([]byte{}, data[pos:pos+8]...)
+	pos += 8 + 1 + 2 + 1 + 2 + 2 + 1 + 10
+	salt = append(salt, data[pos:pos+12]...)
+
+	capability |= tmysql.ClientProtocol41 | tmysql.ClientSecureConnection |
+		tmysql.ClientLongPassword | tmysql.ClientConnectWithDB
+	if len(plugin) > 0 {
+		capability |= tmysql.ClientPluginAuth
+	}
+	if tlsConfig != nil {
+		capability |= tmysql.ClientSSL
+	}
+	resp := make([]byte, 4, 64)
+	resp = append(resp, dumpUint32(capability)...)
+	resp = append(resp, dumpUint32(uint32(tmysql.MaxPayloadLen))...)
+	resp = append(resp, tmysql.DefaultCollationID)
+	resp = append(resp, make([]byte, 23)...)
+	if tlsConfig != nil {
+		// The SSL request is the fixed header of the handshake response.
+		c.Assert(pkg.writePacket(append([]byte{}, resp...)), IsNil)
+		c.Assert(pkg.flush(), IsNil)
+		tlsConn := tls.Client(conn, tlsConfig)
+		c.Assert(tlsConn.Handshake(), IsNil)
+		conn = tlsConn
+		pkg.setConn(tlsConn)
+	}
+	var authData []byte
+	if auth != nil {
+		authData = auth(salt)
+	}
+	resp = append(resp, user...)
+	resp = append(resp, 0, byte(len(authData)))
+	resp = append(resp, authData...)
+	resp = append(resp, "test"...)
+	resp = append(resp, 0)
+	if len(plugin) > 0 {
+		resp = append(resp, plugin...)
+		resp = append(resp, 0)
+	}
+	c.Assert(pkg.writePacket(resp), IsNil)
+	c.Assert(pkg.flush(), IsNil)
+	return conn, pkg, salt
+}
+
+// writeRawCommand writes a command packet and resets the sequences for the response.
+func writeRawCommand(c *C, pkg *packetIO, cmd byte, data []byte) {
+	pkg.resetSequence()
+	packet := append(make([]byte, 4), cmd)
+	packet = append(packet, data...)
+	c.Assert(pkg.writePacket(packet), IsNil)
+	c.Assert(pkg.flush(), IsNil)
+}
+
+func runTestCompressedProtocol(c *C) {
+	conn, pkg := dialRawConn(c, tmysql.ClientCompress)
+	defer conn.Close()
+	pkg.compress = true
+
+	// The long literal makes both the query and the result worth compressing.
+	str := strings.Repeat("x", 1000)
+	for i := 0; i < 2; i++ {
+		writeRawCommand(c, pkg, tmysql.ComQuery, []byte(fmt.Sprintf("select '%s'", str)))
+
+		// Column count, column definition, EOF, row and EOF.
+		data, err := pkg.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(data, DeepEquals, []byte{1})
+		_, err = pkg.readPacket()
+		c.Assert(err, IsNil)
+		data, err = pkg.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(data[0], Equals, tmysql.EOFHeader)
+		data, err = pkg.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(string(data[3:]), Equals, str)
+		data, err = pkg.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(data[0], Equals, tmysql.EOFHeader)
+	}
+}
+
+func runTestCursorFetch(c *C) {
+	runTests(c, dsn, func(dbt *DBTest) {
+		dbt.mustExec("create table test (a int)")
+		dbt.mustExec("insert into test values (1), (2), (3), (4), (5)")
+		runTestCursorFetchRows(c)
+	})
+}
+
+func runTestCursorFetchRows(c *C) {
+	conn, pkg := dialRawConn(c, 0)
+	defer conn.Close()
+
+	writeRawCommand(c, pkg, tmysql.ComStmtPrepare, []byte("select a from test order by a"))
+	data, err := pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, tmysql.OKHeader)
+	stmtID := data[1:5]
+	// Column definition and EOF.
+	for i := 0; i < 2; i++ {
+		_, err = pkg.readPacket()
+		c.Assert(err, IsNil)
+	}
+
+	readEOFStatus := func() uint16 {
+		data, err := pkg.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(data[0], Equals, tmysql.EOFHeader)
+		return binary.LittleEndian.Uint16(data[3:5])
+	}
+	// Executing with a cursor only sends the column definitions.
+	writeRawCommand(c, pkg, tmysql.ComStmtExecute, append(append([]byte{}, stmtID...), tmysql.CursorTypeReadOnly, 1, 0, 0, 0))
+	data, err = pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, []byte{1})
+	_, err = pkg.readPacket()
+	c.Assert(err, IsNil)
+	status := readEOFStatus()
+	c.Assert(status&tmysql.ServerStatusCursorExists, Equals, tmysql.ServerStatusCursorExists)
+
+	fetch := func(expected ...uint32) uint16 {
+		writeRawCommand(c, pkg, tmysql.ComStmtFetch, append(append([]byte{}, stmtID...), 2, 0, 0, 0))
+		for _, v := range expected {
+			data, err := pkg.readPacket()
+			c.Assert(err, IsNil)
+			c.Assert(data[0], Equals, byte(0))
+			c.Assert(binary.LittleEndian.Uint32(data[2:6]), Equals, v)
+		}
+		status := readEOFStatus()
+		c.Assert(status&tmysql.ServerStatusCursorExists, Equals, tmysql.ServerStatusCursorExists)
+		return status & tmysql.ServerStatusLastRowSend
+	}
+	c.Assert(fetch(1, 2), Equals, uint16(0))
+	c.Assert(fetch(3, 4), Equals, uint16(0))
+	c.Assert(fetch(5), Equals, tmysql.ServerStatusLastRowSend)
+
+	// The cursor is closed after the last row is sent.
+	writeRawCommand(c, pkg, tmysql.ComStmtFetch, append(append([]byte{}, stmtID...), 2, 0, 0, 0))
+	data, err = pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, tmysql.ErrHeader)
+	c.Assert(binary.LittleEndian.Uint16(data[1:3]), Equals, uint16(tmysql.ErrStmtHasNoOpenCursor))
+}
+
+// scrambleSha2 calculates the scramble of caching_sha2_password fast authentication.
+func scrambleSha2(pwd string, salt []byte) []byte {
+	stage1 := sha256.Sum256([]byte(pwd))
+	h := sha256.New()
+	h.Write(util.CalcSha2Digest([]byte(pwd)))
+	h.Write(salt)
+	scramble := h.Sum(nil)
+	for i := range scramble {
+		scramble[i] ^= stage1[i]
+	}
+	return scramble
+}
+
+func runTestCachingSha2Auth(c *C) {
+	runTests(c, dsn, func(dbt *DBTest) {
+		dbt.mustExec("create user 'sha2user'@'%' identified with caching_sha2_password by '123'")
+	})
+	readPacket := func(pkg *packetIO) []byte {
+		data, err := pkg.readPacket()
+		c.Assert(err, IsNil)
+		return data
+	}
+	writePacket := func(pkg *packetIO, payload []byte) {
+		c.Assert(pkg.writePacket(append(make([]byte, 4), payload...)), IsNil)
+		c.Assert(pkg.flush(), IsNil)
+	}
+	sha2Auth := func(pwd string) func(salt []byte) []byte {
+		return func(salt []byte) []byte {
+			return scrambleSha2(pwd, salt)
+		}
+	}
+	// fullAuth requests the public key and sends the encrypted password.
+	fullAuth := func(pkg *packetIO, pwd string, salt []byte) []byte {
+		c.Assert(readPacket(pkg), DeepEquals, []byte{authMoreDataHeader, authPerformFullAuth})
+		writePacket(pkg, []byte{authRequestPublicKey})
+		data := readPacket(pkg)
+		c.Assert(data[0], Equals, authMoreDataHeader)
+		block, _ := pem.Decode(data[1:])
+		c.Assert(block, NotNil)
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		c.Assert(err, IsNil)
+		plain := append([]byte(pwd), 0)
+		for i := range plain {
+			plain[i] ^= salt[i%len(salt)]
+		}
+		enc, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, pub.(*rsa.PublicKey), plain, nil)
+		c.Assert(err, IsNil)
+		writePacket(pkg, enc)
+		return readPacket(pkg)
+	}
+
+	// The wrong password fails the full authentication.
+	conn, pkg, salt := rawHandshake(c, "localhost:4001", nil, 0, "sha2user", tmysql.AuthCachingSha2Password, sha2Auth("1234"))
+	data := fullAuth(pkg, "1234", salt)
+	c.Assert(data[0], Equals, tmysql.ErrHeader)
+	c.Assert(binary.LittleEndian.Uint16(data[1:3]), Equals, uint16(tmysql.ErrAccessDenied))
+	conn.Close()
+
+	// The fast authentication fails before the full authentication succeeds.
+	conn, pkg, salt = rawHandshake(c, "localhost:4001", nil, 0, "sha2user", tmysql.AuthCachingSha2Password, sha2Auth("123"))
+	c.Assert(fullAuth(pkg, "123", salt)[0], Equals, tmysql.OKHeader)
+	conn.Close()
+
+	conn, pkg, _ = rawHandshake(c, "localhost:4001", nil, 0, "sha2user", tmysql.AuthCachingSha2Password, sha2Auth("123"))
+	c.Assert(readPacket(pkg), DeepEquals, []byte{authMoreDataHeader, authFastAuthSuccess})
+	c.Assert(readPacket(pkg)[0], Equals, tmysql.OKHeader)
+	conn.Close()
+
+	// The client using mysql_native_password is asked to switch to caching_sha2_password.
+	conn, pkg, salt = rawHandshake(c, "localhost:4001", nil, 0, "sha2user", tmysql.AuthName, func(salt []byte) []byte {
+		return util.CalcPassword(salt, util.Sha1Hash([]byte("123")))
+	})
+	data = readPacket(pkg)
+	c.Assert(data[0], Equals, tmysql.EOFHeader)
+	c.Assert(string(data[1:]), Equals, tmysql.AuthCachingSha2Password+"\x00"+string(salt)+"\x00")
+	writePacket(pkg, scrambleSha2("123", salt))
+	c.Assert(readPacket(pkg), DeepEquals, []byte{authMoreDataHeader, authFastAuthSuccess})
+	c.Assert(readPacket(pkg)[0], Equals, tmysql.OKHeader)
+	conn.Close()
+
+	// The mysql_native_password user is switched back.
+	conn, pkg, salt = rawHandshake(c, "localhost:4001", nil, 0, "root", tmysql.AuthCachingSha2Password, nil)
+	data = readPacket(pkg)
+	c.Assert(string(data[1:]), Equals, tmysql.AuthName+"\x00"+string(salt)+"\x00")
+	writePacket(pkg, nil)
+	c.Assert(readPacket(pkg)[0], Equals, tmysql.OKHeader)
+	conn.Close()
+
+	// The client without CLIENT_PLUGIN_AUTH can't switch to caching_sha2_password.
+	conn, pkg, _ = rawHandshake(c, "localhost:4001", nil, 0, "sha2user", "", sha2Auth("123"))
+	c.Assert(readPacket(pkg)[0], Equals, tmysql.ErrHeader)
+	conn.Close()
+}
+
+func runTestUserResourceLimits(c *C) {
+	runTests(c, dsn, func(dbt *DBTest) {
+		dbt.mustExec("CREATE USER 'conn_limit_user'@'%' WITH MAX_USER_CONNECTIONS 2")
+		dbt.mustExec("CREATE USER 'query_limit_user'@'%'")
+		dbt.mustExec("GRANT SELECT ON *.* TO 'query_limit_user'@'%' WITH MAX_QUERIES_PER_HOUR 3")
+	})
+	openDB := func(user string) *sql.DB {
+		db, err := sql.Open("mysql", user+"@tcp(localhost:4001)/test?strict=true")
+		c.Assert(err, IsNil)
+		db.SetMaxOpenConns(1)
+		return db
+	}
+
+	db1, db2, db3 := openDB("conn_limit_user"), openDB("conn_limit_user"), openDB("conn_limit_user")
+	defer db2.Close()
+	defer db3.Close()
+	c.Assert(db1.Ping(), IsNil)
+	c.Assert(db2.Ping(), IsNil)
+	checkErrorCode(c, db3.Ping(), tmysql.ErrTooManyUserConnections)
+	// The connection is released after it's closed.
+	db1.Close()
+	var err error
+	for i := 0; i < 50; i++ {
+		if err = db3.Ping(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(err, IsNil)
+
+	db := openDB("query_limit_user")
+	defer db.Close()
+	// The driver queries @@max_allowed_packet after it connects, so two more queries are allowed.
+	for i := 0; i < 2; i++ {
+		rows, err := db.Query("select 1")
+		c.Assert(err, IsNil)
+		rows.Close()
+	}
+	_, err = db.Query("select 1")
+	checkErrorCode(c, err, tmysql.ErrUserLimitReached)
+	// Ping is not counted.
+	c.Assert(db.Ping(), IsNil)
+}
+
+// pgTestConn is a raw connection of the PostgreSQL protocol.
+type pgTestConn struct {
+	c    *C
+	conn net.Conn
+	// params are the run-time parameters reported by ParameterStatus.
+	params map[string]string
+}
+
+// pgTestResult is the responses of a query until ReadyForQuery.
+type pgTestResult struct {
+	types  []uint32
+	rows   [][]string
+	tags   []string
+	errors []string
+	// msgs are the types of all the messages.
+	msgs   string
+	status byte
+}
+
+func (tc *pgTestConn) send(tp byte, payload []byte) {
+	data := []byte{tp}
+	data = appendPGInt32(data, int32(len(payload)+4))
+	_, err := tc.conn.Write(append(data, payload...))
+	tc.c.Assert(err, IsNil)
+}
+
+func (tc *pgTestConn) recv() (byte, []byte) {
+	var header [5]byte
+	_, err := io.ReadFull(tc.conn, header[:])
+	tc.c.Assert(err, IsNil)
+	data := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+	_, err = io.ReadFull(tc.conn, data)
+	tc.c.Assert(err, IsNil)
+	return header[0], data
+}
+
+// pgErrorFields returns the SQLSTATE code and the message of ErrorResponse.
+func pgErrorFields(data []byte) (code, message string) {
+	for len(data) > 1 {
+		i := bytes.IndexByte(data, 0)
+		switch data[0] {
+		case 'C':
+			code = string(data[1:i])
+		case 'M':
+			message = string(data[1:i])
+		}
+		data = data[i+1:]
+	}
+	return
+}
+
+// readResult reads the messages until ReadyForQuery, the values of DataRow are decoded as strings.
+func (tc *pgTestConn) readResult() *pgTestResult {
+	res := &pgTestResult{}
+	for {
+		tp, data := tc.recv()
+		res.msgs += string(tp)
+		r := &pgReader{data: data}
+		switch tp {
+		case pgMsgRowDescription:
+			res.types = nil
+			for n := r.int16(); n > 0; n-- {
+				r.string()
+				r.int32()
+				r.int16()
+				res.types = append(res.types, uint32(r.int32()))
+				r.int16()
+				r.int32()
+				r.int16()
+			}
+		case pgMsgDataRow:
+			var row []string
+			for n := r.int16(); n > 0; n-- {
+				l := r.int32()
+				if l < 0 {
+					row = append(row, "NULL")
+					continue
+				}
+				row = append(row, string(r.bytes(int(l))))
+			}
+			res.rows = append(res.rows, row)
+		case pgMsgCommandComplete:
+			res.tags = append(res.tags, r.string())
+		case pgMsgErrorResponse:
+			code, _ := pgErrorFields(data)
+			res.errors = append(res.errors, code)
+		case pgMsgParameterStatus:
+			tc.params[r.string()] = r.string()
+		case pgMsgReadyForQuery:
+			res.status = data[0]
+			return res
+		}
+		tc.c.Assert(r.err, IsNil)
+	}
+}
+
+func (tc *pgTestConn) query(sql string) *pgTestResult {
+	tc.send(pgMsgQuery, appendPGString(nil, sql))
+	return tc.readResult()
+}
+
+func (tc *pgTestConn) close() {
+	tc.send(pgMsgTerminate, nil)
+	tc.conn.Close()
+}
+
+// dialPG connects to the PostgreSQL protocol server and authenticates with the password.
+// It returns the authentication request of the server, and the SQLSTATE code if it fails.
+func dialPG(c *C, user, password, database string) (tc *pgTestConn, authType int32, code string) {
+	conn, err := net.Dial("tcp", "localhost:5433")
+	c.Assert(err, IsNil)
+	// The server replies 'N' to SSLRequest since TLS is not enabled.
+	_, err = conn.Write(appendPGInt32(appendPGInt32(nil, 8), int32(pgSSLRequestCode)))
+	c.Assert(err, IsNil)
+	var reply [1]byte
+	_, err = io.ReadFull(conn, reply[:])
+	c.Assert(err, IsNil)
+	c.Assert(reply[0], Equals, byte('N'))
+	return startupPG(c, conn, user, password, database)
+}
+
+// dialPGAddr connects to the PostgreSQL protocol server at addr, the connection is encrypted if
+// tlsConfig is not nil, otherwise SSLRequest isn't sent.
+func dialPGAddr(c *C, addr string, tlsConfig *tls.Config, user, password, database string) (tc *pgTestConn, authType int32, code string) {
+	conn, err := net.Dial("tcp", addr)
+	c.Assert(err, IsNil)
+	if tlsConfig != nil {
+		_, err = conn.Write(appendPGInt32(appendPGInt32(nil, 8), int32(pgSSLRequestCode)))
+		c.Assert(err, IsNil)
+		var reply [1]byte
+		_, err = io.ReadFull(conn, reply[:])
+		c.Assert(err, IsNil)
+		c.Assert(reply[0], Equals, byte('S'))
+		tlsConn := tls.Client(conn, tlsConfig)
+		c.Assert(tlsConn.Handshake(), IsNil)
+		conn = tlsConn
+	}
+	return startupPG(c, conn, user, password, database)
+}
+
+// startupPG sends the startup message and authenticates the user with the password.
+func startupPG(c *C, conn net.Conn, user, password, database string) (tc *pgTestConn, authType int32, code string) {
+	tc = &pgTestConn{c: c, conn: conn, params: make(map[string]string)}
+	startup := appendPGInt32(nil, int32(pgProtocolVersion3))
+	startup = appendPGString(appendPGString(startup, "user"), user)
+	if len(database) > 0 {
+		startup = appendPGString(appendPGString(startup, "database"), database)
+	}
+	startup = appendPGString(appendPGString(startup, "application_name"), "pgtest")
+	startup = append(startup, 0)
+	_, err := conn.Write(append(appendPGInt32(nil, int32(len(startup)+4)), startup...))
+	c.Assert(err, IsNil)
+
+	tp, data := tc.recv()
+	if tp == pgMsgErrorResponse {
+		code, _ = pgErrorFields(data)
+		return tc, -1, code
+	}
+	c.Assert(tp, Equals, pgMsgAuthentication)
+	authType = int32(binary.BigEndian.Uint32(data))
+	switch authType {
+	case pgAuthCleartextPassword:
+		tc.send(pgMsgPassword, appendPGString(nil, password))
+	case pgAuthMD5Password:
+		digest := util.CalcMD5Digest([]byte(password), user)
+		tc.send(pgMsgPassword, appendPGString(nil, fmt.Sprintf("md5%x", md5.Sum(append(digest, data[4:8]...)))))
+	}
+	tp, data = tc.recv()
+	if tp == pgMsgErrorResponse {
+		code, _ = pgErrorFields(data)
+		return tc, authType, code
+	}
+	c.Assert(tp, Equals, pgMsgAuthentication)
+	c.Assert(binary.BigEndian.Uint32(data), Equals, uint32(pgAuthOK))
+	res := tc.readResult()
+	c.Assert(res.msgs, Equals, strings.Repeat("S", len(res.msgs)-2)+"KZ")
+	c.Assert(res.status, Equals, byte('I'))
+	return tc, authType, ""
+}
+
+func runTestPGProtocol(c *C) {
+	tc, authType, code := dialPG(c, "root", "", "test")
+	c.Assert(code, Equals, "")
+	// The MD5 digest of the empty password is always known.
+	c.Assert(authType, Equals, pgAuthMD5Password)
+	c.Assert(tc.params["server_version"], Equals, pgServerVersion)
+	c.Assert(tc.params["application_name"], Equals, "pgtest")
+	defer tc.close()
+
+	res := tc.query("")
+	c.Assert(res.msgs, Equals, "IZ")
+	res = tc.query("drop table if exists pg_t; create table pg_t (id int, name varchar(20), price decimal(10,2), data blob)")
+	c.Assert(res.errors, HasLen, 0)
+	c.Assert(res.tags, DeepEquals, []string{"DROP TABLE", "CREATE TABLE"})
+	res = tc.query(`insert into pg_t values (1, 'a\b', 1.5, null), (2, 'it''s', -2, 'x')`)
+	c.Assert(res.tags, DeepEquals, []string{"INSERT 0 2"})
+	res = tc.query(`select id, "name", price, data from pg_t order by id`)
+	c.Assert(res.msgs, Equals, "TDDCZ")
+	c.Assert(res.types, DeepEquals, []uint32{pgTypeInt4, pgTypeVarchar, pgTypeNumeric, pgTypeBytea})
+	c.Assert(res.rows, DeepEquals, [][]string{{"1", `a\b`, "1.50", "NULL"}, {"2", "it's", "-2.00", `\x78`}})
+	c.Assert(res.tags, DeepEquals, []string{"SELECT 2"})
+	res = tc.query("update pg_t set price = 3 where id > 0")
+	c.Assert(res.tags, DeepEquals, []string{"UPDATE 2"})
+
+	// The statements after the error are not executed.
+	res = tc.query("select 1; select * from pg_no_such_table; select 2")
+	c.Assert(res.msgs, Equals, "TDCEZ")
+	c.Assert(res.errors, DeepEquals, []string{"42P01"})
+
+	res = tc.query("begin; delete from pg_t where id = 3")
+	c.Assert(res.tags, DeepEquals, []string{"BEGIN", "DELETE 0"})
+	c.Assert(res.status, Equals, byte('T'))
+	res = tc.query("commit")
+	c.Assert(res.status, Equals, byte('I'))
+
+	// The run-time parameters.
+	res = tc.query("SET application_name = 'psql'; SHOW application_name; SET extra_float_digits = 3")
+	c.Assert(res.msgs, Equals, "SCTDCCZ")
+	c.Assert(tc.params["application_name"], Equals, "psql")
+	c.Assert(res.rows, DeepEquals, [][]string{{"psql"}})
+	res = tc.query("SET client_encoding = 'LATIN1'")
+	c.Assert(res.msgs, Equals, "EZ")
+
+	// The minimal pg_catalog.
+	res = tc.query("select t.typname, t.typlen from pg_catalog.pg_type t where t.oid = 23")
+	c.Assert(res.rows, DeepEquals, [][]string{{"int4", "4"}})
+	res = tc.query("select nspname from pg_namespace order by oid")
+	c.Assert(res.rows, DeepEquals, [][]string{{"pg_catalog"}, {"public"}})
+	res = tc.query("select datname from pg_catalog.pg_database where datname = current_database()")
+	c.Assert(res.rows, DeepEquals, [][]string{{"test"}})
+
+	// The extended query, the parameters are in any order.
+	var parse []byte
+	parse = appendPGString(appendPGString(parse, "s1"), "select id, name from pg_t where id >= $2 and name <> $1 order by id")
+	parse = appendPGInt32(appendPGInt16(parse, 2), 0)
+	parse = appendPGInt32(parse, int32(pgTypeInt4))
+	tc.send(pgMsgParse, parse)
+	tc.send(pgMsgDescribe, appendPGString([]byte{'S'}, "s1"))
+	var bind []byte
+	bind = appendPGString(appendPGString(bind, ""), "s1")
+	// The first parameter is text, the second one is binary.
+	bind = appendPGInt16(appendPGInt16(appendPGInt16(bind, 2), 0), 1)
+	bind = appendPGInt16(bind, 2)
+	bind = append(appendPGInt32(bind, 1), 'x')
+	bind = appendPGInt32(appendPGInt32(bind, 4), 1)
+	// The results are binary.
+	bind = appendPGInt16(appendPGInt16(bind, 1), 1)
+	tc.send(pgMsgBind, bind)
+	tc.send(pgMsgDescribe, appendPGString([]byte{'P'}, ""))
+	tc.send(pgMsgExecute, appendPGInt32(appendPGString(nil, ""), 1))
+	tc.send(pgMsgExecute, appendPGInt32(appendPGString(nil, ""), 0))
+	tc.send(pgMsgSync, nil)
+	res = tc.readResult()
+	c.Assert(res.msgs, Equals, "1tT2TDsDCZ")
+	c.Assert(res.types, DeepEquals, []uint32{pgTypeInt4, pgTypeVarchar})
+	c.Assert(res.rows, DeepEquals, [][]string{{"\x00\x00\x00\x01", `a\b`}, {"\x00\x00\x00\x02", "it's"}})
+	c.Assert(res.tags, DeepEquals, []string{"SELECT 2"})
+
+	// The messages after the error are discarded until Sync.
+	tc.send(pgMsgParse, appendPGInt16(appendPGString(appendPGString(nil, ""), "select * from pg_no_such_table"), 0))
+	tc.send(pgMsgBind, bind)
+	tc.send(pgMsgExecute, appendPGInt32(appendPGString(nil, ""), 0))
+	tc.send(pgMsgSync, nil)
+	res = tc.readResult()
+	c.Assert(res.msgs, Equals, "EZ")
+	c.Assert(res.errors, DeepEquals, []string{"42P01"})
+	// The negative counts are malformed messages.
+	tc.send(pgMsgParse, appendPGInt16(appendPGString(appendPGString(nil, ""), "select 1"), -1))
+	tc.send(pgMsgSync, nil)
+	res = tc.readResult()
+	c.Assert(res.msgs, Equals, "EZ")
+	tc.send(pgMsgBind, appendPGInt16(appendPGString(appendPGString(nil, ""), ""), -1))
+	tc.send(pgMsgSync, nil)
+	res = tc.readResult()
+	c.Assert(res.msgs, Equals, "EZ")
+
+	// DML in the extended query.
+	parse = appendPGInt16(appendPGString(appendPGString(nil, ""), "delete from pg_t where id = $1"), 0)
+	tc.send(pgMsgParse, parse)
+	bind = appendPGInt16(appendPGString(appendPGString(nil, ""), ""), 0)
+	bind = append(appendPGInt32(appendPGInt16(bind, 1), 1), '2')
+	bind = appendPGInt16(bind, 0)
+	tc.send(pgMsgBind, bind)
+	tc.send(pgMsgExecute, appendPGInt32(appendPGString(nil, ""), 0))
+	tc.send(pgMsgClose, appendPGString([]byte{'S'}, "s1"))
+	tc.send(pgMsgSync, nil)
+	res = tc.readResult()
+	c.Assert(res.msgs, Equals, "12C3Z")
+	c.Assert(res.tags, DeepEquals, []string{"DELETE 1"})
+	res = tc.query("select count(*) from pg_t; drop table pg_t")
+	c.Assert(res.rows, DeepEquals, [][]string{{"1"}})
+
+	// The cleartext password is asked before the MD5 digest of the user is known, the server of the
+	// test allows it without TLS.
+	res = tc.query("create user 'pguser'@'%' identified by 'pgpwd'")
+	c.Assert(res.errors, HasLen, 0)
+	tc1, authType, code := dialPG(c, "pguser", "wrong", "")
+	c.Assert(authType, Equals, pgAuthCleartextPassword)
+	c.Assert(code, Equals, "28P01")
+	tc1.conn.Close()
+	tc1, authType, code = dialPG(c, "pguser", "pgpwd", "")
+	c.Assert(authType, Equals, pgAuthCleartextPassword)
+	c.Assert(code, Equals, "")
+	tc1.close()
+	tc1, authType, code = dialPG(c, "pguser", "pgpwd", "")
+	c.Assert(authType, Equals, pgAuthMD5Password)
+	c.Assert(code, Equals, "")
+	res = tc1.query("select current_user()")
+	c.Assert(res.rows[0][0], Matches, "pguser@.*")
+	tc1.close()
+	tc1, authType, code = dialPG(c, "pguser", "wrong", "")
+	c.Assert(authType, Equals, pgAuthMD5Password)
+	c.Assert(code, Equals, "28P01")
+	tc1.conn.Close()
+	res = tc.query("delete from mysql.user where user = 'pguser'")
+	c.Assert(res.errors, HasLen, 0)
+
+	// The messages before the authentication succeeds are limited, the large messages are allowed after it.
+	conn, err := net.Dial("tcp", "localhost:5433")
+	c.Assert(err, IsNil)
+	tc1 = &pgTestConn{c: c, conn: conn, params: make(map[string]string)}
+	_, err = conn.Write(appendPGInt32(appendPGInt32(nil, pgMaxStartupPacketLen+1), int32(pgProtocolVersion3)))
+	c.Assert(err, IsNil)
+	tp, _ := tc1.recv()
+	c.Assert(tp, Equals, pgMsgErrorResponse)
+	conn.Close()
+	conn, err = net.Dial("tcp", "localhost:5433")
+	c.Assert(err, IsNil)
+	tc1 = &pgTestConn{c: c, conn: conn, params: make(map[string]string)}
+	startup := appendPGString(appendPGString(appendPGInt32(nil, int32(pgProtocolVersion3)), "user"), "root")
+	startup = append(startup, 0)
+	_, err = conn.Write(append(appendPGInt32(nil, int32(len(startup)+4)), startup...))
+	c.Assert(err, IsNil)
+	tp, _ = tc1.recv()
+	c.Assert(tp, Equals, pgMsgAuthentication)
+	tc1.send(pgMsgPassword, appendPGString(nil, strings.Repeat("x", pgMaxPasswordMessageLen)))
+	tp, _ = tc1.recv()
+	c.Assert(tp, Equals, pgMsgErrorResponse)
+	conn.Close()
+	res = tc.query(fmt.Sprintf("select length('%s')", strings.Repeat("x", pgMaxStartupPacketLen)))
+	c.Assert(res.rows, DeepEquals, [][]string{{fmt.Sprint(pgMaxStartupPacketLen)}})
+
+	_, _, code = dialPG(c, "root", "", "pg_no_such_db")
+	c.Assert(code, Equals, "3D000")
+}
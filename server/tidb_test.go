@@ -15,11 +15,27 @@
 package server
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
+	tmysql "github.com/pingcap/tidb/mysql"
 )
 
 type TidbTestSuite struct {
@@ -39,6 +55,10 @@ func (ts *TidbTestSuite) SetUpSuite(c *C) {
 		LogLevel:     "debug",
 		StatusAddr:   ":10090",
 		ReportStatus: true,
+		PGAddr:       ":5433",
+
+		PGAllowInsecurePassword: true,
+		EnableDebugAPI:          true,
 	}
 	server, err := NewServer(cfg, ts.tidbdrv)
 	c.Assert(err, IsNil)
@@ -99,6 +119,10 @@ func (ts *TidbTestSuite) TestStatusAPI(c *C) {
 	runTestStatusAPI(c)
 }
 
+func (ts *TidbTestSuite) TestRestAPI(c *C) {
+	runTestRestAPI(c)
+}
+
 func (ts *TidbTestSuite) TestMultiPacket(c *C) {
 	runTestMultiPacket(c)
 }
@@ -107,6 +131,150 @@ func (ts *TidbTestSuite) TestMultiStatements(c *C) {
 	runTestMultiStatements(c)
 }
 
+func (ts *TidbTestSuite) TestProcessListAndKill(c *C) {
+	runTestProcessListAndKill(c)
+}
+
+func (ts *TidbTestSuite) TestCompressedProtocol(c *C) {
+	runTestCompressedProtocol(c)
+}
+
+func (ts *TidbTestSuite) TestCursorFetch(c *C) {
+	runTestCursorFetch(c)
+}
+
+func (ts *TidbTestSuite) TestCachingSha2Auth(c *C) {
+	runTestCachingSha2Auth(c)
+}
+
+func (ts *TidbTestSuite) TestUserResourceLimits(c *C) {
+	runTestUserResourceLimits(c)
+}
+
+func (ts *TidbTestSuite) TestPGProtocol(c *C) {
+	runTestPGProtocol(c)
+}
+
+func (ts *TidbTestSuite) TestMaxConnections(c *C) {
+	cfg := &Config{
+		Addr:           ":4003",
+		LogLevel:       "debug",
+		TokenLimit:     1,
+		MaxConnections: 1,
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	c.Assert(err, IsNil)
+	go server.Run()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	db, err := sql.Open("mysql", "root@tcp(localhost:4003)/test?strict=true")
+	c.Assert(err, IsNil)
+	c.Assert(db.Ping(), IsNil)
+
+	// The error packet is sent instead of the initial handshake.
+	conn, err := net.Dial("tcp", "localhost:4003")
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	data, err := newPacketIO(conn).readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, tmysql.ErrHeader)
+	c.Assert(binary.LittleEndian.Uint16(data[1:]), Equals, uint16(tmysql.ErrConCount))
+
+	db.Close()
+	db, err = sql.Open("mysql", "root@tcp(localhost:4003)/test?strict=true")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	for i := 0; i < 50; i++ {
+		if err = db.Ping(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(err, IsNil)
+}
+
+func (ts *TidbTestSuite) TestGracefulDown(c *C) {
+	startServer := func(addr string) *Server {
+		server, err := NewServer(&Config{Addr: addr, LogLevel: "debug"}, ts.tidbdrv)
+		c.Assert(err, IsNil)
+		go server.Run()
+		time.Sleep(time.Millisecond * 100)
+		return server
+	}
+	// LOAD DATA LOCAL keeps running until the client sends the file.
+	startLoadData := func(addr string) (net.Conn, *packetIO) {
+		conn, pkg, _ := rawHandshake(c, addr, nil, tmysql.ClientLocalFiles, "root", "", nil)
+		data, err := pkg.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(data[0], Equals, tmysql.OKHeader)
+		writeRawCommand(c, pkg, tmysql.ComQuery, []byte("load data local infile '/tmp/graceful' into table graceful_load"))
+		data, err = pkg.readPacket()
+		c.Assert(err, IsNil)
+		c.Assert(data[0], Equals, tmysql.LocalInFileHeader)
+		return conn, pkg
+	}
+
+	server := startServer(":4004")
+	runTests(c, "root@tcp(localhost:4004)/test?strict=true", func(dbt *DBTest) {
+		dbt.mustExec("create table graceful_load (a int)")
+	})
+	idle, err := sql.Open("mysql", "root@tcp(localhost:4004)/test?strict=true")
+	c.Assert(err, IsNil)
+	defer idle.Close()
+	c.Assert(idle.Ping(), IsNil)
+	conn, pkg := startLoadData("localhost:4004")
+	defer conn.Close()
+
+	// The running statement finishes before the connection is closed.
+	exited := make(chan struct{})
+	go func() {
+		server.GracefulDown(5 * time.Second)
+		close(exited)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-exited:
+		c.Fatal("the server exits before the running statement finishes")
+	default:
+	}
+	c.Assert(pkg.writePacket(append(make([]byte, 4), "1\n2\n"...)), IsNil)
+	c.Assert(pkg.writePacket(make([]byte, 4)), IsNil)
+	c.Assert(pkg.flush(), IsNil)
+	data, err := pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, tmysql.OKHeader)
+	<-exited
+	c.Assert(server.ConnectionCount(), Equals, 0)
+	_, err = pkg.readPacket()
+	c.Assert(err, NotNil)
+	// The idle connection is closed and the new connections are refused.
+	var n int
+	c.Assert(idle.QueryRow("select 1").Scan(&n), NotNil)
+
+	// The statements still running after the timeout are interrupted.
+	server = startServer(":4005")
+	runTests(c, "root@tcp(localhost:4005)/test?strict=true", func(dbt *DBTest) {
+		var count int
+		err := dbt.db.QueryRow("select count(*) from graceful_load").Scan(&count)
+		dbt.Assert(err, IsNil)
+		dbt.Assert(count, Equals, 2)
+	})
+	conn, pkg = startLoadData("localhost:4005")
+	defer conn.Close()
+	start := time.Now()
+	server.GracefulDown(100 * time.Millisecond)
+	c.Assert(time.Since(start) < time.Second, IsTrue)
+	_, err = pkg.readPacket()
+	c.Assert(err, NotNil)
+
+	se, err := tidb.CreateSession(ts.tidbdrv.store)
+	c.Assert(err, IsNil)
+	defer se.Close()
+	_, err = se.Execute("drop table test.graceful_load")
+	c.Assert(err, IsNil)
+}
+
 func (ts *TidbTestSuite) TestSocket(c *C) {
 	cfg := &Config{
 		LogLevel:   "debug",
@@ -123,3 +291,136 @@ func (ts *TidbTestSuite) TestSocket(c *C) {
 	dsn = tcpDsn
 	server.Close()
 }
+
+func (ts *TidbTestSuite) TestTLS(c *C) {
+	dir, err := ioutil.TempDir("", "tidb-tls")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	caCert, caKey := generateCert(c, 1, "TiDB Test CA", nil, nil, path("ca-cert.pem"), path("ca-key.pem"))
+	generateCert(c, 2, "tidb-server", caCert, caKey, path("server-cert.pem"), path("server-key.pem"))
+	generateCert(c, 3, "tidb-client", caCert, caKey, path("client-cert.pem"), path("client-key.pem"))
+
+	cfg := &Config{
+		Addr:     ":4002",
+		PGAddr:   ":5434",
+		LogLevel: "debug",
+		SSLCert:  path("server-cert.pem"),
+		SSLKey:   path("server-key.pem"),
+		SSLCA:    path("ca-cert.pem"),
+	}
+	server, err := NewServer(cfg, ts.tidbdrv)
+	c.Assert(err, IsNil)
+	go server.Run()
+	time.Sleep(time.Millisecond * 100)
+	defer server.Close()
+
+	clientCert, err := tls.LoadX509KeyPair(path("client-cert.pem"), path("client-key.pem"))
+	c.Assert(err, IsNil)
+	mysql.RegisterTLSConfig("tidb-tls", &tls.Config{InsecureSkipVerify: true})
+	mysql.RegisterTLSConfig("tidb-x509", &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{clientCert}})
+
+	checkStatus := func(dsn string, encrypted bool) {
+		runTests(c, dsn, func(dbt *DBTest) {
+			var name, cipher, version string
+			err := dbt.db.QueryRow("show status like 'Ssl_cipher'").Scan(&name, &cipher)
+			dbt.Assert(err, IsNil)
+			err = dbt.db.QueryRow("show status like 'Ssl_version'").Scan(&name, &version)
+			dbt.Assert(err, IsNil)
+			dbt.Assert(len(cipher) > 0, Equals, encrypted)
+			dbt.Assert(len(version) > 0, Equals, encrypted)
+		})
+	}
+	checkStatus("root@tcp(localhost:4002)/test?strict=true&tls=tidb-tls", true)
+	checkStatus("root@tcp(localhost:4002)/test?strict=true", false)
+
+	runTests(c, "root@tcp(localhost:4002)/test?strict=true&tls=tidb-tls", func(dbt *DBTest) {
+		dbt.mustExec("CREATE USER 'ssl_user'@'%' IDENTIFIED BY '123' REQUIRE SSL")
+		dbt.mustExec("CREATE USER 'x509_user'@'%' IDENTIFIED BY '123'")
+		dbt.mustExec("GRANT ALL ON *.* TO 'x509_user'@'%' REQUIRE X509")
+	})
+	checkConnect := func(dsn string, ok bool) {
+		db, err := sql.Open("mysql", dsn)
+		c.Assert(err, IsNil)
+		defer db.Close()
+		err = db.Ping()
+		c.Assert(err == nil, Equals, ok, Commentf("dsn: %s, err: %v", dsn, err))
+	}
+	checkConnect("ssl_user:123@tcp(localhost:4002)/test", false)
+	checkConnect("ssl_user:123@tcp(localhost:4002)/test?tls=tidb-tls", true)
+	checkConnect("x509_user:123@tcp(localhost:4002)/test?tls=tidb-tls", false)
+	checkConnect("x509_user:123@tcp(localhost:4002)/test?tls=tidb-x509", true)
+	checkConnect("x509_user:456@tcp(localhost:4002)/test?tls=tidb-x509", false)
+
+	// The password of caching_sha2_password full authentication is sent in cleartext on TLS connections.
+	runTests(c, "root@tcp(localhost:4002)/test?strict=true", func(dbt *DBTest) {
+		dbt.mustExec("CREATE USER 'tls_sha2_user'@'%' IDENTIFIED WITH caching_sha2_password BY '123'")
+	})
+	conn, pkg, _ := rawHandshake(c, "localhost:4002", &tls.Config{InsecureSkipVerify: true}, 0,
+		"tls_sha2_user", tmysql.AuthCachingSha2Password, func(salt []byte) []byte {
+			return scrambleSha2("123", salt)
+		})
+	defer conn.Close()
+	data, err := pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, []byte{authMoreDataHeader, authPerformFullAuth})
+	c.Assert(pkg.writePacket(append(make([]byte, 4), "123\x00"...)), IsNil)
+	c.Assert(pkg.flush(), IsNil)
+	data, err = pkg.readPacket()
+	c.Assert(err, IsNil)
+	c.Assert(data[0], Equals, tmysql.OKHeader)
+
+	// The PostgreSQL protocol refuses the cleartext password without TLS, the MD5 authentication
+	// works without TLS after the user logs in with TLS.
+	runTests(c, "root@tcp(localhost:4002)/test?strict=true", func(dbt *DBTest) {
+		dbt.mustExec("CREATE USER 'tls_pg_user'@'%' IDENTIFIED BY '123'")
+	})
+	tc, authType, code := dialPGAddr(c, "localhost:5434", nil, "tls_pg_user", "123", "")
+	c.Assert(authType, Equals, int32(-1))
+	c.Assert(code, Equals, "28P01")
+	tc.conn.Close()
+	tc, authType, code = dialPGAddr(c, "localhost:5434", &tls.Config{InsecureSkipVerify: true}, "tls_pg_user", "123", "")
+	c.Assert(authType, Equals, pgAuthCleartextPassword)
+	c.Assert(code, Equals, "")
+	tc.close()
+	tc, authType, code = dialPGAddr(c, "localhost:5434", nil, "tls_pg_user", "123", "")
+	c.Assert(authType, Equals, pgAuthMD5Password)
+	c.Assert(code, Equals, "")
+	tc.close()
+}
+
+// generateCert generates a certificate and its private key signed by parent,
+// the certificate is a self-signed CA if parent is nil.
+func generateCert(c *C, sn int64, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, certFile, keyFile string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(sn),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	c.Assert(err, IsNil)
+	return cert, key
+}
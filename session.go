@@ -19,8 +19,11 @@ package tidb
 
 import (
 	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -29,9 +32,11 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/bindinfo"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/domain"
 	"github.com/pingcap/tidb/executor"
+	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/meta"
 	"github.com/pingcap/tidb/mysql"
@@ -52,13 +57,15 @@ import (
 
 // Session context
 type Session interface {
-	Status() uint16                               // Flag of current status, such as autocommit.
-	LastInsertID() uint64                         // Last inserted auto_increment id.
-	AffectedRows() uint64                         // Affected rows by latest executed stmt.
-	SetValue(key fmt.Stringer, value interface{}) // SetValue saves a value associated with this session for key.
-	Value(key fmt.Stringer) interface{}           // Value returns the value associated with this session for key.
-	Execute(sql string) ([]ast.RecordSet, error)  // Execute a sql statement.
-	String() string                               // For debug
+	Status() uint16                                  // Flag of current status, such as autocommit.
+	LastInsertID() uint64                            // Last inserted auto_increment id.
+	AffectedRows() uint64                            // Affected rows by latest executed stmt.
+	SetValue(key fmt.Stringer, value interface{})    // SetValue saves a value associated with this session for key.
+	Value(key fmt.Stringer) interface{}              // Value returns the value associated with this session for key.
+	Execute(sql string) ([]ast.RecordSet, error)     // Execute a sql statement.
+	Parse(sql string) ([]ast.StmtNode, error)        // Parse the sql into statements.
+	ExecuteStmt(ast.StmtNode) (ast.RecordSet, error) // Execute a statement returned by Parse.
+	String() string                                  // For debug
 	CommitTxn() error
 	RollbackTxn() error
 	// For execute prepare statement in binary protocol.
@@ -66,11 +73,27 @@ type Session interface {
 	// Execute a prepared statement.
 	ExecutePreparedStmt(stmtID uint32, param ...interface{}) (ast.RecordSet, error)
 	DropPreparedStmt(stmtID uint32) error
-	SetClientCapability(uint32) // Set client capability flags.
+	SetClientCapability(uint32)       // Set client capability flags.
+	SetTLSState(*tls.ConnectionState) // Set the TLS state of the client connection.
 	SetConnectionID(uint64)
+	SetSessionManager(util.SessionManager) // Set the session manager used by SHOW PROCESSLIST and KILL.
+	KillQuery()                            // Interrupt the statement running in the session.
 	Close() error
 	Retry() error
 	Auth(user string, auth []byte, salt []byte) bool
+	AuthPlugin(user string) string // Get the authentication plugin of the user.
+	// Verify the scramble of caching_sha2_password fast authentication with the cached digest.
+	AuthCachingSha2Fast(user string, scramble []byte, salt []byte) bool
+	// Verify the cleartext password of caching_sha2_password full authentication.
+	AuthCachingSha2Full(user string, password []byte) bool
+	// Verify the cleartext password with the authentication plugin of the user, it's used by the PostgreSQL protocol.
+	AuthPassword(user string, password []byte) bool
+	// Check whether the MD5 digest of the user is known, which is required by the PostgreSQL MD5 authentication.
+	HasMD5Digest(user string) bool
+	// Verify the response of the PostgreSQL MD5 authentication.
+	AuthMD5(user string, response []byte, salt []byte) bool
+	// Get the MAX_QUERIES_PER_HOUR and MAX_USER_CONNECTIONS limits of the user, 0 means no limit.
+	UserResourceLimits(user string) (maxQuestions, maxUserConnections uint64)
 }
 
 var (
@@ -127,6 +150,15 @@ type session struct {
 	// For performance_schema only.
 	stmtState *perfschema.StatementState
 	parser    *parser.Parser
+
+	// sessionVars is kept here because KillQuery is called from other goroutines,
+	// which must not read the values map.
+	sessionVars *variable.SessionVars
+
+	// parseStart and parseTime are the parsing details of the last parsed text,
+	// they are counted in the first statement of the text.
+	parseStart time.Time
+	parseTime  time.Duration
 }
 
 func (s *session) cleanRetryInfo() {
@@ -175,10 +207,24 @@ func (s *session) SetClientCapability(capability uint32) {
 	variable.GetSessionVars(s).ClientCapability = capability
 }
 
+func (s *session) SetTLSState(state *tls.ConnectionState) {
+	variable.GetSessionVars(s).TLSConnectionState = state
+}
+
 func (s *session) SetConnectionID(connectionID uint64) {
 	variable.GetSessionVars(s).ConnectionID = connectionID
 }
 
+func (s *session) SetSessionManager(sm util.SessionManager) {
+	util.BindSessionManager(s, sm)
+}
+
+// KillQuery is called from other goroutines, the running executors will
+// notice the flag and return ErrQueryInterrupted.
+func (s *session) KillQuery() {
+	atomic.StoreUint32(&s.sessionVars.Killed, 1)
+}
+
 func (s *session) finishTxn(rollback bool) error {
 	// transaction has already been committed or rolled back
 	if s.txn == nil {
@@ -191,6 +237,7 @@ func (s *session) finishTxn(rollback bool) error {
 	}()
 
 	if rollback {
+		bindinfo.TakeGlobalChanged(s)
 		s.resetHistory()
 		s.cleanRetryInfo()
 		return s.txn.Rollback()
@@ -202,6 +249,7 @@ func (s *session) finishTxn(rollback bool) error {
 			err = s.Retry()
 		}
 		if err != nil {
+			bindinfo.TakeGlobalChanged(s)
 			log.Warnf("txn:%s, %v", s.txn, err)
 			return errors.Trace(err)
 		}
@@ -217,7 +265,17 @@ func (s *session) CommitTxn() error {
 		return errors.Trace(err)
 	}
 
-	return s.finishTxn(false)
+	if err := s.finishTxn(false); err != nil {
+		return errors.Trace(err)
+	}
+	if bindinfo.TakeGlobalChanged(s) {
+		// The transaction is committed, the error only delays the change until the next reload.
+		if err := loadBindInfo(s); err != nil {
+			log.Warnf("reload bindings error %v", errors.ErrorStack(err))
+			s.RollbackTxn()
+		}
+	}
+	return nil
 }
 
 func (s *session) RollbackTxn() error {
@@ -326,7 +384,11 @@ func (s *session) ExecRestrictedSQL(ctx context.Context, sql string) (ast.Record
 	// For example only support DML on system meta table.
 	// TODO: Add more restrictions.
 	log.Debugf("Executing %s [%s]", st.OriginText(), sql)
+	vars := variable.GetSessionVars(s)
+	inRestrictedSQL := vars.InRestrictedSQL
+	vars.InRestrictedSQL = true
 	rs, err := st.Exec(ctx)
+	vars.InRestrictedSQL = inRestrictedSQL
 	return rs, errors.Trace(err)
 }
 
@@ -428,31 +490,15 @@ func (s *session) ParseSQL(sql, charset, collation string) ([]ast.StmtNode, erro
 }
 
 func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
-	if err := s.checkSchemaValidOrRollback(); err != nil {
-		return nil, errors.Trace(err)
-	}
-	charset, collation := getCtxCharsetInfo(s)
-	rawStmts, err := s.ParseSQL(sql, charset, collation)
+	stmts, err := s.Parse(sql)
 	if err != nil {
-		log.Warnf("compiling %s, error: %v", sql, err)
 		return nil, errors.Trace(err)
 	}
 
 	var rs []ast.RecordSet
-	ph := sessionctx.GetDomain(s).PerfSchema()
-	for i, rst := range rawStmts {
-		st, err1 := Compile(s, rst)
-		if err1 != nil {
-			log.Errorf("Syntax error: %s", sql)
-			log.Errorf("Error occurs at %s.", err1)
-			return nil, errors.Trace(err1)
-		}
-		id := variable.GetSessionVars(s).ConnectionID
-		s.stmtState = ph.StartStatement(sql, id, perfschema.CallerNameSessionExecute, rawStmts[i])
-		r, err := runStmt(s, st)
-		ph.EndStatement(s.stmtState)
+	for _, stmt := range stmts {
+		r, err := s.ExecuteStmt(stmt)
 		if err != nil {
-			log.Warnf("session:%v, err:%v", s, err)
 			return nil, errors.Trace(err)
 		}
 		if r != nil {
@@ -467,6 +513,57 @@ func (s *session) Execute(sql string) ([]ast.RecordSet, error) {
 	return rs, nil
 }
 
+// Parse parses the sql with the charset and collation of the session.
+func (s *session) Parse(sql string) ([]ast.StmtNode, error) {
+	charset, collation := getCtxCharsetInfo(s)
+	s.parseStart = time.Now()
+	stmts, err := s.ParseSQL(sql, charset, collation)
+	s.parseTime = time.Since(s.parseStart)
+	if err != nil {
+		log.Warnf("compiling %s, error: %v", sql, err)
+		return nil, errors.Trace(err)
+	}
+	return stmts, nil
+}
+
+// startStmt resets the kill flag and the execution details when a statement starts.
+func (s *session) startStmt() {
+	vars := variable.GetSessionVars(s)
+	atomic.StoreUint32(&vars.Killed, 0)
+	vars.StmtDetails = &variable.StmtExecDetails{StartTime: time.Now()}
+	if !s.parseStart.IsZero() {
+		vars.StmtDetails.StartTime = s.parseStart
+		vars.StmtDetails.ParseTime = s.parseTime
+		s.parseStart = time.Time{}
+	}
+}
+
+// ExecuteStmt executes a statement parsed by Parse.
+func (s *session) ExecuteStmt(stmtNode ast.StmtNode) (ast.RecordSet, error) {
+	if err := s.checkSchemaValidOrRollback(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	s.startStmt()
+	compileStart := time.Now()
+	st, err := Compile(s, stmtNode)
+	variable.GetSessionVars(s).StmtDetails.CompileTime = time.Since(compileStart)
+	if err != nil {
+		log.Errorf("Syntax error: %s", stmtNode.Text())
+		log.Errorf("Error occurs at %s.", err)
+		return nil, errors.Trace(err)
+	}
+	id := variable.GetSessionVars(s).ConnectionID
+	ph := sessionctx.GetDomain(s).PerfSchema()
+	s.stmtState = ph.StartStatement(stmtNode.Text(), id, perfschema.CallerNameSessionExecute, stmtNode)
+	r, err := runStmt(s, st)
+	ph.EndStatement(s.stmtState)
+	if err != nil {
+		log.Warnf("session:%v, err:%v", s, err)
+		return nil, errors.Trace(err)
+	}
+	return r, nil
+}
+
 // For execute prepare statement in binary protocol
 func (s *session) PrepareStmt(sql string) (stmtID uint32, paramCount int, fields []*ast.ResultField, err error) {
 	if err := s.checkSchemaValidOrRollback(); err != nil {
@@ -536,6 +633,7 @@ func (s *session) ExecutePreparedStmt(stmtID uint32, args ...interface{}) (ast.R
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	s.startStmt()
 	st := executor.CompileExecutePreparedStmt(s, stmtID, args...)
 	r, err := runStmt(s, st, args...)
 	return r, errors.Trace(err)
@@ -619,7 +717,15 @@ func (s *session) Close() error {
 	return s.RollbackTxn()
 }
 
+// escapeSQLString escapes the string to be quoted by single quotes in SQL, the user names and hosts come from the
+// clients before they are authenticated, so they are escaped before they're put into the restricted SQL.
+func escapeSQLString(str string) string {
+	str = strings.Replace(str, `\`, `\\`, -1)
+	return strings.Replace(str, "'", `\'`, -1)
+}
+
 func (s *session) getPassword(name, host string) (string, error) {
+	name, host = escapeSQLString(name), escapeSQLString(host)
 	// Get password for name and host.
 	authSQL := fmt.Sprintf("SELECT Password FROM %s.%s WHERE User='%s' and Host='%s';", mysql.SystemDB, mysql.UserTable, name, host)
 	pwd, err := s.getExecRet(s, authSQL)
@@ -634,15 +740,16 @@ func (s *session) getPassword(name, host string) (string, error) {
 	return pwd, errors.Trace(err)
 }
 
-func (s *session) Auth(user string, auth []byte, salt []byte) bool {
+// getUserPassword gets the password of the user like "name@host".
+func (s *session) getUserPassword(user string) (name, host, pwd string, ok bool) {
 	strs := strings.Split(user, "@")
 	if len(strs) != 2 {
 		log.Warnf("Invalid format for user: %s", user)
-		return false
+		return
 	}
 	// Get user password.
-	name := strs[0]
-	host := strs[1]
+	name = strs[0]
+	host = strs[1]
 	pwd, err := s.getPassword(name, host)
 	if err != nil {
 		if terror.ExecResultIsEmpty.Equal(err) {
@@ -650,6 +757,14 @@ func (s *session) Auth(user string, auth []byte, salt []byte) bool {
 		} else {
 			log.Errorf("Get User [%s] password from SystemDB error %v", name, err)
 		}
+		return
+	}
+	return name, host, pwd, true
+}
+
+func (s *session) Auth(user string, auth []byte, salt []byte) bool {
+	name, host, pwd, ok := s.getUserPassword(user)
+	if !ok {
 		return false
 	}
 	if len(pwd) != 0 && len(pwd) != 40 {
@@ -665,10 +780,234 @@ func (s *session) Auth(user string, auth []byte, salt []byte) bool {
 	if !bytes.Equal(auth, checkAuth) {
 		return false
 	}
+	return s.authSucceed(user, name, host)
+}
+
+// sha2AuthCache caches SHA256(SHA256(password)) of the users after the caching_sha2_password
+// full authentication, which is used to verify the scramble of the fast authentication.
+var sha2AuthCache = struct {
+	sync.RWMutex
+	entries map[string]sha2AuthEntry
+}{entries: make(map[string]sha2AuthEntry)}
+
+// sha2AuthEntry is the entry of sha2AuthCache, it's valid only if the password of the user is still pwd.
+type sha2AuthEntry struct {
+	pwd    string
+	digest []byte
+}
+
+func (s *session) AuthPlugin(user string) string {
+	strs := strings.Split(user, "@")
+	if len(strs) != 2 {
+		return mysql.AuthName
+	}
+	plugin, err := s.getUserColumn(strs[0], strs[1], "plugin")
+	if err != nil {
+		log.Errorf("Get User [%s] plugin from SystemDB error %v", strs[0], err)
+	}
+	if len(plugin) == 0 {
+		return mysql.AuthName
+	}
+	return plugin
+}
+
+func (s *session) UserResourceLimits(user string) (maxQuestions, maxUserConnections uint64) {
+	strs := strings.Split(user, "@")
+	if len(strs) != 2 {
+		return 0, 0
+	}
+	return s.getUserResourceLimit(strs[0], strs[1], "max_questions"), s.getUserResourceLimit(strs[0], strs[1], "max_user_connections")
+}
+
+// getUserResourceLimit gets the resource limit column of the user, it returns 0 if the column doesn't exist.
+func (s *session) getUserResourceLimit(name, host, column string) uint64 {
+	value, err := s.getUserColumn(name, host, column)
+	if err != nil {
+		log.Errorf("Get User [%s] %s from SystemDB error %v", name, column, err)
+		return 0
+	}
+	if len(value) == 0 {
+		return 0
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Errorf("Parse User [%s] %s %s error %v", name, column, value, err)
+		return 0
+	}
+	return limit
+}
+
+func (s *session) AuthCachingSha2Fast(user string, scramble []byte, salt []byte) bool {
+	name, host, pwd, ok := s.getUserPassword(user)
+	if !ok {
+		return false
+	}
+	sha2AuthCache.RLock()
+	entry, ok := sha2AuthCache.entries[user]
+	sha2AuthCache.RUnlock()
+	if !ok || entry.pwd != pwd || !util.CheckSha2Scramble(scramble, salt, entry.digest) {
+		return false
+	}
+	return s.authSucceed(user, name, host)
+}
+
+func (s *session) AuthCachingSha2Full(user string, password []byte) bool {
+	name, host, pwd, ok := s.getUserPassword(user)
+	if !ok {
+		return false
+	}
+	if !util.CheckSha2Password(pwd, password) {
+		return false
+	}
+	if !s.authSucceed(user, name, host) {
+		return false
+	}
+	sha2AuthCache.Lock()
+	sha2AuthCache.entries[user] = sha2AuthEntry{pwd: pwd, digest: util.CalcSha2Digest(password)}
+	sha2AuthCache.Unlock()
+	return true
+}
+
+// md5AuthCache caches MD5(password + name) of the users after they are verified with the cleartext
+// password, which is the digest the PostgreSQL MD5 authentication is based on.
+var md5AuthCache = struct {
+	sync.RWMutex
+	entries map[string]sha2AuthEntry
+}{entries: make(map[string]sha2AuthEntry)}
+
+func (s *session) AuthPassword(user string, password []byte) bool {
+	name, host, pwd, ok := s.getUserPassword(user)
+	if !ok {
+		return false
+	}
+	if s.AuthPlugin(user) == mysql.AuthCachingSha2Password {
+		ok = util.CheckSha2Password(pwd, password)
+	} else {
+		ok = subtle.ConstantTimeCompare([]byte(strings.ToLower(pwd)), []byte(util.EncodePassword(string(password)))) == 1
+	}
+	if !ok || !s.authSucceed(user, name, host) {
+		return false
+	}
+	md5AuthCache.Lock()
+	md5AuthCache.entries[user] = sha2AuthEntry{pwd: pwd, digest: util.CalcMD5Digest(password, name)}
+	md5AuthCache.Unlock()
+	return true
+}
+
+// getMD5Digest gets the MD5 digest of the user, the digest of the empty password is always known.
+func (s *session) getMD5Digest(user string) (name, host string, digest []byte, ok bool) {
+	name, host, pwd, ok := s.getUserPassword(user)
+	if !ok {
+		return
+	}
+	if len(pwd) == 0 {
+		return name, host, util.CalcMD5Digest(nil, name), true
+	}
+	md5AuthCache.RLock()
+	entry, ok := md5AuthCache.entries[user]
+	md5AuthCache.RUnlock()
+	if !ok || entry.pwd != pwd {
+		return name, host, nil, false
+	}
+	return name, host, entry.digest, true
+}
+
+func (s *session) HasMD5Digest(user string) bool {
+	_, _, _, ok := s.getMD5Digest(user)
+	return ok
+}
+
+func (s *session) AuthMD5(user string, response []byte, salt []byte) bool {
+	name, host, digest, ok := s.getMD5Digest(user)
+	if !ok || !util.CheckMD5Response(response, salt, digest) {
+		return false
+	}
+	return s.authSucceed(user, name, host)
+}
+
+// authSucceed checks the TLS requirement of the user whose password is verified, then sets the current user.
+func (s *session) authSucceed(user, name, host string) bool {
+	sslType, err := s.getSSLType(name, host)
+	if err != nil {
+		log.Errorf("Get User [%s] ssl_type from SystemDB error %v", name, err)
+		return false
+	}
+	if !checkSSLType(sslType, variable.GetSessionVars(s).TLSConnectionState) {
+		log.Warnf("User [%s] requires a connection with ssl_type %s", name, sslType)
+		return false
+	}
 	variable.GetSessionVars(s).SetCurrentUser(user)
 	return true
 }
 
+// getSSLType gets the ssl_type of the user, which is set by the REQUIRE clause of CREATE USER and GRANT.
+func (s *session) getSSLType(name, host string) (string, error) {
+	return s.getUserColumn(name, host, "ssl_type")
+}
+
+// getUserColumn gets the column of the user in mysql.user table.
+// The user entry of the host is used if it exists, otherwise the entry of any host(%) is used.
+// The mysql.user tables bootstrapped by the old versions may not have the column, it returns empty string then.
+func (s *session) getUserColumn(name, host, column string) (string, error) {
+	cleanTxn := s.txn == nil
+	sql := fmt.Sprintf("SELECT * FROM %s.%s WHERE User='%s' and (Host='%s' or Host='%%');",
+		mysql.SystemDB, mysql.UserTable, escapeSQLString(name), escapeSQLString(host))
+	rs, err := s.ExecRestrictedSQL(s, sql)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer func() {
+		rs.Close()
+		if cleanTxn {
+			s.txn = nil
+		}
+	}()
+	fields, err := rs.Fields()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	hostIdx, colIdx := -1, -1
+	for i, f := range fields {
+		switch f.ColumnAsName.L {
+		case "host":
+			hostIdx = i
+		case column:
+			colIdx = i
+		}
+	}
+	if colIdx < 0 {
+		return "", nil
+	}
+	var value string
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		if row == nil {
+			return value, nil
+		}
+		value, err = types.ToString(row.Data[colIdx].GetValue())
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		if row.Data[hostIdx].GetString() == host {
+			return value, nil
+		}
+	}
+}
+
+// checkSSLType checks whether the connection with TLS state meets the ssl_type of the user.
+func checkSSLType(sslType string, state *tls.ConnectionState) bool {
+	switch sslType {
+	case mysql.SSLTypeAny:
+		return state != nil
+	case mysql.SSLTypeX509:
+		return state != nil && len(state.PeerCertificates) > 0
+	}
+	return true
+}
+
 // Some vars name for debug.
 const (
 	retryEmptyHistoryList = "RetryEmptyHistoryList"
@@ -698,7 +1037,8 @@ func CreateSession(store kv.Storage) (Session, error) {
 	sessionctx.BindDomain(s, domain)
 
 	variable.BindSessionVars(s)
-	variable.GetSessionVars(s).SetStatusFlag(mysql.ServerStatusAutocommit, true)
+	s.sessionVars = variable.GetSessionVars(s)
+	s.sessionVars.SetStatusFlag(mysql.ServerStatusAutocommit, true)
 
 	// session implements variable.GlobalVarAccessor. Bind it to ctx.
 	variable.BindGlobalVarAccessor(s, s)
@@ -727,6 +1067,27 @@ func CreateSession(store kv.Storage) (Session, error) {
 
 		finishBootstrap(store)
 	}
+	if !storeUpgraded[store.UUID()] {
+		upgrade(s)
+		storeUpgraded[store.UUID()] = true
+	}
+
+	bindinfo.BindSessionHandle(s, bindinfo.NewHandle())
+	if !domain.BindHandle().Loaded() {
+		err = loadBindInfo(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		// The global bindings may be changed by other servers, they are reloaded every lease.
+		if lease := domain.DDL().GetLease(); lease > 0 {
+			go loadBindInfoInLoop(store, lease, domain.Exited())
+		}
+	}
+
+	err = loadLongQueryTime(s)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	// TODO: Add auth here
 	privChecker := &privileges.UserPrivileges{}
@@ -734,6 +1095,89 @@ func CreateSession(store kv.Storage) (Session, error) {
 	return s, nil
 }
 
+// loadLongQueryTime initializes the slow query threshold of the session from the
+// global long_query_time, so SET GLOBAL takes effect for the new connections.
+func loadLongQueryTime(s *session) error {
+	val, err := s.GetGlobalSysVar(s, "long_query_time")
+	if err != nil {
+		if variable.UnknownSystemVar.Equal(err) {
+			// Bootstrapped before the variable was added, keep the default.
+			return nil
+		}
+		return errors.Trace(err)
+	}
+	if val == "" {
+		return nil
+	}
+	return errors.Trace(s.sessionVars.SetSystemVar("long_query_time", types.NewStringDatum(val)))
+}
+
+// loadBindInfo loads the global bindings of the domain from the system table.
+func loadBindInfo(s *session) error {
+	sql := fmt.Sprintf("SELECT original_sql, bind_sql, default_db, create_time, update_time FROM %s.%s",
+		mysql.SystemDB, mysql.BindInfoTable)
+	rs, err := s.ExecRestrictedSQL(s, sql)
+	if err != nil {
+		// The store is bootstrapped before the table is added.
+		if infoschema.ErrTableNotExists.Equal(err) {
+			sessionctx.GetDomain(s).BindHandle().Reset(nil)
+			return errors.Trace(s.RollbackTxn())
+		}
+		return errors.Trace(err)
+	}
+	defer rs.Close()
+	charset, collation := getCtxCharsetInfo(s)
+	var records []*bindinfo.BindRecord
+	for {
+		row, err := rs.Next()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if row == nil {
+			break
+		}
+		bindSQL := row.Data[1].GetString()
+		stmt, err := s.parser.ParseOneStmt(bindSQL, charset, collation)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		records = append(records, &bindinfo.BindRecord{
+			OriginalSQL: row.Data[0].GetString(),
+			BindSQL:     bindSQL,
+			DefaultDB:   row.Data[2].GetString(),
+			CreateTime:  row.Data[3].GetMysqlTime(),
+			UpdateTime:  row.Data[4].GetMysqlTime(),
+			BindStmt:    stmt,
+		})
+	}
+	sessionctx.GetDomain(s).BindHandle().Reset(records)
+	return errors.Trace(s.CommitTxn())
+}
+
+// loadBindInfoInLoop reloads the global bindings of the domain every lease until exit is closed.
+func loadBindInfoInLoop(store kv.Storage, lease time.Duration, exit <-chan struct{}) {
+	se, err := CreateSession(store)
+	if err != nil {
+		log.Errorf("create session for reloading bindings err %v", errors.ErrorStack(err))
+		return
+	}
+	defer se.Close()
+	ticker := time.NewTicker(lease)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+		}
+		err = loadBindInfo(se.(*session))
+		if err != nil {
+			log.Errorf("reload bindings in loop err %v", errors.ErrorStack(err))
+			se.(*session).RollbackTxn()
+		}
+	}
+}
+
 func isBootstrapped(store kv.Storage) bool {
 	// check in memory
 	_, ok := storeBootstrapped[store.UUID()]
@@ -14,6 +14,7 @@
 package tidb
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"runtime"
 	"strings"
@@ -24,6 +25,7 @@ import (
 	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
@@ -32,6 +34,7 @@ import (
 	"github.com/pingcap/tidb/sessionctx/autocommit"
 	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -1317,7 +1320,7 @@ func (s *testSessionSuite) TestBootstrap(c *C) {
 	row, err := r.Next()
 	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
-	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
+	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "", []byte("mysql_native_password"), 0, 0)
 
 	c.Assert(se.Auth("root@anyhost", []byte(""), []byte("")), IsTrue)
 	mustExecSQL(c, se, "USE test;")
@@ -1399,7 +1402,7 @@ func (s *testSessionSuite) TestBootstrapWithError(c *C) {
 	row, err := r.Next()
 	c.Assert(err, IsNil)
 	c.Assert(row, NotNil)
-	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y")
+	match(c, row.Data, []byte("%"), []byte("root"), []byte(""), "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "Y", "", []byte("mysql_native_password"), 0, 0)
 	mustExecSQL(c, se, "USE test;")
 	// Check privilege tables.
 	mustExecSQL(c, se, "SELECT * from mysql.db;")
@@ -1422,6 +1425,47 @@ func (s *testSessionSuite) TestBootstrapWithError(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSessionSuite) TestUpgrade(c *C) {
+	defer testleak.AfterTest(c)()
+	dbName := "test_upgrade_db"
+	store := newStore(c, dbName)
+	se := newSession(c, store, dbName)
+	r := mustExecSQL(c, se, fmt.Sprintf(`SELECT VARIABLE_VALUE from mysql.tidb where VARIABLE_NAME="%s";`, tidbServerVersionVar))
+	row, err := r.Next()
+	c.Assert(err, IsNil)
+	match(c, row.Data, []byte(fmt.Sprint(currentBootstrapVersion)))
+
+	// Make the store like the one bootstrapped by version 1.
+	for _, col := range []string{"Process_priv", "Super_priv", "ssl_type", "plugin", "max_questions", "max_user_connections"} {
+		mustExecSQL(c, se, "ALTER TABLE mysql.user DROP COLUMN "+col)
+	}
+	mustExecSQL(c, se, fmt.Sprintf(`DELETE FROM mysql.tidb WHERE VARIABLE_NAME="%s";`, tidbServerVersionVar))
+	mustExecSQL(c, se, `CREATE USER 'upgrade_u1'@'%' IDENTIFIED BY '123'`)
+	mustExecFailed(c, se, `GRANT ALL ON *.* TO 'upgrade_u1'@'%'`)
+	se.Close()
+
+	delete(storeUpgraded, store.UUID())
+	se = newSession(c, store, dbName)
+	r = mustExecSQL(c, se, fmt.Sprintf(`SELECT VARIABLE_VALUE from mysql.tidb where VARIABLE_NAME="%s";`, tidbServerVersionVar))
+	row, err = r.Next()
+	c.Assert(err, IsNil)
+	match(c, row.Data, []byte(fmt.Sprint(currentBootstrapVersion)))
+	r = mustExecSQL(c, se, `SELECT Process_priv, Super_priv, ssl_type, plugin FROM mysql.user WHERE User="root"`)
+	row, err = r.Next()
+	c.Assert(err, IsNil)
+	match(c, row.Data, "Y", "Y", "", []byte("mysql_native_password"))
+	mustExecSQL(c, se, `GRANT ALL ON *.* TO 'upgrade_u1'@'%'`)
+	mustExecSQL(c, se, `CREATE USER 'upgrade_u2'@'%' IDENTIFIED BY '123' REQUIRE SSL`)
+	// The Password column isn't widened, the caching_sha2_password hashes don't fit in it.
+	mustExecSQL(c, se, "ALTER TABLE mysql.user DROP COLUMN Password")
+	mustExecSQL(c, se, "ALTER TABLE mysql.user ADD COLUMN Password CHAR(41)")
+	mustExecFailed(c, se, `CREATE USER 'upgrade_u3'@'%' IDENTIFIED WITH caching_sha2_password BY '123'`)
+	se.Close()
+
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
 func (s *testSessionSuite) TestEnum(c *C) {
 	defer testleak.AfterTest(c)()
 	store := newStore(c, s.dbName)
@@ -2142,6 +2186,54 @@ func (s *testSessionSuite) TestSessionAuth(c *C) {
 	se := newSession(c, store, s.dbName)
 	defer se.Close()
 	c.Assert(se.Auth("Any not exist username with zero password! @anyhost", []byte(""), []byte("")), IsFalse)
+	// The quotes and backslashes in the user name and host are escaped.
+	c.Assert(se.Auth("x' or User='root@anyhost", []byte(""), []byte("")), IsFalse)
+	c.Assert(se.Auth(`x\@anyhost`, []byte(""), []byte("")), IsFalse)
+	c.Assert(se.Auth("x@anyhost' or User='root", []byte(""), []byte("")), IsFalse)
+	c.Assert(se.Auth("root@anyhost", []byte(""), []byte("")), IsTrue)
+
+	err := store.Close()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSessionSuite) TestAuthCachingSha2(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	defer se.Close()
+	mustExecSQL(c, se, "CREATE USER 'sha2user'@'%' IDENTIFIED WITH caching_sha2_password BY '123'")
+	mustExecSQL(c, se, "CREATE USER 'sha2empty'@'%' IDENTIFIED WITH caching_sha2_password")
+	c.Assert(se.AuthPlugin("sha2user@localhost"), Equals, mysql.AuthCachingSha2Password)
+	c.Assert(se.AuthPlugin("root@localhost"), Equals, mysql.AuthName)
+	c.Assert(se.AuthPlugin("notexist@localhost"), Equals, mysql.AuthName)
+	c.Assert(se.AuthPlugin("x' or User='sha2user@localhost"), Equals, mysql.AuthName)
+
+	salt := []byte("01234567890123456789")
+	scramble := func(pwd string) []byte {
+		stage1 := sha256.Sum256([]byte(pwd))
+		h := sha256.New()
+		h.Write(util.CalcSha2Digest([]byte(pwd)))
+		h.Write(salt)
+		b := h.Sum(nil)
+		for i := range b {
+			b[i] ^= stage1[i]
+		}
+		return b
+	}
+	// The fast authentication fails before the full authentication.
+	c.Assert(se.AuthCachingSha2Fast("sha2user@localhost", scramble("123"), salt), IsFalse)
+	c.Assert(se.AuthCachingSha2Full("sha2user@localhost", []byte("1234")), IsFalse)
+	c.Assert(se.AuthCachingSha2Full("sha2user@localhost", []byte("123")), IsTrue)
+	c.Assert(se.AuthCachingSha2Fast("sha2user@localhost", scramble("123"), salt), IsTrue)
+	c.Assert(se.AuthCachingSha2Fast("sha2user@localhost", scramble("1234"), salt), IsFalse)
+	c.Assert(se.AuthCachingSha2Fast("sha2user@otherhost", scramble("123"), salt), IsFalse)
+	c.Assert(se.AuthCachingSha2Full("sha2empty@localhost", nil), IsTrue)
+	c.Assert(se.AuthCachingSha2Full("notexist@localhost", nil), IsFalse)
+	// The cached digest is invalid after the password is changed.
+	mustExecSQL(c, se, "SET PASSWORD FOR 'sha2user'@'%' = '123'")
+	c.Assert(se.AuthCachingSha2Fast("sha2user@localhost", scramble("123"), salt), IsFalse)
+	// The hash of caching_sha2_password doesn't work with mysql_native_password.
+	c.Assert(se.Auth("sha2user@localhost", util.CalcPassword(salt, util.Sha1Hash([]byte("123"))), salt), IsFalse)
 
 	err := store.Close()
 	c.Assert(err, IsNil)
@@ -2242,6 +2334,273 @@ func (s *testSessionSuite) TestMultiColumnIndex(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSessionSuite) TestIndexMergePlan(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecSQL(c, se, "drop table if exists t;")
+	mustExecSQL(c, se, "create table t (id int primary key, a int, b int, c int, key k_a(a), key k_b(b));")
+	mustExecSQL(c, se, "insert into t values (1, 1, 1, 1), (2, 1, 2, 2), (3, 2, 1, 3), (4, 3, 3, 4)")
+
+	checkPlan(c, se, "select id from t where a = 1 or b = 1", "IndexMergeUnion{Index(t.k_a)[[1,1]],Index(t.k_b)[[1,1]]}->Selection->Projection")
+	checkPlan(c, se, "select id from t where (a = 1 and c = 1) or b in (2, 3)", "IndexMergeUnion{Index(t.k_a)[[1,1]],Index(t.k_b)[[2,2] [3,3]]}->Selection->Projection")
+	checkPlan(c, se, "select id from t where a = 1 and b = 1", "IndexMergeIntersection{Index(t.k_a)[[1,1]],Index(t.k_b)[[1,1]]}->Selection->Projection")
+	checkPlan(c, se, "select id from t where a > 1 and b < 3", "IndexMergeIntersection{Index(t.k_b)[[-inf,3)],Index(t.k_a)[(1,+inf]]}->Selection->Projection")
+	// Every item of the DNF condition must be accessed by an index.
+	checkPlan(c, se, "select id from t where a = 1 or c = 1", "Table(t)->Selection->Projection")
+	// Index merge can't keep any order, the merged rows are sorted.
+	checkPlan(c, se, "select id from t where a = 1 or b = 1 order by a", "IndexMergeUnion{Index(t.k_a)[[1,1]],Index(t.k_b)[[1,1]]}->Selection->Projection->Sort->Trim")
+
+	mustExecMatch(c, se, "select id from t where a = 1 or b = 1 order by id", [][]interface{}{{1}, {2}, {3}})
+	mustExecMatch(c, se, "select id from t where (a = 1 and c = 1) or b in (2, 3) order by id", [][]interface{}{{1}, {2}, {4}})
+	mustExecMatch(c, se, "select id from t where a = 1 and b = 1", [][]interface{}{{1}})
+	mustExecMatch(c, se, "select id from t where a > 1 and b < 3", [][]interface{}{{3}})
+	err := se.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSessionSuite) TestCoveringIndexPlan(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecSQL(c, se, "drop table if exists t;")
+	mustExecSQL(c, se, "create table t (id int primary key, a int, b int, c int, key k_a_b(a, b), key k_c(c));")
+	mustExecSQL(c, se, "insert into t values (1, 1, 1, 1), (2, 1, 2, 2), (3, 2, 1, 3), (4, 3, 3, 4)")
+
+	// The covering index is preferred to the table scan that selects the same number of rows.
+	checkPlan(c, se, "select id, b from t where a > 1 and id > 2", "Index(t.k_a_b)[(1,+inf]]->Projection")
+	checkPlan(c, se, "select count(*) from t where a > 1 and id > 2", "Index(t.k_a_b)[(1,+inf]]->Aggr->Projection")
+	// The index doesn't cover column c, the rows are read from the table.
+	checkPlan(c, se, "select id, c from t where a > 1 and id > 2", "Table(t)->Projection")
+	checkPlan(c, se, "select id, c from t where c > 1 and id > 2", "Index(t.k_c)[(1,+inf]]->Projection")
+	// The full index scan isn't preferred, the rows are read in the handle order.
+	checkPlan(c, se, "select id, c from t", "Table(t)->Projection")
+
+	mustExecMatch(c, se, "select id, b from t where a > 1 and id > 2 order by id", [][]interface{}{{3, 1}, {4, 3}})
+	mustExecMatch(c, se, "select count(*) from t where a > 1 and id > 2", [][]interface{}{{2}})
+	mustExecMatch(c, se, "select id, c from t where c > 1 and id > 2 order by id", [][]interface{}{{3, 3}, {4, 4}})
+	err := se.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSessionSuite) TestStreamAggPlan(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecSQL(c, se, "drop table if exists t;")
+	mustExecSQL(c, se, "create table t (id int primary key, a int, b int, c int, key k_a_b(a, b));")
+
+	// The aggregation is pushed down to the coprocessor instead of streaming all the rows by the index order.
+	checkPlan(c, se, "select a, count(*) from t group by a", "Table(t)->Aggr->Projection")
+	checkPlan(c, se, "select id, count(*) from t group by id", "Table(t)->Aggr->Projection")
+	// The distinct aggregation can't be pushed down, so the index order is used.
+	checkPlan(c, se, "select a, count(distinct b) from t group by a", "Index(t.k_a_b)[[<nil>,+inf]]->StreamAgg->Projection")
+	// The sort is eliminated by the stream aggregation.
+	checkPlan(c, se, "select a, count(*) from t group by a order by a desc", "Index(t.k_a_b)[[<nil>,+inf]]->StreamAgg->Projection")
+	checkPlan(c, se, "select a, b, count(*) from t group by a, b order by a, b", "Index(t.k_a_b)[[<nil>,+inf]]->StreamAgg->Projection")
+	// The order isn't on the group by columns, the groups are sorted.
+	checkPlan(c, se, "select a, count(*) as cnt from t group by a order by cnt", "Table(t)->Aggr->Projection->Sort")
+	// The descending order of the index is used to eliminate the sort.
+	checkPlan(c, se, "select a, b from t where a > 1 order by a desc, b desc", "Index(t.k_a_b)[(1,+inf]]->Projection")
+	checkPlan(c, se, "select distinct a from t order by a desc", "Index(t.k_a_b)[[<nil>,+inf]]->Projection->Distinct")
+	// No index provides the order of the group by columns.
+	checkPlan(c, se, "select a, b, sum(id) from t group by b, a", "Table(t)->Aggr->Projection")
+	checkPlan(c, se, "select c, count(*) from t group by c", "Table(t)->Aggr->Projection")
+	err := se.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSessionSuite) TestLoadBindInfoInLoopExit(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	exit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		loadBindInfoInLoop(store, time.Millisecond, exit)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(exit)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("the loop reloading bindings doesn't exit")
+	}
+	err := se.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSessionSuite) TestSQLBinding(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	se1 := newSession(c, store, s.dbName)
+	// checkBindings checks the bindings shown by the statement except for their times.
+	checkBindings := func(se Session, sql string, expected [][]interface{}) {
+		rows, err := GetRows(mustExecSQL(c, se, sql))
+		c.Assert(err, IsNil)
+		for i := range rows {
+			rows[i] = rows[i][:3]
+		}
+		matches(c, rows, expected)
+	}
+	mustExecSQL(c, se, "drop table if exists t;")
+	mustExecSQL(c, se, "create table t (id int primary key, a int, b int, key k_a(a), key k_b(b));")
+	mustExecSQL(c, se, "insert into t values (1, 1, 1), (2, 2, 3), (3, 3, 2), (4, 4, 4)")
+	checkPlan(c, se, "select * from t where a > 1 and b > 2", "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+
+	// The binding is used by the statements that differ only in literals, letter case and white spaces.
+	mustExecSQL(c, se, "create binding for select * from t where a > 1 and b > 2 using select * from t use index (k_b) where a > 1 and b > 2")
+	checkPlan(c, se, "select * from t where a > 1 and b > 2", "Index(t.k_b)[(2,+inf]]->Projection")
+	checkPlan(c, se, "SELECT *  FROM t WHERE a > 3 AND b > 10;", "Index(t.k_b)[(10,+inf]]->Projection")
+	// Other statements don't use the binding.
+	checkPlan(c, se, "select * from t where a > 1 and b > 2 and id > 1", "Table(t)->Projection")
+	mustExecMatch(c, se, "select id from t where a > 1 and b > 2 order by id", [][]interface{}{{2}, {4}})
+	// The session binding isn't seen by other sessions.
+	checkPlan(c, se1, "select * from t where a > 1 and b > 2", "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+	checkBindings(se, "show bindings", [][]interface{}{
+		{"select * from t where a > ? and b > ?", "select * from t use index (k_b) where a > 1 and b > 2", s.dbName}})
+	checkBindings(se1, "show bindings", nil)
+
+	// The global binding is seen by all the sessions, but the session binding takes precedence.
+	mustExecSQL(c, se1, `create global binding for select * from t where a > 1 and b > 2 using select * from t ignore index (k_a, k_b) where a > 1 and b > 2`)
+	checkPlan(c, se1, "select * from t where a > 1 and b > 2", "Table(t)->Projection")
+	checkPlan(c, se, "select * from t where a > 1 and b > 2", "Index(t.k_b)[(2,+inf]]->Projection")
+	checkBindings(se, "show global bindings", [][]interface{}{
+		{"select * from t where a > ? and b > ?", "select * from t ignore index (k_a, k_b) where a > 1 and b > 2", s.dbName}})
+	mustExecSQL(c, se, "drop binding for select * from t where a > 5 and b > 6")
+	checkPlan(c, se, "select * from t where a > 1 and b > 2", "Table(t)->Projection")
+	checkBindings(se, "show bindings", nil)
+
+	// The bindings are bound to the current database.
+	mustExecSQL(c, se, "use mysql")
+	checkPlan(c, se, "select * from "+s.dbName+".t where a > 1 and b > 2", "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+	mustExecSQL(c, se, "use "+s.dbName)
+
+	// The global bindings are loaded from the system table.
+	se2 := newSession(c, store, s.dbName)
+	sessionctx.GetDomain(se2.(context.Context)).BindHandle().Reset(nil)
+	c.Assert(loadBindInfo(se2.(*session)), IsNil)
+	checkPlan(c, se2, "select * from t where a > 1 and b > 2", "Table(t)->Projection")
+	checkBindings(se2, "show global bindings", [][]interface{}{
+		{"select * from t where a > ? and b > ?", "select * from t ignore index (k_a, k_b) where a > 1 and b > 2", s.dbName}})
+
+	mustExecSQL(c, se, "drop global binding for select * from t where a > 1 and b > 2")
+	checkPlan(c, se1, "select * from t where a > 1 and b > 2", "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+	checkBindings(se, "show global bindings", nil)
+	mustExecMatch(c, se, "select count(*) from mysql.bind_info", [][]interface{}{{0}})
+
+	// The global binding is changed only if the transaction is committed.
+	mustExecSQL(c, se, "begin")
+	mustExecSQL(c, se, "create global binding for select * from t where a > 1 and b > 2 using select * from t use index (k_a) where a > 1 and b > 2")
+	mustExecSQL(c, se, "rollback")
+	checkBindings(se1, "show global bindings", nil)
+	checkPlan(c, se1, "select * from t where a > 1 and b > 2", "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+	mustExecSQL(c, se, "begin")
+	mustExecSQL(c, se, "create global binding for select * from t where a > 1 and b > 2 using select * from t use index (k_a) where a > 1 and b > 2")
+	checkBindings(se1, "show global bindings", nil)
+	mustExecSQL(c, se, "commit")
+	checkPlan(c, se1, "select * from t where a > 1 and b > 2", "Index(t.k_a)[(1,+inf]]->Projection")
+	// The bindings changed by other servers are seen after they are reloaded.
+	mustExecSQL(c, se, "delete from mysql.bind_info")
+	checkPlan(c, se1, "select * from t where a > 1 and b > 2", "Index(t.k_a)[(1,+inf]]->Projection")
+	c.Assert(loadBindInfo(se2.(*session)), IsNil)
+	checkBindings(se1, "show global bindings", nil)
+
+	// The prepared statements use the binding created after they are cached, and don't use the dropped binding.
+	for _, enablePlanCache := range []string{"1", "0"} {
+		mustExecSQL(c, se, "set tidb_enable_plan_cache = "+enablePlanCache)
+		stmtID, _, _, err := se.PrepareStmt("select * from t where a > 1 and b > 2")
+		c.Assert(err, IsNil)
+		checkPreparedPlan(c, se, stmtID, "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+		mustExecSQL(c, se, "create binding for select * from t where a > 1 and b > 2 using select * from t use index (k_b) where a > 1 and b > 2")
+		checkPreparedPlan(c, se, stmtID, "Index(t.k_b)[(2,+inf]]->Projection")
+		mustExecSQL(c, se, "drop binding for select * from t where a > 1 and b > 2")
+		checkPreparedPlan(c, se, stmtID, "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+		mustExecSQL(c, se1, "create global binding for select * from t where a > 1 and b > 2 using select * from t ignore index (k_a, k_b) where a > 1 and b > 2")
+		checkPreparedPlan(c, se, stmtID, "Table(t)->Projection")
+		mustExecSQL(c, se1, "drop global binding for select * from t where a > 1 and b > 2")
+		checkPreparedPlan(c, se, stmtID, "IndexMergeIntersection{Index(t.k_a)[(1,+inf]],Index(t.k_b)[(2,+inf]]}->Selection->Projection")
+		c.Assert(se.DropPreparedStmt(stmtID), IsNil)
+	}
+
+	// The statement with hints must be the same statement.
+	mustExecFailed(c, se, "create binding for select * from t where a > 1 using select * from t use index (k_b) where b > 1")
+	mustExecFailed(c, se, "create binding for select * from t where a > 1 using select a from t use index (k_b) where a > 1")
+
+	err := se.Close()
+	c.Assert(err, IsNil)
+	err = se1.Close()
+	c.Assert(err, IsNil)
+	err = se2.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSessionSuite) TestPointGetPlan(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecSQL(c, se, "drop table if exists t;")
+	mustExecSQL(c, se, "create table t (id int primary key, c1 int, c2 varchar(64), c3 int, unique key uk_c1(c1), unique key uk_c2_c3(c2, c3), key k_c3(c3));")
+	mustExecSQL(c, se, "insert into t values (1, 10, 'a', 1), (2, 20, 'b', 2)")
+
+	checkPlan(c, se, "select * from t where id = 1", "PointGet(t)[1]")
+	checkPlan(c, se, "select c1 from t where id in (3, 1, 3)", "PointGet(t)[1 3]")
+	checkPlan(c, se, "select id from t where c1 = 20", "PointGet(t.uk_c1)[[20]]")
+	checkPlan(c, se, "select id from t where c3 = 2 and c2 = 'b'", "PointGet(t.uk_c2_c3)[[b 2]]")
+	checkPlan(c, se, "update t set c3 = 3 where id = 2", "PointGet(t)[2]->*plan.Update")
+	checkPlan(c, se, "delete from t where c1 in (10, 20)", "PointGet(t.uk_c1)[[10] [20]]->*plan.Delete")
+	// The statements of other shapes use the normal plans.
+	checkPlan(c, se, "select id from t where c1 = '20'", "Index(t.uk_c1)[[20,20]]->Projection")
+	checkPlan(c, se, "select id from t where c2 = 'b'", "Index(t.uk_c2_c3)[[b,b]]->Projection")
+	checkPlan(c, se, "select id from t where c3 = 2", "Index(t.k_c3)[[2,2]]->Projection")
+	checkPlan(c, se, "select id from t where id = 1 for update", "Table(t)->Lock->Projection")
+	mustExecMatch(c, se, "select c3 from t where c2 = 'b' and c3 = 2", [][]interface{}{{2}})
+
+	err := se.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
+func (s *testSessionSuite) TestDecorrelatePlan(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	mustExecSQL(c, se, "drop table if exists t, s;")
+	mustExecSQL(c, se, "create table t (a int not null, b int not null);")
+	mustExecSQL(c, se, "create table s (a int not null, b int);")
+
+	checkPlan(c, se, "select * from t where exists(select * from s where s.a = t.a)", "SemiJoin{Table(t)->Table(s)}->Projection")
+	checkPlan(c, se, "select * from t where not exists(select * from s where s.a = t.a and s.b > 1)", "SemiJoin{Table(t)->Table(s)}->Projection")
+	checkPlan(c, se, "select a in (select a from s where s.b = t.b) from t", "SemiJoinWithAux{Table(t)->Table(s)->Projection}->Projection")
+	checkPlan(c, se, "select * from t where a not in (select a from s where s.a = t.b)", "SemiJoin{Table(t)->Table(s)->Projection}->Projection")
+	checkPlan(c, se, "select (select max(b) from s where s.a = t.a) from t", "LeftHashJoin{Table(t)->Table(s)->Aggr->Projection}(test_session_db.t.a,projection_6_col_1)->Projection")
+	// The subqueries that can't be decorrelated are evaluated by apply.
+	checkPlan(c, se, "select * from t where b not in (select b from s where s.a = t.a)", "Table(t)->Apply(Table(s)->Selection->Projection)->Selection->Projection")
+	checkPlan(c, se, "select (select count(*) from s where s.a = t.a) from t", "Table(t)->Apply(Table(s)->Selection->Aggr->Limit->Projection->MaxOneRow)->Projection")
+	checkPlan(c, se, "select (select max(b) from s where s.a < t.a) from t", "Table(t)->Apply(Table(s)->Selection->Aggr->Limit->Projection->MaxOneRow)->Projection")
+	mustExecSQL(c, se, "drop table if exists v;")
+	mustExecSQL(c, se, "create table v (a varchar(10), b int);")
+	checkPlan(c, se, "select (select max(b) from v where v.a = t.a) from t", "Table(t)->Apply(Table(v)->Selection->Aggr->Limit->Projection->MaxOneRow)->Projection")
+
+	err := se.Close()
+	c.Assert(err, IsNil)
+	err = store.Close()
+	c.Assert(err, IsNil)
+}
+
 func (s *testSessionSuite) TestSubstringIndexExpr(c *C) {
 	defer testleak.AfterTest(c)()
 	store := newStore(c, s.dbName)
@@ -2415,6 +2774,25 @@ func (s *testSessionSuite) TestGlobalVarAccessor(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *testSessionSuite) TestGlobalLongQueryTime(c *C) {
+	defer testleak.AfterTest(c)()
+	store := newStore(c, s.dbName)
+	se := newSession(c, store, s.dbName)
+	c.Assert(variable.GetSessionVars(se.(context.Context)).LongQueryTime, Equals, variable.DefLongQueryTime)
+
+	mustExecSQL(c, se, "set global long_query_time = 0.5")
+	c.Assert(variable.GetSessionVars(se.(context.Context)).LongQueryTime, Equals, variable.DefLongQueryTime)
+	se1 := newSession(c, store, s.dbName)
+	c.Assert(variable.GetSessionVars(se1.(context.Context)).LongQueryTime, Equals, 500*time.Millisecond)
+
+	mustExecSQL(c, se1, "set long_query_time = 1")
+	c.Assert(variable.GetSessionVars(se1.(context.Context)).LongQueryTime, Equals, time.Second)
+	mustExecSQL(c, se, "set global long_query_time = 10")
+
+	err := store.Close()
+	c.Assert(err, IsNil)
+}
+
 func checkPlan(c *C, se Session, sql, explain string) {
 	ctx := se.(context.Context)
 	stmts, err := Parse(ctx, sql)
@@ -2428,6 +2806,14 @@ func checkPlan(c *C, se Session, sql, explain string) {
 	c.Assert(plan.ToString(p), Equals, explain)
 }
 
+// checkPreparedPlan checks the plan of the prepared statement without parameters when it is executed.
+func checkPreparedPlan(c *C, se Session, stmtID uint32, explain string) {
+	ctx := se.(context.Context)
+	e := &executor.ExecuteExec{IS: sessionctx.GetDomain(ctx).InfoSchema(), Ctx: ctx, ID: stmtID}
+	c.Assert(e.Build(), IsNil)
+	c.Assert(plan.ToString(e.Plan), Equals, explain)
+}
+
 func mustExecMultiSQL(c *C, se Session, sql string) {
 	ss := strings.Split(sql, "\n")
 	for _, s := range ss {
@@ -14,13 +14,17 @@
 package variable
 
 import (
+	"crypto/tls"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/kvcache"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -84,6 +88,8 @@ type SessionVars struct {
 	PreparedStmtNameToID map[string]uint32
 	// prepared statement auto increment id
 	preparedStmtID uint32
+	// PreparedPlanCache caches the plans of the prepared statements.
+	PreparedPlanCache *kvcache.SimpleLRUCache
 
 	// retry information
 	RetryInfo *RetryInfo
@@ -113,6 +119,74 @@ type SessionVars struct {
 
 	// SnapshotTS is used for reading history data. For simplicity, SnapshotTS only supports distsql request.
 	SnapshotTS uint64
+
+	// MemQuotaQuery is the memory quota in bytes of a single query, executors
+	// spill their intermediate data to temporary files when it is exceeded.
+	MemQuotaQuery int64
+
+	// HashAggConcurrency is the number of partial aggregation workers of hash aggregation,
+	// 1 means the aggregation is executed serially.
+	HashAggConcurrency int
+
+	// ProjectionConcurrency is the number of workers evaluating projection expressions,
+	// 1 means the projection is executed serially.
+	ProjectionConcurrency int
+
+	// JoinReorderThreshold is the max number of tables in a join group that are reordered
+	// by dynamic programming, the larger join groups are reordered greedily.
+	JoinReorderThreshold int
+
+	// UnionConcurrency is the number of workers executing the children of union,
+	// 1 means the children are executed one after another.
+	UnionConcurrency int
+
+	// UnionKeepOrder indicates whether a parallel union returns the rows of its children
+	// in the order of the children.
+	UnionKeepOrder bool
+
+	// EnablePlanCache indicates whether the plans of the prepared statements are cached.
+	EnablePlanCache bool
+
+	// TLSConnectionState is the TLS state of the client connection, it is nil if the connection is not encrypted.
+	TLSConnectionState *tls.ConnectionState
+
+	// Killed is set to 1 by KILL QUERY from another connection, it should be accessed atomically.
+	// Executors check it and stop the running statement, it is reset when the next statement starts.
+	Killed uint32
+
+	// LongQueryTime is the threshold of the slow query log.
+	LongQueryTime time.Duration
+
+	// StmtDetails are the execution details of the running statement, a new one is set when the next statement
+	// starts, so the executors and the slow query log of a statement keep the details of their own.
+	StmtDetails *StmtExecDetails
+
+	// InRestrictedSQL indicates that the session is executing a restricted SQL for internal use,
+	// it is not recorded in the slow query log.
+	InRestrictedSQL bool
+}
+
+// StmtExecDetails are the execution details of a statement recorded in the slow query log.
+type StmtExecDetails struct {
+	// CopWaitTime is the nanoseconds waiting for the coprocessor responses, it should be accessed atomically.
+	CopWaitTime int64
+	// RowsExamined is the number of the rows read from the storage or returned by the coprocessor,
+	// it should be accessed atomically.
+	RowsExamined int64
+	// StartTime is the time when the statement starts, parsing included.
+	StartTime   time.Time
+	ParseTime   time.Duration
+	CompileTime time.Duration
+}
+
+// AddCopWaitTime adds the time waiting for the coprocessor responses.
+func (d *StmtExecDetails) AddCopWaitTime(t time.Duration) {
+	atomic.AddInt64(&d.CopWaitTime, int64(t))
+}
+
+// AddRowsExamined adds the number of the rows read from the storage.
+func (d *StmtExecDetails) AddRowsExamined(rows int64) {
+	atomic.AddInt64(&d.RowsExamined, rows)
 }
 
 // sessionVarsKeyType is a dummy type to avoid naming collision in context.
@@ -128,12 +202,22 @@ const sessionVarsKey sessionVarsKeyType = 0
 // BindSessionVars creates a session vars object and binds it to context.
 func BindSessionVars(ctx context.Context) {
 	v := &SessionVars{
-		Users:                make(map[string]string),
-		systems:              make(map[string]string),
-		PreparedStmts:        make(map[uint32]interface{}),
-		PreparedStmtNameToID: make(map[string]uint32),
-		RetryInfo:            &RetryInfo{},
-		StrictSQLMode:        true,
+		Users:                 make(map[string]string),
+		systems:               make(map[string]string),
+		PreparedStmts:         make(map[uint32]interface{}),
+		PreparedStmtNameToID:  make(map[string]uint32),
+		RetryInfo:             &RetryInfo{},
+		StrictSQLMode:         true,
+		MemQuotaQuery:         DefMemQuotaQuery,
+		HashAggConcurrency:    DefHashAggConcurrency,
+		ProjectionConcurrency: DefProjectionConcurrency,
+		UnionConcurrency:      DefUnionConcurrency,
+		JoinReorderThreshold:  DefJoinReorderThreshold,
+		UnionKeepOrder:        DefUnionKeepOrder,
+		PreparedPlanCache:     kvcache.NewSimpleLRUCache(DefPlanCacheCapacity),
+		EnablePlanCache:       DefEnablePlanCache,
+		LongQueryTime:         DefLongQueryTime,
+		StmtDetails:           &StmtExecDetails{},
 	}
 	ctx.SetValue(sessionVarsKey, v)
 }
@@ -219,9 +303,43 @@ func (s *SessionVars) SetCurrentUser(user string) {
 
 // special session variables.
 const (
-	tidbSnapshot        = "tidb_snapshot"
-	sqlMode             = "sql_mode"
-	characterSetResults = "character_set_results"
+	tidbSnapshot              = "tidb_snapshot"
+	tidbMemQuotaQuery         = "tidb_mem_quota_query"
+	tidbHashAggConcurrency    = "tidb_hashagg_concurrency"
+	tidbProjectionConcurrency = "tidb_projection_concurrency"
+	tidbUnionConcurrency      = "tidb_union_concurrency"
+	tidbUnionKeepOrder        = "tidb_union_keep_order"
+	tidbJoinReorderThreshold  = "tidb_join_reorder_threshold"
+	tidbEnablePlanCache       = "tidb_enable_plan_cache"
+	longQueryTime             = "long_query_time"
+	sqlMode                   = "sql_mode"
+	characterSetResults       = "character_set_results"
+)
+
+const (
+	// DefMemQuotaQuery is the default memory quota of a single query, 32GB.
+	DefMemQuotaQuery = 32 << 30
+	// DefHashAggConcurrency is the default concurrency of hash aggregation.
+	DefHashAggConcurrency = 1
+	// DefProjectionConcurrency is the default concurrency of projection.
+	DefProjectionConcurrency = 1
+	// DefUnionConcurrency is the default concurrency of union.
+	DefUnionConcurrency = 1
+	// MaxConcurrency is the max value of the concurrency variables, a session can't start unlimited goroutines.
+	MaxConcurrency = 256
+	// DefUnionKeepOrder is the default value of tidb_union_keep_order.
+	DefUnionKeepOrder = false
+	// DefJoinReorderThreshold is the default value of tidb_join_reorder_threshold.
+	DefJoinReorderThreshold = 8
+	// MaxJoinReorderThreshold is the max value of tidb_join_reorder_threshold, the cost of
+	// dynamic programming grows exponentially with the number of tables.
+	MaxJoinReorderThreshold = 16
+	// DefEnablePlanCache is the default value of tidb_enable_plan_cache.
+	DefEnablePlanCache = true
+	// DefPlanCacheCapacity is the max number of cached plans of a session.
+	DefPlanCacheCapacity = 100
+	// DefLongQueryTime is the default threshold of the slow query log.
+	DefLongQueryTime = 10 * time.Second
 )
 
 // SetSystemVar sets a system variable.
@@ -250,11 +368,88 @@ func (s *SessionVars) SetSystemVar(key string, value types.Datum) error {
 		if err != nil {
 			return errors.Trace(err)
 		}
+	} else if key == tidbMemQuotaQuery {
+		s.MemQuotaQuery, err = strconv.ParseInt(sVal, 10, 64)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	} else if key == tidbHashAggConcurrency {
+		n, err := parseConcurrency(key, sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.HashAggConcurrency = n
+	} else if key == tidbProjectionConcurrency {
+		n, err := parseConcurrency(key, sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.ProjectionConcurrency = n
+	} else if key == tidbUnionConcurrency {
+		n, err := parseConcurrency(key, sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.UnionConcurrency = n
+	} else if key == tidbUnionKeepOrder {
+		s.UnionKeepOrder = tidbOptOn(sVal)
+	} else if key == tidbJoinReorderThreshold {
+		n, err := parseJoinReorderThreshold(sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.JoinReorderThreshold = n
+	} else if key == tidbEnablePlanCache {
+		s.EnablePlanCache = tidbOptOn(sVal)
+	} else if key == longQueryTime {
+		s.LongQueryTime, err = parseLongQueryTime(sVal)
+		if err != nil {
+			return errors.Trace(err)
+		}
 	}
 	s.systems[key] = sVal
 	return nil
 }
 
+func parseJoinReorderThreshold(sVal string) (int, error) {
+	n, err := strconv.Atoi(sVal)
+	if err != nil {
+		return 0, ErrWrongTypeForVar.Gen("Incorrect argument type to variable '%s'", tidbJoinReorderThreshold)
+	}
+	if n < 0 || n > MaxJoinReorderThreshold {
+		return 0, ErrWrongValueForVar.Gen("Variable '%s' can't be set to the value of '%s'", tidbJoinReorderThreshold, sVal)
+	}
+	return n, nil
+}
+
+func parseConcurrency(key, sVal string) (int, error) {
+	n, err := strconv.Atoi(sVal)
+	if err != nil {
+		return 0, ErrWrongTypeForVar.Gen("Incorrect argument type to variable '%s'", key)
+	}
+	if n < 1 || n > MaxConcurrency {
+		return 0, ErrWrongValueForVar.Gen("Variable '%s' can't be set to the value of '%s'", key, sVal)
+	}
+	return n, nil
+}
+
+// parseLongQueryTime parses the seconds of long_query_time.
+func parseLongQueryTime(sVal string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(sVal, 64)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if seconds < 0 {
+		return 0, errors.Errorf("%s must not be negative, got %s", longQueryTime, sVal)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// tidbOptOn checks whether a boolean tidb option is on.
+func tidbOptOn(sVal string) bool {
+	return strings.EqualFold(sVal, "ON") || sVal == "1"
+}
+
 // epochShiftBits is used to reserve logical part of the timestamp.
 const epochShiftBits = 18
 
@@ -14,8 +14,11 @@
 package variable_test
 
 import (
+	"time"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -68,4 +71,52 @@ func (*testSessionSuite) TestSession(c *C) {
 	c.Assert(collation, Equals, "utf8_general_ci")
 
 	c.Assert(v.SetSystemVar("character_set_results", types.Datum{}), IsNil)
+
+	c.Assert(v.MemQuotaQuery, Equals, int64(variable.DefMemQuotaQuery))
+	c.Assert(v.SetSystemVar("tidb_mem_quota_query", types.NewStringDatum("1024")), IsNil)
+	c.Assert(v.MemQuotaQuery, Equals, int64(1024))
+	c.Assert(v.SetSystemVar("tidb_mem_quota_query", types.NewStringDatum("abc")), NotNil)
+
+	c.Assert(v.HashAggConcurrency, Equals, variable.DefHashAggConcurrency)
+	c.Assert(v.SetSystemVar("tidb_hashagg_concurrency", types.NewStringDatum("4")), IsNil)
+	c.Assert(v.HashAggConcurrency, Equals, 4)
+	err := v.SetSystemVar("tidb_hashagg_concurrency", types.NewStringDatum("0"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue)
+	err = v.SetSystemVar("tidb_hashagg_concurrency", types.NewStringDatum("1000000000"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue)
+	c.Assert(v.HashAggConcurrency, Equals, 4)
+	c.Assert(v.ProjectionConcurrency, Equals, variable.DefProjectionConcurrency)
+	c.Assert(v.SetSystemVar("tidb_projection_concurrency", types.NewStringDatum("3")), IsNil)
+	c.Assert(v.ProjectionConcurrency, Equals, 3)
+	err = v.SetSystemVar("tidb_projection_concurrency", types.NewStringDatum("x"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongTypeForVar), IsTrue)
+	err = v.SetSystemVar("tidb_projection_concurrency", types.NewStringDatum("257"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue)
+
+	c.Assert(v.SetSystemVar("tidb_union_concurrency", types.NewStringDatum("2")), IsNil)
+	c.Assert(v.SetSystemVar("tidb_union_concurrency", types.NewStringDatum("-2")), NotNil)
+	c.Assert(v.UnionConcurrency, Equals, 2)
+
+	c.Assert(v.JoinReorderThreshold, Equals, variable.DefJoinReorderThreshold)
+	c.Assert(v.SetSystemVar("tidb_join_reorder_threshold", types.NewStringDatum("2")), IsNil)
+	c.Assert(v.JoinReorderThreshold, Equals, 2)
+	err = v.SetSystemVar("tidb_join_reorder_threshold", types.NewStringDatum("-1"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue)
+	err = v.SetSystemVar("tidb_join_reorder_threshold", types.NewStringDatum("17"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongValueForVar), IsTrue)
+	err = v.SetSystemVar("tidb_join_reorder_threshold", types.NewStringDatum("x"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrWrongTypeForVar), IsTrue)
+	c.Assert(v.JoinReorderThreshold, Equals, 2)
+
+	c.Assert(v.EnablePlanCache, IsTrue)
+	c.Assert(v.SetSystemVar("tidb_enable_plan_cache", types.NewStringDatum("0")), IsNil)
+	c.Assert(v.EnablePlanCache, IsFalse)
+	c.Assert(v.SetSystemVar("tidb_enable_plan_cache", types.NewStringDatum("on")), IsNil)
+	c.Assert(v.EnablePlanCache, IsTrue)
+
+	c.Assert(v.LongQueryTime, Equals, variable.DefLongQueryTime)
+	c.Assert(v.SetSystemVar("long_query_time", types.NewStringDatum("0.5")), IsNil)
+	c.Assert(v.LongQueryTime, Equals, 500*time.Millisecond)
+	c.Assert(v.SetSystemVar("long_query_time", types.NewStringDatum("-1")), NotNil)
+	c.Assert(v.SetSystemVar("long_query_time", types.NewStringDatum("x")), NotNil)
 }
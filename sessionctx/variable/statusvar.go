@@ -14,6 +14,8 @@
 package variable
 
 import (
+	"crypto/tls"
+
 	"github.com/juju/errors"
 )
 
@@ -42,8 +44,8 @@ func RegisterStatistics(s Statistics) {
 	statisticsList = append(statisticsList, s)
 }
 
-// GetStatusVars gets registered statistics status variables.
-func GetStatusVars() (map[string]*StatusVal, error) {
+// GetStatusVars gets registered statistics status variables and the status variables of the session.
+func GetStatusVars(vars *SessionVars) (map[string]*StatusVal, error) {
 	statusVars := make(map[string]*StatusVal)
 
 	for _, statistics := range statisticsList {
@@ -58,5 +60,32 @@ func GetStatusVars() (map[string]*StatusVal, error) {
 		}
 	}
 
+	if vars != nil {
+		for name, val := range vars.statusVars() {
+			statusVars[name] = &StatusVal{Value: val, Scope: ScopeSession}
+		}
+	}
+
 	return statusVars, nil
 }
+
+// tlsVersionNames maps the TLS versions to the names used by MySQL.
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLSv1",
+	tls.VersionTLS11: "TLSv1.1",
+	tls.VersionTLS12: "TLSv1.2",
+	tls.VersionTLS13: "TLSv1.3",
+}
+
+// statusVars returns the status variables of the session.
+func (s *SessionVars) statusVars() map[string]interface{} {
+	var cipher, version string
+	if state := s.TLSConnectionState; state != nil {
+		cipher = tls.CipherSuiteName(state.CipherSuite)
+		version = tlsVersionNames[state.Version]
+	}
+	return map[string]interface{}{
+		"Ssl_cipher":  cipher,
+		"Ssl_version": version,
+	}
+}
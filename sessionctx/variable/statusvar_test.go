@@ -14,6 +14,8 @@
 package variable
 
 import (
+	"crypto/tls"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -65,8 +67,21 @@ func (s *testStatusVarSuite) TestStatusVar(c *C) {
 	scope = s.ms.GetScope(testSessionStatus)
 	c.Assert(scope, Equals, ScopeSession)
 
-	vars, err := GetStatusVars()
+	vars, err := GetStatusVars(nil)
 	c.Assert(err, IsNil)
 	v := &StatusVal{Scope: DefaultScopeFlag, Value: testStatusVal}
 	c.Assert(v, DeepEquals, vars[testStatus])
+
+	sessionVars := &SessionVars{}
+	vars, err = GetStatusVars(sessionVars)
+	c.Assert(err, IsNil)
+	c.Assert(vars["Ssl_cipher"], DeepEquals, &StatusVal{Scope: ScopeSession, Value: ""})
+	sessionVars.TLSConnectionState = &tls.ConnectionState{
+		Version:     tls.VersionTLS12,
+		CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	}
+	vars, err = GetStatusVars(sessionVars)
+	c.Assert(err, IsNil)
+	c.Assert(vars["Ssl_cipher"].Value, Equals, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	c.Assert(vars["Ssl_version"].Value, Equals, "TLSv1.2")
 }
@@ -14,6 +14,7 @@
 package variable
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/pingcap/tidb/context"
@@ -58,12 +59,21 @@ func GetSysVar(name string) *SysVar {
 const (
 	CodeUnknownStatusVar terror.ErrCode = 1
 	CodeUnknownSystemVar terror.ErrCode = 1193
+	CodeWrongValueForVar terror.ErrCode = 1231
+	CodeWrongTypeForVar  terror.ErrCode = 1232
+	CodeQueryInterrupted terror.ErrCode = 1317
 )
 
 // Variable errors
 var (
 	UnknownStatusVar = terror.ClassVariable.New(CodeUnknownStatusVar, "unknown status variable")
 	UnknownSystemVar = terror.ClassVariable.New(CodeUnknownSystemVar, "unknown system variable")
+	// ErrQueryInterrupted is returned when the running statement is killed by KILL QUERY.
+	ErrQueryInterrupted = terror.ClassVariable.New(CodeQueryInterrupted, "Query execution was interrupted")
+	// ErrWrongValueForVar is returned when a system variable is set to a value out of its range.
+	ErrWrongValueForVar = terror.ClassVariable.New(CodeWrongValueForVar, "wrong value for variable")
+	// ErrWrongTypeForVar is returned when a system variable is set to a value of the wrong type.
+	ErrWrongTypeForVar = terror.ClassVariable.New(CodeWrongTypeForVar, "wrong type for variable")
 )
 
 func init() {
@@ -75,6 +85,9 @@ func init() {
 	// Register terror to mysql error map.
 	mySQLErrCodes := map[terror.ErrCode]uint16{
 		CodeUnknownSystemVar: mysql.ErrUnknownSystemVariable,
+		CodeWrongValueForVar: mysql.ErrWrongValueForVar,
+		CodeWrongTypeForVar:  mysql.ErrWrongTypeForVar,
+		CodeQueryInterrupted: mysql.ErrQueryInterrupted,
 	}
 	terror.ErrClassToMySQLCodes[terror.ClassVariable] = mySQLErrCodes
 }
@@ -581,6 +594,13 @@ var defaultSysVars = []*SysVar{
 	{ScopeGlobal, "sync_frm", "ON"},
 	{ScopeGlobal, "innodb_online_alter_log_max_size", "134217728"},
 	{ScopeSession, tidbSnapshot, ""},
+	{ScopeSession, tidbMemQuotaQuery, strconv.Itoa(DefMemQuotaQuery)},
+	{ScopeSession, tidbHashAggConcurrency, strconv.Itoa(DefHashAggConcurrency)},
+	{ScopeSession, tidbProjectionConcurrency, strconv.Itoa(DefProjectionConcurrency)},
+	{ScopeSession, tidbUnionConcurrency, strconv.Itoa(DefUnionConcurrency)},
+	{ScopeSession, tidbUnionKeepOrder, "0"},
+	{ScopeSession, tidbJoinReorderThreshold, strconv.Itoa(DefJoinReorderThreshold)},
+	{ScopeSession, tidbEnablePlanCache, "1"},
 }
 
 // SetNamesVariables is the system variable names related to set names statements.
@@ -14,6 +14,7 @@
 package localstore
 
 import (
+	"bytes"
 	"net/url"
 	"path/filepath"
 	"runtime/debug"
@@ -24,12 +25,16 @@ import (
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/localstore/engine"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/segmentmap"
 	"github.com/twinj/uuid"
 )
 
 var (
-	_ kv.Storage = (*dbStore)(nil)
+	_ kv.Storage       = (*dbStore)(nil)
+	_ kv.RegionLocator = (*dbStore)(nil)
+	_ kv.MvccInspector = (*dbStore)(nil)
 )
 
 const (
@@ -354,6 +359,43 @@ func (s *dbStore) CurrentVersion() (kv.Version, error) {
 	return globalVersionProvider.CurrentVersion()
 }
 
+// LocateKey implements kv.RegionLocator LocateKey interface.
+func (s *dbStore) LocateKey(key kv.Key) (*kv.RegionInfo, error) {
+	for _, ri := range s.pd.GetRegionInfo() {
+		if ri.startKey.Cmp(key) <= 0 && (len(ri.endKey) == 0 || key.Cmp(ri.endKey) < 0) {
+			return &kv.RegionInfo{ID: uint64(ri.rs.id), StartKey: ri.startKey, EndKey: ri.endKey}, nil
+		}
+	}
+	return nil, errors.Errorf("no region contains key %q", key)
+}
+
+// GetMvccVersions implements kv.MvccInspector GetMvccVersions interface.
+func (s *dbStore) GetMvccVersions(key kv.Key) ([]*kv.MvccVersion, error) {
+	var versions []*kv.MvccVersion
+	// All the versions of the key have the prefix of the encoded key.
+	prefix := codec.EncodeBytes(nil, key)
+	mvccKey := MvccEncodeVersionKey(key, kv.MaxVersion)
+	for {
+		mvccK, v, err := s.Seek([]byte(mvccKey), kv.MaxVersion.Ver)
+		if terror.ErrorEqual(err, engine.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !bytes.HasPrefix(mvccK, prefix) {
+			break
+		}
+		_, ver, err := MvccDecode(mvccK)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		versions = append(versions, &kv.MvccVersion{CommitTS: ver.Ver, Value: v})
+		mvccKey = kv.EncodedKey(kv.Key(mvccK).Next())
+	}
+	return versions, nil
+}
+
 // Begin transaction
 func (s *dbStore) Begin() (kv.Transaction, error) {
 	s.mu.RLock()
@@ -83,8 +83,7 @@ type aggItem struct {
 	// Number of rows, this could be used in cout/avg
 	count uint64
 	// This could be used to store sum/max/min
-	value types.Datum
-	// TODO: support group_concat
+	value  types.Datum
 	buffer *bytes.Buffer // Buffer is used for group_concat.
 }
 
@@ -116,6 +115,8 @@ func (n *aggregateFuncExpr) update(ctx *selectContext, args []types.Datum) error
 		return n.updateMaxMin(ctx, args, true)
 	case tipb.ExprType_Min:
 		return n.updateMaxMin(ctx, args, false)
+	case tipb.ExprType_GroupConcat:
+		return n.updateGroupConcat(ctx, args)
 	}
 	return errors.Errorf("Unknown AggExpr: %v", n.expr.GetTp())
 }
@@ -140,6 +141,8 @@ func (n *aggregateFuncExpr) toDatums() (ds []types.Datum, err error) {
 		}
 		cnt := types.NewUintDatum(item.count)
 		ds = []types.Datum{cnt, sum}
+	case tipb.ExprType_GroupConcat:
+		ds = n.getGroupConcatDatum()
 	}
 	return
 }
@@ -170,6 +173,16 @@ func (n *aggregateFuncExpr) getValueDatum() []types.Datum {
 	return []types.Datum{item.value}
 }
 
+// Convert group_concat buffer to datum list.
+func (n *aggregateFuncExpr) getGroupConcatDatum() []types.Datum {
+	item := n.getAggItem()
+	var d types.Datum
+	if item.buffer != nil {
+		d.SetString(item.buffer.String())
+	}
+	return []types.Datum{d}
+}
+
 var singleGroupKey = []byte("SingleGroup")
 
 // getAggItem gets aggregate evaluation context for the current group.
@@ -267,3 +280,26 @@ func (n *aggregateFuncExpr) updateMaxMin(ctx *selectContext, args []types.Datum,
 	}
 	return nil
 }
+
+func (n *aggregateFuncExpr) updateGroupConcat(ctx *selectContext, args []types.Datum) error {
+	for _, a := range args {
+		if a.IsNull() {
+			return nil
+		}
+	}
+	aggItem := n.getAggItem()
+	if aggItem.buffer == nil {
+		aggItem.buffer = &bytes.Buffer{}
+	} else {
+		// Use comma separator, the same as TiDB does.
+		aggItem.buffer.WriteString(",")
+	}
+	for _, a := range args {
+		s, err := a.ToString()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		aggItem.buffer.WriteString(s)
+	}
+	return nil
+}
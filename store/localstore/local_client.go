@@ -62,11 +62,28 @@ func supportExpr(exprType tipb.ExprType) bool {
 		tipb.ExprType_Not,
 		tipb.ExprType_Like:
 		return true
-	case tipb.ExprType_Plus, tipb.ExprType_Div:
+	case tipb.ExprType_Plus, tipb.ExprType_Div, tipb.ExprType_Minus, tipb.ExprType_Mul,
+		tipb.ExprType_IntDiv, tipb.ExprType_Mod:
 		return true
-	case tipb.ExprType_Count, tipb.ExprType_First, tipb.ExprType_Sum, tipb.ExprType_Avg, tipb.ExprType_Max, tipb.ExprType_Min:
+	// control functions
+	case tipb.ExprType_If, tipb.ExprType_IfNull, tipb.ExprType_NullIf, tipb.ExprType_Case, tipb.ExprType_Coalesce:
 		return true
-	case kv.ReqSubTypeDesc:
+	// string functions
+	case tipb.ExprType_Concat, tipb.ExprType_ConcatWS, tipb.ExprType_Left, tipb.ExprType_Length,
+		tipb.ExprType_Lower, tipb.ExprType_Upper, tipb.ExprType_Repeat, tipb.ExprType_Replace,
+		tipb.ExprType_Strcmp, tipb.ExprType_Substring, tipb.ExprType_SubstringIndex,
+		tipb.ExprType_Locate, tipb.ExprType_Trim:
+		return true
+	// time functions
+	case tipb.ExprType_Year, tipb.ExprType_Month, tipb.ExprType_Day, tipb.ExprType_DayOfMonth,
+		tipb.ExprType_DayOfWeek, tipb.ExprType_DayOfYear, tipb.ExprType_Weekday, tipb.ExprType_WeekOfYear,
+		tipb.ExprType_DayName, tipb.ExprType_Hour, tipb.ExprType_Minute, tipb.ExprType_Second,
+		tipb.ExprType_Microsecond:
+		return true
+	case tipb.ExprType_Count, tipb.ExprType_First, tipb.ExprType_Sum, tipb.ExprType_Avg, tipb.ExprType_Max, tipb.ExprType_Min,
+		tipb.ExprType_GroupConcat:
+		return true
+	case kv.ReqSubTypeDesc, kv.ReqSubTypeTopN:
 		return true
 	default:
 		return false
@@ -56,6 +56,11 @@ type selectContext struct {
 	aggregate    bool
 	keyRanges    []kv.KeyRange
 
+	// Use for top n.
+	topn        bool
+	topnColumns map[int64]*tipb.ColumnInfo
+	topnHeap    *topnHeap
+
 	// Use for DecodeRow.
 	colTps map[int64]*types.FieldType
 
@@ -103,6 +108,18 @@ func (rs *localRegion) Handle(req *regionRequest) (*regionResponse, error) {
 				delete(ctx.aggColumns, k)
 			}
 		}
+		// Only select request with order by expressions is top n, order by without expression means desc scan.
+		ctx.topn = !ctx.aggregate && len(sel.OrderBy) > 0 && sel.OrderBy[0].Expr != nil
+		if ctx.topn {
+			ctx.topnColumns = make(map[int64]*tipb.ColumnInfo)
+			for _, item := range sel.OrderBy {
+				collectColumnsInExpr(item.Expr, ctx, ctx.topnColumns)
+			}
+			ctx.topnHeap = &topnHeap{
+				totalCount: int(sel.GetLimit()),
+				orderBy:    sel.OrderBy,
+			}
+		}
 		if req.Tp == kv.ReqTypeSelect {
 			err = rs.getRowsFromSelectReq(ctx)
 		} else {
@@ -175,7 +192,8 @@ func (rs *localRegion) getRowsFromSelectReq(ctx *selectContext) error {
 
 	kvRanges, desc := rs.extractKVRanges(ctx)
 	limit := int64(-1)
-	if ctx.sel.Limit != nil {
+	// For top n, all the rows need to be scanned, the limit is applied by the heap.
+	if ctx.sel.Limit != nil && !ctx.topn {
 		limit = ctx.sel.GetLimit()
 	}
 	for _, ran := range kvRanges {
@@ -191,6 +209,9 @@ func (rs *localRegion) getRowsFromSelectReq(ctx *selectContext) error {
 	if ctx.aggregate {
 		return rs.getRowsFromAgg(ctx)
 	}
+	if ctx.topn {
+		rs.getRowsFromTopN(ctx)
+	}
 	return nil
 }
 
@@ -255,7 +276,7 @@ func (rs *localRegion) extractKVRanges(ctx *selectContext) (kvRanges []kv.KeyRan
 		}
 		kvRanges = append(kvRanges, kvr)
 	}
-	if sel.OrderBy != nil {
+	if sel.OrderBy != nil && sel.OrderBy[0].Expr == nil {
 		desc = sel.OrderBy[0].Desc
 	}
 	if desc {
@@ -405,6 +426,13 @@ func (rs *localRegion) valuesToRow(ctx *selectContext, handle int64, values map[
 		}
 		return false, nil
 	}
+	if ctx.topn {
+		err = rs.evalTopN(ctx, handle, values, columns)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		return true, nil
+	}
 	chunk := rs.getChunk(ctx)
 	var rowMeta tipb.RowMeta
 	rowMeta.Handle = handle
@@ -143,6 +143,44 @@ func (t *testMvccSuite) TestMvccPutAndDel(c *C) {
 	c.Assert(cnt1, Greater, cnt)
 }
 
+func (t *testMvccSuite) TestGetMvccVersions(c *C) {
+	txn, err := t.s.Begin()
+	c.Assert(err, IsNil)
+	c.Assert(txn.Set(encodeInt(1), []byte("v")), IsNil)
+	c.Assert(txn.Commit(), IsNil)
+	txn, err = t.s.Begin()
+	c.Assert(err, IsNil)
+	c.Assert(txn.Delete(encodeInt(1)), IsNil)
+	c.Assert(txn.Commit(), IsNil)
+
+	versions, err := t.s.(kv.MvccInspector).GetMvccVersions(encodeInt(1))
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 3)
+	// The latest version is the first one.
+	c.Assert(versions[0].Value, HasLen, 0)
+	c.Assert(versions[1].Value, DeepEquals, []byte("v"))
+	c.Assert(versions[2].Value, DeepEquals, []byte(encodeInt(1)))
+	c.Assert(versions[0].CommitTS > versions[1].CommitTS, IsTrue)
+	c.Assert(versions[1].CommitTS > versions[2].CommitTS, IsTrue)
+
+	versions, err = t.s.(kv.MvccInspector).GetMvccVersions(encodeInt(1024))
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 0)
+}
+
+func (t *testMvccSuite) TestLocateKey(c *C) {
+	region, err := t.s.(kv.RegionLocator).LocateKey(kv.Key("t1"))
+	c.Assert(err, IsNil)
+	c.Assert(region.ID, Equals, uint64(2))
+	c.Assert(region.StartKey, DeepEquals, kv.Key("t"))
+	c.Assert(region.EndKey, DeepEquals, kv.Key("u"))
+	region, err = t.s.(kv.RegionLocator).LocateKey(kv.Key(""))
+	c.Assert(err, IsNil)
+	c.Assert(region.ID, Equals, uint64(1))
+	_, err = t.s.(kv.RegionLocator).LocateKey(kv.Key("zz"))
+	c.Assert(err, NotNil)
+}
+
 func (t *testMvccSuite) TestMvccNext(c *C) {
 	txn, _ := t.s.Begin()
 	it, err := txn.Seek(encodeInt(2))
@@ -104,6 +104,7 @@ const (
 	cleanupMaxBackoff       = 10000
 	gcMaxBackoff            = 100000
 	gcResolveLockMaxBackoff = 100000
+	locateKeyMaxBackoff     = 5000
 )
 
 // Backoffer is a utility for retrying queries.
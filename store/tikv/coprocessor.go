@@ -41,7 +41,8 @@ func (c *CopClient) SupportRequestType(reqType, subType int64) bool {
 		case kv.ReqSubTypeGroupBy, kv.ReqSubTypeBasic:
 			return true
 		default:
-			return supportExpr(tipb.ExprType(subType))
+			return supportExpr(tipb.ExprType(subType)) ||
+				(c.store.mockCoprocessor && supportMockExpr(tipb.ExprType(subType)))
 		}
 	}
 	return false
@@ -69,6 +70,36 @@ func supportExpr(exprType tipb.ExprType) bool {
 	}
 }
 
+// supportMockExpr checks the expressions, aggregate functions and TopN that are only implemented by
+// the coprocessor of mock-tikv, they are not sent to TiKV until TiKV supports them.
+func supportMockExpr(exprType tipb.ExprType) bool {
+	switch exprType {
+	case tipb.ExprType_Minus, tipb.ExprType_Mul, tipb.ExprType_IntDiv, tipb.ExprType_Mod:
+		return true
+	// control functions
+	case tipb.ExprType_If, tipb.ExprType_IfNull, tipb.ExprType_NullIf, tipb.ExprType_Case, tipb.ExprType_Coalesce:
+		return true
+	// string functions
+	case tipb.ExprType_Concat, tipb.ExprType_ConcatWS, tipb.ExprType_Left, tipb.ExprType_Length,
+		tipb.ExprType_Lower, tipb.ExprType_Upper, tipb.ExprType_Repeat, tipb.ExprType_Replace,
+		tipb.ExprType_Strcmp, tipb.ExprType_Substring, tipb.ExprType_SubstringIndex,
+		tipb.ExprType_Locate, tipb.ExprType_Trim:
+		return true
+	// time functions
+	case tipb.ExprType_Year, tipb.ExprType_Month, tipb.ExprType_Day, tipb.ExprType_DayOfMonth,
+		tipb.ExprType_DayOfWeek, tipb.ExprType_DayOfYear, tipb.ExprType_Weekday, tipb.ExprType_WeekOfYear,
+		tipb.ExprType_DayName, tipb.ExprType_Hour, tipb.ExprType_Minute, tipb.ExprType_Second,
+		tipb.ExprType_Microsecond:
+		return true
+	case tipb.ExprType_GroupConcat:
+		return true
+	case kv.ReqSubTypeTopN:
+		return true
+	default:
+		return false
+	}
+}
+
 // Send builds the request and gets the coprocessor iterator response.
 func (c *CopClient) Send(req *kv.Request) kv.Response {
 	bo := NewBackoffer(copBuildTaskMaxBackoff)
@@ -18,12 +18,28 @@ import (
 	"github.com/pingcap/tidb/kv"
 	"github.com/pingcap/tidb/store/tikv/mock-tikv"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tipb/go-tipb"
 )
 
 type testCoprocessorSuite struct{}
 
 var _ = Suite(&testCoprocessorSuite{})
 
+func (s *testCoprocessorSuite) TestSupportRequestType(c *C) {
+	// The expressions that are only implemented by mock-tikv are not sent to TiKV.
+	client := &CopClient{store: &tikvStore{}}
+	c.Assert(client.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_EQ)), IsTrue)
+	c.Assert(client.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_Concat)), IsFalse)
+	c.Assert(client.SupportRequestType(kv.ReqTypeIndex, int64(tipb.ExprType_GroupConcat)), IsFalse)
+	c.Assert(client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeTopN), IsFalse)
+
+	client = &CopClient{store: &tikvStore{mockCoprocessor: true}}
+	c.Assert(client.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_EQ)), IsTrue)
+	c.Assert(client.SupportRequestType(kv.ReqTypeSelect, int64(tipb.ExprType_Concat)), IsTrue)
+	c.Assert(client.SupportRequestType(kv.ReqTypeIndex, int64(tipb.ExprType_GroupConcat)), IsTrue)
+	c.Assert(client.SupportRequestType(kv.ReqTypeSelect, kv.ReqSubTypeTopN), IsTrue)
+}
+
 func (s *testCoprocessorSuite) TestBuildHugeTasks(c *C) {
 	cluster := mocktikv.NewCluster()
 	var splitKeys [][]byte
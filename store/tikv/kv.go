@@ -82,6 +82,9 @@ type tikvStore struct {
 	regionCache  *RegionCache
 	lockResolver *LockResolver
 	gcWorker     *GCWorker
+	// mockCoprocessor is true if the coprocessor requests are handled by mock-tikv, which
+	// supports more expressions than TiKV.
+	mockCoprocessor bool
 }
 
 func newTikvStore(uuid string, pdClient pd.Client, client Client, enableGC bool) (*tikvStore, error) {
@@ -113,7 +116,12 @@ func NewMockTikvStore() (kv.Storage, error) {
 	mvccStore := mocktikv.NewMvccStore()
 	client := mocktikv.NewRPCClient(cluster, mvccStore)
 	uuid := fmt.Sprintf("mock-tikv-store-:%v", time.Now().Unix())
-	return newTikvStore(uuid, mocktikv.NewPDClient(cluster), client, false)
+	store, err := newTikvStore(uuid, mocktikv.NewPDClient(cluster), client, false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	store.mockCoprocessor = true
+	return store, nil
 }
 
 func (s *tikvStore) Begin() (kv.Transaction, error) {
@@ -176,6 +184,15 @@ func (s *tikvStore) GetClient() kv.Client {
 	}
 }
 
+// LocateKey implements kv.RegionLocator LocateKey interface.
+func (s *tikvStore) LocateKey(key kv.Key) (*kv.RegionInfo, error) {
+	region, err := s.regionCache.GetRegion(NewBackoffer(locateKeyMaxBackoff), key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &kv.RegionInfo{ID: region.GetID(), StartKey: region.StartKey(), EndKey: region.EndKey()}, nil
+}
+
 // sendKVReq sends req to tikv server. It will retry internally to find the right
 // region leader if i) fails to establish a connection to server or ii) server
 // returns `NotLeader`.
@@ -42,6 +42,11 @@ type selectContext struct {
 	aggregate    bool
 	keyRanges    []*coprocessor.KeyRange
 
+	// Use for top n.
+	topn        bool
+	topnColumns map[int64]*tipb.ColumnInfo
+	topnHeap    *topnHeap
+
 	// Use for DecodeRow.
 	colTps map[int64]*types.FieldType
 }
@@ -91,6 +96,19 @@ func (h *rpcHandler) handleCopRequest(req *coprocessor.Request) (*coprocessor.Re
 			}
 		}
 
+		// Only select request with order by expressions is top n, order by without expression means desc scan.
+		ctx.topn = !ctx.aggregate && len(sel.OrderBy) > 0 && sel.OrderBy[0].Expr != nil
+		if ctx.topn {
+			ctx.topnColumns = make(map[int64]*tipb.ColumnInfo)
+			for _, item := range sel.OrderBy {
+				collectColumnsInExpr(item.Expr, ctx, ctx.topnColumns)
+			}
+			ctx.topnHeap = &topnHeap{
+				totalCount: int(sel.GetLimit()),
+				orderBy:    sel.OrderBy,
+			}
+		}
+
 		var rows []*tipb.Row
 		if req.GetTp() == kv.ReqTypeSelect {
 			rows, err = h.getRowsFromSelectReq(ctx)
@@ -103,11 +121,9 @@ func (h *rpcHandler) handleCopRequest(req *coprocessor.Request) (*coprocessor.Re
 			rows, err = h.getRowsFromIndexReq(ctx)
 		}
 		selResp := new(tipb.SelectResponse)
+		// The evaluation error is returned in the select response, so the client sees the original message.
 		selResp.Error = toPBError(err)
 		selResp.Rows = rows
-		if err != nil {
-			resp.OtherError = err.Error()
-		}
 		data, err := proto.Marshal(selResp)
 		if err != nil {
 			return nil, errors.Trace(err)
@@ -200,7 +216,8 @@ func (h *rpcHandler) getRowsFromSelectReq(ctx *selectContext) ([]*tipb.Row, erro
 	kvRanges, desc := h.extractKVRanges(ctx)
 	var rows []*tipb.Row
 	limit := int64(-1)
-	if ctx.sel.Limit != nil {
+	// For top n, all the rows need to be scanned, the limit is applied by the heap.
+	if ctx.sel.Limit != nil && !ctx.topn {
 		limit = ctx.sel.GetLimit()
 	}
 	for _, ran := range kvRanges {
@@ -217,6 +234,9 @@ func (h *rpcHandler) getRowsFromSelectReq(ctx *selectContext) ([]*tipb.Row, erro
 	if ctx.aggregate {
 		return h.getRowsFromAgg(ctx)
 	}
+	if ctx.topn {
+		return h.getRowsFromTopN(ctx), nil
+	}
 	return rows, nil
 }
 
@@ -237,7 +257,7 @@ func (h *rpcHandler) extractKVRanges(ctx *selectContext) (kvRanges []kv.KeyRange
 		kvr.EndKey = kv.Key(minEndKey(upperKey, h.endKey))
 		kvRanges = append(kvRanges, kvr)
 	}
-	if sel.OrderBy != nil {
+	if sel.OrderBy != nil && sel.OrderBy[0].Expr == nil {
 		desc = sel.OrderBy[0].Desc
 	}
 	if desc {
@@ -409,6 +429,14 @@ func (h *rpcHandler) valuesToRow(ctx *selectContext, handle int64, values map[in
 		for _, col := range columns {
 			row.Data = append(row.Data, values[col.GetColumnId()]...)
 		}
+		if ctx.topn {
+			// The row is kept by the top n heap and returned after all the rows are scanned.
+			err = h.evalTopN(ctx, handle, values, row)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			return nil, nil
+		}
 	}
 	return row, nil
 }
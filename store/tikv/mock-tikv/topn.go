@@ -0,0 +1,148 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocktikv
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/types"
+	"github.com/pingcap/tipb/go-tipb"
+)
+
+// sortRow is a row with its order by keys.
+type sortRow struct {
+	key []types.Datum
+	row *tipb.Row
+}
+
+// topnHeap holds the top totalCount rows of a region ordered by the order by items.
+// It is a max heap, the root is the last row in order, so it will be replaced first
+// when a row with smaller keys comes.
+type topnHeap struct {
+	rows       []*sortRow
+	totalCount int
+	orderBy    []*tipb.ByItem
+	err        error
+}
+
+func (h *topnHeap) lessRow(row1, row2 *sortRow) bool {
+	for i, item := range h.orderBy {
+		ret, err := row1.key[i].CompareDatum(row2.key[i])
+		if err != nil {
+			h.err = errors.Trace(err)
+			return true
+		}
+		if item.Desc {
+			ret = -ret
+		}
+		if ret < 0 {
+			return true
+		} else if ret > 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// Len implements heap.Interface Len interface.
+func (h *topnHeap) Len() int {
+	return len(h.rows)
+}
+
+// Less implements heap.Interface Less interface.
+func (h *topnHeap) Less(i, j int) bool {
+	return h.lessRow(h.rows[j], h.rows[i])
+}
+
+// Swap implements heap.Interface Swap interface.
+func (h *topnHeap) Swap(i, j int) {
+	h.rows[i], h.rows[j] = h.rows[j], h.rows[i]
+}
+
+// Push implements heap.Interface Push interface.
+func (h *topnHeap) Push(x interface{}) {
+	h.rows = append(h.rows, x.(*sortRow))
+}
+
+// Pop implements heap.Interface Pop interface.
+func (h *topnHeap) Pop() interface{} {
+	n := len(h.rows)
+	row := h.rows[n-1]
+	h.rows = h.rows[:n-1]
+	return row
+}
+
+// tryToAddRow adds the row into the heap if it is one of the top rows so far.
+func (h *topnHeap) tryToAddRow(row *sortRow) {
+	if h.totalCount == 0 {
+		return
+	}
+	if len(h.rows) < h.totalCount {
+		heap.Push(h, row)
+		return
+	}
+	if h.lessRow(row, h.rows[0]) {
+		h.rows[0] = row
+		heap.Fix(h, 0)
+	}
+}
+
+// sortedRows returns the rows in the heap in order.
+func (h *topnHeap) sortedRows() []*sortRow {
+	sort.Sort(&topnSorter{h})
+	return h.rows
+}
+
+// topnSorter sorts the rows of a topnHeap in order.
+type topnSorter struct {
+	*topnHeap
+}
+
+// Less implements sort.Interface Less interface.
+func (s *topnSorter) Less(i, j int) bool {
+	return s.lessRow(s.rows[i], s.rows[j])
+}
+
+// evalTopN evaluates the order by items for the row and tries to add it into the top n heap.
+func (h *rpcHandler) evalTopN(ctx *selectContext, handle int64, values map[int64][]byte, row *tipb.Row) error {
+	err := h.setColumnValueToCtx(ctx, handle, values, ctx.topnColumns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	sr := &sortRow{
+		key: make([]types.Datum, 0, len(ctx.sel.OrderBy)),
+		row: row,
+	}
+	for _, item := range ctx.sel.OrderBy {
+		d, err := ctx.eval.Eval(item.Expr)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		sr.key = append(sr.key, d)
+	}
+	ctx.topnHeap.tryToAddRow(sr)
+	return errors.Trace(ctx.topnHeap.err)
+}
+
+// getRowsFromTopN returns the rows kept in the top n heap.
+func (h *rpcHandler) getRowsFromTopN(ctx *selectContext) []*tipb.Row {
+	sortedRows := ctx.topnHeap.sortedRows()
+	rows := make([]*tipb.Row, 0, len(sortedRows))
+	for _, sr := range sortedRows {
+		rows = append(rows, sr.row)
+	}
+	return rows
+}
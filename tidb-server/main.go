@@ -32,6 +32,7 @@ import (
 	"github.com/pingcap/tidb/store/localstore/boltdb"
 	"github.com/pingcap/tidb/store/tikv"
 	"github.com/pingcap/tidb/util/printer"
+	"github.com/pingcap/tidb/util/slowlog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/push"
 )
@@ -48,9 +49,21 @@ var (
 	enablePS        = flag.Bool("perfschema", false, "If enable performance schema.")
 	reportStatus    = flag.Bool("report-status", true, "If enable status report HTTP service.")
 	logFile         = flag.String("log-file", "", "log file path")
+	slowQueryFile   = flag.String("slow-query-file", "", "slow query log file path, leaves it empty will write slow queries to the log.")
 	joinCon         = flag.Int("join-concurrency", 5, "the number of goroutines that participate joining.")
 	metricsAddr     = flag.String("metrics-addr", "", "prometheus pushgateway address, leaves it empty will disable prometheus push.")
 	metricsInterval = flag.Int("metrics-interval", 0, "prometheus client push interval in second, set \"0\" to disable prometheus push.")
+	sslCert         = flag.String("ssl-cert", "", "path of the certificate file for secure connections.")
+	sslKey          = flag.String("ssl-key", "", "path of the private key file for secure connections.")
+	sslCA           = flag.String("ssl-ca", "", "path of the CA file to verify the client certificates with.")
+	authRSAKey      = flag.String("auth-rsa-key", "", "path of the RSA private key file for caching_sha2_password, a key is generated if it's not set.")
+	tokenLimit      = flag.Int("token-limit", 100, "the number of commands executed concurrently.")
+	maxConnections  = flag.Int("max-connections", 0, "the maximum number of client connections, 0 means no limit.")
+	pgPort          = flag.String("pg-port", "", "PostgreSQL protocol server port, leaves it empty will disable the PostgreSQL protocol.")
+	pgAuthMethod    = flag.String("pg-auth-method", server.PGAuthMD5, "authentication method of the PostgreSQL protocol, [md5, password]. md5 needs the user to log in with TLS once.")
+	pgInsecurePwd   = flag.Bool("pg-allow-insecure-password", false, "allow the PostgreSQL protocol to receive the cleartext password on the connections without TLS.")
+	shutdownTimeout = flag.Int("shutdown-timeout", 30, "seconds to wait for the running statements to finish when the server is terminated by SIGTERM.")
+	enableDebugAPI  = flag.Bool("enable-debug-api", false, "enable the MVCC and settings REST API on the status port, the status port is not authenticated.")
 )
 
 func main() {
@@ -68,11 +81,24 @@ func main() {
 	tidb.SetSchemaLease(time.Duration(*lease) * time.Second)
 
 	cfg := &server.Config{
-		Addr:         fmt.Sprintf("%s:%s", *host, *port),
-		LogLevel:     *logLevel,
-		StatusAddr:   fmt.Sprintf(":%s", *statusPort),
-		Socket:       *socket,
-		ReportStatus: *reportStatus,
+		Addr:           fmt.Sprintf("%s:%s", *host, *port),
+		LogLevel:       *logLevel,
+		StatusAddr:     fmt.Sprintf(":%s", *statusPort),
+		Socket:         *socket,
+		ReportStatus:   *reportStatus,
+		SSLCert:        *sslCert,
+		SSLKey:         *sslKey,
+		SSLCA:          *sslCA,
+		AuthRSAKey:     *authRSAKey,
+		TokenLimit:     *tokenLimit,
+		MaxConnections: *maxConnections,
+		PGAuthMethod:   *pgAuthMethod,
+
+		PGAllowInsecurePassword: *pgInsecurePwd,
+		EnableDebugAPI:          *enableDebugAPI,
+	}
+	if len(*pgPort) > 0 {
+		cfg.PGAddr = fmt.Sprintf("%s:%s", *host, *pgPort)
 	}
 
 	// set log options
@@ -83,6 +109,12 @@ func main() {
 		}
 		log.SetRotateByDay()
 	}
+	if len(*slowQueryFile) > 0 {
+		err := slowlog.SetFile(*slowQueryFile)
+		if err != nil {
+			log.Fatal(errors.ErrorStack(err))
+		}
+	}
 
 	if joinCon != nil && *joinCon > 0 {
 		plan.JoinConcurrency = *joinCon
@@ -122,11 +154,16 @@ func main() {
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
 
+	exited := make(chan struct{})
 	go func() {
 		sig := <-sc
 		log.Infof("Got signal [%d] to exit.", sig)
-		svr.Close()
-		os.Exit(0)
+		if sig == syscall.SIGTERM {
+			svr.GracefulDown(time.Duration(*shutdownTimeout) * time.Second)
+		} else {
+			svr.Close()
+		}
+		close(exited)
 	}()
 
 	go systimemon.StartMonitor(time.Now, func() {
@@ -135,7 +172,16 @@ func main() {
 
 	pushMetric(*metricsAddr, time.Duration(*metricsInterval)*time.Second)
 
-	log.Error(svr.Run())
+	if err = svr.Run(); err != nil {
+		log.Error(errors.ErrorStack(err))
+		return
+	}
+	// The server is closed by the signal, wait for the connections to exit.
+	<-exited
+	tidb.CloseDomain(store)
+	if err = store.Close(); err != nil {
+		log.Error(errors.ErrorStack(err))
+	}
 }
 
 // Prometheus push.
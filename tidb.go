@@ -76,6 +76,16 @@ func (dm *domainMap) Get(store kv.Storage) (d *domain.Domain, err error) {
 	return
 }
 
+// Delete closes and removes the domain of the storage.
+func (dm *domainMap) Delete(store kv.Storage) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if d, ok := dm.domains[store.UUID()]; ok {
+		d.Close()
+		delete(dm.domains, store.UUID())
+	}
+}
+
 var (
 	domap = &domainMap{
 		domains: map[string]*domain.Domain{},
@@ -83,6 +93,8 @@ var (
 	stores = make(map[string]kv.Driver)
 	// store.UUID()-> IfBootstrapped
 	storeBootstrapped = make(map[string]bool)
+	// store.UUID()-> IfUpgraded
+	storeUpgraded = make(map[string]bool)
 
 	// schemaLease is the time for re-updating remote schema.
 	// In online DDL, we must wait 2 * SchemaLease time to guarantee
@@ -100,6 +112,18 @@ func SetSchemaLease(lease time.Duration) {
 	schemaLease = lease
 }
 
+// GetDomain gets the domain of the storage, it is created if not exists.
+func GetDomain(store kv.Storage) (*domain.Domain, error) {
+	d, err := domap.Get(store)
+	return d, errors.Trace(err)
+}
+
+// CloseDomain closes the domain of the storage and stops its background loops,
+// it should be called before the storage is closed.
+func CloseDomain(store kv.Storage) {
+	domap.Delete(store)
+}
+
 // What character set should the server translate a statement to after receiving it?
 // For this, the server uses the character_set_connection and collation_connection system variables.
 // It converts statements sent by the client from character_set_client to character_set_connection
@@ -14,8 +14,15 @@
 package util
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"fmt"
+	"strconv"
 
 	"github.com/juju/errors"
 )
@@ -66,3 +73,182 @@ func DecodePassword(pwd string) ([]byte, error) {
 	}
 	return x, nil
 }
+
+const (
+	// sha2SaltLen is the salt length of the caching_sha2_password hash.
+	sha2SaltLen = 20
+	// sha2Rounds is the rounds of the caching_sha2_password hash in thousands.
+	sha2Rounds = 5
+	// sha2HashLen is the length of the caching_sha2_password hash like "$A$005$<salt><hash>".
+	sha2HashLen = 7 + sha2SaltLen + 43
+	// sha2SaltChars are the characters of the generated salt, they are safe in SQL string literals.
+	sha2SaltChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// cryptB64Chars are the characters to encode the hash of crypt.
+	cryptB64Chars = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// EncodeSha2Password converts plaintext password to the caching_sha2_password hash string,
+// which is compatible with the authentication_string of MySQL.
+func EncodeSha2Password(pwd string) string {
+	if len(pwd) == 0 {
+		return ""
+	}
+	salt := make([]byte, sha2SaltLen)
+	rand.Read(salt)
+	for i := range salt {
+		salt[i] = sha2SaltChars[int(salt[i])%len(sha2SaltChars)]
+	}
+	return sha256Crypt([]byte(pwd), salt, sha2Rounds)
+}
+
+// CheckSha2Password checks the plaintext password with the caching_sha2_password hash string.
+func CheckSha2Password(hash string, pwd []byte) bool {
+	if len(hash) == 0 {
+		return len(pwd) == 0
+	}
+	if len(hash) != sha2HashLen || hash[:3] != "$A$" || hash[6] != '$' {
+		return false
+	}
+	rounds, err := strconv.Atoi(hash[3:6])
+	if err != nil || rounds <= 0 {
+		return false
+	}
+	salt := []byte(hash[7 : 7+sha2SaltLen])
+	return subtle.ConstantTimeCompare([]byte(sha256Crypt(pwd, salt, rounds)), []byte(hash)) == 1
+}
+
+// sha256Crypt calculates the SHA-256 crypt of the plaintext with the salt and the rounds in thousands.
+// See https://www.akkadia.org/drepper/SHA-crypt.txt, the numbers in comments are the steps in it.
+func sha256Crypt(plaintext, salt []byte, rounds int) string {
+	// 1, 2, 3
+	a := sha256.New()
+	a.Write(plaintext)
+	a.Write(salt)
+	// 4, 5, 6, 7, 8
+	b := sha256.New()
+	b.Write(plaintext)
+	b.Write(salt)
+	b.Write(plaintext)
+	sumB := b.Sum(nil)
+	// 9, 10
+	i := len(plaintext)
+	for ; i > sha256.Size; i -= sha256.Size {
+		a.Write(sumB)
+	}
+	a.Write(sumB[:i])
+	// 11
+	for i = len(plaintext); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(sumB)
+		} else {
+			a.Write(plaintext)
+		}
+	}
+	// 12
+	sumA := a.Sum(nil)
+	// 13, 14, 15, 16
+	dp := sha256.New()
+	for range plaintext {
+		dp.Write(plaintext)
+	}
+	p := repeatBytes(dp.Sum(nil), len(plaintext))
+	// 17, 18, 19, 20
+	ds := sha256.New()
+	for i = 0; i < 16+int(sumA[0]); i++ {
+		ds.Write(salt)
+	}
+	s := repeatBytes(ds.Sum(nil), len(salt))
+	// 21
+	c := sha256.New()
+	for i = 0; i < rounds*1000; i++ {
+		c.Reset()
+		if i&1 != 0 {
+			c.Write(p)
+		} else {
+			c.Write(sumA)
+		}
+		if i%3 != 0 {
+			c.Write(s)
+		}
+		if i%7 != 0 {
+			c.Write(p)
+		}
+		if i&1 != 0 {
+			c.Write(sumA)
+		} else {
+			c.Write(p)
+		}
+		sumA = c.Sum(sumA[:0])
+	}
+	// 22
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "$A$%03d$%s", rounds, salt)
+	for _, g := range [][3]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+	} {
+		writeCryptB64(&buf, uint(sumA[g[0]])<<16|uint(sumA[g[1]])<<8|uint(sumA[g[2]]), 4)
+	}
+	writeCryptB64(&buf, uint(sumA[31])<<8|uint(sumA[30]), 3)
+	return buf.String()
+}
+
+// repeatBytes repeats the sum to n bytes.
+func repeatBytes(sum []byte, n int) []byte {
+	b := make([]byte, 0, n)
+	for ; n > len(sum); n -= len(sum) {
+		b = append(b, sum...)
+	}
+	return append(b, sum[:n]...)
+}
+
+func writeCryptB64(buf *bytes.Buffer, w uint, n int) {
+	for ; n > 0; n-- {
+		buf.WriteByte(cryptB64Chars[w&0x3f])
+		w >>= 6
+	}
+}
+
+// CalcSha2Digest calculates SHA256(SHA256(password)), it is cached by the server after a
+// full authentication to verify the scramble of the fast authentication of caching_sha2_password.
+func CalcSha2Digest(pwd []byte) []byte {
+	stage1 := sha256.Sum256(pwd)
+	stage2 := sha256.Sum256(stage1[:])
+	return stage2[:]
+}
+
+// CheckSha2Scramble checks the scramble of the caching_sha2_password fast authentication,
+// the scramble is SHA256(password) XOR SHA256(SHA256(SHA256(password)) <concat> nonce).
+func CheckSha2Scramble(scramble, nonce, digest []byte) bool {
+	if len(scramble) != sha256.Size {
+		return false
+	}
+	h := sha256.New()
+	h.Write(digest)
+	h.Write(nonce)
+	stage1 := h.Sum(nil)
+	for i := range stage1 {
+		stage1[i] ^= scramble[i]
+	}
+	stage2 := sha256.Sum256(stage1)
+	return subtle.ConstantTimeCompare(stage2[:], digest) == 1
+}
+
+// CalcMD5Digest calculates the hex of MD5(password <concat> user), which is the password hash of
+// PostgreSQL that its MD5 authentication is based on.
+func CalcMD5Digest(pwd []byte, user string) []byte {
+	h := md5.New()
+	h.Write(pwd)
+	h.Write([]byte(user))
+	return []byte(hex.EncodeToString(h.Sum(nil)))
+}
+
+// CheckMD5Response checks the response of the PostgreSQL MD5 authentication, the response is
+// "md5" <concat> hex(MD5(digest <concat> salt)).
+func CheckMD5Response(response, salt, digest []byte) bool {
+	h := md5.New()
+	h.Write(digest)
+	h.Write(salt)
+	expect := "md5" + hex.EncodeToString(h.Sum(nil))
+	return subtle.ConstantTimeCompare(response, []byte(expect)) == 1
+}
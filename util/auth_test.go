@@ -14,6 +14,11 @@
 package util
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/util/testleak"
 )
@@ -43,3 +48,62 @@ func (s *testAuthSuite) TestCalcPassword(c *C) {
 	checkAuth := []byte{126, 168, 249, 64, 180, 223, 60, 240, 69, 249, 184, 57, 21, 34, 214, 219, 8, 193, 208, 55}
 	c.Assert(CalcPassword(salt, pwd), DeepEquals, checkAuth)
 }
+
+func (s *testAuthSuite) TestSha256Crypt(c *C) {
+	defer testleak.AfterTest(c)()
+	// The results are the same as crypt(3) with "$5$rounds=5000$<salt>$".
+	c.Assert(sha256Crypt([]byte("secret"), []byte("abcdefghijklmnop"), 5), Equals,
+		"$A$005$abcdefghijklmnopi7zHaTeYqXlesRXJ8dXj6o.MEFQdzfIJpJpcWAtszs6")
+	c.Assert(sha256Crypt([]byte(strings.Repeat("a", 70)), []byte("0123456789ABCDEF"), 5), Equals,
+		"$A$005$0123456789ABCDEFr26fnbyMvm0nm/h7o7S.k21tOS8Xojkw91ZC50u2/H0")
+}
+
+func (s *testAuthSuite) TestSha2Password(c *C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(EncodeSha2Password(""), Equals, "")
+	c.Assert(CheckSha2Password("", nil), IsTrue)
+	c.Assert(CheckSha2Password("", []byte("123")), IsFalse)
+
+	hash := EncodeSha2Password("123")
+	c.Assert(hash, HasLen, sha2HashLen)
+	c.Assert(hash, Not(Equals), EncodeSha2Password("123"))
+	c.Assert(CheckSha2Password(hash, []byte("123")), IsTrue)
+	c.Assert(CheckSha2Password(hash, []byte("1234")), IsFalse)
+	c.Assert(CheckSha2Password(hash, nil), IsFalse)
+	c.Assert(CheckSha2Password(EncodePassword("123"), []byte("123")), IsFalse)
+}
+
+func (s *testAuthSuite) TestSha2Scramble(c *C) {
+	defer testleak.AfterTest(c)()
+	nonce := []byte("01234567890123456789")
+	// The scramble sent by the client.
+	stage1 := sha256.Sum256([]byte("123"))
+	h := sha256.New()
+	h.Write(CalcSha2Digest([]byte("123")))
+	h.Write(nonce)
+	scramble := h.Sum(nil)
+	for i := range scramble {
+		scramble[i] ^= stage1[i]
+	}
+	c.Assert(CheckSha2Scramble(scramble, nonce, CalcSha2Digest([]byte("123"))), IsTrue)
+	c.Assert(CheckSha2Scramble(scramble, nonce, CalcSha2Digest([]byte("1234"))), IsFalse)
+	c.Assert(CheckSha2Scramble(scramble[:20], nonce, CalcSha2Digest([]byte("123"))), IsFalse)
+}
+
+func (s *testAuthSuite) TestMD5Response(c *C) {
+	defer testleak.AfterTest(c)()
+	// md5("123root") is the hash of the password "123" of the user "root" in PostgreSQL.
+	digest := CalcMD5Digest([]byte("123"), "root")
+	c.Assert(string(digest), Equals, "3025e018f359290d51652cd3b85acf3c")
+
+	salt := []byte{1, 2, 3, 4}
+	// The response sent by the client.
+	h := md5.New()
+	h.Write(digest)
+	h.Write(salt)
+	response := []byte("md5" + hex.EncodeToString(h.Sum(nil)))
+	c.Assert(CheckMD5Response(response, salt, digest), IsTrue)
+	c.Assert(CheckMD5Response(response, []byte{1, 2, 3, 5}, digest), IsFalse)
+	c.Assert(CheckMD5Response(response, salt, CalcMD5Digest([]byte("1234"), "root")), IsFalse)
+	c.Assert(CheckMD5Response(response[3:], salt, digest), IsFalse)
+}
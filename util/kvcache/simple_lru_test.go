@@ -0,0 +1,69 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvcache
+
+import (
+	"testing"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	check.TestingT(t)
+}
+
+var _ = check.Suite(&testLRUCacheSuite{})
+
+type testLRUCacheSuite struct {
+}
+
+type mockCacheKey struct {
+	key string
+}
+
+func (mk *mockCacheKey) Hash() []byte {
+	return []byte(mk.key)
+}
+
+func (s *testLRUCacheSuite) TestLRUCache(c *check.C) {
+	defer testleak.AfterTest(c)()
+	lru := NewSimpleLRUCache(2)
+	lru.Put(&mockCacheKey{"a"}, 1)
+	lru.Put(&mockCacheKey{"b"}, 2)
+	c.Assert(lru.Size(), check.Equals, 2)
+
+	// Getting "a" makes "b" the least recently used one.
+	v, ok := lru.Get(&mockCacheKey{"a"})
+	c.Assert(ok, check.IsTrue)
+	c.Assert(v, check.Equals, 1)
+	lru.Put(&mockCacheKey{"c"}, 3)
+	c.Assert(lru.Size(), check.Equals, 2)
+	_, ok = lru.Get(&mockCacheKey{"b"})
+	c.Assert(ok, check.IsFalse)
+
+	// Putting an existing key replaces its value.
+	lru.Put(&mockCacheKey{"c"}, 4)
+	v, ok = lru.Get(&mockCacheKey{"c"})
+	c.Assert(ok, check.IsTrue)
+	c.Assert(v, check.Equals, 4)
+	c.Assert(lru.Size(), check.Equals, 2)
+
+	lru.Delete(&mockCacheKey{"a"})
+	_, ok = lru.Get(&mockCacheKey{"a"})
+	c.Assert(ok, check.IsFalse)
+	c.Assert(lru.Size(), check.Equals, 1)
+	lru.Delete(&mockCacheKey{"a"})
+	c.Assert(lru.Size(), check.Equals, 1)
+}
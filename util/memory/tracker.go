@@ -0,0 +1,73 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Tracker is used to track the memory usage during query execution.
+// Trackers form a tree: the root tracker represents a whole query and holds
+// the quota, each memory consuming executor holds a child tracker.
+// Consuming bytes on a tracker also consumes them on all its ancestors.
+type Tracker struct {
+	label         string
+	bytesLimit    int64
+	bytesConsumed int64
+	parent        *Tracker
+}
+
+// NewTracker creates a memory tracker.
+// A non-positive bytesLimit means there is no limit.
+func NewTracker(label string, bytesLimit int64) *Tracker {
+	return &Tracker{
+		label:      label,
+		bytesLimit: bytesLimit,
+	}
+}
+
+// AttachTo attaches this tracker as a child of parent.
+func (t *Tracker) AttachTo(parent *Tracker) {
+	t.parent = parent
+}
+
+// Consume is used to consume a memory usage, bytes can be negative which
+// means the memory is released.
+func (t *Tracker) Consume(bytes int64) {
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		atomic.AddInt64(&tracker.bytesConsumed, bytes)
+	}
+}
+
+// BytesConsumed returns the consumed memory usage value in bytes.
+func (t *Tracker) BytesConsumed() int64 {
+	return atomic.LoadInt64(&t.bytesConsumed)
+}
+
+// Exceeded checks whether this tracker or any of its ancestors consumes more
+// memory than its limit.
+func (t *Tracker) Exceeded() bool {
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		if tracker.bytesLimit > 0 && tracker.BytesConsumed() > tracker.bytesLimit {
+			return true
+		}
+	}
+	return false
+}
+
+// String implements fmt.Stringer interface.
+func (t *Tracker) String() string {
+	return fmt.Sprintf("%s: consumed %d bytes, limit %d bytes", t.label, t.BytesConsumed(), t.bytesLimit)
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"testing"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	check.TestingT(t)
+}
+
+var _ = check.Suite(&testTrackerSuite{})
+
+type testTrackerSuite struct {
+}
+
+func (s *testTrackerSuite) TestTracker(c *check.C) {
+	defer testleak.AfterTest(c)()
+	root := NewTracker("query", 100)
+	sort := NewTracker("sort", -1)
+	sort.AttachTo(root)
+	agg := NewTracker("agg", -1)
+	agg.AttachTo(root)
+
+	sort.Consume(60)
+	c.Assert(sort.BytesConsumed(), check.Equals, int64(60))
+	c.Assert(root.BytesConsumed(), check.Equals, int64(60))
+	c.Assert(sort.Exceeded(), check.IsFalse)
+
+	agg.Consume(50)
+	c.Assert(root.BytesConsumed(), check.Equals, int64(110))
+	c.Assert(sort.Exceeded(), check.IsTrue)
+	c.Assert(agg.Exceeded(), check.IsTrue)
+
+	sort.Consume(-60)
+	c.Assert(sort.BytesConsumed(), check.Equals, int64(0))
+	c.Assert(root.BytesConsumed(), check.Equals, int64(50))
+	c.Assert(agg.Exceeded(), check.IsFalse)
+
+	unlimited := NewTracker("unlimited", 0)
+	unlimited.Consume(1 << 40)
+	c.Assert(unlimited.Exceeded(), check.IsFalse)
+}
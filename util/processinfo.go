@@ -0,0 +1,131 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/privilege"
+	"github.com/pingcap/tidb/sessionctx/variable"
+)
+
+// ProcessInfo is a struct used for show processlist statement.
+type ProcessInfo struct {
+	ID      uint64
+	User    string
+	Host    string
+	DB      string
+	Command string
+	Time    time.Time
+	State   string
+	Info    string
+}
+
+// maxInfoLen is the max length of the statement shown by SHOW PROCESSLIST without FULL.
+const maxInfoLen = 100
+
+// ToRow returns the row of the process info for SHOW PROCESSLIST and
+// information_schema.PROCESSLIST, the statement is truncated if full is false.
+func (pi *ProcessInfo) ToRow(full bool) []interface{} {
+	var info interface{}
+	if len(pi.Info) > 0 {
+		if !full && len(pi.Info) > maxInfoLen {
+			info = pi.Info[:maxInfoLen]
+		} else {
+			info = pi.Info
+		}
+	}
+	var db interface{}
+	if len(pi.DB) > 0 {
+		db = pi.DB
+	}
+	t := uint64(time.Since(pi.Time) / time.Second)
+	return []interface{}{pi.ID, pi.User, pi.Host, db, pi.Command, t, pi.State, info}
+}
+
+// SessionManager is an interface for session manage. Show processlist and
+// kill statement rely on this interface.
+type SessionManager interface {
+	// ShowProcessList returns the process info of all the connections.
+	ShowProcessList() []ProcessInfo
+	// Kill kills the connection with connectionID. If query is true, only the
+	// statement running in the connection is killed.
+	Kill(connectionID uint64, query bool) bool
+}
+
+// A dummy type to avoid naming collision in context.
+type sessionManagerKeyType int
+
+// String defines a Stringer function for debugging and pretty printing.
+func (k sessionManagerKeyType) String() string {
+	return "session manager"
+}
+
+const sessionManagerKey sessionManagerKeyType = 0
+
+// BindSessionManager binds the session manager to context.
+func BindSessionManager(ctx context.Context, sm SessionManager) {
+	ctx.SetValue(sessionManagerKey, sm)
+}
+
+// GetSessionManager gets the session manager from context, it returns nil
+// if the session is not served by a server.
+func GetSessionManager(ctx context.Context) SessionManager {
+	v, ok := ctx.Value(sessionManagerKey).(SessionManager)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// IsCurrentUser returns whether name is the user name of the current user, it's used to check
+// the owner of the processes and the slow queries. The embedded session without a user owns all.
+func IsCurrentUser(ctx context.Context, name string) bool {
+	user := variable.GetSessionVars(ctx).User
+	if len(user) == 0 {
+		return true
+	}
+	if i := strings.LastIndex(user, "@"); i >= 0 {
+		user = user[:i]
+	}
+	return user == name
+}
+
+// VisibleProcessList returns the processes the current user is allowed to see,
+// the processes of the other users need the PROCESS privilege like MySQL.
+func VisibleProcessList(ctx context.Context) ([]ProcessInfo, error) {
+	sm := GetSessionManager(ctx)
+	if sm == nil {
+		return nil, nil
+	}
+	processes := sm.ShowProcessList()
+	ok, err := privilege.CheckGlobal(ctx, mysql.ProcessPriv)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if ok {
+		return processes, nil
+	}
+	visible := make([]ProcessInfo, 0, len(processes))
+	for i := range processes {
+		if IsCurrentUser(ctx, processes[i].User) {
+			visible = append(visible, processes[i])
+		}
+	}
+	return visible, nil
+}
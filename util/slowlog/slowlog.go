@@ -0,0 +1,305 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package slowlog writes and reads the slow query log. The log has the format of
+// the MySQL slow query log, so it can be analyzed by pt-query-digest, e.g.
+//
+//	# Time: 2016-10-18T12:04:25.123456Z
+//	# User@Host: root[root] @ 127.0.0.1 [127.0.0.1]  Id: 3
+//	# Schema: test
+//	# Query_time: 1.234567  Lock_time: 0.000000  Rows_sent: 1  Rows_examined: 1000
+//	# Parse_time: 0.000012  Compile_time: 0.000150  Exec_time: 1.234405  Cop_wait_time: 1.200000
+//	# Plan_digest: 2a97516c...  Digest: 8d4ebd68...
+//	use test;
+//	SET timestamp=1476792265;
+//	select count(*) from t where a > 1;
+package slowlog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// timeFormat is the format of the Time field, the time is in UTC.
+const timeFormat = "2006-01-02T15:04:05.999999Z"
+
+// Entry is an entry of the slow query log.
+type Entry struct {
+	// StartTime is the time when the statement starts.
+	StartTime time.Time
+	User      string
+	Host      string
+	ConnID    uint64
+	DB        string
+	// QueryTime is the total time of the statement, it contains the following phases.
+	QueryTime   time.Duration
+	ParseTime   time.Duration
+	CompileTime time.Duration
+	ExecTime    time.Duration
+	// CopWaitTime is the time waiting for the coprocessor responses during the execution.
+	CopWaitTime  time.Duration
+	RowsSent     int64
+	RowsExamined int64
+	PlanDigest   string
+	Digest       string
+	SQL          string
+}
+
+var slowLog = struct {
+	sync.Mutex
+	path string
+	file *os.File
+}{}
+
+// SetFile sets the file of the slow query log, the entries are appended to it.
+// If the path is empty, the slow queries are written to the TiDB log.
+func SetFile(path string) error {
+	slowLog.Lock()
+	defer slowLog.Unlock()
+	if slowLog.file != nil {
+		slowLog.file.Close()
+		slowLog.file = nil
+	}
+	slowLog.path = path
+	if len(path) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		slowLog.path = ""
+		return errors.Trace(err)
+	}
+	slowLog.file = f
+	return nil
+}
+
+// File returns the path of the slow query log file.
+func File() string {
+	slowLog.Lock()
+	defer slowLog.Unlock()
+	return slowLog.path
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 6, 64)
+}
+
+// Format formats the entry as the MySQL slow query log.
+func (e *Entry) Format() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Time: %s\n", e.StartTime.UTC().Format(timeFormat))
+	fmt.Fprintf(&buf, "# User@Host: %s[%s] @ %s [%s]  Id: %d\n", e.User, e.User, e.Host, e.Host, e.ConnID)
+	if len(e.DB) > 0 {
+		fmt.Fprintf(&buf, "# Schema: %s\n", e.DB)
+	}
+	fmt.Fprintf(&buf, "# Query_time: %s  Lock_time: 0.000000  Rows_sent: %d  Rows_examined: %d\n",
+		formatSeconds(e.QueryTime), e.RowsSent, e.RowsExamined)
+	fmt.Fprintf(&buf, "# Parse_time: %s  Compile_time: %s  Exec_time: %s  Cop_wait_time: %s\n",
+		formatSeconds(e.ParseTime), formatSeconds(e.CompileTime), formatSeconds(e.ExecTime), formatSeconds(e.CopWaitTime))
+	fmt.Fprintf(&buf, "# Plan_digest: %s  Digest: %s\n", e.PlanDigest, e.Digest)
+	if len(e.DB) > 0 {
+		fmt.Fprintf(&buf, "use %s;\n", e.DB)
+	}
+	fmt.Fprintf(&buf, "SET timestamp=%d;\n", e.StartTime.Unix())
+	sql := strings.TrimSpace(e.SQL)
+	if !strings.HasSuffix(sql, ";") {
+		sql += ";"
+	}
+	buf.WriteString(sql)
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// passwordPattern matches the password literals of CREATE USER, GRANT and SET PASSWORD, the
+// first group is the text before the literal.
+var passwordPattern = regexp.MustCompile(`(?is)(\bidentified\s+(?:with\s+\S+\s+)?by\s+(?:password\s+)?|` +
+	`\bset\s+password\b[^=]*=\s*(?:password\s*\(\s*)?)('(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*")`)
+
+// maskPasswords replaces the password literals in sql with '***'.
+func maskPasswords(sql string) string {
+	return passwordPattern.ReplaceAllString(sql, "${1}'***'")
+}
+
+// Write writes the entry to the slow query log, the passwords in the statement are masked.
+func Write(e *Entry) {
+	masked := *e
+	masked.SQL = maskPasswords(e.SQL)
+	e = &masked
+	slowLog.Lock()
+	defer slowLog.Unlock()
+	if slowLog.file == nil {
+		log.Warnf("[SLOW_QUERY] cost_time:%v parse_time:%v compile_time:%v exec_time:%v cop_wait_time:%v conn_id:%d user:%s@%s db:%s rows_examined:%d rows_sent:%d sql:%s",
+			e.QueryTime, e.ParseTime, e.CompileTime, e.ExecTime, e.CopWaitTime, e.ConnID, e.User, e.Host, e.DB, e.RowsExamined, e.RowsSent, e.SQL)
+		return
+	}
+	_, err := slowLog.file.Write(e.Format())
+	if err != nil {
+		log.Errorf("write slow query log error %v", err)
+	}
+}
+
+// maxReadSize is the max size of the slow query log file read by Read.
+var maxReadSize int64 = 16 << 20
+
+// Read reads the entries of the slow query log file. Only the last maxReadSize bytes of the file
+// are read, so the memory used by reading a big file is limited.
+func Read() ([]*Entry, error) {
+	path := File()
+	if len(path) == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if offset := info.Size() - maxReadSize; offset > 0 {
+		// The partial entry at the offset is skipped by Parse.
+		if _, err = f.Seek(offset, io.SeekStart); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	entries, err := Parse(io.LimitReader(f, maxReadSize))
+	return entries, errors.Trace(err)
+}
+
+// Parse parses the slow query log.
+func Parse(r io.Reader) ([]*Entry, error) {
+	var (
+		entries []*Entry
+		e       *Entry
+		sql     []string
+	)
+	finish := func() {
+		if e != nil {
+			e.SQL = strings.TrimSuffix(strings.Join(sql, "\n"), ";")
+			entries = append(entries, e)
+		}
+		e, sql = nil, nil
+	}
+	scanner := bufio.NewScanner(r)
+	// The buffer grows with the longest line, which is not longer than the size read by Read.
+	scanner.Buffer(nil, int(maxReadSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# Time: ") {
+			finish()
+			e = &Entry{}
+			t, err := time.Parse(timeFormat, strings.TrimSpace(line[len("# Time: "):]))
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			e.StartTime = t.Local()
+			continue
+		}
+		if e == nil {
+			// Skip the lines before the first entry, e.g. the header written by MySQL.
+			continue
+		}
+		if len(sql) == 0 && strings.HasPrefix(line, "# ") {
+			err := e.parseFields(line[2:])
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			continue
+		}
+		if len(sql) == 0 && (strings.HasPrefix(line, "use ") || strings.HasPrefix(line, "SET timestamp=")) {
+			continue
+		}
+		sql = append(sql, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	finish()
+	return entries, nil
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// parseFields parses the fields in a header line like "Query_time: 1.000000  Lock_time: 0.000000".
+func (e *Entry) parseFields(line string) error {
+	if strings.HasPrefix(line, "User@Host: ") {
+		// root[root] @ 127.0.0.1 [127.0.0.1]  Id: 3
+		line = line[len("User@Host: "):]
+		if i := strings.IndexByte(line, '['); i >= 0 {
+			e.User = line[:i]
+		}
+		if i := strings.Index(line, " @ "); i >= 0 {
+			// The host name is empty if it is followed by the IP directly.
+			if host := strings.Fields(line[i+len(" @ "):]); len(host) > 0 && !strings.HasPrefix(host[0], "[") {
+				e.Host = host[0]
+			}
+		}
+		if i := strings.Index(line, "Id:"); i >= 0 {
+			id, err := strconv.ParseUint(strings.TrimSpace(line[i+len("Id:"):]), 10, 64)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			e.ConnID = id
+		}
+		return nil
+	}
+	fields := strings.Fields(line)
+	for i := 0; i+1 < len(fields); i += 2 {
+		var err error
+		value := fields[i+1]
+		switch strings.TrimSuffix(fields[i], ":") {
+		case "Schema":
+			e.DB = value
+		case "Query_time":
+			e.QueryTime, err = parseSeconds(value)
+		case "Parse_time":
+			e.ParseTime, err = parseSeconds(value)
+		case "Compile_time":
+			e.CompileTime, err = parseSeconds(value)
+		case "Exec_time":
+			e.ExecTime, err = parseSeconds(value)
+		case "Cop_wait_time":
+			e.CopWaitTime, err = parseSeconds(value)
+		case "Rows_sent":
+			e.RowsSent, err = strconv.ParseInt(value, 10, 64)
+		case "Rows_examined":
+			e.RowsExamined, err = strconv.ParseInt(value, 10, 64)
+		case "Plan_digest":
+			e.PlanDigest = value
+		case "Digest":
+			e.Digest = value
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+)
+
+func TestT(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testSlowLogSuite{})
+
+type testSlowLogSuite struct {
+}
+
+func newTestEntry(sql string) *Entry {
+	return &Entry{
+		StartTime:    time.Date(2016, 10, 18, 12, 4, 25, 123456000, time.UTC).Local(),
+		User:         "root",
+		Host:         "127.0.0.1",
+		ConnID:       3,
+		DB:           "test",
+		QueryTime:    1234567 * time.Microsecond,
+		ParseTime:    12 * time.Microsecond,
+		CompileTime:  150 * time.Microsecond,
+		ExecTime:     1234405 * time.Microsecond,
+		CopWaitTime:  1200 * time.Millisecond,
+		RowsSent:     1,
+		RowsExamined: 1000,
+		PlanDigest:   "2a97516c",
+		Digest:       "8d4ebd68",
+		SQL:          sql,
+	}
+}
+
+func (s *testSlowLogSuite) TestFormat(c *C) {
+	defer testleak.AfterTest(c)()
+	e := newTestEntry("select count(*) from t where a > 1")
+	expect := `# Time: 2016-10-18T12:04:25.123456Z
+# User@Host: root[root] @ 127.0.0.1 [127.0.0.1]  Id: 3
+# Schema: test
+# Query_time: 1.234567  Lock_time: 0.000000  Rows_sent: 1  Rows_examined: 1000
+# Parse_time: 0.000012  Compile_time: 0.000150  Exec_time: 1.234405  Cop_wait_time: 1.200000
+# Plan_digest: 2a97516c  Digest: 8d4ebd68
+use test;
+SET timestamp=1476792265;
+select count(*) from t where a > 1;
+`
+	c.Assert(string(e.Format()), Equals, expect)
+}
+
+func (s *testSlowLogSuite) TestParse(c *C) {
+	defer testleak.AfterTest(c)()
+	e1 := newTestEntry("select 1")
+	e2 := newTestEntry("select a,\n  b from t")
+	e2.DB = ""
+	e2.Host = ""
+	var buf bytes.Buffer
+	// The header of the MySQL slow query log is skipped.
+	buf.WriteString("/usr/sbin/mysqld, Version: 5.7.16. started with:\n")
+	buf.Write(e1.Format())
+	buf.Write(e2.Format())
+	entries, err := Parse(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	for i, e := range []*Entry{e1, e2} {
+		c.Assert(entries[i].StartTime.Equal(e.StartTime), IsTrue)
+		entries[i].StartTime = e.StartTime
+		c.Assert(entries[i], DeepEquals, e)
+	}
+
+	_, err = Parse(strings.NewReader("# Time: yesterday\n"))
+	c.Assert(err, NotNil)
+	_, err = Parse(strings.NewReader("# Time: 2016-10-18T12:04:25Z\n# Query_time: x\n"))
+	c.Assert(err, NotNil)
+}
+
+func (s *testSlowLogSuite) TestFile(c *C) {
+	defer testleak.AfterTest(c)()
+	dir, err := ioutil.TempDir("", "slowlog")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	defer SetFile("")
+
+	// Nothing is read if the file is not set.
+	entries, err := Read()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+
+	c.Assert(SetFile(filepath.Join(dir, "no-such-dir", "slow.log")), NotNil)
+	c.Assert(File(), Equals, "")
+
+	path := filepath.Join(dir, "slow.log")
+	c.Assert(SetFile(path), IsNil)
+	c.Assert(File(), Equals, path)
+	Write(newTestEntry("select 1"))
+	Write(newTestEntry("select 2"))
+	entries, err = Read()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[1].SQL, Equals, "select 2")
+
+	// Only the tail of the file is read, the partial entry at the beginning is skipped.
+	defer func(size int64) {
+		maxReadSize = size
+	}(maxReadSize)
+	maxReadSize = int64(len(newTestEntry("select 3").Format())) + 10
+	Write(newTestEntry("select 3"))
+	entries, err = Read()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].SQL, Equals, "select 3")
+}
+
+func (s *testSlowLogSuite) TestMaskPasswords(c *C) {
+	defer testleak.AfterTest(c)()
+	cases := []struct {
+		sql    string
+		masked string
+	}{
+		{"select 'identified by'", "select 'identified by'"},
+		{"CREATE USER 'u'@'%' IDENTIFIED BY 'secret'", "CREATE USER 'u'@'%' IDENTIFIED BY '***'"},
+		{"create user 'a'@'%' identified by 'x', 'b'@'%' identified by \"y\"", "create user 'a'@'%' identified by '***', 'b'@'%' identified by '***'"},
+		{"create user 'u'@'%' identified with caching_sha2_password by 'it''s\\'s'", "create user 'u'@'%' identified with caching_sha2_password by '***'"},
+		{"grant all on *.* to 'u'@'%' identified by password '*0D3CED9'", "grant all on *.* to 'u'@'%' identified by password '***'"},
+		{"SET PASSWORD FOR 'u'@'%' = PASSWORD('secret')", "SET PASSWORD FOR 'u'@'%' = PASSWORD('***')"},
+		{"set password = 'secret'", "set password = '***'"},
+	}
+	for _, ca := range cases {
+		c.Assert(maskPasswords(ca.sql), Equals, ca.masked, Commentf("sql: %s", ca.sql))
+	}
+
+	dir, err := ioutil.TempDir("", "slowlog")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	defer SetFile("")
+	c.Assert(SetFile(filepath.Join(dir, "slow.log")), IsNil)
+	e := newTestEntry("create user 'u'@'%' identified by 'secret'")
+	Write(e)
+	c.Assert(e.SQL, Equals, "create user 'u'@'%' identified by 'secret'")
+	entries, err := Read()
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 1)
+	c.Assert(entries[0].SQL, Equals, "create user 'u'@'%' identified by '***'")
+}